@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// BackupOptions configures a backup. The zero value backs up with the
+// server's configured defaults.
+type BackupOptions struct {
+	// CustomName is the archive's filename. Its extension selects the
+	// format unless Format is set.
+	CustomName string `json:"customName,omitempty"`
+	// BackupDir saves the backup to this directory on the server rather
+	// than the configured target
+	BackupDir string `json:"backupDir,omitempty"`
+	// Format is tar, gzip, zstd, xz or vhdx
+	Format string `json:"format,omitempty"`
+	// Encrypt overrides backup_encryption.enabled when set
+	Encrypt *bool `json:"encrypt,omitempty"`
+	// Target is a name from backup_targets or a target URI
+	Target string `json:"target,omitempty"`
+}
+
+// Backup starts a job backing up a distro. Follow it with WaitForJob and
+// decode its result into a BackupJobResult.
+func (c *Client) Backup(ctx context.Context, distro string, opts BackupOptions) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodPost, "/distros/"+url.PathEscape(distro)+"/backups", nil, opts, &job)
+	return job, err
+}
+
+// ListBackups lists the backups in the backup directory, or only those of
+// distro if it isn't empty
+func (c *Client) ListBackups(ctx context.Context, distro string) ([]BackupInfo, error) {
+	query := url.Values{}
+	if distro != "" {
+		query.Set("distro", distro)
+	}
+
+	var response struct {
+		Backups []BackupInfo `json:"backups"`
+	}
+	err := c.do(ctx, http.MethodGet, "/backups", query, nil, &response)
+	return response.Backups, err
+}
+
+// PruneOptions configures pruning backups
+type PruneOptions struct {
+	// DryRun reports what would be deleted without deleting it
+	DryRun    bool   `json:"dryRun,omitempty"`
+	BackupDir string `json:"backupDir,omitempty"`
+	// Policy replaces the configured retention policy
+	Policy *RetentionPolicy `json:"policy,omitempty"`
+}
+
+// PruneBackups deletes the backups not kept by the retention policy
+func (c *Client) PruneBackups(ctx context.Context, opts PruneOptions) (PruneResponse, error) {
+	var response PruneResponse
+	err := c.do(ctx, http.MethodPost, "/backups/prune", nil, opts, &response)
+	return response, err
+}
+
+// ListBackupFiles lists the entries in a directory inside a backup, and
+// with recursive everything below it too
+func (c *Client) ListBackupFiles(ctx context.Context, id, path string, recursive bool) ([]BackupEntry, error) {
+	query := url.Values{"path": {path}}
+	if recursive {
+		query.Set("recursive", "true")
+	}
+
+	var response struct {
+		Entries []BackupEntry `json:"entries"`
+	}
+	err := c.do(ctx, http.MethodGet, "/backups/"+url.PathEscape(id)+"/files", query, nil, &response)
+	return response.Entries, err
+}
+
+// DownloadBackupFile returns the content of a file inside a backup. The
+// caller must close it.
+func (c *Client) DownloadBackupFile(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	query := url.Values{"path": {path}, "download": {"true"}}
+	resp, err := c.send(ctx, http.MethodGet, "/backups/"+url.PathEscape(id)+"/files", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// RestoreOptions configures restoring a backup
+type RestoreOptions struct {
+	// NewName registers the restored distro under a different name
+	NewName    string `json:"newName,omitempty"`
+	InstallDir string `json:"installDir,omitempty"`
+	// Overwrite replaces a registered distro with the same name
+	Overwrite bool   `json:"overwrite,omitempty"`
+	BackupDir string `json:"backupDir,omitempty"`
+}
+
+// Restore re-imports the backup with the given ID
+func (c *Client) Restore(ctx context.Context, id string, opts RestoreOptions) (RestoreResult, error) {
+	var result RestoreResult
+	err := c.do(ctx, http.MethodPost, "/backups/"+url.PathEscape(id)+"/restore", nil, opts, &result)
+	return result, err
+}
@@ -0,0 +1,240 @@
+// Package client calls the HTTP API served by wslp serve from Go.
+//
+// Each method mirrors an /api/v1 endpoint and returns the same types the
+// server and the wslp commands use, such as DistroInfo and BackupResult.
+// Errors reported by the server are returned as *Error, whose Code can be
+// matched on. Every method takes a context, and cancelling it aborts the
+// request.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wslp/internal/socket"
+)
+
+// Client calls the wslp API
+type Client struct {
+	// BaseURL is where the server runs, e.g. http://127.0.0.1:8080
+	BaseURL string
+	// Token is sent as a bearer token. The server requires it for every
+	// request over TCP.
+	Token string
+	// HTTPClient makes the requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// New creates a client for the server at baseURL, authenticating with
+// token. baseURL may also be the Unix domain socket or named pipe the
+// server listens on, e.g. unix:///run/wslp.sock or npipe://wslp, which
+// need no token.
+func New(baseURL, token string) *Client {
+	if strings.HasPrefix(baseURL, "http://") || strings.HasPrefix(baseURL, "https://") {
+		return &Client{
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Token:   token,
+		}
+	}
+
+	network, path, err := socket.Parse(baseURL)
+	if err != nil {
+		// Leave it to the first request to report the bad URL
+		return &Client{BaseURL: baseURL, Token: token}
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return socket.Dial(ctx, network, path)
+		},
+	}
+	return &Client{
+		// The host is ignored, as every connection goes to the socket
+		BaseURL:    "http://wslp",
+		Token:      token,
+		HTTPClient: &http.Client{Transport: transport},
+	}
+}
+
+// ReadToken reads the API token the server on port wrote to stateDir
+// when it started (state_dir in the config, %USERPROFILE%\.wslp by
+// default)
+func ReadToken(stateDir, port string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, fmt.Sprintf("server-%s.token", port)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Error is an error reported by the server
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Code identifies the error, e.g. invalid_request or job_not_found
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// ErrorCode returns the code of the server error in err's chain, or "" if
+// there is none
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// do sends a request to the /api/v1 path with body encoded as JSON (if
+// not nil), and decodes the JSON response into out (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request to the /api/v1 path with the extra header, if any,
+// returning the response if it succeeded. The caller must close its body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}, header http.Header) (*http.Response, error) {
+	u := c.BaseURL + "/api/v1" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// decodeError reads the error from a failed response
+func decodeError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Code != "" {
+		return &Error{StatusCode: resp.StatusCode, Code: body.Error.Code, Message: body.Error.Message}
+	}
+
+	// Not an error from the API, e.g. from a proxy in front of it
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: message}
+}
+
+// Shutdown stops the server
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/shutdown", nil, nil, nil)
+}
+
+// AuditOptions selects entries from the audit log
+type AuditOptions struct {
+	// Distro selects the actions on a distro, including renames and copies
+	// to its name
+	Distro string
+	// Action is unregister, rename, set-default, copy, telemetry or
+	// shutdown
+	Action string
+	// Since is how long ago (e.g. 24h, 7d), a date or an RFC 3339 time
+	Since string
+	// Limit caps how many of the most recent entries are returned. Zero
+	// uses the server's default of 100.
+	Limit int
+}
+
+// Audit lists the actions recorded in the audit log, oldest first
+func (c *Client) Audit(ctx context.Context, opts AuditOptions) ([]AuditEntry, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"distro": opts.Distro, "action": opts.Action, "since": opts.Since} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var response struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	err := c.do(ctx, http.MethodGet, "/audit", query, nil, &response)
+	return response.Entries, err
+}
+
+// OpenAPI returns the OpenAPI document describing the API
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/openapi.json", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
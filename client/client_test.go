@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer serves handler for the duration of a test, returning a
+// client for it
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return New(ts.URL+"/", "secret")
+}
+
+func TestSend(t *testing.T) {
+	t.Run("sends the token and JSON body", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/distros/My Distro" {
+				t.Errorf("unexpected path %q", r.URL.Path)
+			}
+			if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("unexpected Authorization %q", auth)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("unexpected Content-Type %q", ct)
+			}
+			fmt.Fprint(w, `{"oldName":"My Distro","newName":"Ubuntu","success":true}`)
+		})
+
+		result, err := c.Rename(context.Background(), "My Distro", "Ubuntu")
+		if err != nil || !result.Success || result.NewName != "Ubuntu" {
+			t.Errorf("unexpected result %+v, %v", result, err)
+		}
+	})
+
+	t.Run("decodes error responses", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"job_not_found","message":"job missing not found"}}`)
+		})
+
+		_, err := c.GetJob(context.Background(), "missing")
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *Error, got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "job_not_found" || apiErr.Message != "job missing not found" {
+			t.Errorf("unexpected error %+v", apiErr)
+		}
+		if ErrorCode(err) != "job_not_found" {
+			t.Errorf("unexpected code %q", ErrorCode(err))
+		}
+	})
+
+	t.Run("keeps plain text errors", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		})
+
+		err := c.Shutdown(context.Background())
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.Message != "bad gateway" || apiErr.Code != "" {
+			t.Errorf("unexpected error %v", err)
+		}
+		if err.Error() != "bad gateway (502)" {
+			t.Errorf("unexpected message %q", err.Error())
+		}
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		release := make(chan struct{})
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		})
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := c.ListDistros(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+}
+
+func TestReadToken(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "server-8080.token"), []byte("abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := ReadToken(dir, "8080")
+	if err != nil || token != "abc123" {
+		t.Errorf("expected abc123, got %q, %v", token, err)
+	}
+	if _, err := ReadToken(dir, "9090"); err == nil {
+		t.Error("expected an error for a missing token file")
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	var polls atomic.Int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		state := JobRunning
+		if polls.Add(1) > 1 {
+			state = JobSucceeded
+		}
+		fmt.Fprintf(w, `{"id":"job-1","kind":"install","state":%q,"result":{"results":[{"distro":"Ubuntu","success":true}]}}`, state)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := c.WaitForJob(ctx, "job-1")
+	if err != nil || job.State != JobSucceeded || polls.Load() != 2 {
+		t.Fatalf("expected the job to succeed on the second poll, got %+v after %d polls, %v", job, polls.Load(), err)
+	}
+
+	var result InstallJobResult
+	if err := job.DecodeResult(&result); err != nil || len(result.Results) != 1 || result.Results[0].Distro != "Ubuntu" {
+		t.Errorf("unexpected result %+v, %v", result, err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("types") != "distro.,job.finished" {
+			t.Errorf("unexpected types %q", r.URL.Query().Get("types"))
+		}
+		if id := r.Header.Get("Last-Event-ID"); id != "4" {
+			t.Errorf("unexpected Last-Event-ID %q", id)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprint(w, "id: 5\nevent: distro.added\ndata: {\"name\":\"Ubuntu\"}\n\n")
+		fmt.Fprint(w, "id: 6\nevent: job.finished\ndata: {\"id\":\"job-1\"}\n\n")
+	})
+
+	events, err := c.Events(context.Background(), 4, "distro.", "job.finished")
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+
+	var got []Event
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %+v", got)
+	}
+	if got[0].ID != 5 || got[0].Type != "distro.added" || string(got[0].Data) != `{"name":"Ubuntu"}` {
+		t.Errorf("unexpected first event %+v", got[0])
+	}
+	if got[1].ID != 6 || got[1].Type != "job.finished" {
+		t.Errorf("unexpected second event %+v", got[1])
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListDistros lists the registered distros
+func (c *Client) ListDistros(ctx context.Context) ([]DistroInfo, error) {
+	var response struct {
+		Distros []DistroInfo `json:"distros"`
+	}
+	err := c.do(ctx, http.MethodGet, "/distros", nil, nil, &response)
+	return response.Distros, err
+}
+
+// GetDistro returns the details of a distro
+func (c *Client) GetDistro(ctx context.Context, name string) (DistroDetailInfo, error) {
+	var info DistroDetailInfo
+	err := c.do(ctx, http.MethodGet, "/distros/"+url.PathEscape(name), nil, nil, &info)
+	return info, err
+}
+
+// ListAvailable lists the distros available to install
+func (c *Client) ListAvailable(ctx context.Context) ([]AvailableDistro, error) {
+	var response struct {
+		Available []AvailableDistro `json:"available"`
+	}
+	err := c.do(ctx, http.MethodGet, "/available", nil, nil, &response)
+	return response.Available, err
+}
+
+// Install starts a job installing distros. Follow it with WaitForJob.
+func (c *Client) Install(ctx context.Context, distros ...string) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodPost, "/distros", nil, map[string]interface{}{"distros": distros}, &job)
+	return job, err
+}
+
+// Unregister unregisters a distro, deleting its data
+func (c *Client) Unregister(ctx context.Context, name string) (UnregisterResult, error) {
+	var result UnregisterResult
+	err := c.do(ctx, http.MethodDelete, "/distros/"+url.PathEscape(name), nil, nil, &result)
+	return result, err
+}
+
+// Terminate stops a running distro
+func (c *Client) Terminate(ctx context.Context, name string) (TerminateResult, error) {
+	var result TerminateResult
+	err := c.do(ctx, http.MethodPost, "/distros/"+url.PathEscape(name)+"/terminate", nil, nil, &result)
+	return result, err
+}
+
+// Launch opens a distro in a new terminal window on the server's desktop
+func (c *Client) Launch(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/distros/"+url.PathEscape(name)+"/launch", nil, nil, nil)
+}
+
+// Rename renames a distro
+func (c *Client) Rename(ctx context.Context, oldName, newName string) (RenameResult, error) {
+	var result RenameResult
+	err := c.do(ctx, http.MethodPatch, "/distros/"+url.PathEscape(oldName), nil, map[string]string{"name": newName}, &result)
+	return result, err
+}
+
+// Copy starts a job copying source to a new distro, newName. An empty
+// installDir uses the server's default location.
+func (c *Client) Copy(ctx context.Context, source, newName, installDir string) (Job, error) {
+	body := map[string]string{"newName": newName}
+	if installDir != "" {
+		body["installDir"] = installDir
+	}
+
+	var job Job
+	err := c.do(ctx, http.MethodPost, "/distros/"+url.PathEscape(source)+"/copy", nil, body, &job)
+	return job, err
+}
+
+// GetDefault returns the name of the default distro
+func (c *Client) GetDefault(ctx context.Context) (string, error) {
+	var response struct {
+		Default string `json:"default"`
+	}
+	err := c.do(ctx, http.MethodGet, "/default", nil, nil, &response)
+	return response.Default, err
+}
+
+// SetDefault makes name the default distro
+func (c *Client) SetDefault(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPut, "/default", nil, map[string]string{"name": name}, nil)
+}
+
+// Workshops lists the Workshop environments in a distro
+func (c *Client) Workshops(ctx context.Context, distro string) ([]WorkshopInfo, error) {
+	var response struct {
+		Workshops []WorkshopInfo `json:"workshops"`
+	}
+	err := c.do(ctx, http.MethodGet, "/distros/"+url.PathEscape(distro)+"/workshops", nil, nil, &response)
+	return response.Workshops, err
+}
+
+// StartWorkshop starts a Workshop environment
+func (c *Client) StartWorkshop(ctx context.Context, distro, project, name string) error {
+	return c.workshopAction(ctx, distro, project, name, "start")
+}
+
+// StopWorkshop stops a Workshop environment
+func (c *Client) StopWorkshop(ctx context.Context, distro, project, name string) error {
+	return c.workshopAction(ctx, distro, project, name, "stop")
+}
+
+// WorkshopShell opens a shell in a Workshop environment, in a new
+// terminal window on the server's desktop
+func (c *Client) WorkshopShell(ctx context.Context, distro, project, name string) error {
+	return c.workshopAction(ctx, distro, project, name, "shell")
+}
+
+func (c *Client) workshopAction(ctx context.Context, distro, project, name, action string) error {
+	path := "/distros/" + url.PathEscape(distro) + "/workshops/" + url.PathEscape(project) + "/" + url.PathEscape(name) + "/" + action
+	return c.do(ctx, http.MethodPost, path, nil, nil, nil)
+}
+
+// WSLInfo returns information about WSL
+func (c *Client) WSLInfo(ctx context.Context) (WSLSystemInfo, error) {
+	var info WSLSystemInfo
+	err := c.do(ctx, http.MethodGet, "/wsl", nil, nil, &info)
+	return info, err
+}
+
+// UbuntuTelemetry reports whether Ubuntu telemetry is enabled
+func (c *Client) UbuntuTelemetry(ctx context.Context) (bool, error) {
+	var response struct {
+		Enabled bool `json:"enabled"`
+	}
+	err := c.do(ctx, http.MethodGet, "/settings/ubuntu-telemetry", nil, nil, &response)
+	return response.Enabled, err
+}
+
+// SetUbuntuTelemetry enables or disables Ubuntu telemetry
+func (c *Client) SetUbuntuTelemetry(ctx context.Context, enabled bool) error {
+	return c.do(ctx, http.MethodPut, "/settings/ubuntu-telemetry", nil, map[string]bool{"enabled": enabled}, nil)
+}
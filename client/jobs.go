@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jobPollInterval is how often WaitForJob checks on a job
+const jobPollInterval = 500 * time.Millisecond
+
+// Jobs lists the jobs, newest first
+func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
+	var response struct {
+		Jobs []Job `json:"jobs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/jobs", nil, nil, &response)
+	return response.Jobs, err
+}
+
+// GetJob returns a job
+func (c *Client) GetJob(ctx context.Context, id string) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, &job)
+	return job, err
+}
+
+// CancelJob cancels a queued or running job
+func (c *Client) CancelJob(ctx context.Context, id string) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil, &job)
+	return job, err
+}
+
+// WaitForJob polls a job until it finishes or ctx is done, returning the
+// finished job
+func (c *Client) WaitForJob(ctx context.Context, id string) (Job, error) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil || job.State.Finished() {
+			return job, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedules lists the backup schedules with their last and next runs
+func (c *Client) Schedules(ctx context.Context) ([]ScheduleStatus, error) {
+	var response struct {
+		Schedules []ScheduleStatus `json:"schedules"`
+	}
+	err := c.do(ctx, http.MethodGet, "/schedules", nil, nil, &response)
+	return response.Schedules, err
+}
+
+// RunSchedule runs a backup schedule now, returning its status once the
+// run finishes
+func (c *Client) RunSchedule(ctx context.Context, id string) (ScheduleStatus, error) {
+	var status ScheduleStatus
+	err := c.do(ctx, http.MethodPost, "/schedules/"+url.PathEscape(id)+"/run", nil, nil, &status)
+	return status, err
+}
+
+// Events streams the server's events until ctx is done or the connection
+// drops, when the channel is closed. types limits the stream to event
+// types or prefixes, e.g. "distro." or "job.finished". A non-zero lastID
+// first replays the recent events after it, so a dropped stream can be
+// resumed from the ID of the last event received.
+func (c *Client) Events(ctx context.Context, lastID uint64, types ...string) (<-chan Event, error) {
+	query := url.Values{}
+	if len(types) > 0 {
+		query.Set("types", strings.Join(types, ","))
+	}
+
+	header := http.Header{}
+	if lastID > 0 {
+		header.Set("Last-Event-ID", strconv.FormatUint(lastID, 10))
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/events", query, nil, header)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var event Event
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.Type == "" {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				event = Event{}
+			case strings.HasPrefix(line, "id: "):
+				event.ID, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+			case strings.HasPrefix(line, "event: "):
+				event.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.Data = append(event.Data, strings.TrimPrefix(line, "data: ")...)
+			}
+		}
+	}()
+	return events, nil
+}
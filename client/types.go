@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"wslp/internal/audit"
+	"wslp/internal/config"
+	"wslp/internal/wsl"
+)
+
+// The types the API returns, shared with the server and the wslp commands
+type (
+	DistroInfo       = wsl.DistroInfo
+	DistroDetailInfo = wsl.DistroDetailInfo
+	AvailableDistro  = wsl.AvailableDistro
+	WSLSystemInfo    = wsl.WSLSystemInfo
+	InstallResult    = wsl.InstallResult
+	UnregisterResult = wsl.UnregisterResult
+	TerminateResult  = wsl.TerminateResult
+	RenameResult     = wsl.RenameResult
+	CopyResult       = wsl.CopyResult
+	BackupResult     = wsl.BackupResult
+	BackupInfo       = wsl.BackupInfo
+	BackupManifest   = wsl.BackupManifest
+	BackupEntry      = wsl.BackupEntry
+	PruneResult      = wsl.PruneResult
+	RestoreResult    = wsl.RestoreResult
+	WorkshopInfo     = wsl.WorkshopInfo
+	RetentionPolicy  = config.RetentionPolicy
+	BackupSchedule   = config.BackupSchedule
+	AuditEntry       = audit.Entry
+	AuditActor       = audit.Actor
+)
+
+// JobState is the state of a job or of one distro within it
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// Finished reports whether the state is final
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// JobItem is the progress of one distro within a job
+type JobItem struct {
+	Distro     string     `json:"distro"`
+	State      JobState   `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Job is an install, backup or copy the server runs in the background
+type Job struct {
+	ID string `json:"id"`
+	// Kind is the operation: install, backup or copy
+	Kind  string   `json:"kind"`
+	State JobState `json:"state"`
+	// Error says why the job failed or was cancelled
+	Error string    `json:"error,omitempty"`
+	Items []JobItem `json:"items"`
+	// Result is set once the job finishes. Decode it with DecodeResult.
+	Result     json.RawMessage `json:"result,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// DecodeResult decodes the job's result into v: an *InstallJobResult for
+// installs, a *BackupJobResult for backups or a *CopyResult for copies
+func (j Job) DecodeResult(v interface{}) error {
+	if len(j.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Result, v)
+}
+
+// InstallJobResult is the result of an install job
+type InstallJobResult struct {
+	Results []InstallResult `json:"results"`
+}
+
+// BackupJobResult is the result of a backup job
+type BackupJobResult struct {
+	Results []BackupResult `json:"results"`
+	// Pruned lists the backups deleted by the retention policy afterwards
+	Pruned     []PruneResult `json:"pruned,omitempty"`
+	PruneError string        `json:"pruneError,omitempty"`
+}
+
+// ScheduleStatus is a backup schedule along with its last and next runs
+type ScheduleStatus struct {
+	BackupSchedule
+	NextRun time.Time  `json:"nextRun"`
+	Running bool       `json:"running"`
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	// LastDuration is how long the last run took, in seconds
+	LastDuration float64 `json:"lastDuration,omitempty"`
+	// LastStatus is success, partial or failed
+	LastStatus  string `json:"lastStatus,omitempty"`
+	LastMessage string `json:"lastMessage,omitempty"`
+	// LastTrigger is schedule, catch-up or manual
+	LastTrigger string         `json:"lastTrigger,omitempty"`
+	LastResults []BackupResult `json:"lastResults,omitempty"`
+	LastPruned  []PruneResult  `json:"lastPruned,omitempty"`
+}
+
+// PruneResponse is the outcome of pruning backups
+type PruneResponse struct {
+	Results    []PruneResult `json:"results"`
+	FreedBytes int64         `json:"freedBytes"`
+	DryRun     bool          `json:"dryRun"`
+}
+
+// Event is a server-sent event, such as job.finished or distro.added
+type Event struct {
+	ID   uint64
+	Type string
+	// Data is the event's JSON data
+	Data json.RawMessage
+}
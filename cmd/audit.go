@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"wslp/internal/audit"
+)
+
+// AuditCmd prints the entries of the audit log selected by filter, oldest
+// first
+func AuditCmd(w io.Writer, log *audit.Log, filter audit.Filter) error {
+	entries, err := log.Read(filter)
+	if err != nil {
+		return err
+	}
+
+	// The columns of each entry, the same for text and tables
+	columns := func(e audit.Entry) []string {
+		return []string{e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, auditTarget(e), resultColumn(e.Success), auditActor(e.Actor)}
+	}
+	header := []string{"TIME", "ACTION", "DISTRO", "RESULT", "BY"}
+
+	return render(w, entries, func() *table {
+		t := newTable(append(header, "ERROR")...)
+		for _, e := range entries {
+			t.add(append(columns(e), e.Error)...)
+		}
+		return t
+	}, func() {
+		if len(entries) == 0 {
+			fmt.Fprintln(w, "No actions recorded")
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, e := range entries {
+			fmt.Fprintln(tw, strings.Join(columns(e), "\t"))
+			if e.Error != "" {
+				fmt.Fprintf(tw, "\t\t\t\t  %s\n", e.Error)
+			}
+		}
+		tw.Flush()
+	})
+}
+
+// auditTarget describes what an action changed, e.g. Ubuntu -> Work for a
+// rename
+func auditTarget(e audit.Entry) string {
+	switch {
+	case e.Details["newName"] != "":
+		return e.Distro + " -> " + e.Details["newName"]
+	case e.Action == audit.ActionTelemetry:
+		if e.Details["enabled"] == "true" {
+			return "(enabled)"
+		}
+		return "(disabled)"
+	case e.Distro == "":
+		return "-"
+	}
+	return e.Distro
+}
+
+// auditActor describes who made a change: the command line for the CLI,
+// or the client for the API
+func auditActor(a audit.Actor) string {
+	var who string
+	if a.Tool == audit.ToolAPI {
+		who = "api"
+		if a.UserAgent != "" {
+			who += " " + a.UserAgent
+		}
+		if a.Remote != "" {
+			who += " from " + a.Remote
+		}
+	} else {
+		who = a.Tool
+		if a.Command != "" {
+			who += ": " + a.Command
+		}
+	}
+	if a.User != "" {
+		who += " (" + a.User + ")"
+	}
+	return who
+}
+
+func init() {
+	RootCmd.AddCommand(newAuditCmd())
+}
+
+func newAuditCmd() *cobra.Command {
+	var distro, action, since string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show the log of destructive actions",
+		Long: `Show the audit log, which records every unregister, rename, copy and change of
+the default distro, whether made with wslp or through the API server (and so
+the GUI), along with Ubuntu telemetry changes and server shutdowns.
+
+Each entry records when the action happened, whether it succeeded, and who
+made it: the command line and user for wslp commands, or the client's user
+agent and address for API requests.
+
+The log is kept as JSON lines in audit.log in the state directory
+(state_dir, %USERPROFILE%\.wslp by default), or the file set by audit_log in
+~/.wslp.yaml. Entries are only ever appended.
+
+Use --distro to show the actions on a distro, including renames and copies
+to its name, --action to show one kind of action and --since to show recent
+ones, e.g. --since 24h, --since 7d or --since 2024-03-01.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			start, err := audit.ParseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			return AuditCmd(cmd.OutOrStdout(), audit.Default, audit.Filter{
+				Distro: distro,
+				Action: action,
+				Since:  start,
+				Limit:  limit,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&distro, "distro", "", "Only show actions on this distro")
+	cmd.Flags().StringVar(&action, "action", "", "Only show this action: unregister, rename, set-default, copy, telemetry or shutdown")
+	cmd.Flags().StringVar(&since, "since", "", "Only show actions since this long ago (e.g. 24h, 7d) or this date")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 50, "Show at most this many of the most recent actions (0 for all)")
+
+	return cmd
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wslp/internal/audit"
+)
+
+// useAuditLog records to a fresh audit log for the duration of a test
+func useAuditLog(t *testing.T) *audit.Log {
+	t.Helper()
+
+	previous := audit.Default
+	audit.Default = audit.New(filepath.Join(t.TempDir(), "audit.log"))
+	t.Cleanup(func() { audit.Default = previous })
+	return audit.Default
+}
+
+func TestAuditCommand(t *testing.T) {
+	t.Run("command metadata", func(t *testing.T) {
+		auditCmd, _, err := RootCmd.Find([]string{"audit"})
+		if err != nil {
+			t.Fatalf("audit command not found: %v", err)
+		}
+		if auditCmd.Short == "" || auditCmd.Long == "" {
+			t.Error("descriptions are empty")
+		}
+		for _, flag := range []string{"distro", "action", "since", "limit"} {
+			if auditCmd.Flags().Lookup(flag) == nil {
+				t.Errorf("--%s flag not found", flag)
+			}
+		}
+	})
+
+	t.Run("shows actions made by commands", func(t *testing.T) {
+		log := useAuditLog(t)
+		mock := &mockUnregisterer{shouldFail: true, failOn: "Debian"}
+		UnregisterDistrosCmd(context.Background(), mock, new(bytes.Buffer), []string{"Ubuntu"})
+
+		out := new(bytes.Buffer)
+		if err := AuditCmd(out, log, audit.Filter{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected a header and one entry, got:\n%s", out.String())
+		}
+		for _, phrase := range []string{"unregister", "Ubuntu", "ok", "cli: "} {
+			if !strings.Contains(lines[1], phrase) {
+				t.Errorf("expected %q in %q", phrase, lines[1])
+			}
+		}
+	})
+
+	t.Run("shows who made API changes and why they failed", func(t *testing.T) {
+		log := useAuditLog(t)
+		ctx := audit.WithActor(context.Background(), audit.Actor{Tool: audit.ToolAPI, Remote: "127.0.0.1:50000", UserAgent: "Dart/3.5"})
+		log.Record(ctx, audit.Entry{Action: audit.ActionRename, Distro: "Ubuntu", Details: map[string]string{"newName": "Work"}, Error: "access denied"})
+		log.Record(ctx, audit.Entry{Action: audit.ActionSetDefault, Distro: "Debian", Success: true})
+
+		out := new(bytes.Buffer)
+		if err := AuditCmd(out, log, audit.Filter{Distro: "work"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		for _, phrase := range []string{"Ubuntu -> Work", "failed", "api Dart/3.5 from 127.0.0.1:50000", "access denied"} {
+			if !strings.Contains(output, phrase) {
+				t.Errorf("expected output to contain %q, got:\n%s", phrase, output)
+			}
+		}
+		if strings.Contains(output, "Debian") {
+			t.Errorf("expected only actions on Work, got:\n%s", output)
+		}
+	})
+
+	t.Run("says when nothing was recorded", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := AuditCmd(out, useAuditLog(t), audit.Filter{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(out.String(), "No actions recorded") {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+	})
+}
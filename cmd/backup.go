@@ -4,49 +4,106 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"wslp/internal/config"
 	"wslp/internal/wsl"
 )
 
-// BackupDistrosCmd backs up one or more WSL distributions.
-func BackupDistrosCmd(ctx context.Context, b wsl.Backuper, w io.Writer, distros []string, customName, backupDir string) error {
+// backupOutput is what backup prints for --output json and yaml: the same
+// result as the API's backup jobs
+type backupOutput struct {
+	Results []wsl.BackupResult `json:"results"`
+	// Pruned lists the backups deleted by the retention policy afterwards
+	Pruned     []wsl.PruneResult `json:"pruned,omitempty"`
+	PruneError string            `json:"pruneError,omitempty"`
+}
+
+// BackupDistrosCmd backs up one or more WSL distributions to backupDir and
+// then opts.Target, if set, as resolved by wsl.ResolveBackupDestination.
+func BackupDistrosCmd(ctx context.Context, b wsl.Backuper, w io.Writer, distros []string, backupDir string, opts wsl.BackupOptions) error {
 	// If customName is provided but multiple distros, return error
-	if customName != "" && len(distros) > 1 {
+	if opts.CustomName != "" && len(distros) > 1 {
 		return fmt.Errorf("custom name can only be used when backing up a single distribution")
 	}
 
-	// Determine backup directory
-	if backupDir == "" {
-		backupDir = config.GetBackupDir()
+	destination := backupDir
+	if opts.Target != nil {
+		destination = opts.Target.URI("")
 	}
 
 	// Ensure backup directory exists
-	if err := config.EnsureBackupDir(); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
-	}
-
-	opts := wsl.BackupOptions{
-		CustomName: customName,
+	dryRun := wsl.IsDryRun(ctx)
+	if !dryRun {
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
 	}
 
 	results := wsl.BackupDistros(ctx, b, distros, backupDir, opts)
+	output := backupOutput{Results: results}
 
-	// Print results
 	successCount := 0
 	for _, result := range results {
 		if result.Success {
 			successCount++
-			fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
-			fmt.Fprintf(w, "  Saved to: %s\n", result.FilePath)
-		} else {
-			fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
 		}
 	}
 
-	if successCount > 0 {
-		fmt.Fprintf(w, "\nSuccessfully backed up %d/%d distribution(s) to %s\n", successCount, len(results), backupDir)
+	// Apply the retention policy, if one is configured. Only local
+	// backups can be listed, so remote targets are not pruned.
+	if opts.Target == nil && !dryRun {
+		pruned, err := wsl.AutoPrune(results, backupDir, config.GetRetentionPolicy())
+		if err != nil {
+			output.PruneError = err.Error()
+		}
+		output.Pruned = pruned
+	}
+
+	err := render(w, output, func() *table {
+		t := newTable("DISTRO", "RESULT", "SIZE", "FILE", "MESSAGE")
+		for _, result := range results {
+			t.add(result.Distro, resultColumn(result.Success), formatSize(result.Size), result.FilePath, result.Message)
+		}
+		for _, result := range output.Pruned {
+			t.add(result.Distro, "pruned", formatSize(result.Size), result.FilePath, result.Message)
+		}
+		return t
+	}, func() {
+		for _, result := range results {
+			if !result.Success {
+				fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
+				continue
+			}
+			fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
+			if !dryRun {
+				fmt.Fprintf(w, "  Saved to: %s\n", result.FilePath)
+			}
+		}
+
+		if successCount > 0 {
+			summary := "Successfully backed up %d/%d distribution(s) to %s"
+			if dryRun {
+				summary = "Dry run: %d/%d distribution(s) would be backed up to %s"
+			}
+			fmt.Fprintf(w, "\n"+summary+"\n", successCount, len(results), destination)
+		}
+
+		if output.PruneError != "" {
+			fmt.Fprintf(w, "\nWarning: failed to prune old backups: %s\n", output.PruneError)
+		} else if len(output.Pruned) > 0 {
+			fmt.Fprintf(w, "\nPruning old backups per retention policy:\n")
+			printPruneResults(w, output.Pruned, false)
+		}
+	})
+	if err != nil {
+		return err
 	}
 
 	if successCount < len(results) {
@@ -56,33 +113,812 @@ func BackupDistrosCmd(ctx context.Context, b wsl.Backuper, w io.Writer, distros
 	return nil
 }
 
+// pruneOutput is what backup prune prints for --output json and yaml, the
+// same as the API's response
+type pruneOutput struct {
+	Results    []wsl.PruneResult `json:"results"`
+	FreedBytes int64             `json:"freedBytes"`
+	DryRun     bool              `json:"dryRun"`
+}
+
+// PruneBackupsCmd deletes the backups not kept by policy. With dryRun it
+// only prints what would be deleted.
+func PruneBackupsCmd(w io.Writer, backupDir string, policy config.RetentionPolicy, dryRun bool) error {
+	if !policy.Enabled() {
+		return fmt.Errorf("no retention policy configured (set backup_retention in ~/.wslp.yaml or pass --keep-* flags)")
+	}
+
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	results, err := wsl.PruneBackups(backupDir, policy, dryRun)
+	if err != nil {
+		return err
+	}
+
+	output := pruneOutput{Results: results, DryRun: dryRun}
+	deleted := 0
+	for _, result := range results {
+		if result.Success {
+			deleted++
+			output.FreedBytes += result.Size
+		}
+	}
+
+	err = render(w, output, func() *table {
+		t := newTable("DISTRO", "RESULT", "SIZE", "FILE", "MESSAGE")
+		for _, result := range results {
+			t.add(result.Distro, resultColumn(result.Success), formatSize(result.Size), result.FilePath, result.Message)
+		}
+		return t
+	}, func() {
+		if len(results) == 0 {
+			fmt.Fprintln(w, "Nothing to prune")
+			return
+		}
+		printPruneResults(w, results, dryRun)
+	})
+	if err != nil {
+		return err
+	}
+
+	if deleted < len(results) {
+		return fmt.Errorf("some backups could not be deleted")
+	}
+
+	return nil
+}
+
+// ListBackupFilesCmd lists the contents of a directory inside a backup
+// without restoring it. backup is an archive path or a distro name, as for
+// resolveBackup.
+func ListBackupFilesCmd(w io.Writer, backup, dir, backupDir, timestamp string, recursive bool) error {
+	archive, err := resolveBackup(backup, backupDir, timestamp)
+	if err != nil {
+		return err
+	}
+
+	entries, err := wsl.ListBackupFiles(archive, dir, recursive)
+	if err != nil {
+		return err
+	}
+
+	// The columns of each entry, the same for text and tables
+	columns := func(e wsl.BackupEntry) []string {
+		name := e.Path
+		if e.LinkTarget != "" {
+			name += " -> " + e.LinkTarget
+		}
+		return []string{e.Mode, formatSize(e.Size), e.ModTime.Local().Format("2006-01-02 15:04"), name}
+	}
+
+	return render(w, entries, func() *table {
+		t := newTable("MODE", "SIZE", "MODIFIED", "PATH")
+		for _, e := range entries {
+			t.add(columns(e)...)
+		}
+		return t
+	}, func() {
+		if len(entries) == 0 {
+			fmt.Fprintf(w, "%s is empty\n", dir)
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintln(tw, strings.Join(columns(e), "\t"))
+		}
+		tw.Flush()
+	})
+}
+
+// ExtractBackupFilesCmd copies a file or directory out of a backup to dest
+// without restoring the distro.
+func ExtractBackupFilesCmd(w io.Writer, backup, src, dest, backupDir, timestamp string) error {
+	archive, err := resolveBackup(backup, backupDir, timestamp)
+	if err != nil {
+		return err
+	}
+
+	result, err := wsl.ExtractBackupFiles(archive, src, dest)
+	if err != nil {
+		return err
+	}
+
+	return render(w, result, func() *table {
+		t := newTable("SOURCE", "DESTINATION", "FILES", "SIZE", "SKIPPED")
+		t.add(result.Source, result.Destination, strconv.Itoa(result.Files), formatSize(result.Bytes), strconv.Itoa(len(result.Skipped)))
+		return t
+	}, func() {
+		fmt.Fprintf(w, "✓ Extracted %d file(s) (%s) from %s to %s\n", result.Files, formatSize(result.Bytes), result.Source, result.Destination)
+		for _, skipped := range result.Skipped {
+			fmt.Fprintf(w, "  Skipped %s\n", skipped)
+		}
+	})
+}
+
+// DiffBackupsCmd reports the files added, removed or modified between two
+// backups. Each backup is an archive path or a distro name, optionally
+// followed by @<timestamp> to select a backup other than the latest.
+func DiffBackupsCmd(w io.Writer, oldRef, newRef, backupDir string) error {
+	oldArchive, err := resolveBackup(splitBackupRef(oldRef, backupDir))
+	if err != nil {
+		return err
+	}
+	newArchive, err := resolveBackup(splitBackupRef(newRef, backupDir))
+	if err != nil {
+		return err
+	}
+
+	diff, err := wsl.DiffBackups(oldArchive, newArchive)
+	if err != nil {
+		return err
+	}
+
+	return renderDiff(w, diff)
+}
+
+// DiffBackupWithDistroCmd reports the files added, removed or modified in
+// a registered distro since a backup, selected as for DiffBackupsCmd
+func DiffBackupWithDistroCmd(ctx context.Context, b wsl.Backuper, w io.Writer, oldRef, distro, backupDir string) error {
+	oldArchive, err := resolveBackup(splitBackupRef(oldRef, backupDir))
+	if err != nil {
+		return err
+	}
+
+	diff, err := wsl.DiffBackupWithDistro(ctx, b, oldArchive, distro)
+	if err != nil {
+		return err
+	}
+
+	return renderDiff(w, diff)
+}
+
+// renderDiff prints the changes between two backups
+func renderDiff(w io.Writer, diff wsl.BackupDiff) error {
+	return render(w, diff, func() *table {
+		t := newTable("CHANGE", "PATH", "DIFFERS")
+		for _, c := range diff.Changes {
+			t.add(c.Change, c.Path, strings.Join(c.Reasons, ", "))
+		}
+		return t
+	}, func() {
+		fmt.Fprintf(w, "Comparing %s\n       to %s\n\n", diff.Old, diff.New)
+		if len(diff.Changes) == 0 {
+			fmt.Fprintln(w, "No differences")
+			return
+		}
+
+		for _, c := range diff.Changes {
+			switch c.Change {
+			case "added":
+				fmt.Fprintf(w, "+ %s\n", c.Path)
+			case "removed":
+				fmt.Fprintf(w, "- %s\n", c.Path)
+			default:
+				fmt.Fprintf(w, "~ %s (%s)\n", c.Path, strings.Join(c.Reasons, ", "))
+			}
+		}
+		fmt.Fprintf(w, "\n%d added, %d removed, %d modified\n", diff.Added, diff.Removed, diff.Modified)
+	})
+}
+
+// splitBackupRef splits a backup reference of the form name[@timestamp]
+// into the arguments of resolveBackup. Existing files are returned as-is
+// so archive paths containing @ still work.
+func splitBackupRef(ref, backupDir string) (string, string, string) {
+	if isFile(ref) {
+		return ref, backupDir, "latest"
+	}
+	if name, timestamp, ok := strings.Cut(ref, "@"); ok {
+		return name, backupDir, timestamp
+	}
+	return ref, backupDir, "latest"
+}
+
+// resolveBackup returns the archive a backup argument refers to: either
+// the path of an archive, or a distro name whose backup taken at
+// timestamp ("latest" for the most recent) is looked up in backupDir.
+func resolveBackup(backup, backupDir, timestamp string) (string, error) {
+	if isFile(backup) {
+		return backup, nil
+	}
+
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	backups, err := wsl.ListBackups(backupDir)
+	if err != nil {
+		return "", err
+	}
+
+	found, err := wsl.FindBackup(backups, backup, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	return found.FilePath, nil
+}
+
+// isFile reports whether path names an existing regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return path != "" && err == nil && !info.IsDir()
+}
+
+// VerifyBackupsCmd checks the integrity of backup archives. target is
+// either the path of an archive or a distro name, in which case the backup
+// taken at timestamp is checked, or every backup of the distro with all.
+// With all and no target, every backup in backupDir is checked.
+func VerifyBackupsCmd(w io.Writer, target, backupDir, timestamp string, all bool) error {
+	if target == "" && !all {
+		return fmt.Errorf("a backup file or distro name is required (or use --all)")
+	}
+
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	var paths []string
+	if all && !isFile(target) {
+		backups, err := wsl.ListBackups(backupDir)
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			if target == "" || strings.EqualFold(b.Distro, target) {
+				paths = append(paths, b.FilePath)
+			}
+		}
+	} else {
+		path, err := resolveBackup(target, backupDir, timestamp)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+	}
+
+	verified := 0
+	results := make([]wsl.VerifyResult, 0, len(paths))
+	for _, path := range paths {
+		result := wsl.VerifyBackup(path)
+		if result.Success {
+			verified++
+		}
+		results = append(results, result)
+	}
+
+	// Backups are named by their distro, or their file if it's unknown
+	name := func(result wsl.VerifyResult) string {
+		if result.Distro == "" {
+			return filepath.Base(result.FilePath)
+		}
+		return result.Distro
+	}
+
+	err := render(w, results, func() *table {
+		t := newTable("DISTRO", "RESULT", "SIZE", "FILE", "MESSAGE")
+		for _, result := range results {
+			t.add(name(result), resultColumn(result.Success), formatSize(result.Size), result.FilePath, result.Message)
+		}
+		return t
+	}, func() {
+		if len(results) == 0 {
+			fmt.Fprintf(w, "No backups found in %s\n", backupDir)
+			return
+		}
+
+		for _, result := range results {
+			if result.Success {
+				fmt.Fprintf(w, "✓ %s: %s\n", name(result), result.Message)
+			} else {
+				fmt.Fprintf(w, "✗ %s: %s\n", name(result), result.Message)
+			}
+			fmt.Fprintf(w, "  %s (%s)\n", result.FilePath, formatSize(result.Size))
+		}
+
+		if len(results) > 1 {
+			fmt.Fprintf(w, "\nVerified %d/%d backup(s)\n", verified, len(results))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if verified < len(paths) {
+		return fmt.Errorf("backup verification failed")
+	}
+
+	return nil
+}
+
+// printPruneResults prints prune results and a summary of the space freed
+func printPruneResults(w io.Writer, results []wsl.PruneResult, dryRun bool) {
+	var freed int64
+	deleted := 0
+	for _, result := range results {
+		if result.Success {
+			deleted++
+			freed += result.Size
+			fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
+		} else {
+			fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
+		}
+		fmt.Fprintf(w, "  %s (%s)\n", result.FilePath, formatSize(result.Size))
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "\nWould delete %d backup(s), freeing %s\n", deleted, formatSize(freed))
+	} else {
+		fmt.Fprintf(w, "\nDeleted %d backup(s), freeing %s\n", deleted, formatSize(freed))
+	}
+}
+
+// ListBackupsCmd prints the backup catalog, optionally filtered to a single
+// distro. With details, the full manifest of each backup is shown.
+func ListBackupsCmd(w io.Writer, distro, backupDir string, details bool) error {
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	backups, err := wsl.ListBackups(backupDir)
+	if err != nil {
+		return err
+	}
+
+	var found []wsl.BackupInfo
+	for _, b := range backups {
+		if distro == "" || strings.EqualFold(b.Distro, distro) {
+			found = append(found, b)
+		}
+	}
+
+	// The columns of each backup, the same for text and tables
+	columns := func(b wsl.BackupInfo) []string {
+		name := b.Distro
+		if name == "" {
+			name = "(custom name)"
+		}
+		wslVersion, flavor := "-", "-"
+		if b.Manifest != nil {
+			if b.Manifest.WSLVersion != 0 {
+				wslVersion = fmt.Sprint(b.Manifest.WSLVersion)
+			}
+			if b.Manifest.Flavor != "" {
+				flavor = b.Manifest.Flavor
+			}
+		}
+		return []string{name, b.Timestamp.Format("2006-01-02 15:04:05"), formatSize(b.Size), wslVersion, flavor, b.FilePath}
+	}
+	header := []string{"DISTRO", "CREATED", "SIZE", "WSL", "FLAVOR", "FILE"}
+
+	return render(w, found, func() *table {
+		t := newTable(header...)
+		for _, b := range found {
+			t.add(columns(b)...)
+		}
+		return t
+	}, func() {
+		if len(found) == 0 {
+			fmt.Fprintf(w, "No backups found in %s\n", backupDir)
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, b := range found {
+			fmt.Fprintln(tw, strings.Join(columns(b), "\t"))
+			if details {
+				tw.Flush()
+				printManifestDetails(w, b.Manifest)
+			}
+		}
+		tw.Flush()
+	})
+}
+
+// printManifestDetails prints the manifest fields not shown in the
+// backup list table
+func printManifestDetails(w io.Writer, m *wsl.BackupManifest) {
+	if m == nil {
+		fmt.Fprintf(w, "  (no manifest)\n\n")
+		return
+	}
+
+	fmt.Fprintf(w, "  GUID:         %s\n", m.GUID)
+	fmt.Fprintf(w, "  Default UID:  %d\n", m.DefaultUID)
+	fmt.Fprintf(w, "  SHA-256:      %s\n", m.SHA256)
+	if m.Encryption != "" {
+		fmt.Fprintf(w, "  Encryption:   %s\n", m.Encryption)
+		for _, r := range m.Recipients {
+			fmt.Fprintf(w, "    %s\n", r)
+		}
+	}
+	fmt.Fprintf(w, "  wslp version: %s\n", m.WslpVersion)
+	if len(m.EnvironmentVars) > 0 {
+		fmt.Fprintf(w, "  Environment:\n")
+		keys := make([]string, 0, len(m.EnvironmentVars))
+		for k := range m.EnvironmentVars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "    %s=%s\n", k, m.EnvironmentVars[k])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// formatSize renders a byte count in human-readable units
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 func init() {
 	RootCmd.AddCommand(newBackupCmd())
 }
 
 func newBackupCmd() *cobra.Command {
-	var customName string
+	var sel wsl.Selector
+	var opts wsl.BackupOptions
+	var format string
 	var backupDir string
+	var encrypt bool
+	var target string
 
 	cmd := &cobra.Command{
 		Use:   "backup <distro> [distro...]",
 		Short: "Backup one or more WSL distributions",
-		Long: `Backup one or more WSL distributions to tar.gz files.
+		Long: `Backup one or more WSL distributions to archive files.
+
+Each archive is checked for corruption once exported and saved with a JSON
+manifest describing the distro it contains. Use 'wslp backup list' to see
+existing backups and 'wslp backup verify' to check them again later. If a
+retention policy is configured, old backups are pruned after each successful
+backup (see 'wslp backup prune').
 
 By default, backups are saved to %USERPROFILE%\WSLBackups with an auto-generated
 name including the distro name and timestamp (e.g., Ubuntu-20240301-143022.tar.gz).
 
+Archives are tar.gz by default. Use --format to choose another format:
+
+    tar      uncompressed tar, fastest to write but largest
+    tar.gz   gzip-compressed tar, as written by wsl --export
+    zstd     zstd-compressed tar (.tar.zst), much faster than gzip on large distros
+    xz       xz-compressed tar (.tar.xz), smallest but slowest
+    vhdx     copy of a WSL2 distro's virtual disk, fastest to restore
+
+Use --encrypt to encrypt archives with age (https://age-encryption.org). The
+encrypted archive gets an extra .age extension and the unencrypted export is
+deleted. Archives are encrypted to the public keys listed in
+backup_encryption.recipients in ~/.wslp.yaml or, if there are none, with the
+passphrase from the WSLP_BACKUP_PASSPHRASE environment variable or
+backup_encryption.passphrase_file. Set backup_encryption.enabled to encrypt
+every backup. Restoring, browsing and verifying encrypted backups needs
+backup_encryption.identity_file or the passphrase.
+
+Backups can be stored somewhere other than the backup directory with --target
+or by setting backup_target in ~/.wslp.yaml. A target is a name defined under
+backup_targets in the config file, or one of:
+
+    C:\path\to\dir            a local directory
+    \\server\share\dir        a Windows network share (also smb://server/share/dir)
+    s3://bucket/prefix        an S3 bucket, with credentials from
+                              AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY. Add
+                              ?endpoint=host:port for S3-compatible services
+                              such as MinIO, and &insecure=true for plain HTTP.
+
+Backups are exported and verified in the backup directory, then copied or
+uploaded to the target and removed locally. Uploads to S3 are streamed in parts
+(16MB by default, set with part_size), so large backups use little memory.
+Backups too large for 10,000 parts, the most S3 allows, use larger parts.
+The saved location is shown as a URI. Retention policies, 'backup list' and the
+other backup subcommands only work with backups in a local directory.
+
 You can specify a custom name for single distro backups using the --name flag.
-The backup directory can be customized via the --backup-dir flag or by setting
-backup_dir in ~/.wslp.yaml, or via the WSLP_BACKUP_DIR environment variable.`,
-		Args: cobra.MinimumNArgs(1),
+If the name ends in a supported extension and --format isn't given, the format
+is taken from the name. The backup directory can be customized via the --backup-dir flag or by setting
+backup_dir in ~/.wslp.yaml, or via the WSLP_BACKUP_DIR environment variable.
+--dry-run checks the distros can be backed up and shows where the backups
+would be saved, without exporting anything.
+
+` + selectorHelp,
+		Args: selectorArgs(&sel),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			distros, err := selectDistros(context.Background(), wsl.RealLister{}, sel, args)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("format") {
+				if opts.Format, err = wsl.ParseBackupFormat(format); err != nil {
+					return err
+				}
+			}
+			opts.Encrypt = encrypt
+			if !cmd.Flags().Changed("encrypt") {
+				enc, err := config.GetBackupEncryption()
+				if err != nil {
+					return err
+				}
+				opts.Encrypt = enc.Enabled
+			}
+			// An explicit --backup-dir saves locally unless a target is
+			// also given
+			dir, dest, err := wsl.ResolveBackupDestination(target, backupDir)
+			if err != nil {
+				return err
+			}
+			opts.Target = dest
+			return BackupDistrosCmd(lockContext(cmd), wsl.RealBackuper{}, cmd.OutOrStdout(), distros, dir, opts)
+		},
+	}
+	addSelectorFlags(cmd, &sel)
+
+	cmd.Flags().StringVarP(&opts.CustomName, "name", "n", "", "Custom name for the backup file (only for single distro)")
+	cmd.Flags().StringVarP(&format, "format", "f", string(wsl.DefaultBackupFormat), "Archive format: tar, tar.gz, zstd, xz or vhdx")
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to save backups, or to stage them in for other targets (overrides config)")
+	cmd.Flags().StringVar(&target, "target", "", "Where to store backups: a name from backup_targets, a path, smb:// or s3:// URI (overrides config)")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt archives with age (default from backup_encryption.enabled)")
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
+
+	cmd.AddCommand(newBackupListCmd())
+	cmd.AddCommand(newBackupPruneCmd())
+	cmd.AddCommand(newBackupVerifyCmd())
+	cmd.AddCommand(newBackupLsCmd())
+	cmd.AddCommand(newBackupExtractCmd())
+	cmd.AddCommand(newBackupDiffCmd())
+
+	return cmd
+}
+
+func newBackupListCmd() *cobra.Command {
+	var backupDir string
+	var details bool
+
+	cmd := &cobra.Command{
+		Use:   "list [distro]",
+		Short: "List backups in the backup directory",
+		Long: `List the backups in the backup directory, newest first.
+
+Each backup has a JSON manifest next to its archive recording the distro's
+name, GUID, WSL version, default UID, flavor and environment variables, along
+with the archive's size and SHA-256 checksum. Use --details to show the full
+manifest of each backup. Backups made before manifests were introduced are
+still listed, with the details that can be read from their filename.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			distro := ""
+			if len(args) == 1 {
+				distro = args[0]
+			}
+			return ListBackupsCmd(cmd.OutOrStdout(), distro, backupDir, details)
+		},
+	}
+
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to list backups from (overrides config)")
+	cmd.Flags().BoolVar(&details, "details", false, "Show the full manifest of each backup")
+
+	return cmd
+}
+
+func newBackupPruneCmd() *cobra.Command {
+	var backupDir string
+	var dryRun bool
+	var maxTotalSize string
+	var override config.RetentionPolicy
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old backups according to the retention policy",
+		Long: `Delete old backups according to the retention policy.
+
+The policy is read from backup_retention in ~/.wslp.yaml, e.g.:
+
+    backup_retention:
+      keep_last: 3        # the 3 most recent backups of each distro
+      keep_daily: 7       # the newest backup of each of the last 7 days
+      keep_weekly: 4      # the newest backup of each of the last 4 weeks
+      keep_monthly: 6     # the newest backup of each of the last 6 months
+      max_total_size: 50GB
+
+A backup is kept if any keep_* rule selects it; max_total_size then deletes
+the oldest remaining backups until the backup directory fits, though the most
+recent backup of each distro is always kept. Backups saved with a custom name
+and no manifest are never pruned. Rules can be overridden with the --keep-*
+and --max-total-size flags.
+
+When a policy is configured, pruning also runs automatically after every
+successful 'wslp backup'. Use --dry-run to see what would be deleted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy := config.GetRetentionPolicy()
+			flags := cmd.Flags()
+			if flags.Changed("keep-last") {
+				policy.KeepLast = override.KeepLast
+			}
+			if flags.Changed("keep-daily") {
+				policy.KeepDaily = override.KeepDaily
+			}
+			if flags.Changed("keep-weekly") {
+				policy.KeepWeekly = override.KeepWeekly
+			}
+			if flags.Changed("keep-monthly") {
+				policy.KeepMonthly = override.KeepMonthly
+			}
+			if flags.Changed("max-total-size") {
+				size, err := config.ParseSize(maxTotalSize)
+				if err != nil {
+					return err
+				}
+				policy.MaxTotalBytes = size
+			}
+			return PruneBackupsCmd(cmd.OutOrStdout(), backupDir, policy, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to prune backups in (overrides config)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be deleted without deleting anything")
+	cmd.Flags().IntVar(&override.KeepLast, "keep-last", 0, "Keep the N most recent backups of each distro")
+	cmd.Flags().IntVar(&override.KeepDaily, "keep-daily", 0, "Keep the newest backup of each of the last N days")
+	cmd.Flags().IntVar(&override.KeepWeekly, "keep-weekly", 0, "Keep the newest backup of each of the last N weeks")
+	cmd.Flags().IntVar(&override.KeepMonthly, "keep-monthly", 0, "Keep the newest backup of each of the last N months")
+	cmd.Flags().StringVar(&maxTotalSize, "max-total-size", "", "Maximum total size of all backups (e.g., 50GB)")
+
+	return cmd
+}
+
+func newBackupVerifyCmd() *cobra.Command {
+	var backupDir string
+	var timestamp string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "verify <file|distro>",
+		Short: "Check backup archives for corruption",
+		Long: `Check backup archives for corruption.
+
+The whole archive is read to make sure it is not truncated or corrupt: the
+compressed stream must decompress with valid checksums, every tar entry must
+be readable, and the archive must contain /etc/os-release. VHDX backups are
+disk images, so only their signature is checked. If the backup has a
+manifest, the archive must also match the SHA-256 checksum recorded in it.
+Encrypted backups are decrypted on the fly; without a decryption key only
+their checksum can be checked.
+
+Pass the path of an archive, or a distro name to check its most recent
+backup (or the one selected with --timestamp). Use --all to check every
+backup of a distro, or every backup when no distro is given.
+
+New backups are verified automatically as soon as they are created.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := ""
+			if len(args) == 1 {
+				target = args[0]
+			}
+			return VerifyBackupsCmd(cmd.OutOrStdout(), target, backupDir, timestamp, all)
+		},
+	}
+
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to look for backups in (overrides config)")
+	cmd.Flags().StringVarP(&timestamp, "timestamp", "t", "latest", "Timestamp of the backup to verify (YYYYMMDD-HHMMSS or latest)")
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Verify every backup of the distro, or every backup if no distro is given")
+
+	return cmd
+}
+
+func newBackupLsCmd() *cobra.Command {
+	var backupDir string
+	var timestamp string
+	var recursive bool
+
+	cmd := &cobra.Command{
+		Use:   "ls <backup> [path]",
+		Short: "List files inside a backup",
+		Long: `List the files inside a backup without restoring it.
+
+<backup> is the path of a backup archive, or a distro name to browse its most
+recent backup (or the one selected with --timestamp). [path] is a directory
+or file inside the distro and defaults to /.
+
+The archive is read directly, so browsing works even for large backups and
+never touches the running distro. Encrypted backups are decrypted on the fly.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "/"
+			if len(args) == 2 {
+				dir = args[1]
+			}
+			return ListBackupFilesCmd(cmd.OutOrStdout(), args[0], dir, backupDir, timestamp, recursive)
+		},
+	}
+
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to look for backups in (overrides config)")
+	cmd.Flags().StringVarP(&timestamp, "timestamp", "t", "latest", "Timestamp of the backup to browse (YYYYMMDD-HHMMSS or latest)")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "List everything below path, not just its direct children")
+
+	return cmd
+}
+
+func newBackupExtractCmd() *cobra.Command {
+	var backupDir string
+	var timestamp string
+
+	cmd := &cobra.Command{
+		Use:   "extract <backup> <path> <dest>",
+		Short: "Copy files out of a backup",
+		Long: `Copy a file or directory out of a backup without restoring the distro.
+
+<backup> is the path of a backup archive, or a distro name to use its most
+recent backup (or the one selected with --timestamp). <path> is the file or
+directory inside the distro, and <dest> where to put it on Windows. If <dest>
+is an existing directory, <path> is extracted into it under its own name.
+Directories are extracted recursively and existing files are overwritten.
+
+Symbolic links are recreated where Windows allows it and reported as skipped
+otherwise. Encrypted backups are decrypted on the fly.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ExtractBackupFilesCmd(cmd.OutOrStdout(), args[0], args[1], args[2], backupDir, timestamp)
+		},
+	}
+
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to look for backups in (overrides config)")
+	cmd.Flags().StringVarP(&timestamp, "timestamp", "t", "latest", "Timestamp of the backup to extract from (YYYYMMDD-HHMMSS or latest)")
+
+	return cmd
+}
+
+func newBackupDiffCmd() *cobra.Command {
+	var backupDir string
+	var live bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Show what changed between two backups, or since a backup",
+		Long: `Show the files added, removed or modified between two backups.
+
+Each backup is the path of a backup archive, or a distro name to use its most
+recent backup. Append @<timestamp> to a distro name to pick an older backup,
+e.g. Ubuntu@20240301-143022.
+
+With --live, <new> is a registered distro and the backup is compared with what
+is in the distro now, e.g. 'wslp backup diff Ubuntu Ubuntu --live' shows what
+changed since the last backup. The distro is exported to a temporary archive
+for the comparison, which takes as long as a backup and needs as much space.
+
+Files are compared by type, size, permissions, modification time, link target
+and a SHA-256 of their content. Changes are listed as:
+
+    + added
+    - removed
+    ~ modified (what differs)
+
+Use --output json or --output yaml for machine-readable output.`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return BackupDistrosCmd(context.Background(), wsl.RealBackuper{}, cmd.OutOrStdout(), args, customName, backupDir)
+			if live {
+				return DiffBackupWithDistroCmd(lockContext(cmd), wsl.RealBackuper{}, cmd.OutOrStdout(), args[0], args[1], backupDir)
+			}
+			return DiffBackupsCmd(cmd.OutOrStdout(), args[0], args[1], backupDir)
 		},
 	}
 
-	cmd.Flags().StringVarP(&customName, "name", "n", "", "Custom name for the backup file (only for single distro)")
-	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to save backups (overrides config)")
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to look for backups in (overrides config)")
+	cmd.Flags().BoolVar(&live, "live", false, "Compare the backup with the current contents of the distro named by <new>")
+	addWaitFlag(cmd)
 
 	return cmd
 }
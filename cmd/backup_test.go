@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"wslp/internal/config"
+	"wslp/internal/wsl"
 )
 
 type mockBackuper struct {
@@ -20,11 +28,38 @@ func (m *mockBackuper) IsRegistered(ctx context.Context, name string) (bool, err
 	return true, nil
 }
 
-func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string) error {
+func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string, format wsl.BackupFormat) error {
 	if m.shouldFail && m.failOn == distroName {
 		return errors.New("export failed")
 	}
-	return nil
+	if format == wsl.FormatTar {
+		return os.WriteFile(outputPath, buildTestTar(), 0644)
+	}
+	return os.WriteFile(outputPath, buildTestArchive(), 0644)
+}
+
+// buildTestArchive returns a minimal tar.gz distro archive
+func buildTestArchive() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(buildTestTar())
+	gz.Close()
+	return buf.Bytes()
+}
+
+// buildTestTar returns the uncompressed archive in buildTestArchive
+func buildTestTar() []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := "NAME=\"Ubuntu\"\n"
+	tw.WriteHeader(&tar.Header{Name: "./etc/os-release", Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	return buf.Bytes()
+}
+
+func (m *mockBackuper) Info(ctx context.Context, name string) (wsl.DistroDetailInfo, error) {
+	return wsl.DistroDetailInfo{Name: name}, nil
 }
 
 func TestBackupCommand(t *testing.T) {
@@ -56,7 +91,7 @@ func TestBackupCommand(t *testing.T) {
 		mock := &mockBackuper{}
 		out := new(bytes.Buffer)
 
-		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, "", "")
+		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, t.TempDir(), wsl.BackupOptions{})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -67,11 +102,31 @@ func TestBackupCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("dry run doesn't export", func(t *testing.T) {
+		skipIfNotWindows(t)
+		mock := &mockBackuper{}
+		out := new(bytes.Buffer)
+		dir := filepath.Join(t.TempDir(), "backups")
+
+		err := BackupDistrosCmd(wsl.WithDryRun(context.Background()), mock, out, []string{"Ubuntu"}, dir, wsl.BackupOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Dry run: 1/1 distribution(s) would be backed up") {
+			t.Errorf("expected dry run summary in output, got:\n%s", output)
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("expected the backup directory not to be created, got %v", err)
+		}
+	})
+
 	t.Run("custom name with multiple distros returns error", func(t *testing.T) {
 		mock := &mockBackuper{}
 		out := new(bytes.Buffer)
 
-		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu", "Debian"}, "custom", "")
+		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu", "Debian"}, "", wsl.BackupOptions{CustomName: "custom"})
 		if err == nil {
 			t.Fatal("expected error for custom name with multiple distros")
 		}
@@ -86,7 +141,7 @@ func TestBackupCommand(t *testing.T) {
 		mock := &mockBackuper{shouldFail: true, failOn: "Ubuntu"}
 		out := new(bytes.Buffer)
 
-		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, "", "")
+		err := BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, t.TempDir(), wsl.BackupOptions{})
 		if err == nil {
 			t.Fatal("expected error, got nil")
 		}
@@ -101,7 +156,7 @@ func TestBackupCommand(t *testing.T) {
 		mock := &mockBackuper{shouldFail: true, failOn: "Ubuntu"}
 		out := new(bytes.Buffer)
 
-		BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, "", "")
+		BackupDistrosCmd(context.Background(), mock, out, []string{"Ubuntu"}, t.TempDir(), wsl.BackupOptions{})
 
 		output := out.String()
 		if !strings.Contains(output, "✗") {
@@ -124,5 +179,304 @@ func TestBackupCommand(t *testing.T) {
 		if backupDirFlag == nil {
 			t.Fatal("backup-dir flag not found")
 		}
+
+		formatFlag := backupCmd.Flags().Lookup("format")
+		if formatFlag == nil {
+			t.Fatal("format flag not found")
+		}
+		if formatFlag.DefValue != "tar.gz" {
+			t.Errorf("expected format default 'tar.gz', got '%s'", formatFlag.DefValue)
+		}
+	})
+	t.Run("list subcommand is registered", func(t *testing.T) {
+		listCmd, _, err := RootCmd.Find([]string{"backup", "list"})
+		if err != nil {
+			t.Fatalf("backup list command not found: %v", err)
+		}
+
+		if listCmd.Use != "list [distro]" {
+			t.Errorf("expected Use='list [distro]', got '%s'", listCmd.Use)
+		}
+
+		if listCmd.Flags().Lookup("details") == nil {
+			t.Error("details flag not found")
+		}
 	})
 }
+
+func TestListBackupsCmd(t *testing.T) {
+	t.Run("shows manifest details", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz")
+		manifest := `{"distro":"Ubuntu","guid":"{1234}","wslVersion":2,"flavor":"ubuntu","sha256":"abc123","environmentVars":{"LANG":"C.UTF-8"}}`
+		if err := os.WriteFile(filepath.Join(dir, "Ubuntu-20240301-143022.tar.gz.json"), []byte(manifest), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+		out := new(bytes.Buffer)
+
+		if err := ListBackupsCmd(out, "", dir, true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		for _, phrase := range []string{"Ubuntu", "ubuntu", "{1234}", "abc123", "LANG=C.UTF-8"} {
+			if !strings.Contains(output, phrase) {
+				t.Errorf("expected output to contain %q, got:\n%s", phrase, output)
+			}
+		}
+	})
+
+	t.Run("does not list manifests as backups", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz", "Ubuntu-20240301-143022.tar.gz.json")
+		out := new(bytes.Buffer)
+
+		if err := ListBackupsCmd(out, "", dir, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if strings.Count(out.String(), "Ubuntu-20240301-143022") != 1 {
+			t.Errorf("expected a single backup row, got:\n%s", out.String())
+		}
+	})
+}
+
+func TestPruneBackupsCmd(t *testing.T) {
+	t.Run("returns error without a policy", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := PruneBackupsCmd(out, t.TempDir(), config.RetentionPolicy{}, false); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("dry run lists backups without deleting", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz")
+		out := new(bytes.Buffer)
+
+		if err := PruneBackupsCmd(out, dir, config.RetentionPolicy{KeepLast: 1}, true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Ubuntu-20240101-000000.tar.gz") || !strings.Contains(output, "Would delete 1 backup(s)") {
+			t.Errorf("expected dry-run summary in output, got:\n%s", output)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "Ubuntu-20240101-000000.tar.gz")); err != nil {
+			t.Error("expected backup to still exist after dry run")
+		}
+	})
+
+	t.Run("deletes old backups", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz")
+		out := new(bytes.Buffer)
+
+		if err := PruneBackupsCmd(out, dir, config.RetentionPolicy{KeepLast: 1}, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "Ubuntu-20240101-000000.tar.gz")); !os.IsNotExist(err) {
+			t.Error("expected old backup to be deleted")
+		}
+	})
+
+	t.Run("prune subcommand flags", func(t *testing.T) {
+		pruneCmd, _, err := RootCmd.Find([]string{"backup", "prune"})
+		if err != nil {
+			t.Fatalf("backup prune command not found: %v", err)
+		}
+
+		for _, name := range []string{"dry-run", "backup-dir", "keep-last", "keep-daily", "keep-weekly", "keep-monthly", "max-total-size"} {
+			if pruneCmd.Flags().Lookup(name) == nil {
+				t.Errorf("%s flag not found", name)
+			}
+		}
+	})
+}
+
+func TestVerifyBackupsCmd(t *testing.T) {
+	newVerifyDir := func(t *testing.T) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Ubuntu-20240301-000000.tar.gz"), buildTestArchive(), 0644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Ubuntu-20240101-000000.tar.gz"), []byte("corrupt"), 0644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		return dir
+	}
+
+	t.Run("verifies latest backup of a distro", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := VerifyBackupsCmd(out, "Ubuntu", newVerifyDir(t), "latest", false); err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, out.String())
+		}
+
+		if !strings.Contains(out.String(), "✓") || !strings.Contains(out.String(), "Ubuntu-20240301-000000.tar.gz") {
+			t.Errorf("expected latest backup to be verified, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("verifies a file path", func(t *testing.T) {
+		dir := newVerifyDir(t)
+		out := new(bytes.Buffer)
+
+		err := VerifyBackupsCmd(out, filepath.Join(dir, "Ubuntu-20240101-000000.tar.gz"), "", "latest", false)
+		if err == nil {
+			t.Fatal("expected error for corrupt archive")
+		}
+
+		if !strings.Contains(out.String(), "✗") {
+			t.Errorf("expected failure indicator in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("verifies all backups", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := VerifyBackupsCmd(out, "", newVerifyDir(t), "latest", true); err == nil {
+			t.Fatal("expected error when one backup is corrupt")
+		}
+
+		if !strings.Contains(out.String(), "Verified 1/2 backup(s)") {
+			t.Errorf("expected summary in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("requires a target", func(t *testing.T) {
+		if err := VerifyBackupsCmd(new(bytes.Buffer), "", t.TempDir(), "latest", false); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestBackupFilesCmds(t *testing.T) {
+	newArchiveDir := func(t *testing.T) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Ubuntu-20240301-000000.tar.gz"), buildTestArchive(), 0644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		return dir
+	}
+
+	t.Run("lists files in the latest backup of a distro", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := ListBackupFilesCmd(out, "Ubuntu", "/etc", newArchiveDir(t), "latest", false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(out.String(), "/etc/os-release") {
+			t.Errorf("expected /etc/os-release in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("returns error for missing path", func(t *testing.T) {
+		if err := ListBackupFilesCmd(new(bytes.Buffer), "Ubuntu", "/nope", newArchiveDir(t), "latest", false); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("extracts a file from an archive path", func(t *testing.T) {
+		archive := filepath.Join(newArchiveDir(t), "Ubuntu-20240301-000000.tar.gz")
+		dest := t.TempDir()
+		out := new(bytes.Buffer)
+
+		if err := ExtractBackupFilesCmd(out, archive, "/etc/os-release", dest, "", "latest"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dest, "os-release")); err != nil {
+			t.Errorf("expected extracted file: %v", err)
+		}
+		if !strings.Contains(out.String(), "Extracted 1 file(s)") {
+			t.Errorf("expected summary in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("subcommands are registered", func(t *testing.T) {
+		for _, name := range []string{"ls", "extract"} {
+			sub, _, err := RootCmd.Find([]string{"backup", name})
+			if err != nil || sub.Name() != name {
+				t.Errorf("backup %s command not found: %v", name, err)
+				continue
+			}
+			if sub.Flags().Lookup("timestamp") == nil || sub.Flags().Lookup("backup-dir") == nil {
+				t.Errorf("backup %s is missing --timestamp or --backup-dir", name)
+			}
+		}
+	})
+}
+
+func TestDiffBackupsCmd(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Ubuntu-20240301-000000.tar.gz", "Ubuntu-20240308-000000.tar.gz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), buildTestArchive(), 0644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+	}
+
+	t.Run("compares backups selected by timestamp", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := DiffBackupsCmd(out, "Ubuntu@20240301-000000", "Ubuntu", dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Ubuntu-20240301-000000.tar.gz") || !strings.Contains(output, "Ubuntu-20240308-000000.tar.gz") {
+			t.Errorf("expected both archives in output, got:\n%s", output)
+		}
+		if !strings.Contains(output, "No differences") {
+			t.Errorf("expected no differences, got:\n%s", output)
+		}
+	})
+
+	t.Run("outputs JSON", func(t *testing.T) {
+		useOutput(t, "json")
+		out := new(bytes.Buffer)
+
+		if err := DiffBackupsCmd(out, "Ubuntu@20240301-000000", "Ubuntu@latest", dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var diff wsl.BackupDiff
+		if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out.String())
+		}
+		if len(diff.Changes) != 0 {
+			t.Errorf("expected no changes, got %+v", diff.Changes)
+		}
+	})
+
+	t.Run("returns error for unknown timestamp", func(t *testing.T) {
+		if err := DiffBackupsCmd(new(bytes.Buffer), "Ubuntu@20990101-000000", "Ubuntu", dir); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("compares a backup with the live distro", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := DiffBackupWithDistroCmd(context.Background(), &mockBackuper{}, out, "Ubuntu", "Ubuntu", dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "to Ubuntu (live)") || !strings.Contains(output, "No differences") {
+			t.Errorf("expected no differences from the live distro, got:\n%s", output)
+		}
+	})
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for bytes, want := range tests {
+		if got := formatSize(bytes); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// interactive reports whether a command can ask the user questions, which
+// needs stdin to be a terminal. Tests replace it.
+var interactive = func(cmd *cobra.Command) bool {
+	_, ok := terminalInput(cmd)
+	return ok
+}
+
+// addYesFlag adds --yes to a command that asks before acting on distros
+func addYesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+}
+
+// confirmation is what a destructive command is about to do, for the
+// user to confirm before it goes on
+type confirmation struct {
+	// verb is what happens to the distros, e.g. terminate
+	verb    string
+	distros []string
+	// warning, if set, follows the list of distros
+	warning string
+	// typeName has the user type the name of the distro, or the number of
+	// distros, rather than y, for actions that can't be undone
+	typeName bool
+}
+
+// confirm lists what a command is about to do and asks the user to go on.
+// --yes and --dry-run skip the question. Without a terminal to ask on, it
+// fails rather than go on.
+func confirm(cmd *cobra.Command, c confirmation) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return nil
+	}
+	if !interactive(cmd) {
+		return fmt.Errorf("not asking to %s %d distro(s) as stdin isn't a terminal: pass --yes to go on", c.verb, len(c.distros))
+	}
+
+	// Asked on stderr, so the results on stdout can still be parsed
+	w := cmd.ErrOrStderr()
+	fmt.Fprintf(w, "This will %s %d distro(s):\n", c.verb, len(c.distros))
+	for _, name := range c.distros {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+	if c.warning != "" {
+		fmt.Fprintln(w, c.warning)
+	}
+
+	expected := ""
+	switch {
+	case !c.typeName:
+		fmt.Fprint(w, "Continue? [y/N] ")
+	case len(c.distros) == 1:
+		expected = c.distros[0]
+		fmt.Fprintf(w, "Type the name of the distro, %s, to confirm: ", expected)
+	default:
+		expected = strconv.Itoa(len(c.distros))
+		fmt.Fprintf(w, "Type the number of distros, %s, to confirm: ", expected)
+	}
+
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if expected != "" && answer == expected {
+		return nil
+	}
+	if expected == "" && (strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")) {
+		return nil
+	}
+	return errors.New("cancelled (pass --yes to skip confirmation)")
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// useInteractive makes commands treat stdin as a terminal, or not, for the
+// duration of a test
+func useInteractive(t *testing.T, ok bool) {
+	t.Helper()
+
+	old := interactive
+	interactive = func(*cobra.Command) bool { return ok }
+	t.Cleanup(func() { interactive = old })
+}
+
+func TestConfirm(t *testing.T) {
+	// newCmd creates a command that reads input and accepts --yes and
+	// --dry-run
+	newCmd := func(input string) (*cobra.Command, *bytes.Buffer) {
+		cmd := &cobra.Command{}
+		addYesFlag(cmd)
+		addDryRunFlag(cmd)
+		cmd.SetIn(strings.NewReader(input))
+		stderr := new(bytes.Buffer)
+		cmd.SetErr(stderr)
+		return cmd, stderr
+	}
+	distros := []string{"Ubuntu", "Debian"}
+
+	t.Run("lists the distros and goes on when confirmed", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("y\n")
+		if err := confirm(cmd, confirmation{verb: "terminate", distros: distros, warning: "Be careful."}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		for _, phrase := range []string{"terminate 2 distro(s)", "  Ubuntu", "  Debian", "Be careful.", "[y/N]"} {
+			if !strings.Contains(stderr.String(), phrase) {
+				t.Errorf("expected %q in:\n%s", phrase, stderr.String())
+			}
+		}
+	})
+
+	t.Run("cancels without an answer", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, _ := newCmd("")
+		if err := confirm(cmd, confirmation{verb: "terminate", distros: distros}); err == nil || !strings.Contains(err.Error(), "--yes") {
+			t.Errorf("expected the command to be cancelled, got %v", err)
+		}
+	})
+
+	t.Run("doesn't ask with --yes", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("")
+		cmd.Flags().Set("yes", "true")
+		if err := confirm(cmd, confirmation{verb: "unregister", distros: distros, typeName: true}); err != nil || stderr.Len() != 0 {
+			t.Errorf("expected no question, got %v:\n%s", err, stderr.String())
+		}
+	})
+
+	t.Run("doesn't ask with --dry-run", func(t *testing.T) {
+		useInteractive(t, false)
+		cmd, stderr := newCmd("")
+		cmd.Flags().Set("dry-run", "true")
+		if err := confirm(cmd, confirmation{verb: "unregister", distros: distros, typeName: true}); err != nil || stderr.Len() != 0 {
+			t.Errorf("expected no question, got %v:\n%s", err, stderr.String())
+		}
+	})
+
+	t.Run("fails without a terminal", func(t *testing.T) {
+		useInteractive(t, false)
+		cmd, stderr := newCmd("y\n")
+		err := confirm(cmd, confirmation{verb: "unregister", distros: distros})
+		if err == nil || !strings.Contains(err.Error(), "--yes") {
+			t.Errorf("expected an error mentioning --yes, got %v", err)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("expected no question, got:\n%s", stderr.String())
+		}
+	})
+
+	t.Run("goes on when the distro name is typed", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("Ubuntu\n")
+		if err := confirm(cmd, confirmation{verb: "unregister", distros: []string{"Ubuntu"}, typeName: true}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(stderr.String(), "Type the name of the distro, Ubuntu") {
+			t.Errorf("expected to be asked for the name, got:\n%s", stderr.String())
+		}
+	})
+
+	t.Run("cancels when another answer is typed", func(t *testing.T) {
+		useInteractive(t, true)
+		for _, input := range []string{"y\n", "ubuntu\n", "Debian\n"} {
+			cmd, _ := newCmd(input)
+			if err := confirm(cmd, confirmation{verb: "unregister", distros: []string{"Ubuntu"}, typeName: true}); err == nil {
+				t.Errorf("expected %q to cancel", input)
+			}
+		}
+	})
+
+	t.Run("asks for the number of distros", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("2\n")
+		if err := confirm(cmd, confirmation{verb: "unregister", distros: distros, typeName: true}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(stderr.String(), "number of distros, 2") {
+			t.Errorf("expected to be asked for the number, got:\n%s", stderr.String())
+		}
+
+		cmd, _ = newCmd("Ubuntu\n")
+		if err := confirm(cmd, confirmation{verb: "unregister", distros: distros, typeName: true}); err == nil {
+			t.Error("expected a name to cancel")
+		}
+	})
+}
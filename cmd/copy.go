@@ -11,14 +11,28 @@ import (
 
 // CopyDistroCmd copies a WSL distribution under a new name.
 func CopyDistroCmd(ctx context.Context, c wsl.Copier, w io.Writer, source, newName, installDir string) error {
-	fmt.Fprintf(w, "Copying %s to %s...\n", source, newName)
+	if textOutput() && !wsl.IsDryRun(ctx) {
+		fmt.Fprintf(w, "Copying %s to %s...\n", source, newName)
+	}
 
 	result := wsl.CopyDistro(ctx, c, source, newName, installDir)
 
-	if result.Success {
-		fmt.Fprintf(w, "✓ %s\n", result.Message)
-	} else {
-		fmt.Fprintf(w, "✗ %s\n", result.Message)
+	err := render(w, result, func() *table {
+		t := newTable("SOURCE", "NEW NAME", "RESULT", "MESSAGE")
+		t.add(result.Source, result.NewName, resultColumn(result.Success), result.Message)
+		return t
+	}, func() {
+		if result.Success {
+			fmt.Fprintf(w, "✓ %s\n", result.Message)
+		} else {
+			fmt.Fprintf(w, "✗ %s\n", result.Message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !result.Success {
 		return fmt.Errorf("copy failed")
 	}
 
@@ -38,14 +52,17 @@ func newCopyCmd() *cobra.Command {
 		Long: `Copy a WSL distribution by exporting it and importing it under a new name.
 
 The new distribution is stored in %USERPROFILE%\WSLCopies\<new-name> by default.
-You can override this with the --install-dir flag.`,
+You can override this with the --install-dir flag. --dry-run checks the
+distro can be copied under the new name without copying it.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return CopyDistroCmd(context.Background(), wsl.RealCopier{}, cmd.OutOrStdout(), args[0], args[1], installDir)
+			return CopyDistroCmd(lockContext(cmd), wsl.RealCopier{}, cmd.OutOrStdout(), args[0], args[1], installDir)
 		},
 	}
 
 	cmd.Flags().StringVarP(&installDir, "install-dir", "d", "", "Directory to store the new distro's virtual disk (overrides default)")
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
 
 	return cmd
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"wslp/internal/wsl"
 )
 
 type mockCopier struct {
@@ -81,6 +83,24 @@ func TestCopyCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("dry run doesn't copy", func(t *testing.T) {
+		mock := &mockCopier{}
+		out := new(bytes.Buffer)
+
+		err := CopyDistroCmd(wsl.WithDryRun(context.Background()), mock, out, "Ubuntu", "UbuntuCopy", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Would copy Ubuntu to UbuntuCopy") {
+			t.Errorf("expected dry run message in output, got:\n%s", output)
+		}
+		if strings.Contains(output, "Copying") {
+			t.Errorf("expected no copy to start, got:\n%s", output)
+		}
+	})
+
 	t.Run("returns error when copy fails", func(t *testing.T) {
 		mock := &mockCopier{shouldFail: true}
 		out := new(bytes.Buffer)
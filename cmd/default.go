@@ -2,49 +2,132 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"wslp/internal/config"
 	"wslp/internal/wsl"
 )
 
 var defaultCmd = &cobra.Command{
 	Use:   "default",
 	Short: "Manage the default WSL distro",
-	Long:  `Manage the default Linux distro, including showing it (done) and changing it (todo).`,
+	Long:  `Manage the default Linux distro, including showing it and changing it.`,
 }
 
+// ShowDefault prints the default distro
 func ShowDefault(ctx context.Context, g wsl.DefaultGetter, out io.Writer) error {
-	fmt.Fprintln(out, "The default WSL distro is:")
+	if textOutput() {
+		fmt.Fprintln(out, "The default WSL distro is:")
+	}
 
 	name, err := wsl.GetDefaultDistro(ctx, g)
 	if err != nil {
-		fmt.Fprintln(out, err)
 		return err
 	}
 
-	fmt.Fprintln(out, name)
-	return nil
+	return render(out, map[string]string{"default": name}, func() *table {
+		t := newTable("DEFAULT")
+		t.add(name)
+		return t
+	}, func() {
+		fmt.Fprintln(out, name)
+	})
 }
 
 var defaultShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show the default distro",
 	Long:  `Prints the default WSL distribution on the Windows host.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		ShowDefault(context.Background(), wsl.RealDefaultGetter{}, cmd.OutOrStdout())
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ShowDefault(context.Background(), wsl.RealDefaultGetter{}, cmd.OutOrStdout())
 	},
 }
 
-// TODO
+// ChangeDefault sets name as the default distro, remembering the default
+// it replaces for --previous
+func ChangeDefault(ctx context.Context, name string, g wsl.DefaultGetter, s wsl.DefaultSetter, out io.Writer) error {
+	if err := wsl.ChangeDefaultDistro(ctx, name, g, s, config.GetStateDir()); err != nil {
+		return err
+	}
+
+	return render(out, map[string]string{"default": name}, func() *table {
+		t := newTable("DEFAULT")
+		t.add(name)
+		return t
+	}, func() {
+		fmt.Fprintf(out, "%s is now the default WSL distro\n", name)
+	})
+}
+
+// PickDefault lets the user pick the new default from the registered
+// distros, showing which is running and which is the default now
+func PickDefault(ctx context.Context, cmd *cobra.Command, l wsl.Lister, g wsl.DefaultGetter) (string, error) {
+	distros, err := wsl.ListDistros(ctx, l)
+	if err != nil {
+		return "", err
+	}
+	// There is no default when no distro is registered
+	current, _ := g.GetDefault(ctx)
+
+	items := make([]pickerItem, len(distros))
+	for i, d := range distros {
+		items[i] = pickerItem{name: d.Name, isDefault: strings.EqualFold(d.Name, current), running: d.Running}
+	}
+	return pickDistro(cmd, "Pick the default distro", items)
+}
+
+// previousDefault returns the default distro before the last change
+func previousDefault() (string, error) {
+	name, err := wsl.PreviousDefaultDistro(config.GetStateDir())
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", errors.New("no previous default distro is known")
+	}
+	return name, nil
+}
+
 var defaultChangeCmd = &cobra.Command{
-	Use:   "change [distroName]",
-	Short: "Change the default distro (STUB: not implemented)",
-	Long:  `Changes the default WSL distro.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("This subcommand is not implemented (sorry!).")
+	Use:     "change [distroName]",
+	Aliases: []string{"set"},
+	Short:   "Change the default distro",
+	Long: `Sets the default WSL distribution on the Windows host.
+
+Without a distro name, a picker lists the registered distros, marking the
+current default and the running ones: type to filter them, use the arrow keys
+to move and enter to choose. The picker needs a terminal, so scripts must name
+the distro.
+
+The default being replaced is remembered in the state directory, including
+when the API server changes it, and --previous switches back to it. Running
+--previous again switches back and forth between the two.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		var name string
+		var err error
+		switch previous, _ := cmd.Flags().GetBool("previous"); {
+		case previous && len(args) > 0:
+			return errors.New("--previous can't be given a distro name")
+		case previous:
+			name, err = previousDefault()
+		case len(args) == 1:
+			name = args[0]
+		default:
+			name, err = PickDefault(ctx, cmd, wsl.RealLister{}, wsl.RealDefaultGetter{})
+		}
+		if err != nil {
+			return err
+		}
+
+		return ChangeDefault(ctx, name, wsl.RealDefaultGetter{}, wsl.RealDefaultSetter{}, cmd.OutOrStdout())
 	},
 }
 
@@ -55,4 +138,6 @@ func init() {
 	// Add subcommands to the default command
 	defaultCmd.AddCommand(defaultShowCmd)
 	defaultCmd.AddCommand(defaultChangeCmd)
+
+	defaultChangeCmd.Flags().Bool("previous", false, "Switch back to the default before the last change")
 }
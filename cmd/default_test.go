@@ -6,6 +6,11 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"wslp/internal/wsl"
 )
 
 type mockDefaultGetter struct {
@@ -20,6 +25,31 @@ func (m *mockDefaultGetter) GetDefault(ctx context.Context) (string, error) {
 	return m.name, nil
 }
 
+type mockDefaultSetter struct {
+	registered bool
+	setName    string
+}
+
+func (m *mockDefaultSetter) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return m.registered, nil
+}
+
+func (m *mockDefaultSetter) SetAsDefault(ctx context.Context, name string) error {
+	m.setName = name
+	return nil
+}
+
+// useStateDir sets a fresh state directory for the duration of a test
+func useStateDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	previous := viper.GetString("state_dir")
+	viper.Set("state_dir", dir)
+	t.Cleanup(func() { viper.Set("state_dir", previous) })
+	return dir
+}
+
 func TestDefaultCommand(t *testing.T) {
 	t.Run("command metadata", func(t *testing.T) {
 		if RootCmd == nil {
@@ -71,3 +101,93 @@ func TestDefaultCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestChangeDefault(t *testing.T) {
+	t.Run("command metadata", func(t *testing.T) {
+		changeCmd, _, err := RootCmd.Find([]string{"default", "set"})
+		if err != nil {
+			t.Fatalf("default set command not found: %v", err)
+		}
+		if changeCmd != defaultChangeCmd {
+			t.Error("set is not an alias of default change")
+		}
+		if changeCmd.Flags().Lookup("previous") == nil {
+			t.Error("--previous flag not found")
+		}
+	})
+
+	t.Run("sets the default and remembers the previous one", func(t *testing.T) {
+		useAuditLog(t)
+		dir := useStateDir(t)
+		setter := &mockDefaultSetter{registered: true}
+		out := new(bytes.Buffer)
+
+		err := ChangeDefault(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, setter, out)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if setter.setName != "Debian" {
+			t.Errorf("expected Debian to be set as default, got %q", setter.setName)
+		}
+		if !strings.Contains(out.String(), "Debian is now the default") {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+
+		if previous, _ := wsl.PreviousDefaultDistro(dir); previous != "Ubuntu" {
+			t.Errorf("expected previous default Ubuntu, got %q", previous)
+		}
+		if name, err := previousDefault(); err != nil || name != "Ubuntu" {
+			t.Errorf("expected Ubuntu from previousDefault, got %q, %v", name, err)
+		}
+	})
+
+	t.Run("fails for an unregistered distro", func(t *testing.T) {
+		useAuditLog(t)
+		useStateDir(t)
+
+		err := ChangeDefault(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, &mockDefaultSetter{}, new(bytes.Buffer))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("--previous fails without a previous default", func(t *testing.T) {
+		useStateDir(t)
+
+		if _, err := previousDefault(); err == nil || !strings.Contains(err.Error(), "no previous default") {
+			t.Errorf("expected no previous default error, got %v", err)
+		}
+	})
+
+	t.Run("--previous takes no distro name", func(t *testing.T) {
+		err := defaultChangeCmd.Flags().Set("previous", "true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer defaultChangeCmd.Flags().Set("previous", "false")
+
+		err = defaultChangeCmd.RunE(defaultChangeCmd, []string{"Ubuntu"})
+		if err == nil || !strings.Contains(err.Error(), "--previous") {
+			t.Errorf("expected --previous error, got %v", err)
+		}
+	})
+
+	t.Run("picking needs a terminal", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		cmd.SetIn(strings.NewReader(""))
+
+		_, err := PickDefault(context.Background(), cmd, &mockLister{names: []string{"Ubuntu"}}, &mockDefaultGetter{name: "Ubuntu"})
+		if err == nil || !strings.Contains(err.Error(), "terminal") {
+			t.Errorf("expected terminal error, got %v", err)
+		}
+	})
+
+	t.Run("picking fails if listing fails", func(t *testing.T) {
+		cmd := &cobra.Command{}
+
+		_, err := PickDefault(context.Background(), cmd, &mockLister{shouldFail: true}, &mockDefaultGetter{})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
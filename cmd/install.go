@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 
 	"github.com/spf13/cobra"
 
@@ -12,38 +13,65 @@ import (
 )
 
 // InstallDistros has the core logic for installing distros
-func InstallDistros(ctx context.Context, out io.Writer, distros []string) {
+func InstallDistros(ctx context.Context, out io.Writer, distros []string) error {
 	results := wsl.InstallDistros(ctx, distros, false)
-	wsl.PrintInstallResults(out, results)
+	return printInstallResults(out, results)
 }
 
 // InstallDistrosConcurrent installs distros concurrently using a semaphore
-func InstallDistrosConcurrent(ctx context.Context, out io.Writer, distros []string) {
+func InstallDistrosConcurrent(ctx context.Context, out io.Writer, distros []string) error {
 	results := wsl.InstallDistros(ctx, distros, true)
-	wsl.PrintInstallResults(out, results)
+	return printInstallResults(out, results)
+}
+
+// printInstallResults prints the results of installs in the chosen output
+// format
+func printInstallResults(out io.Writer, results []wsl.InstallResult) error {
+	return render(out, results, func() *table {
+		t := newTable("DISTRO", "RESULT", "REGISTERED", "MESSAGE")
+		for _, r := range results {
+			t.add(r.Distro, resultColumn(r.Success), strconv.FormatBool(r.Registered), r.Message)
+		}
+		return t
+	}, func() {
+		wsl.PrintInstallResults(out, results)
+	})
 }
 
 // installCmd represents the install command
 var installCmd = &cobra.Command{
 	Use:   "install <distro> [distro...]",
 	Short: "Install WSL distros",
-	Long:  `Install one or more WSL distros`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Install one or more WSL distros.
+
+Distros can also be matched with glob patterns against the distros available
+online (see 'wsl --list --online'), e.g. 'Ubuntu-*'. Quote patterns so the
+shell leaves them alone, and use --exclude to leave distros out.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			fmt.Fprintln(cmd.OutOrStdout(), "Error: No distros specified")
-			return
+			return nil
+		}
+		exclude, _ := cmd.Flags().GetStringSlice("exclude")
+		distros, err := wsl.SelectAvailableDistros(context.Background(), wsl.Selector{Distros: args, Exclude: exclude})
+		if err != nil {
+			return err
 		}
+
 		concurrent, _ := cmd.Flags().GetBool("experimental-concurrent")
 		if concurrent {
-			fmt.Fprintf(cmd.OutOrStdout(), "experimental: installing distros concurrently (max %d at a time)\n", config.GetMaxConcurrentInstalls())
-			InstallDistrosConcurrent(context.Background(), cmd.OutOrStdout(), args)
-		} else {
-			InstallDistros(context.Background(), cmd.OutOrStdout(), args)
+			if textOutput() {
+				fmt.Fprintf(cmd.OutOrStdout(), "experimental: installing distros concurrently (max %d at a time)\n", config.GetMaxConcurrentInstalls())
+			}
+			return InstallDistrosConcurrent(lockContext(cmd), cmd.OutOrStdout(), distros)
 		}
+		return InstallDistros(lockContext(cmd), cmd.OutOrStdout(), distros)
 	},
 }
 
 func init() {
 	installCmd.Flags().Bool("experimental-concurrent", false, "experimental: install distros concurrently")
+	installCmd.Flags().StringSlice("exclude", nil, "Leave out the distros matching this name or pattern (repeatable)")
+	addWaitFlag(installCmd)
 	RootCmd.AddCommand(installCmd)
 }
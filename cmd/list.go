@@ -10,29 +10,45 @@ import (
 	"wslp/internal/wsl"
 )
 
+// ListDistros prints the registered distros
 func ListDistros(ctx context.Context, l wsl.Lister, out io.Writer) error {
-	fmt.Fprintln(out, "Finding registered distros...")
+	if textOutput() {
+		fmt.Fprintln(out, "Finding registered distros...")
+	}
 
 	distros, err := wsl.ListDistros(ctx, l)
 	if err != nil {
-		fmt.Fprintln(out, err)
 		return err
 	}
 
-	fmt.Fprintf(out, "%d distros are registered:\n", len(distros))
-	for _, d := range distros {
-		fmt.Fprintln(out, d.Name)
-	}
-
-	return nil
+	return render(out, distros, func() *table {
+		t := newTable("NAME", "STATE", "BUSY")
+		for _, d := range distros {
+			busy := "-"
+			if d.Busy {
+				busy = d.Operation
+			}
+			t.add(d.Name, d.State, busy)
+		}
+		return t
+	}, func() {
+		fmt.Fprintf(out, "%d distros are registered:\n", len(distros))
+		for _, d := range distros {
+			if d.Busy {
+				fmt.Fprintf(out, "%s (busy: %s in progress)\n", d.Name, d.Operation)
+				continue
+			}
+			fmt.Fprintln(out, d.Name)
+		}
+	})
 }
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List registered WSL distros",
 	Long:  `Lists all WSL distributions registered on the Windows host.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		ListDistros(context.Background(), wsl.RealLister{}, cmd.OutOrStdout())
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListDistros(context.Background(), wsl.RealLister{}, cmd.OutOrStdout())
 	},
 }
 
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"wslp/internal/wsl"
 )
 
 type mockLister struct {
@@ -61,6 +63,27 @@ func TestListCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("marks busy distros", func(t *testing.T) {
+		previous := wsl.Locks
+		wsl.Locks = wsl.NewLockManager("")
+		defer func() { wsl.Locks = previous }()
+		unlock, err := wsl.Locks.TryLock("Ubuntu", "backup")
+		if err != nil {
+			t.Fatalf("TryLock failed: %v", err)
+		}
+		defer unlock()
+
+		mock := &mockLister{names: []string{"Ubuntu", "Debian"}}
+		out := new(bytes.Buffer)
+		if err := ListDistros(context.Background(), mock, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(out.String(), "Ubuntu (busy: backup in progress)\nDebian\n") {
+			t.Errorf("expected Ubuntu to be marked busy, got:\n%s", out.String())
+		}
+	})
+
 	t.Run("prints zero count when no distros", func(t *testing.T) {
 		mock := &mockLister{names: []string{}}
 		out := new(bytes.Buffer)
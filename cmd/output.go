@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+
+	"wslp/internal/config"
+)
+
+// The formats commands print their results in, chosen with --output
+const (
+	OutputText  = "text"
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// outputFormat returns the format chosen with --output or output in
+// ~/.wslp.yaml
+func outputFormat() string {
+	return strings.ToLower(config.GetOutputFormat())
+}
+
+// checkOutputFormat rejects an unknown --output before the command runs,
+// rather than after it has changed anything
+func checkOutputFormat(cmd *cobra.Command, args []string) error {
+	switch outputFormat() {
+	case OutputText, OutputTable, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q (must be text, table, json or yaml)", config.GetOutputFormat())
+}
+
+// textOutput reports whether results are printed as text, the only format
+// in which commands also print progress and hints around them
+func textOutput() bool {
+	return outputFormat() == OutputText
+}
+
+// table holds a command's results as rows of columns, for --output table
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// newTable creates a table with the given column names
+func newTable(header ...string) *table {
+	return &table{header: header}
+}
+
+// add appends a row
+func (t *table) add(columns ...string) {
+	t.rows = append(t.rows, columns)
+}
+
+// write prints the table with its columns aligned
+func (t *table) write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// render prints a command's results v in the chosen output format: as
+// JSON or YAML, using the same field names as the API, as the table built
+// by rows, or by calling text. Commands with no table of their own pass a
+// nil rows and print text for --output table.
+func render(w io.Writer, v interface{}, rows func() *table, text func()) error {
+	switch outputFormat() {
+	case OutputJSON:
+		return writeJSON(w, v)
+	case OutputYAML:
+		return writeYAML(w, v)
+	case OutputTable:
+		if rows != nil {
+			rows().write(w)
+			return nil
+		}
+	}
+	text()
+	return nil
+}
+
+// emptySlice turns a nil slice into an empty one, so no results are
+// printed as [] rather than null
+func emptySlice(v interface{}) interface{} {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		return reflect.MakeSlice(rv.Type(), 0, 0).Interface()
+	}
+	return v
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(emptySlice(v))
+}
+
+// writeYAML prints v as YAML. It is converted from JSON so the keys match
+// the JSON output and stay in the same order.
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(emptySlice(v))
+	if err != nil {
+		return err
+	}
+
+	// JSON is YAML, but in flow style with every string quoted
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	setBlockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	enc.Close()
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// setBlockStyle clears the flow style and quoting JSON was parsed with, so
+// values are quoted only where YAML needs it
+func setBlockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		setBlockStyle(child)
+	}
+}
+
+// resultColumn describes whether an operation succeeded, for tables
+func resultColumn(success bool) string {
+	if success {
+		return "ok"
+	}
+	return "failed"
+}
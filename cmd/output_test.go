@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"wslp/internal/wsl"
+)
+
+// useOutput prints results in format for the duration of a test
+func useOutput(t *testing.T, format string) {
+	t.Helper()
+
+	previous := viper.Get("output")
+	viper.Set("output", format)
+	t.Cleanup(func() { viper.Set("output", previous) })
+}
+
+func TestOutputFlag(t *testing.T) {
+	if RootCmd.PersistentFlags().ShorthandLookup("o") == nil {
+		t.Fatal("-o/--output flag not found")
+	}
+
+	for _, format := range []string{"text", "table", "JSON", "yaml"} {
+		useOutput(t, format)
+		if err := checkOutputFormat(RootCmd, nil); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", format, err)
+		}
+	}
+
+	useOutput(t, "xml")
+	if err := checkOutputFormat(RootCmd, nil); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("expected an error naming the format, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	results := []wsl.UnregisterResult{
+		{Distro: "Ubuntu", Success: true, Message: "Unregistered"},
+		{Distro: "Debian", Message: "yes: not registered"},
+	}
+	rows := func() *table {
+		t := newTable("DISTRO", "RESULT")
+		for _, r := range results {
+			t.add(r.Distro, resultColumn(r.Success))
+		}
+		return t
+	}
+	text := func(w *bytes.Buffer) func() {
+		return func() { w.WriteString("people\n") }
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "people\n"},
+		{"table", "DISTRO  RESULT\nUbuntu  ok\nDebian  failed\n"},
+		{"yaml", `- distro: Ubuntu
+  success: true
+  message: Unregistered
+- distro: Debian
+  success: false
+  message: 'yes: not registered'
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			useOutput(t, tt.format)
+			out := new(bytes.Buffer)
+			if err := render(out, results, rows, text(out)); err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		useOutput(t, "json")
+		out := new(bytes.Buffer)
+		if err := render(out, results, rows, text(out)); err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		var decoded []wsl.UnregisterResult
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1].Message != "yes: not registered" {
+			t.Errorf("unexpected JSON %s, %v", out.String(), err)
+		}
+	})
+
+	t.Run("empty results", func(t *testing.T) {
+		useOutput(t, "json")
+		out := new(bytes.Buffer)
+		render(out, []wsl.UnregisterResult(nil), nil, nil)
+		if strings.TrimSpace(out.String()) != "[]" {
+			t.Errorf("expected [], got %s", out.String())
+		}
+	})
+
+	t.Run("tables fall back to text", func(t *testing.T) {
+		useOutput(t, "table")
+		out := new(bytes.Buffer)
+		render(out, results, nil, text(out))
+		if out.String() != "people\n" {
+			t.Errorf("expected the text output, got %q", out.String())
+		}
+	})
+}
+
+func TestCommandOutput(t *testing.T) {
+	t.Run("list prints only JSON", func(t *testing.T) {
+		useOutput(t, "json")
+		out := new(bytes.Buffer)
+		if err := ListDistros(context.Background(), &mockLister{names: []string{"Ubuntu", "Debian"}}, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var distros []wsl.DistroInfo
+		if err := json.Unmarshal(out.Bytes(), &distros); err != nil {
+			t.Fatalf("output isn't JSON: %v\n%s", err, out.String())
+		}
+		if len(distros) != 2 || distros[0].Name != "Ubuntu" {
+			t.Errorf("unexpected distros %+v", distros)
+		}
+	})
+
+	t.Run("default show as YAML", func(t *testing.T) {
+		useOutput(t, "yaml")
+		out := new(bytes.Buffer)
+		if err := ShowDefault(context.Background(), &mockDefaultGetter{name: "Ubuntu"}, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.String() != "default: Ubuntu\n" {
+			t.Errorf("unexpected output %q", out.String())
+		}
+	})
+
+	t.Run("unregister prints results and still fails", func(t *testing.T) {
+		useOutput(t, "json")
+		out := new(bytes.Buffer)
+		err := UnregisterDistrosCmd(context.Background(), &mockUnregisterer{shouldFail: true, failOn: "Debian"}, out, []string{"Ubuntu", "Debian"})
+		if err == nil {
+			t.Error("expected an error")
+		}
+
+		var results []wsl.UnregisterResult
+		if err := json.Unmarshal(out.Bytes(), &results); err != nil || len(results) != 2 || results[1].Success {
+			t.Errorf("unexpected output %s, %v", out.String(), err)
+		}
+	})
+
+	t.Run("rename as a table", func(t *testing.T) {
+		useOutput(t, "table")
+		out := new(bytes.Buffer)
+		RenameDistroCmd(context.Background(), &mockRenamer{registered: map[string]bool{"Ubuntu": true}}, out, "Ubuntu", "Work")
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "OLD NAME") || !strings.Contains(lines[1], "Work") || strings.Contains(out.String(), "wsl --shutdown") {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+	})
+}
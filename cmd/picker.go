@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+// errPickerCancelled is returned when the picker is left without choosing
+var errPickerCancelled = errors.New("cancelled")
+
+// pickerItem is a distro offered by the picker
+type pickerItem struct {
+	name      string
+	isDefault bool
+	running   bool
+}
+
+// distroPicker is a Bubble Tea model letting the user pick a distro,
+// filtering the list as they type
+type distroPicker struct {
+	title   string
+	items   []pickerItem
+	query   string
+	matches []pickerItem
+	cursor  int
+	// chosen is the distro picked, or "" if the picker was cancelled
+	chosen string
+}
+
+// newDistroPicker creates a picker offering items in the given order
+func newDistroPicker(title string, items []pickerItem) distroPicker {
+	p := distroPicker{title: title, items: items}
+	p.filter()
+	return p
+}
+
+func (p distroPicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p distroPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	switch key.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		return p, tea.Quit
+	case tea.KeyEnter:
+		if len(p.matches) > 0 {
+			p.chosen = p.matches[p.cursor].name
+			return p, tea.Quit
+		}
+	case tea.KeyUp, tea.KeyCtrlP:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if p.cursor < len(p.matches)-1 {
+			p.cursor++
+		}
+	case tea.KeyBackspace:
+		if p.query != "" {
+			_, size := utf8.DecodeLastRuneInString(p.query)
+			p.query = p.query[:len(p.query)-size]
+			p.filter()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		p.query += string(key.Runes)
+		p.filter()
+	}
+	return p, nil
+}
+
+func (p distroPicker) View() string {
+	if p.chosen != "" {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (type to filter, enter to choose, esc to cancel)\n", p.title)
+	fmt.Fprintf(&b, "> %s\n", p.query)
+	if len(p.matches) == 0 {
+		b.WriteString("  No distros match\n")
+		return b.String()
+	}
+
+	width := 0
+	for _, item := range p.matches {
+		width = max(width, len(item.name))
+	}
+	for i, item := range p.matches {
+		cursor := " "
+		if i == p.cursor {
+			cursor = ">"
+		}
+		mark := " "
+		if item.isDefault {
+			mark = "*"
+		}
+		state := "stopped"
+		if item.running {
+			state = "running"
+		}
+		if item.isDefault {
+			state += ", default"
+		}
+		fmt.Fprintf(&b, "%s %s %-*s  %s\n", cursor, mark, width, item.name, state)
+	}
+	return b.String()
+}
+
+// filter keeps the items matching the query, best matches first, and
+// keeps the cursor on one of them
+func (p *distroPicker) filter() {
+	type match struct {
+		item  pickerItem
+		score int
+	}
+	var matches []match
+	for _, item := range p.items {
+		if score, ok := fuzzyScore(p.query, item.name); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	p.matches = nil
+	for _, m := range matches {
+		p.matches = append(p.matches, m.item)
+	}
+	p.cursor = min(p.cursor, max(len(p.matches)-1, 0))
+}
+
+// fuzzyScore reports whether the letters of query appear in name in order,
+// ignoring case, and scores the match: letters next to each other or at
+// the start of a word score higher
+func fuzzyScore(query, name string) (int, bool) {
+	query = strings.ToLower(query)
+	name = strings.ToLower(name)
+
+	score := 0
+	q := []rune(query)
+	matched := 0
+	previous := -2
+	var last rune
+	for i, r := range []rune(name) {
+		if matched < len(q) && r == q[matched] {
+			score++
+			if i == previous+1 {
+				score += 2
+			}
+			if i == 0 || strings.ContainsRune("-_. ", last) {
+				score += 3
+			}
+			previous = i
+			matched++
+		}
+		last = r
+	}
+	return score, matched == len(q)
+}
+
+// pickDistro shows the picker on the terminal and returns the distro
+// picked. It fails if stdin isn't a terminal, such as in scripts.
+func pickDistro(cmd *cobra.Command, title string, items []pickerItem) (string, error) {
+	in, ok := terminalInput(cmd)
+	if !ok {
+		return "", errors.New("no distro given, and picking one needs a terminal")
+	}
+	if len(items) == 0 {
+		return "", errors.New("no distros are registered")
+	}
+
+	// Drawn on stderr, so the result on stdout can still be parsed
+	model, err := tea.NewProgram(newDistroPicker(title, items), tea.WithInput(in), tea.WithOutput(cmd.ErrOrStderr())).Run()
+	if err != nil {
+		return "", fmt.Errorf("picker failed: %w", err)
+	}
+	if chosen := model.(distroPicker).chosen; chosen != "" {
+		return chosen, nil
+	}
+	return "", errPickerCancelled
+}
+
+// terminalInput returns the command's stdin if it is a terminal, which
+// interactive screens need
+func terminalInput(cmd *cobra.Command) (*os.File, bool) {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !term.IsTerminal(in.Fd()) {
+		return nil, false
+	}
+	return in, true
+}
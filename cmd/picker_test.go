@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickerKeys sends keys to a picker, as Bubble Tea would
+func pickerKeys(p distroPicker, keys ...tea.KeyMsg) distroPicker {
+	for _, key := range keys {
+		model, _ := p.Update(key)
+		p = model.(distroPicker)
+	}
+	return p
+}
+
+// typed returns the key presses typing s
+func typed(s string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for _, r := range s {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+func matchNames(p distroPicker) []string {
+	var names []string
+	for _, item := range p.matches {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, name string
+		ok          bool
+	}{
+		{"", "Ubuntu", true},
+		{"ubu", "Ubuntu-22.04", true},
+		{"u22", "Ubuntu-22.04", true},
+		{"UBU", "ubuntu", true},
+		{"deb", "Ubuntu", false},
+		{"ubuntuu", "Ubuntu", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.name); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.name, ok, tt.ok)
+		}
+	}
+
+	prefix, _ := fuzzyScore("de", "Debian")
+	scattered, _ := fuzzyScore("de", "docker-desktop")
+	if prefix <= scattered {
+		t.Errorf("expected a prefix to score higher, got %d <= %d", prefix, scattered)
+	}
+}
+
+func TestDistroPicker(t *testing.T) {
+	items := []pickerItem{
+		{name: "Ubuntu", isDefault: true, running: true},
+		{name: "Debian"},
+		{name: "docker-desktop", running: true},
+	}
+
+	t.Run("lists every distro with its state", func(t *testing.T) {
+		p := newDistroPicker("Pick", items)
+
+		view := p.View()
+		for _, want := range []string{"> * Ubuntu", "running, default", "Debian", "stopped"} {
+			if !strings.Contains(view, want) {
+				t.Errorf("expected view to contain %q, got:\n%s", want, view)
+			}
+		}
+	})
+
+	t.Run("filters as the user types", func(t *testing.T) {
+		p := pickerKeys(newDistroPicker("Pick", items), typed("de")...)
+
+		names := matchNames(p)
+		if len(names) != 2 || names[0] != "Debian" || names[1] != "docker-desktop" {
+			t.Errorf("unexpected matches %v", names)
+		}
+
+		p = pickerKeys(p, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+		if len(p.matches) != len(items) {
+			t.Errorf("expected every distro after clearing the query, got %v", matchNames(p))
+		}
+	})
+
+	t.Run("chooses the distro under the cursor", func(t *testing.T) {
+		p := pickerKeys(newDistroPicker("Pick", items),
+			tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyDown},
+			tea.KeyMsg{Type: tea.KeyUp})
+
+		model, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if chosen := model.(distroPicker).chosen; chosen != "Debian" {
+			t.Errorf("expected Debian, got %q", chosen)
+		}
+		if cmd == nil {
+			t.Error("expected the picker to quit")
+		}
+	})
+
+	t.Run("keeps the cursor on a match", func(t *testing.T) {
+		p := pickerKeys(newDistroPicker("Pick", items), tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyDown})
+		p = pickerKeys(p, typed("ubu")...)
+
+		if p.cursor != 0 || p.matches[p.cursor].name != "Ubuntu" {
+			t.Errorf("expected the cursor on Ubuntu, got %d in %v", p.cursor, matchNames(p))
+		}
+	})
+
+	t.Run("chooses nothing without a match", func(t *testing.T) {
+		p := pickerKeys(newDistroPicker("Pick", items), typed("zzz")...)
+
+		if !strings.Contains(p.View(), "No distros match") {
+			t.Errorf("unexpected view:\n%s", p.View())
+		}
+		model, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if model.(distroPicker).chosen != "" || cmd != nil {
+			t.Error("expected nothing to be chosen")
+		}
+	})
+
+	t.Run("escape cancels", func(t *testing.T) {
+		model, cmd := newDistroPicker("Pick", items).Update(tea.KeyMsg{Type: tea.KeyEsc})
+		if model.(distroPicker).chosen != "" {
+			t.Error("expected nothing to be chosen")
+		}
+		if cmd == nil {
+			t.Error("expected the picker to quit")
+		}
+	})
+}
@@ -13,15 +13,29 @@ import (
 func RenameDistroCmd(ctx context.Context, r wsl.Renamer, w io.Writer, oldName, newName string) error {
 	result := wsl.RenameDistro(ctx, r, oldName, newName)
 
-	if result.Success {
-		fmt.Fprintf(w, "✓ %s\n", result.Message)
-		fmt.Fprintf(w, "\nTo apply changes, run:\n")
-		fmt.Fprintf(w, "  wsl --shutdown\n")
-		return nil
-	} else {
-		fmt.Fprintf(w, "✗ %s\n", result.Message)
+	err := render(w, result, func() *table {
+		t := newTable("OLD NAME", "NEW NAME", "RESULT", "MESSAGE")
+		t.add(result.OldName, result.NewName, resultColumn(result.Success), result.Message)
+		return t
+	}, func() {
+		if result.Success {
+			fmt.Fprintf(w, "✓ %s\n", result.Message)
+			if !wsl.IsDryRun(ctx) {
+				fmt.Fprintf(w, "\nTo apply changes, run:\n")
+				fmt.Fprintf(w, "  wsl --shutdown\n")
+			}
+		} else {
+			fmt.Fprintf(w, "✗ %s\n", result.Message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !result.Success {
 		return fmt.Errorf("rename failed")
 	}
+	return nil
 }
 
 func init() {
@@ -42,12 +56,16 @@ you should restart WSL for the changes to take effect:
 The rename operation:
 - Validates the old distro exists
 - Checks the new name doesn't conflict with existing distros
-- Updates the registry entry directly (fast, no export/import needed)`,
+- Updates the registry entry directly (fast, no export/import needed)
+
+--dry-run makes the same checks without renaming the distro.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return RenameDistroCmd(context.Background(), wsl.RealRenamer{}, cmd.OutOrStdout(), args[0], args[1])
+			return RenameDistroCmd(lockContext(cmd), wsl.RealRenamer{}, cmd.OutOrStdout(), args[0], args[1])
 		},
 	}
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
 
 	return cmd
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"wslp/internal/wsl"
 )
 
 type mockRenamer struct {
@@ -81,6 +83,24 @@ func TestRenameCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("dry run doesn't ask for a shutdown", func(t *testing.T) {
+		mock := &mockRenamer{}
+		out := new(bytes.Buffer)
+
+		err := RenameDistroCmd(wsl.WithDryRun(context.Background()), mock, out, "Ubuntu", "MyUbuntu")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Would rename Ubuntu to MyUbuntu") {
+			t.Errorf("expected dry run message in output, got:\n%s", output)
+		}
+		if strings.Contains(output, "wsl --shutdown") {
+			t.Errorf("expected no shutdown instruction in output, got:\n%s", output)
+		}
+	})
+
 	t.Run("returns error when rename fails", func(t *testing.T) {
 		mock := &mockRenamer{shouldFail: true}
 		out := new(bytes.Buffer)
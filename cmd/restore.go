@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"wslp/internal/config"
+	"wslp/internal/wsl"
+)
+
+// RestoreDistroCmd restores a WSL distribution from a backup archive.
+func RestoreDistroCmd(ctx context.Context, r wsl.Restorer, w io.Writer, distro, backupDir string, opts wsl.RestoreOptions) error {
+	if distro == "" && opts.File == "" {
+		return fmt.Errorf("a distro name or --file is required")
+	}
+
+	// Determine backup directory
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	result := wsl.RestoreDistro(ctx, r, distro, backupDir, opts)
+
+	err := render(w, result, func() *table {
+		t := newTable("DISTRO", "NEW NAME", "RESULT", "FILE", "MESSAGE")
+		t.add(result.Distro, result.NewName, resultColumn(result.Success), result.FilePath, result.Message)
+		return t
+	}, func() {
+		if !result.Success {
+			fmt.Fprintf(w, "✗ %s\n", result.Message)
+			return
+		}
+		fmt.Fprintf(w, "✓ %s\n", result.Message)
+		if !wsl.IsDryRun(ctx) {
+			fmt.Fprintf(w, "  Restored from: %s\n", result.FilePath)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !result.Success {
+		return fmt.Errorf("restore failed")
+	}
+	return nil
+}
+
+// confirmOverwrite asks before restore --overwrite replaces a registered
+// distro, as unregister does. Errors checking the distro are left for the
+// restore to report.
+func confirmOverwrite(cmd *cobra.Command, r wsl.Restorer, distro string, opts wsl.RestoreOptions) error {
+	name := opts.NewName
+	if name == "" {
+		name = distro
+	}
+	if !opts.Overwrite || name == "" {
+		return nil
+	}
+	if exists, err := r.IsRegistered(context.Background(), name); err != nil || !exists {
+		return nil
+	}
+	return confirm(cmd, confirmation{
+		verb:     "replace",
+		distros:  []string{name},
+		warning:  "It will be deleted permanently and replaced by the backup.",
+		typeName: true,
+	})
+}
+
+func init() {
+	RootCmd.AddCommand(newRestoreCmd())
+}
+
+func newRestoreCmd() *cobra.Command {
+	var opts wsl.RestoreOptions
+	var backupDir string
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <distro>",
+		Short: "Restore a WSL distribution from a backup",
+		Long: `Restore a WSL distribution from a backup created with 'wslp backup'.
+
+Backups are looked up in %USERPROFILE%\WSLBackups (or the configured backup_dir)
+by distro name. The most recent backup is restored unless a specific one is
+selected with --timestamp (e.g., 20240301-143022). Use --list to see the
+backups available.
+
+Encrypted backups (.age) are decrypted on the fly using
+backup_encryption.identity_file or the WSLP_BACKUP_PASSPHRASE passphrase.
+
+By default the distro is restored under its original name and stored in
+%USERPROFILE%\WSLRestores\<name>. Use --name and --install-dir to change this.
+
+Restoring onto a name that is already registered is refused unless --overwrite
+is given, in which case the backup is verified and the existing distribution is
+unregistered before importing it. A backup that is corrupt or can't be
+decrypted leaves the existing distribution in place.
+WARNING: this permanently deletes the existing distribution's data.
+
+Before replacing a distro, the command asks you to type its name to confirm.
+Pass --yes to skip the question, which scripts must do as it can only be asked
+on a terminal. --dry-run checks the backup could be restored without
+restoring it.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			distro := ""
+			if len(args) == 1 {
+				distro = args[0]
+			}
+			if list {
+				return ListBackupsCmd(cmd.OutOrStdout(), distro, backupDir, false)
+			}
+			if err := confirmOverwrite(cmd, wsl.RealRestorer{}, distro, opts); err != nil {
+				return err
+			}
+			return RestoreDistroCmd(lockContext(cmd), wsl.RealRestorer{}, cmd.OutOrStdout(), distro, backupDir, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Timestamp, "timestamp", "t", "latest", "Timestamp of the backup to restore (YYYYMMDD-HHMMSS or latest)")
+	cmd.Flags().StringVarP(&opts.File, "file", "f", "", "Restore a specific backup file instead of looking one up by distro")
+	cmd.Flags().StringVarP(&opts.NewName, "name", "n", "", "Register the restored distro under a new name")
+	cmd.Flags().StringVarP(&opts.InstallDir, "install-dir", "i", "", "Directory to store the restored distro's virtual disk (overrides default)")
+	cmd.Flags().BoolVar(&opts.Overwrite, "overwrite", false, "Replace an existing distro with the same name")
+	cmd.Flags().StringVarP(&backupDir, "backup-dir", "d", "", "Directory to look for backups in (overrides config)")
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "List available backups instead of restoring")
+	addYesFlag(cmd)
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
+
+	return cmd
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"wslp/internal/wsl"
+)
+
+type mockRestorer struct {
+	registered bool
+	shouldFail bool
+}
+
+func (m *mockRestorer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return m.registered, nil
+}
+
+func (m *mockRestorer) Unregister(ctx context.Context, name string) error {
+	return nil
+}
+
+func (m *mockRestorer) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	if m.shouldFail {
+		return errors.New("import failed")
+	}
+	return nil
+}
+
+// newBackupDir creates a temporary backup directory containing the named files
+func newBackupDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestRestoreCommand(t *testing.T) {
+	t.Run("command metadata", func(t *testing.T) {
+		restoreCmd, _, err := RootCmd.Find([]string{"restore"})
+		if err != nil {
+			t.Fatalf("restore command not found: %v", err)
+		}
+
+		if restoreCmd.Use != "restore <distro>" {
+			t.Errorf("expected Use='restore <distro>', got '%s'", restoreCmd.Use)
+		}
+
+		if restoreCmd.Short == "" {
+			t.Error("Short description is empty")
+		}
+
+		if restoreCmd.Long == "" {
+			t.Error("Long description is empty")
+		}
+	})
+
+	t.Run("restores latest backup", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz")
+		out := new(bytes.Buffer)
+
+		err := RestoreDistroCmd(context.Background(), &mockRestorer{}, out, "Ubuntu", dir, wsl.RestoreOptions{InstallDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, out.String())
+		}
+
+		if !strings.Contains(out.String(), "Ubuntu-20240301-143022.tar.gz") {
+			t.Errorf("expected restored file in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("returns error when target is registered", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz")
+		out := new(bytes.Buffer)
+
+		err := RestoreDistroCmd(context.Background(), &mockRestorer{registered: true}, out, "Ubuntu", dir, wsl.RestoreOptions{InstallDir: t.TempDir()})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		if !strings.Contains(out.String(), "✗") {
+			t.Errorf("expected failure indicator in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("dry run doesn't restore", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz")
+		out := new(bytes.Buffer)
+
+		err := RestoreDistroCmd(wsl.WithDryRun(context.Background()), &mockRestorer{shouldFail: true}, out, "Ubuntu", dir, wsl.RestoreOptions{InstallDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, out.String())
+		}
+		if !strings.Contains(out.String(), "Would restore Ubuntu") || strings.Contains(out.String(), "Restored from") {
+			t.Errorf("expected a dry run message, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("requires distro or file", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		err := RestoreDistroCmd(context.Background(), &mockRestorer{}, out, "", t.TempDir(), wsl.RestoreOptions{})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("lists backups for a distro", func(t *testing.T) {
+		dir := newBackupDir(t, "Ubuntu-20240301-143022.tar.gz", "Debian-20240301-143022.tar.gz")
+		out := new(bytes.Buffer)
+
+		if err := ListBackupsCmd(out, "Ubuntu", dir, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Ubuntu") || strings.Contains(output, "Debian") {
+			t.Errorf("expected only Ubuntu backups in output, got:\n%s", output)
+		}
+	})
+
+	t.Run("reports empty backup directory", func(t *testing.T) {
+		out := new(bytes.Buffer)
+
+		if err := ListBackupsCmd(out, "", t.TempDir(), false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(out.String(), "No backups found") {
+			t.Errorf("expected 'No backups found' in output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("flag defaults", func(t *testing.T) {
+		restoreCmd, _, err := RootCmd.Find([]string{"restore"})
+		if err != nil {
+			t.Fatalf("restore command not found: %v", err)
+		}
+
+		for _, name := range []string{"timestamp", "file", "name", "install-dir", "overwrite", "backup-dir", "list", "yes", "dry-run"} {
+			if restoreCmd.Flags().Lookup(name) == nil {
+				t.Errorf("%s flag not found", name)
+			}
+		}
+
+		if got := restoreCmd.Flags().Lookup("timestamp").DefValue; got != "latest" {
+			t.Errorf("expected timestamp default 'latest', got '%s'", got)
+		}
+	})
+}
+
+func TestConfirmOverwrite(t *testing.T) {
+	// newCmd creates a command that reads input and accepts --yes
+	newCmd := func(input string) (*cobra.Command, *bytes.Buffer) {
+		cmd := &cobra.Command{}
+		addYesFlag(cmd)
+		addDryRunFlag(cmd)
+		cmd.SetIn(strings.NewReader(input))
+		stderr := new(bytes.Buffer)
+		cmd.SetErr(stderr)
+		return cmd, stderr
+	}
+	overwrite := wsl.RestoreOptions{Overwrite: true}
+
+	t.Run("asks for the name of a registered distro", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("Ubuntu\n")
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", overwrite); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(stderr.String(), "replace 1 distro(s)") {
+			t.Errorf("expected to be asked, got:\n%s", stderr.String())
+		}
+
+		cmd, _ = newCmd("y\n")
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", overwrite); err == nil {
+			t.Error("expected anything but the name to cancel")
+		}
+	})
+
+	t.Run("asks about the new name", func(t *testing.T) {
+		useInteractive(t, true)
+		cmd, stderr := newCmd("Work\n")
+		opts := wsl.RestoreOptions{Overwrite: true, NewName: "Work"}
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", opts); err != nil {
+			t.Errorf("unexpected error: %v\n%s", err, stderr.String())
+		}
+	})
+
+	t.Run("fails without a terminal unless --yes is given", func(t *testing.T) {
+		useInteractive(t, false)
+		cmd, _ := newCmd("")
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", overwrite); err == nil || !strings.Contains(err.Error(), "--yes") {
+			t.Errorf("expected an error mentioning --yes, got %v", err)
+		}
+
+		cmd.Flags().Set("yes", "true")
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", overwrite); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("doesn't ask when nothing is replaced", func(t *testing.T) {
+		useInteractive(t, false)
+		cmd, _ := newCmd("")
+		if err := confirmOverwrite(cmd, &mockRestorer{}, "Ubuntu", overwrite); err != nil {
+			t.Errorf("unexpected error for an unregistered distro: %v", err)
+		}
+		if err := confirmOverwrite(cmd, &mockRestorer{registered: true}, "Ubuntu", wsl.RestoreOptions{}); err != nil {
+			t.Errorf("unexpected error without --overwrite: %v", err)
+		}
+	})
+}
@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/charmbracelet/fang"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"wslp/internal/audit"
 	"wslp/internal/config"
+	"wslp/internal/logging"
+	"wslp/internal/wsl"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -17,6 +23,8 @@ var RootCmd = &cobra.Command{
 
 	// Avoid the "Auto generated by spf13/cobra" line in the generated markdown docs
 	DisableAutoGenTag: true,
+
+	PersistentPreRunE: checkOutputFormat,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -30,4 +38,61 @@ func Execute() {
 func init() {
 	// Initialize configuration
 	config.Init()
+	cobra.OnInitialize(initLogging, initLocks, initAudit)
+
+	RootCmd.PersistentFlags().String("log-level", "", "Lowest level to log: debug, info, warn or error (default from config, or info)")
+	RootCmd.PersistentFlags().String("log-format", "", "Log format: text or json (default from config, or text)")
+	viper.BindPFlag("log_level", RootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("log_format", RootCmd.PersistentFlags().Lookup("log-format"))
+
+	RootCmd.PersistentFlags().StringP("output", "o", "", "Output format: text, table, json or yaml (default from config, or text)")
+	viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
+}
+
+// initLogging writes logs to stderr, keeping them apart from the output of
+// commands
+func initLogging() {
+	if err := logging.Setup(os.Stderr, config.GetLogLevel(), config.GetLogFormat()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; logging at info level as text\n", err)
+		logging.Setup(os.Stderr, "info", logging.FormatText)
+	}
+}
+
+// initAudit records destructive actions, whether made by commands or
+// through the API server, in the same audit log
+func initAudit() {
+	audit.Default.SetPath(config.GetAuditLogPath())
+}
+
+// initLocks keeps the distro lock files in the state directory, so
+// commands and the API server never run conflicting operations on a
+// distro at once
+func initLocks() {
+	if dir := config.GetStateDir(); dir != "" {
+		wsl.Locks.SetDir(filepath.Join(dir, "locks"))
+	}
+}
+
+// addWaitFlag adds --wait to a command that changes distros
+func addWaitFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "Wait for other operations on the same distros to finish instead of failing")
+}
+
+// addDryRunFlag adds --dry-run to a command changing distros
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Check what would be done, without changing anything")
+}
+
+// lockContext returns the context a command runs its operations with,
+// which waits for busy distros if --wait was given and only checks the
+// operations could run with --dry-run
+func lockContext(cmd *cobra.Command) context.Context {
+	ctx := context.Background()
+	if wait, _ := cmd.Flags().GetBool("wait"); wait {
+		ctx = wsl.WithLockWait(ctx)
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		ctx = wsl.WithDryRun(ctx)
+	}
+	return ctx
 }
@@ -24,7 +24,7 @@ func TestRootCommand(t *testing.T) {
 	})
 
 	t.Run("common subcommands are registered", func(t *testing.T) {
-		expectedCommands := []string{"list", "default", "backup", "copy", "terminate", "rename", "unregister", "install", "launch", "serve"}
+		expectedCommands := []string{"list", "default", "backup", "copy", "terminate", "rename", "unregister", "install", "launch", "serve", "restore"}
 
 		for _, cmd := range expectedCommands {
 			found, _, err := RootCmd.Find([]string{cmd})
@@ -37,4 +37,16 @@ func TestRootCommand(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("destructive commands accept --dry-run", func(t *testing.T) {
+		for _, name := range []string{"backup", "copy", "rename", "restore", "terminate", "unregister"} {
+			found, _, err := RootCmd.Find([]string{name})
+			if err != nil {
+				t.Fatalf("command '%s' not found: %v", name, err)
+			}
+			if found.Flags().Lookup("dry-run") == nil {
+				t.Errorf("expected --dry-run on '%s'", name)
+			}
+		}
+	})
 }
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"wslp/internal/wsl"
+)
+
+// selectorHelp explains the selector flags, for the Long description of
+// the bulk commands
+const selectorHelp = `Distros can be named, matched with glob patterns such as 'Ubuntu-*' (quote
+them so the shell leaves them alone), or selected with --all, --running,
+--stopped or --tag. --running and --stopped also narrow the distros named or
+matched. Tags are given to distros under distro_tags in ~/.wslp.yaml:
+
+    distro_tags:
+      Ubuntu-22.04: [dev, work]
+      docker-desktop: [infra]
+
+Use --exclude to leave distros out, e.g. --all --exclude 'docker-desktop*'.`
+
+// addSelectorFlags adds the flags selecting distros by state, tag or
+// pattern to a bulk command, whose arguments are names or patterns
+func addSelectorFlags(cmd *cobra.Command, sel *wsl.Selector) {
+	cmd.Flags().BoolVar(&sel.All, "all", false, "Select every registered distro")
+	cmd.Flags().BoolVar(&sel.Running, "running", false, "Select the running distros, or only those among the distros named")
+	cmd.Flags().BoolVar(&sel.Stopped, "stopped", false, "Select the stopped distros, or only those among the distros named")
+	cmd.Flags().StringSliceVar(&sel.Tags, "tag", nil, "Select the distros with this tag under distro_tags (repeatable)")
+	cmd.Flags().StringSliceVar(&sel.Exclude, "exclude", nil, "Leave out the distros matching this name or pattern (repeatable)")
+}
+
+// selectorArgs requires a bulk command to be given distros or a selector
+func selectorArgs(sel *wsl.Selector) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !sel.All && !sel.Running && !sel.Stopped && len(sel.Tags) == 0 {
+			return errors.New("requires at least one distro, pattern or selector such as --all")
+		}
+		return nil
+	}
+}
+
+// selectDistros resolves the distros a bulk command acts on from its
+// arguments and selector flags
+func selectDistros(ctx context.Context, l wsl.Lister, sel wsl.Selector, args []string) ([]string, error) {
+	sel.Distros = args
+	return wsl.SelectDistros(ctx, l, sel)
+}
+
+// matched reports whether the distros a bulk command acts on were matched
+// by a pattern or selector, rather than all named
+func matched(sel wsl.Selector, args []string) bool {
+	sel.Distros = args
+	return !sel.Explicit()
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"wslp/internal/wsl"
+)
+
+func TestSelectorFlags(t *testing.T) {
+	for _, name := range []string{"backup", "terminate", "unregister"} {
+		cmd, _, err := RootCmd.Find([]string{name})
+		if err != nil {
+			t.Fatalf("%s command not found: %v", name, err)
+		}
+		for _, flag := range []string{"all", "running", "stopped", "tag", "exclude"} {
+			if cmd.Flags().Lookup(flag) == nil {
+				t.Errorf("%s: --%s flag not found", name, flag)
+			}
+		}
+		if err := cmd.Args(cmd, nil); err == nil {
+			t.Errorf("%s: expected an error without distros or selectors", name)
+		}
+	}
+
+	for _, name := range []string{"terminate", "unregister"} {
+		cmd, _, _ := RootCmd.Find([]string{name})
+		if cmd.Flags().ShorthandLookup("y") == nil {
+			t.Errorf("%s: -y/--yes flag not found", name)
+		}
+	}
+}
+
+func TestSelectDistros(t *testing.T) {
+	t.Run("patterns and exclusions", func(t *testing.T) {
+		lister := &mockLister{names: []string{"Ubuntu-22.04", "Ubuntu-24.04", "docker-desktop", "docker-desktop-data"}}
+
+		got, err := selectDistros(context.Background(), lister, wsl.Selector{All: true, Exclude: []string{"docker-desktop*"}}, nil)
+		if err != nil || strings.Join(got, ",") != "Ubuntu-22.04,Ubuntu-24.04" {
+			t.Errorf("got %v, %v", got, err)
+		}
+
+		got, err = selectDistros(context.Background(), lister, wsl.Selector{}, []string{"ubuntu-2?.04"})
+		if err != nil || len(got) != 2 {
+			t.Errorf("got %v, %v", got, err)
+		}
+	})
+
+	t.Run("names need no listing", func(t *testing.T) {
+		got, err := selectDistros(context.Background(), &mockLister{shouldFail: true}, wsl.Selector{}, []string{"Ubuntu"})
+		if err != nil || len(got) != 1 {
+			t.Errorf("got %v, %v", got, err)
+		}
+	})
+}
+
+func TestMatched(t *testing.T) {
+	if matched(wsl.Selector{}, []string{"Ubuntu", "Debian"}) {
+		t.Error("expected named distros not to be matched")
+	}
+	if !matched(wsl.Selector{}, []string{"Ub*"}) {
+		t.Error("expected a pattern to be matched")
+	}
+	if !matched(wsl.Selector{All: true}, nil) {
+		t.Error("expected --all to be matched")
+	}
+}
@@ -2,24 +2,121 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"wslp/internal/config"
 	"wslp/server"
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the HTTP API server",
-	Long:  `Starts an HTTP server that exposes WSL operations via REST API for the Flutter GUI.`,
+	Long: `Starts an HTTP server that exposes WSL operations via REST API for the Flutter GUI.
+
+The API is served under /api/v1, with resources such as /api/v1/distros/{name}
+and /api/v1/distros/{name}/backups. It is described by the OpenAPI document at
+/api/v1/openapi.json. Errors are returned as {"error": {"code": ..., "message":
+...}}, where code is machine-readable, e.g. invalid_request or job_not_found.
+The unversioned /api routes remain as aliases while clients move to /api/v1.
+
+The server listens on 127.0.0.1, so only this machine can reach it. Use
+--bind (or server_bind in ~/.wslp.yaml) to listen on another address.
+
+To avoid a TCP port altogether, use --listen (or server_listen) with a Unix
+domain socket, unix:///path/to/wslp.sock, or a Windows named pipe,
+npipe:////./pipe/wslp (npipe:// alone uses \\.\pipe\wslp). Only the current
+user can connect to the socket or pipe, so requests over it need no API token
+and --bind and --port are ignored. The GUI connects over TCP only.
+
+Each time the server starts it makes a random API token and writes it to
+server-<port>.token in the state directory (state_dir, %USERPROFILE%\.wslp by
+default), readable only by the current user. The GUI reads the token from
+there. Every request, except CORS preflights and /api/v1/openapi.json, must
+send it in an Authorization: Bearer header, or is rejected with 401
+Unauthorized.
+
+Browser pages may only call the server from the origins listed under
+server_allowed_origins in ~/.wslp.yaml ("*" allows any). With none listed,
+pages served from this machine (e.g. http://localhost:5000) are allowed.
+
+Installs, backups and copies run in the background as jobs, one at a time.
+Those endpoints return 202 Accepted with the job straight away. Jobs are
+followed with GET /api/v1/jobs/{id}, listed with GET /api/v1/jobs and
+cancelled with DELETE /api/v1/jobs/{id}.
+
+GET /api/v1/events streams server-sent events: job.* and operation.* events as
+jobs progress (including bytes written by backups and the export and import
+phases of copies), distro.added, distro.removed and distro.state when distros
+change, and default.changed when the default distro changes. Limit the stream
+with ?types=, e.g. ?types=distro.,default.changed.
+
+Requests and jobs are cancelled, stopping the WSL operation they started,
+when the client disconnects or they run longer than the timeout for their
+endpoint. Timeouts are set under server_timeouts in ~/.wslp.yaml, by the
+first path segment after /api/, with default covering the rest. The event
+stream and files downloaded from backups only time out if their own endpoint
+(events or backups) has a timeout, not after the default:
+
+    server_timeouts:
+      default: 5m
+      install: 2h
+      backup: 4h
+      copy: 4h
+      restore: 4h
+      schedules: 4h
+      events: 0       # 0 means no limit
+
+A cancelled export or copy removes the partial archive or disk it wrote.
+
+While the server is running it also runs the backup schedules listed under
+backup_schedules in ~/.wslp.yaml. Each schedule has an id, a cron expression
+(five fields, or a descriptor such as @daily), the distros to back up, and
+optionally a format, target, encrypt setting and retention policy:
+
+    backup_schedules:
+      - id: nightly
+        cron: "0 2 * * *"
+        distros: [Ubuntu, Debian]
+        format: zstd
+        retention:
+          keep_last: 7
+        catch_up: true
+
+The status of each schedule is kept in the state directory (state_dir,
+%USERPROFILE%\.wslp by default). With catch_up set, a run missed while the
+server was down happens once when it starts. Schedules are listed with
+GET /api/v1/schedules and run immediately with POST /api/v1/schedules/{id}/run.
+
+Requests are logged to stderr: changes at info level and reads at debug
+level. Set the level and format with --log-level and --log-format, e.g.
+--log-format json. Unregisters, renames, copies, default and telemetry
+changes and shutdowns are recorded in the audit log with the client that made
+them, and listed with GET /api/v1/audit or wslp audit.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		port, _ := cmd.Flags().GetString("port")
+		bind, _ := cmd.Flags().GetString("bind")
+		if bind == "" {
+			bind = config.GetServerBind()
+		}
 
-		s := server.NewServer(port)
+		listen, _ := cmd.Flags().GetString("listen")
+		if listen == "" {
+			listen = config.GetServerListen()
+		}
+
+		s := server.NewServer(bind, port)
+		if listen != "" {
+			var err error
+			if s, err = server.NewSocketServer(listen); err != nil {
+				slog.Error("Server error", "error", err)
+				return
+			}
+		}
 
 		errCh := make(chan error, 1)
 		go func() {
@@ -34,23 +131,25 @@ var serveCmd = &cobra.Command{
 		select {
 		case err := <-errCh:
 			if err != nil {
-				fmt.Printf("Server error: %v\n", err)
+				slog.Error("Server error", "error", err)
 			}
 		case <-sigCh:
-			fmt.Println("Shutting down server...")
+			slog.Info("Shutting down server")
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			if err := s.Shutdown(ctx); err != nil {
-				fmt.Printf("Error during shutdown: %v\n", err)
+				slog.Error("Error during shutdown", "error", err)
 			}
 			<-errCh
 		}
 
-		fmt.Println("Server stopped")
+		slog.Info("Server stopped")
 	},
 }
 
 func init() {
 	serveCmd.Flags().StringP("port", "p", "8080", "Port to run the server on")
+	serveCmd.Flags().String("bind", "", "Address to listen on (default from config, or 127.0.0.1)")
+	serveCmd.Flags().String("listen", "", "Unix socket (unix:///path) or named pipe (npipe://name) to listen on instead of TCP")
 	RootCmd.AddCommand(serveCmd)
 }
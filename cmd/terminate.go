@@ -13,19 +13,38 @@ import (
 func TerminateDistrosCmd(ctx context.Context, t wsl.Terminator, w io.Writer, distros []string) error {
 	results := wsl.TerminateDistros(ctx, t, distros)
 
-	// Print results
 	successCount := 0
 	for _, result := range results {
 		if result.Success {
 			successCount++
-			fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
-		} else {
-			fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
 		}
 	}
 
-	if successCount > 0 {
-		fmt.Fprintf(w, "\nSuccessfully terminated %d/%d distribution(s)\n", successCount, len(results))
+	err := render(w, results, func() *table {
+		t := newTable("DISTRO", "RESULT", "MESSAGE")
+		for _, result := range results {
+			t.add(result.Distro, resultColumn(result.Success), result.Message)
+		}
+		return t
+	}, func() {
+		for _, result := range results {
+			if result.Success {
+				fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
+			} else {
+				fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
+			}
+		}
+
+		if successCount > 0 {
+			summary := "Successfully terminated %d/%d distribution(s)"
+			if wsl.IsDryRun(ctx) {
+				summary = "Dry run: %d/%d distribution(s) would be terminated"
+			}
+			fmt.Fprintf(w, "\n"+summary+"\n", successCount, len(results))
+		}
+	})
+	if err != nil {
+		return err
 	}
 
 	if successCount < len(results) {
@@ -40,6 +59,8 @@ func init() {
 }
 
 func newTerminateCmd() *cobra.Command {
+	var sel wsl.Selector
+
 	cmd := &cobra.Command{
 		Use:     "terminate <distro> [distro...]",
 		Aliases: []string{"stop", "kill"},
@@ -47,12 +68,32 @@ func newTerminateCmd() *cobra.Command {
 		Long: `Terminate (stop) one or more running WSL distributions.
 
 This is useful before performing operations like backups, or to free up system resources.
-Terminating a distro will stop all processes running in that distribution.`,
-		Args: cobra.MinimumNArgs(1),
+Terminating a distro will stop all processes running in that distribution.
+
+` + selectorHelp + `
+
+Before terminating several distros, or distros that were matched rather than
+named, the command lists them and asks for confirmation. Pass --yes to skip the
+question, which scripts must do as it can only be asked on a terminal.
+--dry-run checks the distros could be terminated without terminating them.`,
+		Args: selectorArgs(&sel),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return TerminateDistrosCmd(context.Background(), wsl.RealTerminator{}, cmd.OutOrStdout(), args)
+			distros, err := selectDistros(context.Background(), wsl.RealLister{}, sel, args)
+			if err != nil {
+				return err
+			}
+			if len(distros) > 1 || matched(sel, args) {
+				if err := confirm(cmd, confirmation{verb: "terminate", distros: distros}); err != nil {
+					return err
+				}
+			}
+			return TerminateDistrosCmd(lockContext(cmd), wsl.RealTerminator{}, cmd.OutOrStdout(), distros)
 		},
 	}
+	addSelectorFlags(cmd, &sel)
+	addYesFlag(cmd)
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
 
 	return cmd
 }
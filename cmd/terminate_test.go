@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"wslp/internal/wsl"
 )
 
 type mockTerminator struct {
@@ -143,4 +145,21 @@ func TestTerminateCommand(t *testing.T) {
 			t.Errorf("expected partial success message, got:\n%s", output)
 		}
 	})
+	t.Run("dry run reports what would be terminated", func(t *testing.T) {
+		mock := &mockTerminator{}
+		out := new(bytes.Buffer)
+
+		err := TerminateDistrosCmd(wsl.WithDryRun(context.Background()), mock, out, []string{"Ubuntu", "Debian"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "Dry run: 2/2 distribution(s) would be terminated") {
+			t.Errorf("expected dry run summary, got:\n%s", output)
+		}
+		if strings.Contains(output, "Successfully") {
+			t.Errorf("expected nothing to be reported as done, got:\n%s", output)
+		}
+	})
 }
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"wslp/internal/tui"
+)
+
+func init() {
+	RootCmd.AddCommand(newTuiCmd())
+}
+
+func newTuiCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Manage WSL distributions in a full-screen terminal UI",
+		Long: `Open a full-screen terminal UI for managing WSL distributions, as an
+alternative to the GUI.
+
+The table lists the registered distributions with their state, WSL version,
+flavor and which is the default. Select one with the arrow keys, then:
+
+    enter  open a shell in it, returning to the table when it exits
+    t      terminate it
+    b      back it up, as 'wslp backup' does without flags
+    c      copy it under a new name
+    r      rename it
+    u      unregister it
+    w      show or hide the Workshops running in it
+
+Terminating, renaming and unregistering ask for confirmation first. Backups
+and copies run in the background, showing their progress below the table, and
+one operation runs at a time. Press R to refresh the table, ? for every key and
+q to quit. Quitting with ctrl+c cancels the running operation.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in, ok := terminalInput(cmd)
+			if !ok {
+				return errors.New("the TUI needs a terminal")
+			}
+			return tui.Run(context.Background(), tui.RealBackend(), in, cmd.OutOrStdout())
+		},
+	}
+
+	return cmd
+}
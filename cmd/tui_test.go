@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTuiCommand(t *testing.T) {
+	t.Run("command metadata", func(t *testing.T) {
+		tuiCmd, _, err := RootCmd.Find([]string{"tui"})
+		if err != nil {
+			t.Fatalf("tui command not found: %v", err)
+		}
+
+		if tuiCmd.Use != "tui" {
+			t.Errorf("expected Use='tui', got '%s'", tuiCmd.Use)
+		}
+
+		if tuiCmd.Short == "" || tuiCmd.Long == "" {
+			t.Error("descriptions are empty")
+		}
+	})
+
+	t.Run("needs a terminal", func(t *testing.T) {
+		cmd := newTuiCmd()
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetArgs([]string{})
+
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "terminal") {
+			t.Errorf("expected terminal error, got %v", err)
+		}
+	})
+}
@@ -13,19 +13,38 @@ import (
 func UnregisterDistrosCmd(ctx context.Context, u wsl.Unregisterer, w io.Writer, distros []string) error {
 	results := wsl.UnregisterDistros(ctx, u, distros)
 
-	// Print results
 	successCount := 0
 	for _, result := range results {
 		if result.Success {
 			successCount++
-			fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
-		} else {
-			fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
 		}
 	}
 
-	if successCount > 0 {
-		fmt.Fprintf(w, "\nSuccessfully unregistered %d/%d distribution(s)\n", successCount, len(results))
+	err := render(w, results, func() *table {
+		t := newTable("DISTRO", "RESULT", "MESSAGE")
+		for _, result := range results {
+			t.add(result.Distro, resultColumn(result.Success), result.Message)
+		}
+		return t
+	}, func() {
+		for _, result := range results {
+			if result.Success {
+				fmt.Fprintf(w, "✓ %s: %s\n", result.Distro, result.Message)
+			} else {
+				fmt.Fprintf(w, "✗ %s: %s\n", result.Distro, result.Message)
+			}
+		}
+
+		if successCount > 0 {
+			summary := "Successfully unregistered %d/%d distribution(s)"
+			if wsl.IsDryRun(ctx) {
+				summary = "Dry run: %d/%d distribution(s) would be unregistered"
+			}
+			fmt.Fprintf(w, "\n"+summary+"\n", successCount, len(results))
+		}
+	})
+	if err != nil {
+		return err
 	}
 
 	if successCount < len(results) {
@@ -40,6 +59,8 @@ func init() {
 }
 
 func newUnregisterCmd() *cobra.Command {
+	var sel wsl.Selector
+
 	cmd := &cobra.Command{
 		Use:     "unregister <distro> [distro...]",
 		Aliases: []string{"delete", "remove"},
@@ -47,12 +68,37 @@ func newUnregisterCmd() *cobra.Command {
 		Long: `Unregister (delete) one or more WSL distributions.
 
 WARNING: This will permanently delete the distribution and all its data.
-Make sure to backup any important data before unregistering.`,
-		Args: cobra.MinimumNArgs(1),
+Make sure to backup any important data before unregistering.
+
+` + selectorHelp + `
+
+Before unregistering anything, the command lists the distros and asks you to
+type the name of the distro, or the number of distros, to confirm. Pass --yes
+to skip the question, which scripts must do as it can only be asked on a
+terminal. --dry-run checks the distros could be unregistered without
+unregistering them.`,
+		Args: selectorArgs(&sel),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return UnregisterDistrosCmd(context.Background(), wsl.RealUnregisterer{}, cmd.OutOrStdout(), args)
+			distros, err := selectDistros(context.Background(), wsl.RealLister{}, sel, args)
+			if err != nil {
+				return err
+			}
+			err = confirm(cmd, confirmation{
+				verb:     "unregister",
+				distros:  distros,
+				warning:  "They will be deleted permanently, with all their files.",
+				typeName: true,
+			})
+			if err != nil {
+				return err
+			}
+			return UnregisterDistrosCmd(lockContext(cmd), wsl.RealUnregisterer{}, cmd.OutOrStdout(), distros)
 		},
 	}
+	addSelectorFlags(cmd, &sel)
+	addYesFlag(cmd)
+	addDryRunFlag(cmd)
+	addWaitFlag(cmd)
 
 	return cmd
 }
@@ -7,9 +7,36 @@ import 'services/api_service.dart';
 
 void main(List<String> args) {
   ApiService.configurePort(_resolvePort(args));
+  final token = _resolveToken(args);
+  if (token != null) {
+    ApiService.configureToken(token);
+  }
   runApp(const MainApp());
 }
 
+/// Resolves a fixed API token for the wslp server, from a `--token=<t>` or
+/// `--token <t>` command-line argument or a `WSLP_TOKEN` environment
+/// variable. Returns null when neither is given, in which case the token is
+/// read from the file the server writes when it starts.
+String? _resolveToken(List<String> args) {
+  for (var i = 0; i < args.length; i++) {
+    final arg = args[i];
+    if (arg.startsWith('--token=')) {
+      return arg.substring('--token='.length);
+    }
+    if (arg == '--token' && i + 1 < args.length) {
+      return args[i + 1];
+    }
+  }
+
+  final envToken = Platform.environment['WSLP_TOKEN'];
+  if (envToken != null && envToken.isNotEmpty) {
+    return envToken;
+  }
+
+  return null;
+}
+
 /// Resolves which port the GUI should talk to the wslp server on, so the
 /// GUI can be pointed at a server started with a custom port (e.g.
 /// `wslp serve --port 9090`). Checks, in order:
@@ -68,6 +95,7 @@ class _MainScreenState extends State<MainScreen> {
   bool _isInstalling = false;
   String _currentlyInstalling = '';
   Timer? _refreshTimer;
+  StreamSubscription<Map<String, dynamic>>? _eventsSubscription;
   bool _ubuntuTelemetry = false;
   // Workshop (canonical/workshop) environments found per distro, keyed by
   // distro name (lowercased). Populated lazily/best-effort; a missing entry
@@ -82,10 +110,8 @@ class _MainScreenState extends State<MainScreen> {
     _loadDistros();
     _loadUbuntuTelemetry();
 
-    // Set up periodic refresh every 5 seconds
-    _refreshTimer = Timer.periodic(const Duration(seconds: 5), (timer) {
-      _loadDistros();
-    });
+    // Refresh when the server reports distros changing
+    _listenForChanges();
 
     // The GUI depends on the wslp server, so when the user closes this
     // window (native close button), stop the server too rather than
@@ -108,10 +134,30 @@ class _MainScreenState extends State<MainScreen> {
   @override
   void dispose() {
     _refreshTimer?.cancel();
+    _eventsSubscription?.cancel();
     _lifecycleListener?.dispose();
     super.dispose();
   }
 
+  // Reload the distros whenever the server reports a distro or the default
+  // changing. If the event stream drops (e.g. the server restarts), reload
+  // every 5 seconds until it can be reopened.
+  void _listenForChanges() {
+    _eventsSubscription = ApiService.events(types: ['distro.', 'default.']).listen(
+      (_) => _loadDistros(),
+      onError: (_) => _retryEvents(),
+      onDone: _retryEvents,
+      cancelOnError: true,
+    );
+  }
+
+  void _retryEvents() {
+    if (!mounted) return;
+    _loadDistros();
+    _refreshTimer?.cancel();
+    _refreshTimer = Timer(const Duration(seconds: 5), _listenForChanges);
+  }
+
   void _addLog(String message) {
     setState(() {
       _logs.add(message);
@@ -1,4 +1,5 @@
 import 'dart:convert';
+import 'dart:io';
 import 'package:http/http.dart' as http;
 
 class ApiService {
@@ -15,8 +16,100 @@ class ApiService {
     _port = port;
   }
 
+  // Every request must carry the server's API token. The server
+  // makes a new one each time it starts and writes it to
+  // %USERPROFILE%\.wslp\server-<port>.token, which is read before each
+  // request so a restarted server is picked up. [configureToken] sets a
+  // fixed token instead (see main() for the --token argument / WSLP_TOKEN
+  // environment variable).
+  static String? _token;
+
+  static void configureToken(String token) {
+    _token = token;
+  }
+
+  static String _readToken() {
+    if (_token != null) {
+      return _token!;
+    }
+    final home = Platform.environment['USERPROFILE'] ?? Platform.environment['HOME'] ?? '';
+    final file = File('$home${Platform.pathSeparator}.wslp${Platform.pathSeparator}server-$_port.token');
+    try {
+      return file.readAsStringSync().trim();
+    } on FileSystemException {
+      return '';
+    }
+  }
+
+  static Map<String, String> _authHeaders() => {'Authorization': 'Bearer ${_readToken()}'};
+
+  static Map<String, String> _jsonHeaders() => {
+    'Content-Type': 'application/json',
+    ..._authHeaders(),
+  };
+
+  // Installs, backups and copies run as jobs on the server: the request
+  // returns 202 Accepted with the job straight away. This polls the job
+  // until it finishes and returns its result, which has the same shape the
+  // endpoint used to return directly.
+  static Future<Map<String, dynamic>> _waitForJob(http.Response response) async {
+    if (response.statusCode != 202) {
+      throw Exception(response.body);
+    }
+
+    var job = json.decode(response.body) as Map<String, dynamic>;
+    while (job['state'] == 'queued' || job['state'] == 'running') {
+      await Future.delayed(const Duration(seconds: 1));
+      final poll = await http.get(Uri.parse('$baseUrl/api/jobs/${job['id']}'), headers: _authHeaders());
+      if (poll.statusCode != 200) {
+        throw Exception('Failed to get job status');
+      }
+      job = json.decode(poll.body) as Map<String, dynamic>;
+    }
+
+    final result = job['result'];
+    if (result == null) {
+      throw Exception(job['error'] ?? 'Job ${job['state']}');
+    }
+    return result as Map<String, dynamic>;
+  }
+
+  // Streams server-sent events from /api/events, optionally limited to the
+  // given event types or prefixes (e.g. 'distro.'). Each event is a map
+  // with 'type' and 'data'. The stream ends if the connection drops.
+  static Stream<Map<String, dynamic>> events({List<String> types = const []}) async* {
+    final query = types.isEmpty ? '' : '?types=${Uri.encodeQueryComponent(types.join(','))}';
+    final client = http.Client();
+    try {
+      final request = http.Request('GET', Uri.parse('$baseUrl/api/events$query'));
+      request.headers['Accept'] = 'text/event-stream';
+      final response = await client.send(request);
+      if (response.statusCode != 200) {
+        throw Exception('Failed to subscribe to events');
+      }
+
+      String? type;
+      final data = StringBuffer();
+      await for (final line in response.stream.transform(utf8.decoder).transform(const LineSplitter())) {
+        if (line.isEmpty) {
+          if (type != null) {
+            yield {'type': type, 'data': json.decode(data.toString())};
+          }
+          type = null;
+          data.clear();
+        } else if (line.startsWith('event: ')) {
+          type = line.substring('event: '.length);
+        } else if (line.startsWith('data: ')) {
+          data.write(line.substring('data: '.length));
+        }
+      }
+    } finally {
+      client.close();
+    }
+  }
+
   static Future<List<Map<String, dynamic>>> getDistros() async {
-    final response = await http.get(Uri.parse('$baseUrl/api/distros'));
+    final response = await http.get(Uri.parse('$baseUrl/api/distros'), headers: _authHeaders());
 
     if (response.statusCode == 200) {
       final data = json.decode(response.body);
@@ -32,7 +125,7 @@ class ApiService {
   }
 
   static Future<String> getDefaultDistro() async {
-    final response = await http.get(Uri.parse('$baseUrl/api/default'));
+    final response = await http.get(Uri.parse('$baseUrl/api/default'), headers: _authHeaders());
     
     if (response.statusCode == 200) {
       final data = json.decode(response.body);
@@ -43,7 +136,7 @@ class ApiService {
   }
 
   static Future<List<Map<String, String>>> getAvailableDistros() async {
-    final response = await http.get(Uri.parse('$baseUrl/api/available'));
+    final response = await http.get(Uri.parse('$baseUrl/api/available'), headers: _authHeaders());
     
     if (response.statusCode == 200) {
       final data = json.decode(response.body);
@@ -60,28 +153,29 @@ class ApiService {
   static Future<List<Map<String, dynamic>>> installDistros(List<String> distros) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/install'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'distros': distros}),
     );
-    
-    if (response.statusCode == 200) {
-      final data = json.decode(response.body);
-      final results = data['results'] as List;
-      return results.map((r) => {
-        'distro': r['distro'] as String,
-        'success': r['success'] as bool,
-        'message': r['message'] as String,
-        'registered': r['registered'] as bool? ?? false,
-      }).toList();
-    } else {
-      throw Exception('Failed to install distros');
+
+    final Map<String, dynamic> data;
+    try {
+      data = await _waitForJob(response);
+    } catch (e) {
+      throw Exception('Failed to install distros: $e');
     }
+    final results = data['results'] as List;
+    return results.map((r) => {
+      'distro': r['distro'] as String,
+      'success': r['success'] as bool,
+      'message': r['message'] as String,
+      'registered': r['registered'] as bool? ?? false,
+    }).toList();
   }
 
   static Future<List<Map<String, dynamic>>> unregisterDistros(List<String> distros) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/unregister'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'distros': distros}),
     );
 
@@ -101,7 +195,7 @@ class ApiService {
   static Future<void> setDefaultDistro(String name) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/set-default'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'name': name}),
     );
 
@@ -123,28 +217,29 @@ class ApiService {
 
     final response = await http.post(
       Uri.parse('$baseUrl/api/backup'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode(body),
     );
 
-    if (response.statusCode == 200) {
-      final data = json.decode(response.body);
-      final results = data['results'] as List;
-      return results.map((r) => {
-        'distro': r['distro'] as String,
-        'success': r['success'] as bool,
-        'message': r['message'] as String,
-        'filePath': r['filePath'] as String? ?? '',
-      }).toList();
-    } else {
-      throw Exception('Failed to backup distros');
+    final Map<String, dynamic> data;
+    try {
+      data = await _waitForJob(response);
+    } catch (e) {
+      throw Exception('Failed to backup distros: $e');
     }
+    final results = data['results'] as List;
+    return results.map((r) => {
+      'distro': r['distro'] as String,
+      'success': r['success'] as bool,
+      'message': r['message'] as String,
+      'filePath': r['filePath'] as String? ?? '',
+    }).toList();
   }
 
   static Future<List<Map<String, dynamic>>> terminateDistros(List<String> distros) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/terminate'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'distros': distros}),
     );
 
@@ -164,7 +259,7 @@ class ApiService {
   static Future<void> launchDistro(String name) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/launch'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'name': name}),
     );
 
@@ -176,7 +271,7 @@ class ApiService {
   static Future<Map<String, dynamic>> renameDistro(String oldName, String newName) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/rename'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'oldName': oldName, 'newName': newName}),
     );
 
@@ -194,7 +289,7 @@ class ApiService {
   }
 
   static Future<Map<String, dynamic>> getWSLInfo() async {
-    final response = await http.get(Uri.parse('$baseUrl/api/wsl-info'));
+    final response = await http.get(Uri.parse('$baseUrl/api/wsl-info'), headers: _authHeaders());
 
     if (response.statusCode == 200) {
       return json.decode(response.body) as Map<String, dynamic>;
@@ -206,6 +301,7 @@ class ApiService {
   static Future<Map<String, dynamic>> getDistroInfo(String name) async {
     final response = await http.get(
       Uri.parse('$baseUrl/api/distro-info?name=${Uri.encodeComponent(name)}'),
+      headers: _authHeaders(),
     );
 
     if (response.statusCode == 200) {
@@ -221,6 +317,7 @@ class ApiService {
   static Future<List<Map<String, dynamic>>> getWorkshops(String name) async {
     final response = await http.get(
       Uri.parse('$baseUrl/api/workshops?distro=${Uri.encodeComponent(name)}'),
+      headers: _authHeaders(),
     );
 
     if (response.statusCode == 200) {
@@ -247,7 +344,7 @@ class ApiService {
   }) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/workshop-action'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({
         'distro': distro,
         'project': project,
@@ -275,7 +372,7 @@ class ApiService {
   }) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/workshop-shell'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({
         'distro': distro,
         'project': project,
@@ -293,7 +390,10 @@ class ApiService {
   /// here doesn't guarantee it's fully stopped yet, only that it accepted
   /// the request.
   static Future<void> shutdownServer() async {
-    final response = await http.post(Uri.parse('$baseUrl/api/shutdown'));
+    final response = await http.post(
+      Uri.parse('$baseUrl/api/shutdown'),
+      headers: _authHeaders(),
+    );
 
     if (response.statusCode != 200) {
       throw Exception('Failed to stop server: ${response.body}');
@@ -301,7 +401,7 @@ class ApiService {
   }
 
   static Future<bool> getUbuntuTelemetry() async {
-    final response = await http.get(Uri.parse('$baseUrl/api/ubuntu-telemetry'));
+    final response = await http.get(Uri.parse('$baseUrl/api/ubuntu-telemetry'), headers: _authHeaders());
 
     if (response.statusCode == 200) {
       final data = json.decode(response.body);
@@ -314,7 +414,7 @@ class ApiService {
   static Future<bool> setUbuntuTelemetry(bool enabled) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/ubuntu-telemetry'),
-      headers: {'Content-Type': 'application/json'},
+      headers: _jsonHeaders(),
       body: json.encode({'enabled': enabled}),
     );
 
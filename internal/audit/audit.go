@@ -0,0 +1,251 @@
+// Package audit keeps an append-only log of destructive actions, such as
+// unregistering or renaming a distro, recording what was done, when and by
+// which tool, so a distro that disappeared can be traced back to the
+// command or API client that removed it
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The actions recorded in the audit log
+const (
+	ActionUnregister = "unregister"
+	ActionRename     = "rename"
+	ActionSetDefault = "set-default"
+	ActionCopy       = "copy"
+	ActionTelemetry  = "telemetry"
+	ActionShutdown   = "shutdown"
+)
+
+// The tools an action can be made with
+const (
+	ToolCLI = "cli"
+	ToolAPI = "api"
+)
+
+// Default is the audit log the commands and the API server record to.
+// Until SetPath is called it records nothing.
+var Default = New("")
+
+// Entry is one action in the audit log
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Distro string    `json:"distro,omitempty"`
+	// Details are specific to the action, e.g. newName for a rename
+	Details map[string]string `json:"details,omitempty"`
+	Success bool              `json:"success"`
+	// Error says why the action failed
+	Error string `json:"error,omitempty"`
+	Actor Actor  `json:"actor"`
+}
+
+// Actor is who made a change
+type Actor struct {
+	// Tool is cli for commands and api for requests to the server
+	Tool string `json:"tool"`
+	// User is the account the tool ran as
+	User string `json:"user,omitempty"`
+	PID  int    `json:"pid"`
+	// Command is the command line of a command
+	Command string `json:"command,omitempty"`
+	// Request, Remote and UserAgent describe an API request and the client
+	// that made it, such as the GUI
+	Request   string `json:"request,omitempty"`
+	Remote    string `json:"remote,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// ProcessActor returns this process as an actor, run as a command
+func ProcessActor() Actor {
+	actor := Actor{
+		Tool:    ToolCLI,
+		PID:     os.Getpid(),
+		Command: strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " "),
+	}
+	if u, err := user.Current(); err == nil {
+		actor.User = u.Username
+	}
+	return actor
+}
+
+// actorKey is the context key of the actor making a change
+type actorKey struct{}
+
+// WithActor returns a context whose changes are recorded as made by actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, or else this process
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return ProcessActor()
+}
+
+// Log is an audit log kept as JSON lines in a file. Entries are only ever
+// appended, so several processes can record to the same file.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New creates an audit log kept at path, or recording nothing if path is
+// empty
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// SetPath sets the file the log is kept in
+func (l *Log) SetPath(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.path = path
+}
+
+// Path returns the file the log is kept in, or "" if it records nothing
+func (l *Log) Path() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.path
+}
+
+// Record appends an entry to the log. Its time and actor are filled in,
+// from ctx, unless already set.
+func (l *Log) Record(ctx context.Context, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Actor.Tool == "" {
+		entry.Actor = ActorFrom(ctx)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	// A single write keeps entries from other processes from interleaving
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// Filter selects entries from the log. Zero fields select everything.
+type Filter struct {
+	// Distro matches entries about a distro, case-insensitively, including
+	// renames and copies to it
+	Distro string
+	Action string
+	// Since drops entries from before it
+	Since time.Time
+	// Limit keeps only the most recent entries
+	Limit int
+}
+
+// matches reports whether the filter selects entry
+func (f Filter) matches(entry Entry) bool {
+	if f.Action != "" && !strings.EqualFold(entry.Action, f.Action) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if f.Distro != "" && !strings.EqualFold(entry.Distro, f.Distro) && !strings.EqualFold(entry.Details["newName"], f.Distro) {
+		return false
+	}
+	return true
+}
+
+// Read returns the entries selected by filter, oldest first. Lines that
+// can't be parsed, e.g. one cut short by a crash, are skipped.
+func (l *Log) Read(filter Filter) ([]Entry, error) {
+	path := l.Path()
+	if path == "" {
+		return []Entry{}, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// ParseSince parses the start of a time range: a duration back from now,
+// such as 24h or 7d, a date such as 2024-03-01, or an RFC 3339 time.
+// Empty means no start.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if d, err := time.ParseDuration(days + "h"); err == nil && d >= 0 {
+			return now.Add(-24 * d), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a duration such as 24h or 7d, a date such as 2024-03-01, or an RFC 3339 time", s)
+}
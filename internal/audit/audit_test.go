@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	t.Run("appends JSON lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state", "audit.log")
+		log := New(path)
+
+		ctx := WithActor(context.Background(), Actor{Tool: ToolAPI, Remote: "127.0.0.1:50000", UserAgent: "Dart/3.5 (dart:io)"})
+		if err := log.Record(ctx, Entry{Action: ActionUnregister, Distro: "Ubuntu", Success: true}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		if err := log.Record(context.Background(), Entry{Action: ActionRename, Distro: "Debian", Details: map[string]string{"newName": "Work"}}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read the log: %v", err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %q", data)
+		}
+
+		entries, err := log.Read(Filter{})
+		if err != nil || len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %+v, %v", entries, err)
+		}
+		if entries[0].Actor.Tool != ToolAPI || entries[0].Actor.UserAgent != "Dart/3.5 (dart:io)" || entries[0].Time.IsZero() {
+			t.Errorf("unexpected first entry %+v", entries[0])
+		}
+		if entries[1].Actor.Tool != ToolCLI || entries[1].Actor.PID != os.Getpid() || entries[1].Actor.Command == "" {
+			t.Errorf("expected the second entry to be made by this process, got %+v", entries[1].Actor)
+		}
+	})
+
+	t.Run("records nothing without a path", func(t *testing.T) {
+		log := New("")
+		if err := log.Record(context.Background(), Entry{Action: ActionShutdown}); err != nil {
+			t.Errorf("Record failed: %v", err)
+		}
+		if entries, err := log.Read(Filter{}); err != nil || len(entries) != 0 {
+			t.Errorf("expected no entries, got %+v, %v", entries, err)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log := New(path)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, entry := range []Entry{
+		{Action: ActionUnregister, Distro: "Ubuntu"},
+		{Action: ActionRename, Distro: "Debian", Details: map[string]string{"newName": "ubuntu"}},
+		{Action: ActionSetDefault, Distro: "Debian"},
+		{Action: ActionUnregister, Distro: "Debian"},
+	} {
+		entry.Time = start.Add(time.Duration(i) * time.Hour)
+		if err := log.Record(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A line cut short by a crash is skipped
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"time":"2024-03`)
+	file.Close()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"everything", Filter{}, []string{"unregister Ubuntu", "rename Debian", "set-default Debian", "unregister Debian"}},
+		{"by action", Filter{Action: "UNREGISTER"}, []string{"unregister Ubuntu", "unregister Debian"}},
+		{"by distro, including renames to it", Filter{Distro: "ubuntu"}, []string{"unregister Ubuntu", "rename Debian"}},
+		{"since", Filter{Since: start.Add(90 * time.Minute)}, []string{"set-default Debian", "unregister Debian"}},
+		{"most recent", Filter{Limit: 1}, []string{"unregister Debian"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := log.Read(tt.filter)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Action+" "+e.Distro)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"24h", now.Add(-24 * time.Hour)},
+		{"7d", now.Add(-7 * 24 * time.Hour)},
+		{"2024-03-01T08:00:00Z", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseSince("last week", now); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
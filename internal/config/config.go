@@ -1,8 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -37,6 +42,38 @@ func Init() {
 func SetDefaults() {
 	viper.SetDefault("backup_dir", DefaultBackupDir())
 	viper.SetDefault("max_concurrent_installs", 3)
+	viper.SetDefault("state_dir", DefaultStateDir())
+
+	// Backup retention is disabled unless at least one rule is configured
+	viper.SetDefault("backup_retention.keep_last", 0)
+	viper.SetDefault("backup_retention.keep_daily", 0)
+	viper.SetDefault("backup_retention.keep_weekly", 0)
+	viper.SetDefault("backup_retention.keep_monthly", 0)
+	viper.SetDefault("backup_retention.max_total_size", "0")
+
+	// Backups are not encrypted unless enabled
+	viper.SetDefault("backup_encryption.enabled", false)
+
+	// Server endpoints are cancelled if they run longer than this. Long
+	// operations get longer limits and event streams none.
+	viper.SetDefault("server_timeouts.default", "5m")
+	viper.SetDefault("server_timeouts.install", "2h")
+	viper.SetDefault("server_timeouts.backup", "4h")
+	viper.SetDefault("server_timeouts.copy", "4h")
+	viper.SetDefault("server_timeouts.restore", "4h")
+	viper.SetDefault("server_timeouts.schedules", "4h")
+	viper.SetDefault("server_timeouts.events", "0")
+
+	// The server only accepts connections from this machine unless told
+	// otherwise
+	viper.SetDefault("server_bind", "127.0.0.1")
+
+	// Only informational messages and above are logged, as text
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_format", "text")
+
+	// Commands print their results for people to read
+	viper.SetDefault("output", "text")
 }
 
 // GetMaxConcurrentInstalls returns the max number of concurrent distro installs
@@ -47,6 +84,11 @@ func GetMaxConcurrentInstalls() int {
 // DefaultBackupDir returns the default backup directory path
 // Uses %USERPROFILE%\WSLBackups on Windows
 func DefaultBackupDir() string {
+	return filepath.Join(userProfileDir(), "WSLBackups")
+}
+
+// userProfileDir returns %USERPROFILE%, falling back to the home directory
+func userProfileDir() string {
 	userProfile := os.Getenv("USERPROFILE")
 	if userProfile == "" {
 		home, err := os.UserHomeDir()
@@ -54,7 +96,7 @@ func DefaultBackupDir() string {
 			userProfile = home
 		}
 	}
-	return filepath.Join(userProfile, "WSLBackups")
+	return userProfile
 }
 
 // GetBackupDir returns the configured backup directory
@@ -62,6 +104,529 @@ func GetBackupDir() string {
 	return viper.GetString("backup_dir")
 }
 
+// RetentionPolicy decides which backups are kept when pruning the backup
+// directory. The keep rules are applied per distro and a backup is kept if
+// any rule selects it; MaxTotalBytes then caps the size of everything kept.
+// A zero value disables the corresponding rule.
+type RetentionPolicy struct {
+	// KeepLast keeps the N most recent backups
+	KeepLast int `json:"keepLast"`
+	// KeepDaily keeps the most recent backup of each of the last N days
+	// that have backups
+	KeepDaily int `json:"keepDaily"`
+	// KeepWeekly keeps the most recent backup of each of the last N weeks
+	// that have backups
+	KeepWeekly int `json:"keepWeekly"`
+	// KeepMonthly keeps the most recent backup of each of the last N months
+	// that have backups
+	KeepMonthly int `json:"keepMonthly"`
+	// MaxTotalBytes deletes the oldest backups until the backup directory
+	// fits within this many bytes
+	MaxTotalBytes int64 `json:"maxTotalBytes"`
+}
+
+// Enabled reports whether any retention rule is configured
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.MaxTotalBytes > 0
+}
+
+// GetRetentionPolicy returns the configured backup retention policy, read
+// from the backup_retention section of the config file, e.g.
+//
+//	backup_retention:
+//	  keep_last: 3
+//	  keep_daily: 7
+//	  keep_weekly: 4
+//	  keep_monthly: 6
+//	  max_total_size: 50GB
+func GetRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		KeepLast:      viper.GetInt("backup_retention.keep_last"),
+		KeepDaily:     viper.GetInt("backup_retention.keep_daily"),
+		KeepWeekly:    viper.GetInt("backup_retention.keep_weekly"),
+		KeepMonthly:   viper.GetInt("backup_retention.keep_monthly"),
+		MaxTotalBytes: getSize("backup_retention.max_total_size"),
+	}
+}
+
+// getSize reads a size setting, treating an invalid value as unset
+func getSize(key string) int64 {
+	size, err := ParseSize(viper.GetString(key))
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// ParseSize parses a size such as "512MB" or "50GB" into bytes. Units are
+// powers of 1024 and case-insensitive; a bare number is taken as bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	multipliers := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	factor := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(s, m.suffix) {
+			factor = m.factor
+			s = strings.TrimSpace(strings.TrimSuffix(s, m.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * factor, nil
+}
+
+// passphraseEnv is the environment variable holding the backup passphrase.
+// It is read directly since viper can't map nested keys to variables.
+const passphraseEnv = "WSLP_BACKUP_PASSPHRASE"
+
+// BackupEncryption configures how new backups are encrypted and which keys
+// are tried when reading encrypted ones. Backups are encrypted with age
+// (https://age-encryption.org).
+type BackupEncryption struct {
+	// Enabled encrypts every new backup by default
+	Enabled bool
+	// Recipients are age public keys (age1...) that new backups are
+	// encrypted to. Encrypting to a public key needs no secret, so
+	// scheduled backups can run unattended.
+	Recipients []string
+	// IdentityFile is an age identity file holding the private keys used
+	// to decrypt backups
+	IdentityFile string
+	// Passphrase encrypts new backups when no recipients are configured,
+	// and is always tried when decrypting
+	Passphrase string
+}
+
+// GetBackupEncryption returns the backup encryption settings, read from the
+// backup_encryption section of the config file, e.g.
+//
+//	backup_encryption:
+//	  enabled: true
+//	  recipients:
+//	    - age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+//	  identity_file: C:\Users\me\.wslp\age-key.txt
+//	  passphrase_file: C:\Users\me\.wslp\backup-passphrase.txt
+//
+// The passphrase can also be given in the WSLP_BACKUP_PASSPHRASE
+// environment variable, which takes precedence over passphrase_file.
+func GetBackupEncryption() (BackupEncryption, error) {
+	enc := BackupEncryption{
+		Enabled:      viper.GetBool("backup_encryption.enabled"),
+		Recipients:   viper.GetStringSlice("backup_encryption.recipients"),
+		IdentityFile: viper.GetString("backup_encryption.identity_file"),
+		Passphrase:   os.Getenv(passphraseEnv),
+	}
+
+	if file := viper.GetString("backup_encryption.passphrase_file"); enc.Passphrase == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return enc, fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		enc.Passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return enc, nil
+}
+
+// Backup target types
+const (
+	TargetLocal = "local"
+	TargetSMB   = "smb"
+	TargetS3    = "s3"
+)
+
+// DefaultPartSize is the part size of multipart uploads to S3 targets
+const DefaultPartSize = 16 << 20
+
+// BackupTarget describes where finished backups are stored
+type BackupTarget struct {
+	// Name is the target's key in backup_targets, or the URI or path it
+	// was given as
+	Name string
+	// Type is local, smb or s3
+	Type string
+	// Path is the directory of local targets and the UNC path of smb
+	// targets, e.g. \\nas\backups\wsl
+	Path string
+	// Endpoint is the host[:port] of an S3-compatible service
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Insecure connects to the endpoint over plain HTTP, e.g. for a local
+	// MinIO server
+	Insecure bool
+	// PartSize is the size of each part of a multipart upload
+	PartSize int64
+}
+
+// GetBackupTarget resolves a backup target from its name in the
+// backup_targets section of the config file, or from a URI or path:
+//
+//	backup_target: offsite
+//	backup_targets:
+//	  nas:
+//	    type: smb
+//	    path: \\nas\backups\wsl
+//	  offsite:
+//	    type: s3
+//	    endpoint: s3.eu-west-1.amazonaws.com
+//	    region: eu-west-1
+//	    bucket: my-backups
+//	    prefix: wsl
+//	    part_size: 64MB
+//
+// URIs take the form C:\path or file:///C:/path for a local directory,
+// \\server\share\path or smb://server/share/path for a network share, and
+// s3://bucket/prefix for S3, with optional endpoint, region and insecure
+// query parameters for S3-compatible services. An empty target selects
+// backup_target, or else the backup directory. S3 credentials not given in
+// the config are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func GetBackupTarget(target string) (BackupTarget, error) {
+	if target == "" {
+		target = viper.GetString("backup_target")
+	}
+	if target == "" {
+		return BackupTarget{Name: TargetLocal, Type: TargetLocal, Path: GetBackupDir()}, nil
+	}
+
+	var t BackupTarget
+	key := "backup_targets." + strings.ToLower(target)
+	if viper.IsSet(key) {
+		t = BackupTarget{
+			Type:            strings.ToLower(viper.GetString(key + ".type")),
+			Path:            viper.GetString(key + ".path"),
+			Endpoint:        viper.GetString(key + ".endpoint"),
+			Region:          viper.GetString(key + ".region"),
+			Bucket:          viper.GetString(key + ".bucket"),
+			Prefix:          viper.GetString(key + ".prefix"),
+			AccessKeyID:     viper.GetString(key + ".access_key_id"),
+			SecretAccessKey: viper.GetString(key + ".secret_access_key"),
+			Insecure:        viper.GetBool(key + ".insecure"),
+			PartSize:        getSize(key + ".part_size"),
+		}
+	} else {
+		var err error
+		if t, err = parseTargetURI(target); err != nil {
+			return t, err
+		}
+	}
+	t.Name = target
+
+	switch t.Type {
+	case TargetLocal, TargetSMB:
+		if t.Path == "" {
+			return t, fmt.Errorf("backup target %q has no path", target)
+		}
+	case TargetS3:
+		if t.Bucket == "" {
+			return t, fmt.Errorf("backup target %q has no bucket", target)
+		}
+		if t.Endpoint == "" {
+			t.Endpoint = "s3.amazonaws.com"
+		}
+		if t.Region == "" {
+			t.Region = "us-east-1"
+		}
+		if t.AccessKeyID == "" {
+			t.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if t.SecretAccessKey == "" {
+			t.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if t.PartSize == 0 {
+			t.PartSize = DefaultPartSize
+		}
+	default:
+		return t, fmt.Errorf("backup target %q has unsupported type %q (use local, smb or s3)", target, t.Type)
+	}
+
+	return t, nil
+}
+
+// parseTargetURI parses a backup target given as a URI or path
+func parseTargetURI(target string) (BackupTarget, error) {
+	if strings.HasPrefix(target, `\\`) {
+		return BackupTarget{Type: TargetSMB, Path: target}, nil
+	}
+
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || len(scheme) == 1 {
+		// Not a URI. A bare word is most likely a mistyped target name, so
+		// only absolute paths are accepted.
+		if !filepath.IsAbs(target) {
+			return BackupTarget{}, fmt.Errorf("unknown backup target %q (not in backup_targets and not an absolute path)", target)
+		}
+		return BackupTarget{Type: TargetLocal, Path: target}, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return BackupTarget{}, fmt.Errorf("invalid backup target %q: %w", target, err)
+	}
+
+	switch strings.ToLower(scheme) {
+	case "file":
+		// file:///C:/path has the drive letter after the leading slash
+		path := u.Path
+		if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		return BackupTarget{Type: TargetLocal, Path: filepath.FromSlash(path)}, nil
+
+	case "smb":
+		share := strings.Trim(rest, "/")
+		if !strings.Contains(share, "/") {
+			return BackupTarget{}, fmt.Errorf("invalid backup target %q: expected smb://server/share/path", target)
+		}
+		return BackupTarget{Type: TargetSMB, Path: `\\` + strings.ReplaceAll(share, "/", `\`)}, nil
+
+	case "s3":
+		q := u.Query()
+		return BackupTarget{
+			Type:     TargetS3,
+			Bucket:   u.Host,
+			Prefix:   strings.Trim(u.Path, "/"),
+			Endpoint: q.Get("endpoint"),
+			Region:   q.Get("region"),
+			Insecure: q.Get("insecure") == "true",
+		}, nil
+	}
+
+	return BackupTarget{}, fmt.Errorf("unsupported backup target scheme %q (use file, smb or s3)", scheme)
+}
+
+// BackupSchedule is a backup that wslp serve runs on a cron schedule
+type BackupSchedule struct {
+	// ID identifies the schedule in the API, e.g. nightly
+	ID string `json:"id"`
+	// Cron is a five-field cron expression such as "0 2 * * *", or a
+	// descriptor such as @daily
+	Cron    string   `json:"cron"`
+	Distros []string `json:"distros"`
+	// Format is the archive format. Empty selects the default.
+	Format string `json:"format,omitempty"`
+	// Target is a name from backup_targets or a target URI. Empty selects
+	// backup_target.
+	Target string `json:"target,omitempty"`
+	// Encrypt overrides backup_encryption.enabled when set
+	Encrypt *bool `json:"encrypt,omitempty"`
+	// Retention replaces backup_retention for this schedule's runs when
+	// any rule is set
+	Retention RetentionPolicy `json:"retention"`
+	// CatchUp runs the backup once when the server starts if a scheduled
+	// run was missed while it was down
+	CatchUp bool `json:"catchUp"`
+}
+
+// GetBackupSchedules returns the backup schedules, read from the
+// backup_schedules section of the config file, e.g.
+//
+//	backup_schedules:
+//	  - id: nightly
+//	    cron: "0 2 * * *"
+//	    distros: [Ubuntu, Debian]
+//	    format: zstd
+//	    catch_up: true
+//	    retention:
+//	      keep_daily: 7
+//	      keep_weekly: 4
+func GetBackupSchedules() ([]BackupSchedule, error) {
+	var raw []struct {
+		ID        string   `mapstructure:"id"`
+		Cron      string   `mapstructure:"cron"`
+		Distros   []string `mapstructure:"distros"`
+		Format    string   `mapstructure:"format"`
+		Target    string   `mapstructure:"target"`
+		Encrypt   *bool    `mapstructure:"encrypt"`
+		CatchUp   bool     `mapstructure:"catch_up"`
+		Retention struct {
+			KeepLast     int    `mapstructure:"keep_last"`
+			KeepDaily    int    `mapstructure:"keep_daily"`
+			KeepWeekly   int    `mapstructure:"keep_weekly"`
+			KeepMonthly  int    `mapstructure:"keep_monthly"`
+			MaxTotalSize string `mapstructure:"max_total_size"`
+		} `mapstructure:"retention"`
+	}
+	if err := viper.UnmarshalKey("backup_schedules", &raw); err != nil {
+		return nil, fmt.Errorf("invalid backup_schedules: %w", err)
+	}
+
+	schedules := make([]BackupSchedule, 0, len(raw))
+	seen := map[string]bool{}
+	for i, r := range raw {
+		if r.ID == "" {
+			return nil, fmt.Errorf("backup schedule %d has no id", i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("duplicate backup schedule id %q", r.ID)
+		}
+		seen[r.ID] = true
+		if r.Cron == "" {
+			return nil, fmt.Errorf("backup schedule %q has no cron expression", r.ID)
+		}
+		if len(r.Distros) == 0 {
+			return nil, fmt.Errorf("backup schedule %q has no distros", r.ID)
+		}
+
+		maxTotal, err := ParseSize(r.Retention.MaxTotalSize)
+		if err != nil {
+			return nil, fmt.Errorf("backup schedule %q: %w", r.ID, err)
+		}
+
+		schedules = append(schedules, BackupSchedule{
+			ID:      r.ID,
+			Cron:    r.Cron,
+			Distros: r.Distros,
+			Format:  r.Format,
+			Target:  r.Target,
+			Encrypt: r.Encrypt,
+			CatchUp: r.CatchUp,
+			Retention: RetentionPolicy{
+				KeepLast:      r.Retention.KeepLast,
+				KeepDaily:     r.Retention.KeepDaily,
+				KeepWeekly:    r.Retention.KeepWeekly,
+				KeepMonthly:   r.Retention.KeepMonthly,
+				MaxTotalBytes: maxTotal,
+			},
+		})
+	}
+
+	return schedules, nil
+}
+
+// DefaultStateDir returns the default directory for state wslp keeps
+// between runs, such as the status of scheduled backups
+// Uses %USERPROFILE%\.wslp on Windows
+func DefaultStateDir() string {
+	return filepath.Join(userProfileDir(), ".wslp")
+}
+
+// GetStateDir returns the configured state directory
+func GetStateDir() string {
+	return viper.GetString("state_dir")
+}
+
+// GetLogLevel returns the lowest level logged: debug, info, warn or error
+func GetLogLevel() string {
+	return viper.GetString("log_level")
+}
+
+// GetLogFormat returns how log records are written: text or json
+func GetLogFormat() string {
+	return viper.GetString("log_format")
+}
+
+// GetOutputFormat returns how commands print their results: text, table,
+// json or yaml
+func GetOutputFormat() string {
+	return viper.GetString("output")
+}
+
+// GetAuditLogPath returns the file destructive actions are recorded in,
+// from audit_log, or else audit.log in the state directory. It is "" if
+// neither is set.
+func GetAuditLogPath() string {
+	if path := strings.TrimSpace(viper.GetString("audit_log")); path != "" {
+		return path
+	}
+	if dir := GetStateDir(); dir != "" {
+		return filepath.Join(dir, "audit.log")
+	}
+	return ""
+}
+
+// GetServerTimeout returns how long the server lets an endpoint, such as
+// backup or distros, run before cancelling it. It is read from
+// server_timeouts.<endpoint>, or else server_timeouts.default. Zero means
+// no limit.
+func GetServerTimeout(endpoint string) (time.Duration, error) {
+	key := "server_timeouts." + endpoint
+	if !viper.IsSet(key) {
+		key = "server_timeouts.default"
+	}
+
+	value := strings.TrimSpace(viper.GetString(key))
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 30s or 2h", key, value)
+	}
+	return timeout, nil
+}
+
+// HasServerTimeout reports whether server_timeouts.<endpoint> is set,
+// rather than falling back to server_timeouts.default
+func HasServerTimeout(endpoint string) bool {
+	return viper.IsSet("server_timeouts." + endpoint)
+}
+
+// GetServerBind returns the address the server listens on, 127.0.0.1
+// unless server_bind is set
+func GetServerBind() string {
+	if bind := viper.GetString("server_bind"); bind != "" {
+		return bind
+	}
+	return "127.0.0.1"
+}
+
+// GetServerListen returns the Unix domain socket or named pipe the server
+// listens on instead of a TCP port, from server_listen, or "" to use TCP
+func GetServerListen() string {
+	return strings.TrimSpace(viper.GetString("server_listen"))
+}
+
+// GetServerAllowedOrigins returns the browser origins allowed to call the
+// server, from server_allowed_origins. "*" allows any origin. When none are
+// configured the server allows origins on this machine, such as
+// http://localhost:5000.
+func GetServerAllowedOrigins() []string {
+	var origins []string
+	for _, origin := range viper.GetStringSlice("server_allowed_origins") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// GetDistroTags returns the tags given to distros under distro_tags, keyed
+// by distro name in lower case, e.g.:
+//
+//	distro_tags:
+//	  Ubuntu-22.04: [dev, work]
+//	  docker-desktop: [infra]
+func GetDistroTags() map[string][]string {
+	tags := map[string][]string{}
+	for name, t := range viper.GetStringMapStringSlice("distro_tags") {
+		tags[strings.ToLower(name)] = t
+	}
+	return tags
+}
+
 // EnsureBackupDir creates the backup directory if it doesn't exist
 func EnsureBackupDir() error {
 	backupDir := GetBackupDir()
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -112,3 +114,422 @@ func TestInit(t *testing.T) {
 		t.Errorf("after Init(), backup_dir is empty, want a non-empty path")
 	}
 }
+
+func TestGetRetentionPolicy(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		SetDefaults()
+		if GetRetentionPolicy().Enabled() {
+			t.Errorf("expected retention to be disabled by default, got %+v", GetRetentionPolicy())
+		}
+	})
+
+	t.Run("reads configured rules", func(t *testing.T) {
+		viper.Set("backup_retention.keep_last", 3)
+		viper.Set("backup_retention.keep_daily", 7)
+		viper.Set("backup_retention.keep_weekly", 4)
+		viper.Set("backup_retention.keep_monthly", 6)
+		viper.Set("backup_retention.max_total_size", "2GB")
+
+		want := RetentionPolicy{KeepLast: 3, KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 6, MaxTotalBytes: 2 << 30}
+		if got := GetRetentionPolicy(); got != want {
+			t.Errorf("GetRetentionPolicy() = %+v, want %+v", got, want)
+		}
+		if !GetRetentionPolicy().Enabled() {
+			t.Error("expected retention to be enabled")
+		}
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"1024":  1024,
+		"512B":  512,
+		"1kb":   1 << 10,
+		"10 MB": 10 << 20,
+		"50GB":  50 << 30,
+		"1TB":   1 << 40,
+	}
+	for in, want := range tests {
+		got, err := ParseSize(in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) unexpected error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"lots", "-1GB", "1.5GB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) expected error", in)
+		}
+	}
+}
+
+func TestGetBackupEncryption(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+		SetDefaults()
+
+		enc, err := GetBackupEncryption()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if enc.Enabled || len(enc.Recipients) != 0 || enc.Passphrase != "" {
+			t.Errorf("expected no encryption settings, got %+v", enc)
+		}
+	})
+
+	t.Run("reads recipients and passphrase file", func(t *testing.T) {
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+		file := filepath.Join(t.TempDir(), "passphrase.txt")
+		if err := os.WriteFile(file, []byte("correct horse\r\n"), 0600); err != nil {
+			t.Fatalf("failed to write passphrase file: %v", err)
+		}
+		viper.Set("backup_encryption.enabled", true)
+		viper.Set("backup_encryption.recipients", []string{"age1abc"})
+		viper.Set("backup_encryption.passphrase_file", file)
+
+		enc, err := GetBackupEncryption()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !enc.Enabled || len(enc.Recipients) != 1 || enc.Recipients[0] != "age1abc" {
+			t.Errorf("unexpected settings %+v", enc)
+		}
+		if enc.Passphrase != "correct horse" {
+			t.Errorf("expected passphrase from file, got %q", enc.Passphrase)
+		}
+	})
+
+	t.Run("environment passphrase takes precedence", func(t *testing.T) {
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "from env")
+		viper.Set("backup_encryption.passphrase_file", filepath.Join(t.TempDir(), "missing"))
+
+		enc, err := GetBackupEncryption()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if enc.Passphrase != "from env" {
+			t.Errorf("expected passphrase from environment, got %q", enc.Passphrase)
+		}
+	})
+
+	t.Run("returns error for unreadable passphrase file", func(t *testing.T) {
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+		viper.Set("backup_encryption.passphrase_file", filepath.Join(t.TempDir(), "missing"))
+
+		if _, err := GetBackupEncryption(); err == nil {
+			t.Error("expected error for missing passphrase file")
+		}
+	})
+}
+
+func TestGetBackupTarget(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	backupDir := t.TempDir()
+	viper.Set("backup_dir", backupDir)
+	viper.Set("backup_targets.nas.type", "smb")
+	viper.Set("backup_targets.nas.path", `\\nas\backups\wsl`)
+	viper.Set("backup_targets.offsite.type", "s3")
+	viper.Set("backup_targets.offsite.bucket", "my-backups")
+	viper.Set("backup_targets.offsite.prefix", "wsl")
+	viper.Set("backup_targets.offsite.access_key_id", "config-key")
+	viper.Set("backup_targets.offsite.part_size", "64MB")
+
+	tests := map[string]struct {
+		target string
+		want   BackupTarget
+	}{
+		"defaults to backup dir": {
+			target: "",
+			want:   BackupTarget{Name: "local", Type: TargetLocal, Path: backupDir},
+		},
+		"named smb target": {
+			target: "nas",
+			want:   BackupTarget{Name: "nas", Type: TargetSMB, Path: `\\nas\backups\wsl`},
+		},
+		"named s3 target": {
+			target: "offsite",
+			want: BackupTarget{
+				Name: "offsite", Type: TargetS3, Bucket: "my-backups", Prefix: "wsl",
+				Endpoint: "s3.amazonaws.com", Region: "us-east-1",
+				AccessKeyID: "config-key", SecretAccessKey: "env-secret", PartSize: 64 << 20,
+			},
+		},
+		"absolute path": {
+			target: backupDir,
+			want:   BackupTarget{Name: backupDir, Type: TargetLocal, Path: backupDir},
+		},
+		"unc path": {
+			target: `\\server\share\dir`,
+			want:   BackupTarget{Name: `\\server\share\dir`, Type: TargetSMB, Path: `\\server\share\dir`},
+		},
+		"smb uri": {
+			target: "smb://server/share/dir",
+			want:   BackupTarget{Name: "smb://server/share/dir", Type: TargetSMB, Path: `\\server\share\dir`},
+		},
+		"s3 uri with endpoint": {
+			target: "s3://bucket/a/b?endpoint=localhost:9000&insecure=true",
+			want: BackupTarget{
+				Name: "s3://bucket/a/b?endpoint=localhost:9000&insecure=true", Type: TargetS3,
+				Bucket: "bucket", Prefix: "a/b", Endpoint: "localhost:9000", Region: "us-east-1", Insecure: true,
+				AccessKeyID: "env-key", SecretAccessKey: "env-secret", PartSize: DefaultPartSize,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := GetBackupTarget(tc.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("GetBackupTarget(%q) = %+v, want %+v", tc.target, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("uses configured default target", func(t *testing.T) {
+		viper.Set("backup_target", "nas")
+		defer viper.Set("backup_target", "")
+
+		got, err := GetBackupTarget("")
+		if err != nil || got.Name != "nas" {
+			t.Errorf("expected the nas target, got %+v (%v)", got, err)
+		}
+	})
+
+	for _, target := range []string{"nowhere", "ftp://server/dir", "s3://", "smb://"} {
+		if _, err := GetBackupTarget(target); err == nil {
+			t.Errorf("GetBackupTarget(%q) expected error", target)
+		}
+	}
+}
+
+func TestGetBackupSchedules(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Run("none by default", func(t *testing.T) {
+		schedules, err := GetBackupSchedules()
+		if err != nil || len(schedules) != 0 {
+			t.Errorf("expected no schedules, got %+v (%v)", schedules, err)
+		}
+	})
+
+	t.Run("reads schedules", func(t *testing.T) {
+		viper.Set("backup_schedules", []map[string]interface{}{
+			{
+				"id":       "nightly",
+				"cron":     "0 2 * * *",
+				"distros":  []string{"Ubuntu", "Debian"},
+				"format":   "zstd",
+				"encrypt":  true,
+				"catch_up": true,
+				"retention": map[string]interface{}{
+					"keep_daily":     7,
+					"max_total_size": "10GB",
+				},
+			},
+			{"id": "weekly", "cron": "@weekly", "distros": []string{"Ubuntu"}},
+		})
+
+		schedules, err := GetBackupSchedules()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(schedules) != 2 {
+			t.Fatalf("expected 2 schedules, got %d", len(schedules))
+		}
+
+		nightly := schedules[0]
+		if nightly.ID != "nightly" || nightly.Cron != "0 2 * * *" || len(nightly.Distros) != 2 || nightly.Format != "zstd" || !nightly.CatchUp {
+			t.Errorf("unexpected schedule %+v", nightly)
+		}
+		if nightly.Encrypt == nil || !*nightly.Encrypt {
+			t.Error("expected encrypt to be set")
+		}
+		if want := (RetentionPolicy{KeepDaily: 7, MaxTotalBytes: 10 << 30}); nightly.Retention != want {
+			t.Errorf("expected retention %+v, got %+v", want, nightly.Retention)
+		}
+		if schedules[1].Encrypt != nil || schedules[1].Retention.Enabled() {
+			t.Errorf("expected defaults for weekly schedule, got %+v", schedules[1])
+		}
+	})
+
+	invalid := map[string]map[string]interface{}{
+		"missing id":      {"cron": "@daily", "distros": []string{"Ubuntu"}},
+		"missing cron":    {"id": "a", "distros": []string{"Ubuntu"}},
+		"missing distros": {"id": "a", "cron": "@daily"},
+		"invalid size":    {"id": "a", "cron": "@daily", "distros": []string{"Ubuntu"}, "retention": map[string]interface{}{"max_total_size": "lots"}},
+	}
+	for name, schedule := range invalid {
+		t.Run(name, func(t *testing.T) {
+			viper.Set("backup_schedules", []map[string]interface{}{schedule})
+			if _, err := GetBackupSchedules(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	t.Run("duplicate ids", func(t *testing.T) {
+		s := map[string]interface{}{"id": "a", "cron": "@daily", "distros": []string{"Ubuntu"}}
+		viper.Set("backup_schedules", []map[string]interface{}{s, s})
+		if _, err := GetBackupSchedules(); err == nil {
+			t.Error("expected error for duplicate ids")
+		}
+	})
+}
+
+func TestGetServerTimeout(t *testing.T) {
+	defer viper.Reset()
+
+	tests := map[string]struct {
+		endpoint string
+		set      map[string]interface{}
+		want     time.Duration
+		wantErr  bool
+	}{
+		"uses default":             {endpoint: "distros", want: 5 * time.Minute},
+		"uses endpoint default":    {endpoint: "backup", want: 4 * time.Hour},
+		"events never time out":    {endpoint: "events", want: 0},
+		"uses configured endpoint": {endpoint: "backup", set: map[string]interface{}{"server_timeouts.backup": "90m"}, want: 90 * time.Minute},
+		"uses configured default":  {endpoint: "distros", set: map[string]interface{}{"server_timeouts.default": "10s"}, want: 10 * time.Second},
+		"zero disables":            {endpoint: "install", set: map[string]interface{}{"server_timeouts.install": 0}, want: 0},
+		"rejects invalid duration": {endpoint: "copy", set: map[string]interface{}{"server_timeouts.copy": "soon"}, wantErr: true},
+		"rejects negative":         {endpoint: "copy", set: map[string]interface{}{"server_timeouts.copy": "-1m"}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			viper.Reset()
+			SetDefaults()
+			for key, value := range tc.set {
+				viper.Set(key, value)
+			}
+
+			got, err := GetServerTimeout(tc.endpoint)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("GetServerTimeout(%s) = %v, want %v", tc.endpoint, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasServerTimeout(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	SetDefaults()
+	viper.Set("server_timeouts.backups", "1h")
+
+	for endpoint, want := range map[string]bool{"backups": true, "events": true, "distros": false} {
+		if got := HasServerTimeout(endpoint); got != want {
+			t.Errorf("HasServerTimeout(%s) = %v, want %v", endpoint, got, want)
+		}
+	}
+}
+
+func TestGetServerBind(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if got := GetServerBind(); got != "127.0.0.1" {
+		t.Errorf("expected 127.0.0.1 by default, got %q", got)
+	}
+
+	viper.Set("server_bind", "0.0.0.0")
+	if got := GetServerBind(); got != "0.0.0.0" {
+		t.Errorf("expected configured address, got %q", got)
+	}
+}
+
+func TestGetServerListen(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if got := GetServerListen(); got != "" {
+		t.Errorf("expected TCP by default, got %q", got)
+	}
+
+	viper.Set("server_listen", " unix:///run/wslp.sock ")
+	if got := GetServerListen(); got != "unix:///run/wslp.sock" {
+		t.Errorf("expected configured socket, got %q", got)
+	}
+}
+
+func TestGetAuditLogPath(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if got := GetAuditLogPath(); got != "" {
+		t.Errorf("expected no audit log without a state directory, got %q", got)
+	}
+
+	viper.Set("state_dir", filepath.Join("home", ".wslp"))
+	if got, want := GetAuditLogPath(), filepath.Join("home", ".wslp", "audit.log"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	viper.Set("audit_log", " /var/log/wslp-audit.log ")
+	if got := GetAuditLogPath(); got != "/var/log/wslp-audit.log" {
+		t.Errorf("expected the configured path, got %q", got)
+	}
+}
+
+func TestGetServerAllowedOrigins(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if got := GetServerAllowedOrigins(); len(got) != 0 {
+		t.Errorf("expected no origins by default, got %v", got)
+	}
+
+	viper.Set("server_allowed_origins", []string{"https://example.com/", " ", "http://localhost:3000"})
+	got := GetServerAllowedOrigins()
+	if len(got) != 2 || got[0] != "https://example.com" || got[1] != "http://localhost:3000" {
+		t.Errorf("unexpected origins %v", got)
+	}
+}
+
+func TestGetDistroTags(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+distro_tags:
+  Ubuntu-22.04: [dev, work]
+  docker-desktop: [infra]
+`))
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+
+	tags := GetDistroTags()
+	if len(tags) != 2 || len(tags["ubuntu-22.04"]) != 2 || tags["ubuntu-22.04"][1] != "work" || tags["docker-desktop"][0] != "infra" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
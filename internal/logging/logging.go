@@ -0,0 +1,62 @@
+// Package logging sets up the structured logger shared by the commands,
+// the API server and the WSL operations they run
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formats are the log formats Setup accepts
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// NewHandler returns a handler writing records at level or above to w, as
+// key=value text or JSON lines
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q: expected text or json", format)
+}
+
+// Setup makes slog's default logger, used throughout wslp, write to w at
+// level or above in format
+func Setup(w io.Writer, level, format string) error {
+	handler, err := NewHandler(w, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ParseLevel parses a level name: debug, info, warn or error. Empty means
+// info.
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	level = strings.TrimSpace(level)
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	if strings.EqualFold(level, "warning") {
+		level = "warn"
+	}
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", level)
+	}
+	return lvl, nil
+}
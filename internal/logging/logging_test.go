@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"warning", slog.LevelWarn},
+		{" error ", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("writes text at the level or above", func(t *testing.T) {
+		var out bytes.Buffer
+		handler, err := NewHandler(&out, "warn", "")
+		if err != nil {
+			t.Fatalf("NewHandler failed: %v", err)
+		}
+
+		logger := slog.New(handler)
+		logger.Info("ignored")
+		logger.Warn("backup failed", "distro", "Ubuntu")
+
+		if got := out.String(); strings.Contains(got, "ignored") || !strings.Contains(got, `level=WARN msg="backup failed" distro=Ubuntu`) {
+			t.Errorf("unexpected output %q", got)
+		}
+	})
+
+	t.Run("writes JSON lines", func(t *testing.T) {
+		var out bytes.Buffer
+		handler, err := NewHandler(&out, "info", "JSON")
+		if err != nil {
+			t.Fatalf("NewHandler failed: %v", err)
+		}
+		slog.New(handler).Info("server started", "address", "127.0.0.1:8080")
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatalf("expected a JSON line, got %q: %v", out.String(), err)
+		}
+		if record["msg"] != "server started" || record["address"] != "127.0.0.1:8080" {
+			t.Errorf("unexpected record %v", record)
+		}
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		if _, err := NewHandler(&bytes.Buffer{}, "info", "xml"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package socket
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// pipeSecurity lets only the pipe's owner, the user running the server,
+// and the system connect
+const pipeSecurity = "D:P(A;;GA;;;OW)(A;;GA;;;SY)"
+
+func listenPipe(path string) (net.Listener, error) {
+	return winio.ListenPipe(path, &winio.PipeConfig{SecurityDescriptor: pipeSecurity})
+}
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
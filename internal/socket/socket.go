@@ -0,0 +1,107 @@
+// Package socket listens on and dials the Unix domain sockets and Windows
+// named pipes the API server can be served over instead of a TCP port.
+package socket
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The networks an address can select
+const (
+	Unix = "unix"
+	Pipe = "npipe"
+)
+
+// DefaultPipe is the named pipe used by an npipe:// address without a name
+const DefaultPipe = `\\.\pipe\wslp`
+
+// Parse splits an address such as unix:///run/wslp.sock or
+// npipe:////./pipe/wslp into its network and path. A pipe may also be
+// given by name alone, e.g. npipe://wslp.
+func Parse(address string) (network, path string, err error) {
+	scheme, rest, ok := strings.Cut(address, "://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid address %q: expected unix:///path or npipe://name", address)
+	}
+
+	switch scheme {
+	case Unix:
+		if rest == "" {
+			return "", "", fmt.Errorf("invalid address %q: missing socket path", address)
+		}
+		return Unix, rest, nil
+	case Pipe:
+		return Pipe, pipePath(rest), nil
+	default:
+		return "", "", fmt.Errorf("unsupported address %q: expected unix:///path or npipe://name", address)
+	}
+}
+
+// pipePath turns the part of an npipe:// address after the scheme into a
+// pipe path such as \\.\pipe\wslp
+func pipePath(name string) string {
+	if name == "" {
+		return DefaultPipe
+	}
+
+	name = strings.ReplaceAll(name, "/", `\`)
+	switch {
+	case strings.HasPrefix(name, `\\`):
+		return name
+	case strings.HasPrefix(name, `.\pipe\`):
+		return `\\` + name
+	default:
+		return `\\.\pipe\` + strings.TrimLeft(name, `\`)
+	}
+}
+
+// Listen listens on a socket or pipe returned by Parse. Only the current
+// user may connect to it.
+func Listen(network, path string) (net.Listener, error) {
+	if network == Pipe {
+		return listenPipe(path)
+	}
+	return listenUnix(path)
+}
+
+// Dial connects to a socket or pipe returned by Parse
+func Dial(ctx context.Context, network, path string) (net.Conn, error) {
+	if network == Pipe {
+		return dialPipe(ctx, path)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", path)
+}
+
+// listenUnix listens on a Unix domain socket readable and writable only by
+// the current user. The socket file is removed when the listener closes.
+func listenUnix(path string) (net.Listener, error) {
+	// A socket left behind by a server that didn't shut down cleanly would
+	// stop net.Listen, so remove it unless a server still answers on it
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a server is already listening on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,106 @@
+package socket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		path    string
+		wantErr bool
+	}{
+		{address: "unix:///run/wslp.sock", network: Unix, path: "/run/wslp.sock"},
+		{address: `unix://C:\Users\me\wslp.sock`, network: Unix, path: `C:\Users\me\wslp.sock`},
+		{address: "npipe://", network: Pipe, path: DefaultPipe},
+		{address: "npipe:////./pipe/wslp-dev", network: Pipe, path: `\\.\pipe\wslp-dev`},
+		{address: "npipe://./pipe/wslp-dev", network: Pipe, path: `\\.\pipe\wslp-dev`},
+		{address: "npipe://wslp-dev", network: Pipe, path: `\\.\pipe\wslp-dev`},
+		{address: "unix://", wantErr: true},
+		{address: "tcp://127.0.0.1:8080", wantErr: true},
+		{address: "/run/wslp.sock", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		network, path, err := Parse(tt.address)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q): expected an error", tt.address)
+			}
+			continue
+		}
+		if err != nil || network != tt.network || path != tt.path {
+			t.Errorf("Parse(%q) = %q, %q, %v; want %q, %q", tt.address, network, path, err, tt.network, tt.path)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "wslp.sock")
+	listener, err := Listen(Unix, path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("socket missing: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("expected socket permissions 0600, got %o", perm)
+		}
+	}
+
+	if _, err := Listen(Unix, path); err == nil {
+		t.Error("expected an error listening on a socket in use")
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, Unix, path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Close()
+	if err := <-accepted; err != nil {
+		t.Errorf("Accept failed: %v", err)
+	}
+
+	listener.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed on close, got %v", err)
+	}
+}
+
+func TestListenUnixRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wslp.sock")
+	listener, err := Listen(Unix, path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	// Leave the socket file behind, as a crashed server would
+	listener.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	listener.Close()
+
+	listener, err = Listen(Unix, path)
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+}
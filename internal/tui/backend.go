@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"wslp/internal/config"
+	"wslp/internal/wsl"
+)
+
+// Backend is what the TUI lists and changes distros with. Tests replace
+// its parts with mocks.
+type Backend struct {
+	Lister wsl.Lister
+	// Info returns the details shown in the table, such as a distro's WSL
+	// version and flavor
+	Info         func(ctx context.Context, name string) (wsl.DistroDetailInfo, error)
+	Terminator   wsl.Terminator
+	Backuper     wsl.Backuper
+	Copier       wsl.Copier
+	Renamer      wsl.Renamer
+	Unregisterer wsl.Unregisterer
+	Workshops    wsl.WorkshopRunner
+	// Shell returns the command opening a shell in a distro, which runs in
+	// the terminal while the TUI is suspended
+	Shell func(distro string) *exec.Cmd
+}
+
+// RealBackend manages the distros registered on this machine
+func RealBackend() Backend {
+	return Backend{
+		Lister:       wsl.RealLister{},
+		Info:         wsl.GetDistroDetailInfo,
+		Terminator:   wsl.RealTerminator{},
+		Backuper:     wsl.RealBackuper{},
+		Copier:       wsl.RealCopier{},
+		Renamer:      wsl.RealRenamer{},
+		Unregisterer: wsl.RealUnregisterer{},
+		Workshops:    wsl.RealWorkshopRunner{},
+		Shell: func(distro string) *exec.Cmd {
+			return exec.Command("wsl.exe", "-d", distro, "--cd", "~")
+		},
+	}
+}
+
+// distroRow is a distro shown in the table
+type distroRow struct {
+	wsl.DistroInfo
+	// Version is the WSL version, or 0 if it couldn't be read
+	Version int
+	Default bool
+	Flavor  string
+}
+
+// load lists the registered distros with their details. Distros whose
+// details can't be read are still listed.
+func (b Backend) load(ctx context.Context) ([]distroRow, error) {
+	distros, err := wsl.ListDistros(ctx, b.Lister)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]distroRow, len(distros))
+	for i, d := range distros {
+		rows[i] = distroRow{DistroInfo: d}
+		info, err := b.Info(ctx, d.Name)
+		if err != nil {
+			continue
+		}
+		rows[i].Version = info.WSLVersion
+		rows[i].Default = info.IsDefault
+		rows[i].Flavor = info.Flavor
+	}
+	return rows, nil
+}
+
+// terminate stops a running distro
+func (b Backend) terminate(ctx context.Context, distro string) (string, error) {
+	result := wsl.TerminateDistros(ctx, b.Terminator, []string{distro})[0]
+	if !result.Success {
+		return "", errors.New(result.Message)
+	}
+	return result.Message, nil
+}
+
+// backup backs up a distro as 'wslp backup <distro>' does: to the
+// configured target, with the configured encryption and retention policy.
+// progress is called with the number of bytes exported so far.
+func (b Backend) backup(ctx context.Context, distro string, progress func(bytesWritten int64)) (string, error) {
+	backupDir, target, err := wsl.ResolveBackupDestination("", "")
+	if err != nil {
+		return "", err
+	}
+	encryption, err := config.GetBackupEncryption()
+	if err != nil {
+		return "", err
+	}
+
+	opts := wsl.BackupOptions{
+		Encrypt: encryption.Enabled,
+		Target:  target,
+		Progress: func(_ string, bytesWritten int64) {
+			progress(bytesWritten)
+		},
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	results := wsl.BackupDistros(ctx, b.Backuper, []string{distro}, backupDir, opts)
+	if !results[0].Success {
+		return "", errors.New(results[0].Message)
+	}
+	if target == nil {
+		if _, err := wsl.AutoPrune(results, backupDir, config.GetRetentionPolicy()); err != nil {
+			return "", fmt.Errorf("backed up to %s, but failed to prune old backups: %w", results[0].FilePath, err)
+		}
+	}
+	return fmt.Sprintf("Backed up %s to %s", distro, results[0].FilePath), nil
+}
+
+// copy copies a distro under a new name, to the default install directory
+func (b Backend) copy(ctx context.Context, source, newName string) (string, error) {
+	result := wsl.CopyDistro(ctx, b.Copier, source, newName, "")
+	if !result.Success {
+		return "", errors.New(result.Message)
+	}
+	return result.Message, nil
+}
+
+// rename renames a distro
+func (b Backend) rename(ctx context.Context, oldName, newName string) (string, error) {
+	result := wsl.RenameDistro(ctx, b.Renamer, oldName, newName)
+	if !result.Success {
+		return "", errors.New(result.Message)
+	}
+	return result.Message + " (run 'wsl --shutdown' to apply)", nil
+}
+
+// unregister unregisters a distro, deleting its files
+func (b Backend) unregister(ctx context.Context, distro string) (string, error) {
+	if err := wsl.UnregisterDistro(ctx, distro, b.Unregisterer); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Unregistered %s", distro), nil
+}
@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"wslp/internal/wsl"
+)
+
+type mockLister struct {
+	names      []string
+	shouldFail bool
+}
+
+func (m *mockLister) List(ctx context.Context) ([]string, error) {
+	if m.shouldFail {
+		return nil, errors.New("mock list error")
+	}
+	return m.names, nil
+}
+
+// mockInfo returns details for the distros in infos, and fails for others
+func mockInfo(infos ...wsl.DistroDetailInfo) func(ctx context.Context, name string) (wsl.DistroDetailInfo, error) {
+	return func(ctx context.Context, name string) (wsl.DistroDetailInfo, error) {
+		for _, info := range infos {
+			if info.Name == name {
+				return info, nil
+			}
+		}
+		return wsl.DistroDetailInfo{}, errors.New("mock info error")
+	}
+}
+
+type mockBackuper struct {
+	shouldFail bool
+}
+
+func (m *mockBackuper) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return true, nil
+}
+
+func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string, format wsl.BackupFormat) error {
+	if m.shouldFail {
+		return errors.New("export failed")
+	}
+	return os.WriteFile(outputPath, buildTestArchive(), 0644)
+}
+
+func (m *mockBackuper) Info(ctx context.Context, name string) (wsl.DistroDetailInfo, error) {
+	return wsl.DistroDetailInfo{Name: name}, nil
+}
+
+// buildTestArchive returns a minimal tar.gz distro archive
+func buildTestArchive() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := "NAME=\"Ubuntu\"\n"
+	tw.WriteHeader(&tar.Header{Name: "./etc/os-release", Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// useBackupDir backs up to a fresh local directory for the duration of a
+// test
+func useBackupDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	viper.Set("backup_dir", dir)
+	t.Cleanup(viper.Reset)
+	return dir
+}
+
+func TestBackendLoad(t *testing.T) {
+	t.Run("lists distros with their details", func(t *testing.T) {
+		b := Backend{
+			Lister: &mockLister{names: []string{"Ubuntu", "Debian"}},
+			Info:   mockInfo(wsl.DistroDetailInfo{Name: "Ubuntu", WSLVersion: 2, IsDefault: true, Flavor: "ubuntu"}),
+		}
+
+		rows, err := b.load(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 distros, got %d", len(rows))
+		}
+		if rows[0].Version != 2 || !rows[0].Default || rows[0].Flavor != "ubuntu" {
+			t.Errorf("unexpected details %+v", rows[0])
+		}
+		// Distros without details are still listed
+		if rows[1].Name != "Debian" || rows[1].Version != 0 {
+			t.Errorf("unexpected row %+v", rows[1])
+		}
+	})
+
+	t.Run("fails if listing fails", func(t *testing.T) {
+		b := Backend{Lister: &mockLister{shouldFail: true}}
+
+		if _, err := b.load(context.Background()); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestBackendBackup(t *testing.T) {
+	t.Run("backs up to the backup directory with progress", func(t *testing.T) {
+		dir := useBackupDir(t)
+		b := Backend{Backuper: &mockBackuper{}}
+
+		var written int64
+		message, err := b.backup(context.Background(), "Ubuntu", func(bytesWritten int64) {
+			written = bytesWritten
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(message, "Backed up Ubuntu") {
+			t.Errorf("unexpected message %q", message)
+		}
+		if written == 0 {
+			t.Error("expected progress to be reported")
+		}
+
+		entries, _ := os.ReadDir(dir)
+		if len(entries) == 0 {
+			t.Error("expected a backup in the backup directory")
+		}
+	})
+
+	t.Run("reports a failed backup", func(t *testing.T) {
+		useBackupDir(t)
+		b := Backend{Backuper: &mockBackuper{shouldFail: true}}
+
+		_, err := b.backup(context.Background(), "Ubuntu", func(int64) {})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
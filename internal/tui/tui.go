@@ -0,0 +1,595 @@
+// Package tui is the full-screen terminal interface of 'wslp tui', for
+// managing distros without the GUI
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+
+	"wslp/internal/wsl"
+)
+
+// refreshInterval is how often the table is reloaded while nothing is
+// running, to follow changes made outside the TUI
+var refreshInterval = 10 * time.Second
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	faintStyle    = lipgloss.NewStyle().Faint(true)
+)
+
+// keyMap holds the key bindings, which the help at the bottom lists
+type keyMap struct {
+	Up, Down, Launch, Refresh                   key.Binding
+	Terminate, Backup, Copy, Rename, Unregister key.Binding
+	Workshops, Help, Quit                       key.Binding
+}
+
+func newKeyMap() keyMap {
+	return keyMap{
+		Up:         key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+		Down:       key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+		Launch:     key.NewBinding(key.WithKeys("enter", "l"), key.WithHelp("enter", "shell")),
+		Refresh:    key.NewBinding(key.WithKeys("R", "f5"), key.WithHelp("R", "refresh")),
+		Terminate:  key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "terminate")),
+		Backup:     key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "back up")),
+		Copy:       key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Rename:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename")),
+		Unregister: key.NewBinding(key.WithKeys("u", "delete"), key.WithHelp("u", "unregister")),
+		Workshops:  key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "workshops")),
+		Help:       key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more keys")),
+		Quit:       key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Launch, k.Terminate, k.Backup, k.Copy, k.Rename, k.Unregister, k.Workshops, k.Help, k.Quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Launch, k.Refresh},
+		{k.Terminate, k.Backup, k.Copy},
+		{k.Rename, k.Unregister, k.Workshops},
+		{k.Help, k.Quit},
+	}
+}
+
+// mode is what the keys act on
+type mode int
+
+const (
+	// modeTable moves around the table and acts on the selected distro
+	modeTable mode = iota
+	// modeConfirm asks a yes or no question before an action
+	modeConfirm
+	// modeInput asks for a name, such as that of a copy
+	modeInput
+)
+
+// operation is a long-running action, shown with its progress until it
+// finishes. Only one runs at a time.
+type operation struct {
+	title   string
+	started time.Time
+	// bytes is how much has been written so far, for backups
+	bytes  int64
+	events <-chan tea.Msg
+}
+
+// opFunc runs an operation, calling progress with the bytes written so far
+// if it can tell, and returns a message describing its success
+type opFunc func(ctx context.Context, progress func(bytesWritten int64)) (string, error)
+
+// The messages the model handles besides keys
+type (
+	distrosMsg struct {
+		rows []distroRow
+		err  error
+	}
+	workshopsMsg struct {
+		distro    string
+		workshops []wsl.WorkshopInfo
+	}
+	progressMsg struct {
+		bytes int64
+	}
+	doneMsg struct {
+		message string
+		err     error
+	}
+	shellDoneMsg struct {
+		distro string
+		err    error
+	}
+	tickMsg struct{}
+)
+
+// Model is the Bubble Tea model of the TUI: a table of the registered
+// distros, with keys acting on the selected one
+type Model struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	backend Backend
+	keys    keyMap
+	help    help.Model
+
+	distros  []distroRow
+	cursor   int
+	loading  bool
+	lastLoad time.Time
+	loadErr  error
+
+	mode   mode
+	prompt string
+	input  textinput.Model
+	// then runs once the question asked in modeConfirm or modeInput is
+	// answered, with the name entered in modeInput
+	then func(m *Model, text string) tea.Cmd
+
+	op *operation
+
+	showWorkshops bool
+	// workshops caches the workshops of each distro shown so far
+	workshops        map[string][]wsl.WorkshopInfo
+	loadingWorkshops map[string]bool
+
+	status    string
+	statusErr bool
+	showHelp  bool
+}
+
+// New creates the TUI, which manages distros with b. Quitting cancels the
+// operation running, if any.
+func New(ctx context.Context, b Backend) Model {
+	ctx, cancel := context.WithCancel(ctx)
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 64
+	// A blinking cursor would redraw the whole screen twice a second
+	input.Cursor.SetMode(cursor.CursorStatic)
+
+	return Model{
+		ctx:              ctx,
+		cancel:           cancel,
+		backend:          b,
+		keys:             newKeyMap(),
+		help:             help.New(),
+		loading:          true,
+		input:            input,
+		workshops:        map[string][]wsl.WorkshopInfo{},
+		loadingWorkshops: map[string]bool{},
+	}
+}
+
+// Run shows the TUI full-screen until the user quits
+func Run(ctx context.Context, b Backend, in io.Reader, out io.Writer) error {
+	_, err := tea.NewProgram(New(ctx, b), tea.WithAltScreen(), tea.WithInput(in), tea.WithOutput(out), tea.WithContext(ctx)).Run()
+	return err
+}
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(loadDistros(m.ctx, m.backend), tick())
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.help.Width = msg.Width
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case distrosMsg:
+		m.loading = false
+		m.lastLoad = time.Now()
+		m.loadErr = msg.err
+		if msg.err == nil {
+			m.setDistros(msg.rows)
+		}
+		cmd := m.loadWorkshops()
+		return m, cmd
+
+	case workshopsMsg:
+		m.workshops[msg.distro] = msg.workshops
+		delete(m.loadingWorkshops, msg.distro)
+
+	case progressMsg:
+		if m.op != nil {
+			m.op.bytes = msg.bytes
+			return m, waitForEvent(m.op.events)
+		}
+
+	case doneMsg:
+		m.op = nil
+		m.setStatus(msg.message, msg.err)
+		cmd := m.load()
+		return m, cmd
+
+	case shellDoneMsg:
+		if msg.err != nil {
+			m.setStatus("", fmt.Errorf("shell in %s failed: %w", msg.distro, msg.err))
+		}
+		cmd := m.load()
+		return m, cmd
+
+	case tickMsg:
+		// Ticks also redraw the time an operation has been running
+		cmds := []tea.Cmd{tick()}
+		if m.op == nil && !m.loading && time.Since(m.lastLoad) >= refreshInterval {
+			cmds = append(cmds, m.load())
+		}
+		return m, tea.Batch(cmds...)
+	}
+	return m, nil
+}
+
+// handleKey acts on a key press, answering the question asked if any
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.mode {
+	case modeConfirm:
+		m.mode = modeTable
+		if msg.String() == "y" || msg.String() == "Y" {
+			cmd := m.then(&m, "")
+			return m, cmd
+		}
+		m.setStatus("Cancelled", nil)
+		return m, nil
+
+	case modeInput:
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.mode = modeTable
+			m.input.Blur()
+			text := strings.TrimSpace(m.input.Value())
+			if text == "" {
+				m.setStatus("Cancelled", nil)
+				return m, nil
+			}
+			cmd := m.then(&m, text)
+			return m, cmd
+		case tea.KeyEsc, tea.KeyCtrlC:
+			m.mode = modeTable
+			m.input.Blur()
+			m.setStatus("Cancelled", nil)
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		if m.op != nil && msg.String() != "ctrl+c" {
+			m.setStatus("", fmt.Errorf("%s is still running: press ctrl+c to cancel it and quit", m.op.title))
+			return m, nil
+		}
+		m.cancel()
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		cmd := m.loadWorkshops()
+		return m, cmd
+	case key.Matches(msg, m.keys.Down):
+		if m.cursor < len(m.distros)-1 {
+			m.cursor++
+		}
+		cmd := m.loadWorkshops()
+		return m, cmd
+	case key.Matches(msg, m.keys.Refresh):
+		m.workshops = map[string][]wsl.WorkshopInfo{}
+		cmd := m.load()
+		return m, tea.Batch(cmd, m.loadWorkshops())
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = !m.showHelp
+		m.help.ShowAll = m.showHelp
+		return m, nil
+	case key.Matches(msg, m.keys.Workshops):
+		m.showWorkshops = !m.showWorkshops
+		cmd := m.loadWorkshops()
+		return m, cmd
+	}
+
+	if m.cursor >= len(m.distros) {
+		return m, nil
+	}
+	distro := m.distros[m.cursor].Name
+	b := m.backend
+
+	if key.Matches(msg, m.keys.Launch) {
+		// The TUI is suspended while the shell runs in the terminal
+		return m, tea.ExecProcess(b.Shell(distro), func(err error) tea.Msg {
+			return shellDoneMsg{distro: distro, err: err}
+		})
+	}
+
+	if m.op != nil {
+		for _, k := range []key.Binding{m.keys.Terminate, m.keys.Backup, m.keys.Copy, m.keys.Rename, m.keys.Unregister} {
+			if key.Matches(msg, k) {
+				m.setStatus("", fmt.Errorf("%s is still running", m.op.title))
+				return m, nil
+			}
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Terminate):
+		m.confirm(fmt.Sprintf("Terminate %s?", distro), func(m *Model, _ string) tea.Cmd {
+			return m.start("Terminating "+distro, func(ctx context.Context, _ func(int64)) (string, error) {
+				return b.terminate(ctx, distro)
+			})
+		})
+	case key.Matches(msg, m.keys.Backup):
+		cmd := m.start("Backing up "+distro, func(ctx context.Context, progress func(int64)) (string, error) {
+			return b.backup(ctx, distro, progress)
+		})
+		return m, cmd
+	case key.Matches(msg, m.keys.Copy):
+		cmd := m.ask(fmt.Sprintf("Name of the copy of %s:", distro), func(m *Model, newName string) tea.Cmd {
+			return m.start(fmt.Sprintf("Copying %s to %s", distro, newName), func(ctx context.Context, _ func(int64)) (string, error) {
+				return b.copy(ctx, distro, newName)
+			})
+		})
+		return m, cmd
+	case key.Matches(msg, m.keys.Rename):
+		cmd := m.ask(fmt.Sprintf("New name for %s:", distro), func(m *Model, newName string) tea.Cmd {
+			m.confirm(fmt.Sprintf("Rename %s to %s?", distro, newName), func(m *Model, _ string) tea.Cmd {
+				return m.start(fmt.Sprintf("Renaming %s to %s", distro, newName), func(ctx context.Context, _ func(int64)) (string, error) {
+					return b.rename(ctx, distro, newName)
+				})
+			})
+			return nil
+		})
+		return m, cmd
+	case key.Matches(msg, m.keys.Unregister):
+		m.confirm(fmt.Sprintf("Unregister %s? This deletes the distro and all its files.", distro), func(m *Model, _ string) tea.Cmd {
+			return m.start("Unregistering "+distro, func(ctx context.Context, _ func(int64)) (string, error) {
+				return b.unregister(ctx, distro)
+			})
+		})
+	}
+	return m, nil
+}
+
+// confirm asks a yes or no question, running then if the answer is yes
+func (m *Model) confirm(question string, then func(m *Model, text string) tea.Cmd) {
+	m.mode = modeConfirm
+	m.prompt = question + " [y/N]"
+	m.then = then
+}
+
+// ask asks for a name, running then with it once entered
+func (m *Model) ask(question string, then func(m *Model, text string) tea.Cmd) tea.Cmd {
+	m.mode = modeInput
+	m.prompt = question
+	m.then = then
+	m.input.SetValue("")
+	return m.input.Focus()
+}
+
+// start runs fn in the background as the current operation. Its progress
+// and result come back as messages.
+func (m *Model) start(title string, fn opFunc) tea.Cmd {
+	if m.op != nil {
+		m.setStatus("", fmt.Errorf("%s is still running", m.op.title))
+		return nil
+	}
+
+	events := make(chan tea.Msg, 16)
+	m.op = &operation{title: title, started: time.Now(), events: events}
+	m.status = ""
+
+	ctx := m.ctx
+	go func() {
+		progress := func(bytesWritten int64) {
+			// Progress that can't be shown yet is superseded by the next
+			select {
+			case events <- progressMsg{bytes: bytesWritten}:
+			default:
+			}
+		}
+		message, err := fn(ctx, progress)
+		events <- doneMsg{message: message, err: err}
+	}()
+	return waitForEvent(events)
+}
+
+// waitForEvent waits for the next message from a running operation
+func waitForEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// load reloads the table in the background
+func (m *Model) load() tea.Cmd {
+	m.loading = true
+	return loadDistros(m.ctx, m.backend)
+}
+
+func loadDistros(ctx context.Context, b Backend) tea.Cmd {
+	return func() tea.Msg {
+		rows, err := b.load(ctx)
+		return distrosMsg{rows: rows, err: err}
+	}
+}
+
+// loadWorkshops loads the workshops of the selected distro in the
+// background, if they are shown and weren't loaded yet
+func (m *Model) loadWorkshops() tea.Cmd {
+	if !m.showWorkshops || m.cursor >= len(m.distros) {
+		return nil
+	}
+	distro := m.distros[m.cursor].Name
+	if _, ok := m.workshops[distro]; ok || m.loadingWorkshops[distro] {
+		return nil
+	}
+
+	m.loadingWorkshops[distro] = true
+	ctx, runner := m.ctx, m.backend.Workshops
+	return func() tea.Msg {
+		return workshopsMsg{distro: distro, workshops: wsl.GetWorkshops(ctx, distro, runner)}
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
+
+// setDistros replaces the rows of the table, keeping the same distro
+// selected if it is still there
+func (m *Model) setDistros(rows []distroRow) {
+	selected := ""
+	if m.cursor < len(m.distros) {
+		selected = m.distros[m.cursor].Name
+	}
+
+	m.distros = rows
+	m.cursor = min(m.cursor, max(len(rows)-1, 0))
+	for i, row := range rows {
+		if strings.EqualFold(row.Name, selected) {
+			m.cursor = i
+		}
+	}
+}
+
+// setStatus shows the outcome of an action below the table: err if it
+// failed, or else message
+func (m *Model) setStatus(message string, err error) {
+	m.statusErr = err != nil
+	m.status = message
+	if err != nil {
+		m.status = "Error: " + err.Error()
+	}
+}
+
+func (m Model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf("WSL Plus: %d distros", len(m.distros))))
+	b.WriteString("\n\n")
+
+	switch {
+	case m.loadErr != nil:
+		b.WriteString(errorStyle.Render("Failed to list distros: "+m.loadErr.Error()) + "\n")
+	case m.loading && len(m.distros) == 0:
+		b.WriteString("Loading distros...\n")
+	case len(m.distros) == 0:
+		b.WriteString("No distros are registered\n")
+	default:
+		m.viewTable(&b)
+	}
+
+	if m.showWorkshops && m.cursor < len(m.distros) {
+		m.viewWorkshops(&b, m.distros[m.cursor].Name)
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.mode == modeConfirm:
+		b.WriteString(m.prompt + "\n")
+	case m.mode == modeInput:
+		b.WriteString(m.prompt + " " + m.input.View() + "\n")
+	case m.op != nil:
+		b.WriteString(m.op.describe() + "\n")
+	case m.statusErr:
+		b.WriteString(errorStyle.Render(m.status) + "\n")
+	case m.status != "":
+		b.WriteString(m.status + "\n")
+	default:
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n" + m.help.View(m.keys) + "\n")
+	return b.String()
+}
+
+// viewTable draws the table of distros, with the selected one highlighted
+func (m Model) viewTable(b *strings.Builder) {
+	rows := [][]string{{"NAME", "STATE", "VERSION", "DEFAULT", "FLAVOR"}}
+	for _, d := range m.distros {
+		state := d.State
+		if d.Busy {
+			state += " (" + d.Operation + ")"
+		}
+		version := "-"
+		if d.Version > 0 {
+			version = strconv.Itoa(d.Version)
+		}
+		isDefault := ""
+		if d.Default {
+			isDefault = "*"
+		}
+		flavor := d.Flavor
+		if flavor == "" {
+			flavor = "-"
+		}
+		rows = append(rows, []string{d.Name, state, version, isDefault, flavor})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, column := range row {
+			widths[i] = max(widths[i], lipgloss.Width(column))
+		}
+	}
+
+	for i, row := range rows {
+		columns := make([]string, len(row))
+		for j, column := range row {
+			columns[j] = column + strings.Repeat(" ", widths[j]-lipgloss.Width(column))
+		}
+		line := "  " + strings.Join(columns, "  ")
+		switch {
+		case i == 0:
+			line = headerStyle.Render(line)
+		case i-1 == m.cursor:
+			line = selectedStyle.Render("> " + line[2:])
+		}
+		b.WriteString(line + "\n")
+	}
+}
+
+// viewWorkshops lists the workshops of a distro, under the table
+func (m Model) viewWorkshops(b *strings.Builder, distro string) {
+	b.WriteString("\n" + titleStyle.Render("Workshops in "+distro) + "\n")
+
+	workshops, ok := m.workshops[distro]
+	switch {
+	case !ok:
+		b.WriteString(faintStyle.Render("  Loading...") + "\n")
+	case len(workshops) == 0:
+		b.WriteString(faintStyle.Render("  No workshops") + "\n")
+	}
+	for _, w := range workshops {
+		fmt.Fprintf(b, "  %s/%s  %s\n", w.Project, w.Name, w.Status)
+	}
+}
+
+// describe shows what the operation is doing and for how long
+func (op *operation) describe() string {
+	elapsed := time.Since(op.started).Truncate(time.Second)
+	if op.bytes > 0 {
+		return fmt.Sprintf("%s... %s, %s written", op.title, elapsed, humanize.IBytes(uint64(op.bytes)))
+	}
+	return fmt.Sprintf("%s... %s", op.title, elapsed)
+}
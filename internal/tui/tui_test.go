@@ -0,0 +1,424 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wslp/internal/wsl"
+)
+
+type mockTerminator struct {
+	terminated []string
+}
+
+func (m *mockTerminator) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return true, nil
+}
+
+func (m *mockTerminator) Terminate(ctx context.Context, name string) error {
+	m.terminated = append(m.terminated, name)
+	return nil
+}
+
+type mockCopier struct {
+	// release, if set, holds the export until it is closed
+	release chan struct{}
+	copied  []string
+}
+
+func (m *mockCopier) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return name == "Ubuntu", nil
+}
+
+func (m *mockCopier) Export(ctx context.Context, distroName, outputPath string) error {
+	if m.release != nil {
+		<-m.release
+	}
+	return nil
+}
+
+func (m *mockCopier) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	m.copied = append(m.copied, newName)
+	return nil
+}
+
+type mockRenamer struct {
+	renamedTo string
+}
+
+func (m *mockRenamer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return name == "Ubuntu", nil
+}
+
+func (m *mockRenamer) GetDistroGUID(ctx context.Context, name string) (string, error) {
+	return "12345678-1234-1234-1234-123456789012", nil
+}
+
+func (m *mockRenamer) RenameInRegistry(guid, newName string) error {
+	m.renamedTo = newName
+	return nil
+}
+
+type mockUnregisterer struct {
+	unregistered []string
+}
+
+func (m *mockUnregisterer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return true, nil
+}
+
+func (m *mockUnregisterer) Unregister(ctx context.Context, name string) error {
+	m.unregistered = append(m.unregistered, name)
+	return nil
+}
+
+type mockWorkshopRunner struct {
+	output map[string]string
+}
+
+func (m *mockWorkshopRunner) ListWorkshops(ctx context.Context, distro string) ([]byte, error) {
+	output, ok := m.output[distro]
+	if !ok {
+		return nil, errors.New("workshop: command not found")
+	}
+	return []byte(output), nil
+}
+
+// testBackend returns a backend with Ubuntu and Debian registered
+func testBackend() Backend {
+	return Backend{
+		Lister: &mockLister{names: []string{"Ubuntu", "Debian"}},
+		Info: mockInfo(
+			wsl.DistroDetailInfo{Name: "Ubuntu", WSLVersion: 2, IsDefault: true, Flavor: "ubuntu"},
+			wsl.DistroDetailInfo{Name: "Debian", WSLVersion: 1, Flavor: "debian"},
+		),
+		Terminator:   &mockTerminator{},
+		Backuper:     &mockBackuper{},
+		Copier:       &mockCopier{},
+		Renamer:      &mockRenamer{},
+		Unregisterer: &mockUnregisterer{},
+		Workshops:    &mockWorkshopRunner{},
+	}
+}
+
+// loaded returns the TUI once it has listed the distros of b
+func loaded(t *testing.T, b Backend) Model {
+	t.Helper()
+	m := New(context.Background(), b)
+	return drive(m, loadDistros(m.ctx, b)())
+}
+
+// drive gives msg to the model, then the messages of the commands it
+// returns until there are none left, as Bubble Tea would
+func drive(m Model, msgs ...tea.Msg) Model {
+	for len(msgs) > 0 {
+		msg := msgs[0]
+		msgs = msgs[1:]
+
+		model, cmd := m.Update(msg)
+		m = model.(Model)
+		msgs = append(msgs, run(cmd)...)
+	}
+	return m
+}
+
+// run runs a command and returns its messages. Ticks are left out, as
+// they never stop.
+func run(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case nil, tickMsg:
+		return nil
+	case tea.BatchMsg:
+		var msgs []tea.Msg
+		for _, c := range msg {
+			msgs = append(msgs, run(c)...)
+		}
+		return msgs
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+// keys returns the key presses typing s, with enter for \n
+func keys(s string) []tea.Msg {
+	var msgs []tea.Msg
+	for _, r := range s {
+		if r == '\n' {
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEnter})
+			continue
+		}
+		msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return msgs
+}
+
+func TestTable(t *testing.T) {
+	t.Run("lists distros with their details", func(t *testing.T) {
+		m := loaded(t, testBackend())
+
+		view := m.View()
+		for _, want := range []string{"2 distros", "NAME", "VERSION", "FLAVOR", "> Ubuntu", "ubuntu", "Debian", "debian"} {
+			if !strings.Contains(view, want) {
+				t.Errorf("expected view to contain %q, got:\n%s", want, view)
+			}
+		}
+	})
+
+	t.Run("moves the selection", func(t *testing.T) {
+		m := drive(loaded(t, testBackend()), tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyDown})
+
+		if m.cursor != 1 {
+			t.Errorf("expected the last distro selected, got %d", m.cursor)
+		}
+		if !strings.Contains(m.View(), "> Debian") {
+			t.Errorf("expected Debian selected, got:\n%s", m.View())
+		}
+
+		m = drive(m, keys("k")...)
+		if m.cursor != 0 {
+			t.Errorf("expected the first distro selected, got %d", m.cursor)
+		}
+	})
+
+	t.Run("keeps the selected distro when reloading", func(t *testing.T) {
+		b := testBackend()
+		m := drive(loaded(t, b), keys("j")...)
+
+		b.Lister = &mockLister{names: []string{"Alpine", "Ubuntu", "Debian"}}
+		m.backend = b
+		m = drive(m, keys("R")...)
+
+		if m.distros[m.cursor].Name != "Debian" {
+			t.Errorf("expected Debian still selected, got %s", m.distros[m.cursor].Name)
+		}
+	})
+
+	t.Run("shows listing errors", func(t *testing.T) {
+		m := loaded(t, Backend{Lister: &mockLister{shouldFail: true}})
+
+		if !strings.Contains(m.View(), "Failed to list distros") {
+			t.Errorf("expected the error, got:\n%s", m.View())
+		}
+	})
+}
+
+func TestActions(t *testing.T) {
+	t.Run("terminates after confirmation", func(t *testing.T) {
+		b := testBackend()
+		terminator := b.Terminator.(*mockTerminator)
+
+		m := drive(loaded(t, b), keys("t")...)
+		if !strings.Contains(m.View(), "Terminate Ubuntu? [y/N]") {
+			t.Fatalf("expected a confirmation, got:\n%s", m.View())
+		}
+
+		m = drive(m, keys("y")...)
+		if len(terminator.terminated) != 1 || terminator.terminated[0] != "Ubuntu" {
+			t.Errorf("expected Ubuntu to be terminated, got %v", terminator.terminated)
+		}
+		if m.op != nil || m.statusErr {
+			t.Errorf("expected the operation to succeed, got status %q", m.status)
+		}
+	})
+
+	t.Run("declining cancels", func(t *testing.T) {
+		b := testBackend()
+		unregisterer := b.Unregisterer.(*mockUnregisterer)
+
+		m := drive(loaded(t, b), keys("u")...)
+		if !strings.Contains(m.View(), "deletes the distro") {
+			t.Fatalf("expected a confirmation, got:\n%s", m.View())
+		}
+
+		m = drive(m, keys("n")...)
+		if len(unregisterer.unregistered) != 0 {
+			t.Errorf("expected nothing unregistered, got %v", unregisterer.unregistered)
+		}
+		if !strings.Contains(m.View(), "Cancelled") {
+			t.Errorf("expected Cancelled, got:\n%s", m.View())
+		}
+	})
+
+	t.Run("unregisters after confirmation", func(t *testing.T) {
+		b := testBackend()
+		unregisterer := b.Unregisterer.(*mockUnregisterer)
+
+		m := drive(loaded(t, b), keys("jUy")...)
+		if len(unregisterer.unregistered) != 0 {
+			t.Fatalf("expected U not to unregister, got %v", unregisterer.unregistered)
+		}
+
+		m = drive(m, keys("uy")...)
+		if len(unregisterer.unregistered) != 1 || unregisterer.unregistered[0] != "Debian" {
+			t.Errorf("expected Debian to be unregistered, got %v", unregisterer.unregistered)
+		}
+		if !strings.Contains(m.View(), "Unregistered Debian") {
+			t.Errorf("expected the result, got:\n%s", m.View())
+		}
+	})
+
+	t.Run("copies under the name entered", func(t *testing.T) {
+		b := testBackend()
+		copier := b.Copier.(*mockCopier)
+
+		m := drive(loaded(t, b), keys("c")...)
+		if !strings.Contains(m.View(), "Name of the copy of Ubuntu:") {
+			t.Fatalf("expected a name to be asked, got:\n%s", m.View())
+		}
+
+		drive(m, keys("Ubuntu-dev\n")...)
+		if len(copier.copied) != 1 || copier.copied[0] != "Ubuntu-dev" {
+			t.Errorf("expected a copy named Ubuntu-dev, got %v", copier.copied)
+		}
+	})
+
+	t.Run("renames after the name and a confirmation", func(t *testing.T) {
+		b := testBackend()
+		renamer := b.Renamer.(*mockRenamer)
+
+		m := drive(loaded(t, b), keys("rUbuntu-old\n")...)
+		if !strings.Contains(m.View(), "Rename Ubuntu to Ubuntu-old? [y/N]") {
+			t.Fatalf("expected a confirmation, got:\n%s", m.View())
+		}
+		if renamer.renamedTo != "" {
+			t.Fatal("expected no rename before the confirmation")
+		}
+
+		m = drive(m, keys("y")...)
+		if renamer.renamedTo != "Ubuntu-old" {
+			t.Errorf("expected a rename to Ubuntu-old, got %q", renamer.renamedTo)
+		}
+		if !strings.Contains(m.View(), "wsl --shutdown") {
+			t.Errorf("expected a hint to restart WSL, got:\n%s", m.View())
+		}
+	})
+
+	t.Run("escape cancels entering a name", func(t *testing.T) {
+		b := testBackend()
+		copier := b.Copier.(*mockCopier)
+
+		m := drive(loaded(t, b), keys("cUbuntu-dev")...)
+		m = drive(m, tea.KeyMsg{Type: tea.KeyEsc})
+		if m.mode != modeTable || len(copier.copied) != 0 {
+			t.Errorf("expected the copy to be cancelled, got %v", copier.copied)
+		}
+	})
+
+	t.Run("backs up with progress", func(t *testing.T) {
+		useBackupDir(t)
+
+		m := drive(loaded(t, testBackend()), keys("b")...)
+		if m.op != nil {
+			t.Fatal("expected the backup to have finished")
+		}
+		if m.statusErr || !strings.Contains(m.status, "Backed up Ubuntu") {
+			t.Errorf("unexpected status %q", m.status)
+		}
+	})
+
+	t.Run("shows operation errors", func(t *testing.T) {
+		useBackupDir(t)
+		b := testBackend()
+		b.Backuper = &mockBackuper{shouldFail: true}
+
+		m := drive(loaded(t, b), keys("b")...)
+		if !m.statusErr || !strings.Contains(m.View(), "Error:") {
+			t.Errorf("expected an error, got:\n%s", m.View())
+		}
+	})
+
+	t.Run("launches a shell", func(t *testing.T) {
+		m := loaded(t, testBackend())
+		m.backend.Shell = func(distro string) *exec.Cmd {
+			return exec.Command("wsl.exe", "-d", distro)
+		}
+
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Error("expected a shell to be run")
+		}
+	})
+}
+
+func TestOperations(t *testing.T) {
+	t.Run("shows the running operation and refuses another", func(t *testing.T) {
+		b := testBackend()
+		copier := &mockCopier{release: make(chan struct{})}
+		b.Copier = copier
+
+		m := loaded(t, b)
+		model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+		m = drive(model.(Model), keys("Ubuntu-dev")...)
+
+		// The copy waits for release, so it is run by hand
+		model, wait := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		m = model.(Model)
+		if m.op == nil || !strings.Contains(m.View(), "Copying Ubuntu to Ubuntu-dev...") {
+			t.Fatalf("expected the copy to run, got:\n%s", m.View())
+		}
+
+		m = drive(m, keys("t")...)
+		if m.mode != modeTable || !strings.Contains(m.status, "still running") {
+			t.Errorf("expected another operation to be refused, got %q", m.status)
+		}
+
+		m = drive(m, keys("q")...)
+		if !strings.Contains(m.status, "ctrl+c") {
+			t.Errorf("expected quitting to be refused, got %q", m.status)
+		}
+
+		close(copier.release)
+		m = drive(m, run(wait)...)
+		if m.op != nil || len(copier.copied) != 1 {
+			t.Errorf("expected the copy to finish, got %v", copier.copied)
+		}
+	})
+
+	t.Run("ctrl+c cancels and quits", func(t *testing.T) {
+		m := loaded(t, testBackend())
+
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		if cmd == nil {
+			t.Fatal("expected the TUI to quit")
+		}
+		if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Error("expected a quit message")
+		}
+		if m.ctx.Err() == nil {
+			t.Error("expected the context to be cancelled")
+		}
+	})
+}
+
+func TestWorkshops(t *testing.T) {
+	b := testBackend()
+	b.Workshops = &mockWorkshopRunner{output: map[string]string{
+		"Ubuntu": "myproject  dev  Running\nmyproject  test  Stopped  needs refresh\n",
+	}}
+
+	m := drive(loaded(t, b), keys("w")...)
+	view := m.View()
+	for _, want := range []string{"Workshops in Ubuntu", "myproject/dev  Running", "myproject/test  Stopped"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected view to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	m = drive(m, keys("j")...)
+	if !strings.Contains(m.View(), "Workshops in Debian") || !strings.Contains(m.View(), "No workshops") {
+		t.Errorf("expected Debian's workshops, got:\n%s", m.View())
+	}
+
+	m = drive(m, keys("w")...)
+	if strings.Contains(m.View(), "Workshops in") {
+		t.Errorf("expected the workshops to be hidden, got:\n%s", m.View())
+	}
+}
@@ -0,0 +1,8 @@
+// Package version holds the wslp version string.
+package version
+
+// Version is the wslp version, recorded in backup manifests. Release builds
+// override it with:
+//
+//	go build -ldflags "-X wslp/internal/version.Version=v1.2.3"
+var Version = "dev"
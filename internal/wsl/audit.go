@@ -0,0 +1,25 @@
+package wsl
+
+import (
+	"context"
+	"log/slog"
+
+	"wslp/internal/audit"
+)
+
+// recordAudit records an action in the audit log. A log that can't be
+// written is reported, but doesn't fail the action, which has already
+// happened.
+func recordAudit(ctx context.Context, entry audit.Entry) {
+	if err := audit.Default.Record(ctx, entry); err != nil {
+		slog.Warn("Failed to record in the audit log", "action", entry.Action, "distro", entry.Distro, "error", err)
+	}
+}
+
+// errorMessage returns err's message, or "" for nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
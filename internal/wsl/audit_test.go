@@ -0,0 +1,68 @@
+package wsl
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"wslp/internal/audit"
+)
+
+// useAuditLog records to a fresh audit log for the duration of a test
+func useAuditLog(t *testing.T) *audit.Log {
+	t.Helper()
+
+	previous := audit.Default
+	audit.Default = audit.New(filepath.Join(t.TempDir(), "audit.log"))
+	t.Cleanup(func() { audit.Default = previous })
+	return audit.Default
+}
+
+func TestRecordAudit(t *testing.T) {
+	t.Run("records renames with the actor from the context", func(t *testing.T) {
+		log := useAuditLog(t)
+		renamer := &mockRenamer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		ctx := audit.WithActor(context.Background(), audit.Actor{Tool: audit.ToolAPI, Remote: "127.0.0.1:50000"})
+
+		if result := RenameDistro(ctx, renamer, "Ubuntu", "Work"); !result.Success {
+			t.Fatalf("rename failed: %s", result.Message)
+		}
+
+		entries, err := log.Read(audit.Filter{})
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("expected one entry, got %+v, %v", entries, err)
+		}
+		e := entries[0]
+		if e.Action != audit.ActionRename || e.Distro != "Ubuntu" || e.Details["newName"] != "Work" || !e.Success {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if e.Actor.Tool != audit.ToolAPI || e.Actor.Remote != "127.0.0.1:50000" {
+			t.Errorf("unexpected actor %+v", e.Actor)
+		}
+	})
+
+	t.Run("records failed actions", func(t *testing.T) {
+		log := useAuditLog(t)
+		setter := &mockDefaultSetter{registered: true, setFails: true}
+
+		if err := SetDefaultDistro(context.Background(), "Ubuntu", setter); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		entries, _ := log.Read(audit.Filter{})
+		if len(entries) != 1 || entries[0].Success || entries[0].Error != "mock set error" || entries[0].Actor.Tool != audit.ToolCLI {
+			t.Errorf("expected a failed set-default by this process, got %+v", entries)
+		}
+	})
+
+	t.Run("doesn't record refused actions", func(t *testing.T) {
+		log := useAuditLog(t)
+		renamer := &mockRenamer{isRegisteredResults: map[string]bool{}}
+
+		RenameDistro(context.Background(), renamer, "Missing", "Work")
+
+		if entries, _ := log.Read(audit.Filter{}); len(entries) != 0 {
+			t.Errorf("expected nothing to be recorded, got %+v", entries)
+		}
+	})
+}
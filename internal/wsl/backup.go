@@ -3,20 +3,34 @@ package wsl
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"filippo.io/age"
 	gowsl "github.com/ubuntu/gowsl"
 )
 
 // BackupResult contains the result of a single distro backup
 type BackupResult struct {
-	Distro   string `json:"distro"`
-	Success  bool   `json:"success"`
-	Message  string `json:"message"`
+	Distro  string `json:"distro"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	// FilePath is a URI identifying where the backup was stored, e.g.
+	// file:///C:/Users/me/WSLBackups/Ubuntu-20240301-143022.tar.gz or
+	// s3://bucket/wsl/Ubuntu-20240301-143022.tar.gz
 	FilePath string `json:"filePath"`
+	// ManifestPath is the URI of the JSON sidecar describing the archive.
+	// Empty if the manifest could not be written.
+	ManifestPath string `json:"manifestPath,omitempty"`
+	// Size is the size of the archive in bytes, once stored
+	Size int64 `json:"size,omitempty"`
+	// Duration is how long the backup took, from the start of the export
+	// until the archive was stored, in seconds
+	Duration float64 `json:"duration,omitempty"`
 }
 
 // BackupOptions contains options for backup operations
@@ -24,12 +38,27 @@ type BackupOptions struct {
 	// CustomName is an optional custom name for the backup file
 	// If empty, auto-generates name with timestamp
 	CustomName string
+	// Format is the archive format. If empty, it is taken from the
+	// extension of CustomName, or else DefaultBackupFormat is used.
+	Format BackupFormat
+	// Encrypt encrypts the archive with age, using the recipients or
+	// passphrase from the backup_encryption config
+	Encrypt bool
+	// Target is where finished backups are stored. If nil, they stay in
+	// the backup directory; otherwise the backup directory is only used
+	// for staging.
+	Target BackupTarget
+	// Progress, if set, is called with the number of bytes written while a
+	// distro is exported, about once a second and once more when the
+	// export finishes
+	Progress func(distro string, bytesWritten int64)
 }
 
 // Backuper interface for backing up distros
 type Backuper interface {
 	IsRegistered(ctx context.Context, name string) (bool, error)
-	Export(ctx context.Context, distroName, outputPath string) error
+	Export(ctx context.Context, distroName, outputPath string, format BackupFormat) error
+	Info(ctx context.Context, name string) (DistroDetailInfo, error)
 }
 
 // RealBackuper implements Backuper using wsl.exe
@@ -49,17 +78,39 @@ func (r RealBackuper) IsRegistered(ctx context.Context, name string) (bool, erro
 	return false, nil
 }
 
-// Export backs up a distro using wsl.exe --export with tar.gz format
-func (r RealBackuper) Export(ctx context.Context, distroName, outputPath string) error {
-	cmd := exec.CommandContext(ctx, "wsl.exe", "--export", distroName, outputPath, "--format", "tar.gz")
+// Export backs up a distro using wsl.exe --export. Formats wsl.exe can't
+// write itself are compressed in Go.
+func (r RealBackuper) Export(ctx context.Context, distroName, outputPath string, format BackupFormat) error {
+	if format == FormatTarZst || format == FormatTarXz {
+		return exportCompressed(ctx, distroName, outputPath, format)
+	}
+
+	args, err := exportArgs(distroName, outputPath, format)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "wsl.exe", args...)
+	cmd.WaitDelay = exportWaitDelay
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		// wsl.exe leaves whatever it wrote so far behind when it fails or
+		// is killed because ctx was cancelled
+		removePartial(outputPath)
+		if ctx.Err() != nil {
+			return fmt.Errorf("export cancelled: %w", ctx.Err())
+		}
 		return fmt.Errorf("export failed: %v (output: %s)", err, string(output))
 	}
 
 	return nil
 }
 
+// Info retrieves the distro details recorded in backup manifests
+func (r RealBackuper) Info(ctx context.Context, name string) (DistroDetailInfo, error) {
+	return GetDistroDetailInfo(ctx, name)
+}
+
 // BackupDistros backs up one or more distros
 func BackupDistros(ctx context.Context, b Backuper, distros []string, backupDir string, opts BackupOptions) []BackupResult {
 	results := make([]BackupResult, 0, len(distros))
@@ -68,6 +119,29 @@ func BackupDistros(ctx context.Context, b Backuper, distros []string, backupDir
 		return results
 	}
 
+	// Resolve keys up front so a misconfiguration fails before anything
+	// is exported
+	var recipients []age.Recipient
+	var encryption manifestEncryption
+	if opts.Encrypt {
+		var err error
+		recipients, encryption, err = backupEncryption()
+		if err != nil {
+			for _, distroName := range distros {
+				results = append(results, BackupResult{
+					Distro:  distroName,
+					Message: fmt.Sprintf("Backup failed: %v", err),
+				})
+			}
+			return results
+		}
+	}
+
+	target := opts.Target
+	if target == nil {
+		target = LocalTarget{Dir: backupDir}
+	}
+
 	for _, distroName := range distros {
 		result := BackupResult{
 			Distro:  distroName,
@@ -88,35 +162,211 @@ func BackupDistros(ctx context.Context, b Backuper, distros []string, backupDir
 		}
 
 		// Generate filename
-		var filename string
-		if opts.CustomName != "" {
-			filename = opts.CustomName
-			// Add extension if not present
-			if filepath.Ext(filename) != ".gz" && filepath.Ext(filename) != ".tar" {
-				filename += ".tar.gz"
-			}
-		} else {
-			// Auto-generate name with timestamp
-			timestamp := time.Now().Format("20060102-150405")
-			filename = fmt.Sprintf("%s-%s.tar.gz", distroName, timestamp)
+		format, filename := backupFilename(distroName, opts)
+		outputPath := filepath.Join(backupDir, filename)
+
+		// Only the export needs the distro, so hold its lock for that
+		unlock, err := lockDistros(ctx, "backup", distroName)
+		if err != nil {
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
 		}
 
-		outputPath := filepath.Join(backupDir, filename)
+		if IsDryRun(ctx) {
+			unlock()
+			if opts.Encrypt {
+				filename += encryptedSuffix
+			}
+			result.Success = true
+			result.FilePath = target.URI(filename)
+			result.Message = fmt.Sprintf("Would back up to %s (dry run)", result.FilePath)
+			results = append(results, result)
+			continue
+		}
 
 		// Perform export
-		err = b.Export(ctx, distroName, outputPath)
+		slog.Debug("Exporting distro", "distro", distroName, "path", outputPath, "format", format)
+		started := time.Now()
+		stopProgress := func() {}
+		if opts.Progress != nil {
+			stopProgress = watchFileSize(outputPath, func(n int64) {
+				opts.Progress(distroName, n)
+			})
+		}
+		err = b.Export(ctx, distroName, outputPath, format)
+		stopProgress()
+		unlock()
 		if err != nil {
+			// A failed or cancelled export can leave a partial archive
+			// of several GB behind
+			removePartial(outputPath)
 			result.Message = fmt.Sprintf("Backup failed: %v", err)
 			results = append(results, result)
 			continue
 		}
 
+		// wsl --export can fail mid-stream and still leave a partial
+		// archive behind, so check the archive before reporting success
+		stats, err := verifyArchive(outputPath)
+		if err != nil {
+			// Don't leave a broken archive where restore or retention
+			// would mistake it for a good backup
+			os.Remove(outputPath)
+			result.Message = fmt.Sprintf("Backup verification failed: %v", err)
+			results = append(results, result)
+			continue
+		}
+
+		manifest := BackupManifest{Size: stats.size, SHA256: stats.sha256}
+		if opts.Encrypt {
+			// The plaintext was verified above; the manifest records the
+			// encrypted file so verify can check it without a key
+			outputPath, stats, err = encryptArchive(outputPath, recipients)
+			if err != nil {
+				result.Message = fmt.Sprintf("Backup encryption failed: %v", err)
+				plaintext := filepath.Join(backupDir, filename)
+				if err := removeArchive(plaintext); err != nil && !os.IsNotExist(err) {
+					result.Message += fmt.Sprintf(" (the unencrypted archive is still at %s; delete it)", plaintext)
+				}
+				results = append(results, result)
+				continue
+			}
+			manifest = BackupManifest{
+				Size:       stats.size,
+				SHA256:     stats.sha256,
+				Encryption: encryption.method,
+				Recipients: encryption.recipients,
+			}
+		}
+
+		// A missing manifest doesn't invalidate the archive itself, so
+		// report it without failing the backup
+		message := "Backup completed successfully"
+		_, manifestErr := writeManifest(ctx, b, distroName, outputPath, manifest)
+		if manifestErr != nil {
+			message = fmt.Sprintf("Backup completed successfully (manifest not written: %v)", manifestErr)
+		}
+
+		// Hand the archive to its target. If that fails the staged copy is
+		// kept so the export isn't lost.
+		if err := target.Store(ctx, outputPath); err != nil {
+			result.Message = fmt.Sprintf("Failed to store backup: %v (kept at %s)", err, outputPath)
+			results = append(results, result)
+			continue
+		}
+		archiveName := filepath.Base(outputPath)
+		if manifestErr == nil {
+			if err := target.Store(ctx, ManifestPath(outputPath)); err != nil {
+				message = fmt.Sprintf("Backup completed successfully (manifest not stored: %v)", err)
+			} else {
+				result.ManifestPath = target.URI(ManifestPath(archiveName))
+			}
+		}
+
 		result.Success = true
-		result.FilePath = outputPath
-		result.Message = "Backup completed successfully"
+		result.FilePath = target.URI(archiveName)
+		result.Message = message
+		result.Size = manifest.Size
+		result.Duration = time.Since(started).Seconds()
+		slog.Debug("Backed up distro", "distro", distroName, "archive", result.FilePath, "size", result.Size, "duration", result.Duration)
 
 		results = append(results, result)
 	}
 
 	return results
 }
+
+// backupFilename returns the format and filename of a new backup of
+// distroName
+func backupFilename(distroName string, opts BackupOptions) (BackupFormat, string) {
+	format := opts.Format
+	if opts.CustomName == "" {
+		if format == "" {
+			format = DefaultBackupFormat
+		}
+		// Auto-generate name with timestamp
+		timestamp := time.Now().Format(backupTimestampFormat)
+		return format, distroName + "-" + timestamp + format.Extension()
+	}
+
+	named, hasExt := FormatFromPath(opts.CustomName)
+	if format == "" {
+		format = DefaultBackupFormat
+		if hasExt {
+			format = named
+		}
+	}
+
+	// Add extension if not present
+	filename := strings.TrimSuffix(opts.CustomName, encryptedSuffix)
+	if !hasExt || named != format {
+		filename += format.Extension()
+	}
+
+	return format, filename
+}
+
+// progressInterval is how often watchFileSize reports the size of a file
+const progressInterval = time.Second
+
+// watchFileSize reports the size of the file at path every
+// progressInterval while it is being written, until the returned function
+// is called. The final size is reported once more when it stops. Nothing
+// is reported while the file doesn't exist.
+func watchFileSize(path string, report func(int64)) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	reportSize := func() {
+		if info, err := os.Stat(path); err == nil {
+			report(info.Size())
+		}
+	}
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				reportSize()
+				return
+			case <-ticker.C:
+				reportSize()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+const (
+	// exportWaitDelay is how long a cancelled export waits for wsl.exe to
+	// exit and release its output before giving up on it
+	exportWaitDelay = 10 * time.Second
+	// removeRetryInterval and removeRetries bound how long removePartial
+	// waits for a file to be released
+	removeRetryInterval = 500 * time.Millisecond
+	removeRetries       = 10
+)
+
+// removePartial deletes a partially written file. The WSL service can keep
+// writing an export for a moment after wsl.exe is killed, and Windows
+// won't delete a file that is still open, so removing it is retried for a
+// few seconds.
+func removePartial(path string) {
+	var err error
+	for i := 0; i < removeRetries; i++ {
+		err = os.Remove(path)
+		if err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(removeRetryInterval)
+	}
+	slog.Warn("Failed to remove partial file", "path", path, "error", err)
+}
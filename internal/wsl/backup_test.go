@@ -2,7 +2,12 @@ package wsl
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +15,16 @@ type mockBackuper struct {
 	isRegisteredResults map[string]bool
 	isRegisteredErrors  map[string]error
 	exportErrors        map[string]error
+	// content, if set, is written to the output path on Export in place
+	// of a valid archive
+	content []byte
+	info    DistroDetailInfo
+	infoErr error
+	// exportedFormat records the format of the last Export call
+	exportedFormat BackupFormat
+	// partial makes a failing Export write part of an archive first, as an
+	// interrupted wsl --export does
+	partial bool
 }
 
 func (m *mockBackuper) IsRegistered(ctx context.Context, name string) (bool, error) {
@@ -19,11 +34,22 @@ func (m *mockBackuper) IsRegistered(ctx context.Context, name string) (bool, err
 	return m.isRegisteredResults[name], nil
 }
 
-func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string) error {
+func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string, format BackupFormat) error {
+	m.exportedFormat = format
 	if err, ok := m.exportErrors[distroName]; ok {
+		if m.partial {
+			os.WriteFile(outputPath, []byte("partial"), 0644)
+		}
 		return err
 	}
-	return nil
+	if m.content != nil {
+		return os.WriteFile(outputPath, m.content, 0644)
+	}
+	return os.WriteFile(outputPath, buildFormatArchive(format, testRootFS), 0644)
+}
+
+func (m *mockBackuper) Info(ctx context.Context, name string) (DistroDetailInfo, error) {
+	return m.info, m.infoErr
 }
 
 func TestBackupDistros(t *testing.T) {
@@ -36,7 +62,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 0 {
 			t.Errorf("expected 0 results for empty distros, got %d", len(results))
@@ -50,7 +76,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 1 {
 			t.Fatalf("expected 1 result, got %d", len(results))
@@ -65,6 +91,9 @@ func TestBackupDistros(t *testing.T) {
 		if !matches(results[0].FilePath, `Ubuntu-\d{8}-\d{6}\.tar\.gz`) {
 			t.Errorf("expected auto-generated filename with timestamp, got %s", results[0].FilePath)
 		}
+		if results[0].Size <= 0 || results[0].Duration <= 0 {
+			t.Errorf("expected the size and duration to be recorded, got %+v", results[0])
+		}
 	})
 
 	t.Run("backs up multiple distros", func(t *testing.T) {
@@ -78,7 +107,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:       make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu", "Debian", "Fedora"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu", "Debian", "Fedora"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 3 {
 			t.Fatalf("expected 3 results, got %d", len(results))
@@ -97,7 +126,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 1 {
 			t.Fatalf("expected 1 result, got %d", len(results))
@@ -119,7 +148,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors: make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 1 {
 			t.Fatalf("expected 1 result, got %d", len(results))
@@ -141,7 +170,7 @@ func TestBackupDistros(t *testing.T) {
 			},
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 1 {
 			t.Fatalf("expected 1 result, got %d", len(results))
@@ -161,7 +190,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{
 			CustomName: "my-backup",
 		})
 
@@ -184,7 +213,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{
 			CustomName: "my-backup.tar.gz",
 		})
 
@@ -207,7 +236,7 @@ func TestBackupDistros(t *testing.T) {
 			exportErrors:        make(map[string]error),
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, "/backup", BackupOptions{
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{
 			CustomName: "my-backup.tar.gz",
 		})
 
@@ -236,7 +265,7 @@ func TestBackupDistros(t *testing.T) {
 			},
 		}
 
-		results := BackupDistros(ctx, mock, []string{"Ubuntu", "Debian", "Fedora"}, "/backup", BackupOptions{})
+		results := BackupDistros(ctx, mock, []string{"Ubuntu", "Debian", "Fedora"}, t.TempDir(), BackupOptions{})
 
 		if len(results) != 3 {
 			t.Fatalf("expected 3 results, got %d", len(results))
@@ -253,6 +282,224 @@ func TestBackupDistros(t *testing.T) {
 	})
 }
 
+func TestBackupDistrosManifest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("writes manifest next to the archive", func(t *testing.T) {
+		dir := t.TempDir()
+		content := buildTestArchive(testRootFS)
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			content:             content,
+			info: DistroDetailInfo{
+				GUID:            "{1234}",
+				WSLVersion:      2,
+				DefaultUID:      1000,
+				Flavor:          "ubuntu",
+				EnvironmentVars: map[string]string{"LANG": "C.UTF-8"},
+			},
+		}
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, dir, BackupOptions{})
+
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("expected successful backup, got %+v", results)
+		}
+		if results[0].ManifestPath != ManifestPath(results[0].FilePath) {
+			t.Errorf("expected manifest URI %s, got %s", ManifestPath(results[0].FilePath), results[0].ManifestPath)
+		}
+
+		manifest, err := ReadManifest(resultPath(t, results[0]))
+		if err != nil {
+			t.Fatalf("failed to read manifest: %v", err)
+		}
+
+		sum := sha256.Sum256(content)
+		if manifest.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("expected checksum %x, got %s", sum, manifest.SHA256)
+		}
+		if manifest.Size != int64(len(content)) {
+			t.Errorf("expected size %d, got %d", len(content), manifest.Size)
+		}
+		if manifest.Distro != "Ubuntu" || manifest.GUID != "{1234}" || manifest.WSLVersion != 2 ||
+			manifest.DefaultUID != 1000 || manifest.Flavor != "ubuntu" {
+			t.Errorf("unexpected distro details in manifest: %+v", manifest)
+		}
+		if manifest.EnvironmentVars["LANG"] != "C.UTF-8" {
+			t.Errorf("expected environment vars in manifest, got %v", manifest.EnvironmentVars)
+		}
+		if manifest.WslpVersion == "" || manifest.Created.IsZero() {
+			t.Errorf("expected version and creation time in manifest: %+v", manifest)
+		}
+	})
+
+	t.Run("writes manifest even when distro details are unavailable", func(t *testing.T) {
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			infoErr:             errors.New("info failed"),
+		}
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
+
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("expected successful backup, got %+v", results)
+		}
+		if _, err := ReadManifest(resultPath(t, results[0])); err != nil {
+			t.Errorf("expected manifest to be written: %v", err)
+		}
+	})
+
+	t.Run("reports missing manifest without failing the backup", func(t *testing.T) {
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+		}
+		dir := t.TempDir()
+		// A directory in the manifest's place makes writing it fail
+		if err := os.Mkdir(filepath.Join(dir, "my-backup.tar.gz.json"), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, dir, BackupOptions{CustomName: "my-backup"})
+
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("expected successful backup, got %+v", results)
+		}
+		if !strings.Contains(results[0].Message, "manifest not written") {
+			t.Errorf("expected manifest warning in message, got: %s", results[0].Message)
+		}
+		if results[0].ManifestPath != "" {
+			t.Errorf("expected empty manifest path, got %s", results[0].ManifestPath)
+		}
+	})
+}
+
+func TestBackupDistrosVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fails and removes a truncated archive", func(t *testing.T) {
+		valid := buildTestArchive(testRootFS)
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			content:             valid[:len(valid)/2],
+		}
+		dir := t.TempDir()
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, dir, BackupOptions{})
+
+		if len(results) != 1 || results[0].Success {
+			t.Fatalf("expected failed backup, got %+v", results)
+		}
+		if !strings.Contains(results[0].Message, "verification failed") {
+			t.Errorf("expected verification failure message, got: %s", results[0].Message)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("expected partial archive to be removed, found %d file(s)", len(entries))
+		}
+	})
+
+	t.Run("fails when export writes nothing", func(t *testing.T) {
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			content:             []byte{},
+		}
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{})
+
+		if len(results) != 1 || results[0].Success {
+			t.Fatalf("expected failed backup, got %+v", results)
+		}
+	})
+}
+
+func TestBackupDistrosFormat(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		opts       BackupOptions
+		wantFormat BackupFormat
+		wantSuffix string
+	}{
+		"defaults to tar.gz":                   {BackupOptions{}, FormatTarGz, ".tar.gz"},
+		"uses requested format":                {BackupOptions{Format: FormatTarZst}, FormatTarZst, ".tar.zst"},
+		"writes VHDX":                          {BackupOptions{Format: FormatVHDX}, FormatVHDX, ".vhdx"},
+		"infers format from custom name":       {BackupOptions{CustomName: "snap.tar.xz"}, FormatTarXz, "snap.tar.xz"},
+		"adds extension of format to name":     {BackupOptions{CustomName: "snap", Format: FormatTar}, FormatTar, "snap.tar"},
+		"keeps custom name matching format":    {BackupOptions{CustomName: "snap.tar", Format: FormatTar}, FormatTar, "snap.tar"},
+		"appends extension on format mismatch": {BackupOptions{CustomName: "snap.tar", Format: FormatTarZst}, FormatTarZst, "snap.tar.tar.zst"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+			results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), tc.opts)
+
+			if len(results) != 1 || !results[0].Success {
+				t.Fatalf("expected successful backup, got %+v", results)
+			}
+			if mock.exportedFormat != tc.wantFormat {
+				t.Errorf("expected export as %s, got %s", tc.wantFormat, mock.exportedFormat)
+			}
+			if !strings.HasSuffix(results[0].FilePath, tc.wantSuffix) {
+				t.Errorf("expected file path ending in %s, got %s", tc.wantSuffix, results[0].FilePath)
+			}
+
+			manifest, err := ReadManifest(resultPath(t, results[0]))
+			if err != nil {
+				t.Fatalf("failed to read manifest: %v", err)
+			}
+			if manifest.Format != tc.wantFormat {
+				t.Errorf("expected manifest format %s, got %s", tc.wantFormat, manifest.Format)
+			}
+		})
+	}
+}
+
+func TestBackupDistrosCancelledExport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	backupDir := t.TempDir()
+	mock := &mockBackuper{
+		isRegisteredResults: map[string]bool{"Ubuntu": true},
+		exportErrors:        map[string]error{"Ubuntu": ctx.Err()},
+		partial:             true,
+	}
+
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, backupDir, BackupOptions{})
+
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("expected failed backup, got %+v", results)
+	}
+	if entries, _ := os.ReadDir(backupDir); len(entries) != 0 {
+		t.Errorf("expected partial archive to be removed, got %d file(s)", len(entries))
+	}
+}
+
+func TestBackupDistrosProgress(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+	var reported []int64
+	opts := BackupOptions{Progress: func(distro string, bytesWritten int64) {
+		if distro != "Ubuntu" {
+			t.Errorf("unexpected distro %s", distro)
+		}
+		reported = append(reported, bytesWritten)
+	}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), opts)
+
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected successful backup, got %+v", results)
+	}
+	info, err := os.Stat(resultPath(t, results[0]))
+	if err != nil {
+		t.Fatalf("failed to stat archive: %v", err)
+	}
+	if len(reported) == 0 || reported[len(reported)-1] != info.Size() {
+		t.Errorf("expected final size %d to be reported, got %v", info.Size(), reported)
+	}
+}
+
 // matches checks if s matches a simple pattern (for test assertions)
 func matches(s, pattern string) bool {
 	switch {
@@ -0,0 +1,466 @@
+package wsl
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotInBackup is returned when a path does not exist in a backup archive
+var ErrNotInBackup = errors.New("path not found in backup")
+
+// BackupEntry describes a file, directory or link inside a backup archive
+type BackupEntry struct {
+	// Path is the absolute path of the entry in the distro, e.g. /etc/hosts
+	Path string `json:"path"`
+	// Type is one of file, dir, symlink, hardlink or other
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	ModTime    time.Time `json:"modTime"`
+	LinkTarget string    `json:"linkTarget,omitempty"`
+}
+
+// ExtractResult contains the result of extracting files from a backup
+type ExtractResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Files       int    `json:"files"`
+	Bytes       int64  `json:"bytes"`
+	// Skipped lists entries that could not be recreated, e.g. symlinks
+	// when the user lacks the privilege to create them
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// archiveReader streams the entries of a backup archive
+type archiveReader struct {
+	*tar.Reader
+	file       *os.File
+	decompress io.Closer
+}
+
+// openArchive opens a backup archive for streaming. The caller must Close
+// it.
+func openArchive(archivePath string) (*archiveReader, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decompress(archivePath, bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &archiveReader{Reader: tar.NewReader(r), file: f, decompress: r}, nil
+}
+
+// Close releases the archive
+func (a *archiveReader) Close() error {
+	a.decompress.Close()
+	return a.file.Close()
+}
+
+// ListBackupFiles lists the contents of dir inside a backup archive. If
+// dir names a file, only that file is returned. With recursive, everything
+// below dir is listed rather than just its direct children. The archive is
+// streamed, so listing is fast and uses little memory even for large
+// backups.
+func ListBackupFiles(archivePath, dir string, recursive bool) ([]BackupEntry, error) {
+	dir = cleanArchivePath(dir)
+
+	ar, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	children := map[string]BackupEntry{}
+	found := dir == "/"
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := cleanArchivePath(hdr.Name)
+		if name == dir {
+			found = true
+			if hdr.Typeflag != tar.TypeDir {
+				return []BackupEntry{newBackupEntry(name, hdr)}, nil
+			}
+			continue
+		}
+
+		rel, ok := relArchivePath(dir, name)
+		if !ok {
+			continue
+		}
+		found = true
+
+		if recursive {
+			children[name] = newBackupEntry(name, hdr)
+			continue
+		}
+
+		// Archives don't always contain entries for every directory, so
+		// directories are inferred from the paths below them
+		if first, _, nested := strings.Cut(rel, "/"); nested {
+			child := path.Join(dir, first)
+			if _, ok := children[child]; !ok {
+				children[child] = BackupEntry{Path: child, Type: "dir", Mode: (os.ModeDir | 0755).String()}
+			}
+			continue
+		}
+		children[name] = newBackupEntry(name, hdr)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNotInBackup, dir)
+	}
+
+	entries := make([]BackupEntry, 0, len(children))
+	for _, e := range children {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// OpenBackupFile opens a regular file inside a backup archive for reading.
+// The content is streamed from the archive; the caller must Close the
+// returned reader.
+func OpenBackupFile(archivePath, filePath string) (io.ReadCloser, BackupEntry, error) {
+	filePath = cleanArchivePath(filePath)
+
+	ar, err := openArchive(archivePath)
+	if err != nil {
+		return nil, BackupEntry{}, err
+	}
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ar.Close()
+			return nil, BackupEntry{}, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if cleanArchivePath(hdr.Name) != filePath {
+			continue
+		}
+		entry := newBackupEntry(filePath, hdr)
+		if hdr.Typeflag != tar.TypeReg {
+			ar.Close()
+			return nil, entry, fmt.Errorf("%s is not a regular file (%s)", filePath, entry.Type)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{ar, ar}, entry, nil
+	}
+
+	ar.Close()
+	return nil, BackupEntry{}, fmt.Errorf("%w: %s", ErrNotInBackup, filePath)
+}
+
+// ExtractBackupFiles copies src, a file or directory inside a backup
+// archive, to dest on the local filesystem. If dest is an existing
+// directory, src is extracted into it under its own name. Directories are
+// extracted recursively. Existing files are overwritten.
+func ExtractBackupFiles(archivePath, src, dest string) (ExtractResult, error) {
+	src = cleanArchivePath(src)
+	result := ExtractResult{Source: src}
+
+	if info, err := os.Stat(dest); err == nil && info.IsDir() && src != "/" {
+		dest = filepath.Join(dest, path.Base(src))
+	}
+	result.Destination = dest
+
+	ar, err := openArchive(archivePath)
+	if err != nil {
+		return result, err
+	}
+	defer ar.Close()
+
+	// Hard links are stored as references to an earlier entry; remember
+	// where each extracted file went so links to it can be copied
+	extracted := map[string]string{}
+	// Links whose target lies outside src, resolved in a second pass
+	pending := map[string][]string{}
+	found := false
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := cleanArchivePath(hdr.Name)
+		rel, ok := relArchivePath(src, name)
+		if name == src {
+			rel, ok = "", true
+		}
+		if !ok {
+			continue
+		}
+		found = true
+
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if err := checkNoSymlinks(dest, target); err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s (%v)", name, err))
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return result, err
+			}
+
+		case tar.TypeReg:
+			n, err := writeFile(target, ar, hdr)
+			if err != nil {
+				return result, err
+			}
+			extracted[name] = target
+			result.Files++
+			result.Bytes += n
+
+		case tar.TypeLink:
+			linkName := cleanArchivePath(hdr.Linkname)
+			if from, ok := extracted[linkName]; ok {
+				n, err := copyLocalFile(from, target)
+				if err != nil {
+					return result, err
+				}
+				result.Files++
+				result.Bytes += n
+			} else {
+				pending[linkName] = append(pending[linkName], target)
+			}
+
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return result, err
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s -> %s (%v)", name, hdr.Linkname, err))
+			}
+
+		default:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s (unsupported type)", name))
+		}
+	}
+
+	if !found {
+		return result, fmt.Errorf("%w: %s", ErrNotInBackup, src)
+	}
+
+	if len(pending) > 0 {
+		if err := extractLinkTargets(archivePath, dest, pending, &result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// extractLinkTargets makes a second pass over an archive to extract the
+// content of hard link targets that lie outside the extracted tree
+func extractLinkTargets(archivePath, dest string, pending map[string][]string, result *ExtractResult) error {
+	ar, err := openArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+
+	for len(pending) > 0 {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := cleanArchivePath(hdr.Name)
+		targets, ok := pending[name]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		delete(pending, name)
+
+		// Symlinks extracted in the first pass may now lie on the way
+		var safe []string
+		for _, target := range targets {
+			if err := checkNoSymlinks(dest, target); err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s (%v)", target, err))
+				continue
+			}
+			safe = append(safe, target)
+		}
+		if len(safe) == 0 {
+			continue
+		}
+
+		n, err := writeFile(safe[0], ar, hdr)
+		if err != nil {
+			return err
+		}
+		result.Files++
+		result.Bytes += n
+		for _, target := range safe[1:] {
+			n, err := copyLocalFile(safe[0], target)
+			if err != nil {
+				return err
+			}
+			result.Files++
+			result.Bytes += n
+		}
+	}
+
+	for name := range pending {
+		result.Skipped = append(result.Skipped, fmt.Sprintf("%s (hard link target not found)", name))
+	}
+
+	return nil
+}
+
+// checkNoSymlinks fails if a directory between dest and target is a
+// symlink. An archive can hold a symlink to / followed by files below it,
+// which would otherwise be written outside dest.
+func checkNoSymlinks(dest, target string) error {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == "." {
+		return err
+	}
+
+	dir := dest
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through symlink %s", dir)
+		}
+	}
+	return nil
+}
+
+// writeFile writes the content of a tar entry to target, preserving its
+// permissions and modification time. A symlink at target is replaced
+// rather than followed.
+func writeFile(target string, r io.Reader, hdr *tar.Header) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return 0, err
+		}
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm()|0200)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to write %s: %w", target, err)
+	}
+
+	os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	return n, nil
+}
+
+// copyLocalFile copies an already extracted file to target
+func copyLocalFile(from, target string) (int64, error) {
+	src, err := os.Open(from)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	hdr := &tar.Header{Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()}
+	return writeFile(target, src, hdr)
+}
+
+// newBackupEntry describes a tar entry found at name
+func newBackupEntry(name string, hdr *tar.Header) BackupEntry {
+	entry := BackupEntry{
+		Path:       name,
+		Size:       hdr.Size,
+		Mode:       hdr.FileInfo().Mode().String(),
+		ModTime:    hdr.ModTime,
+		LinkTarget: hdr.Linkname,
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		entry.Type = "file"
+	case tar.TypeDir:
+		entry.Type = "dir"
+	case tar.TypeSymlink:
+		entry.Type = "symlink"
+	case tar.TypeLink:
+		entry.Type = "hardlink"
+		entry.LinkTarget = cleanArchivePath(hdr.Linkname)
+	default:
+		entry.Type = "other"
+	}
+
+	return entry
+}
+
+// cleanArchivePath normalizes a path inside an archive to an absolute
+// slash-separated path. wsl --export writes names relative to the root,
+// with or without a leading "./", and users may type Windows separators.
+func cleanArchivePath(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+}
+
+// relArchivePath returns name relative to dir if name lies below dir
+func relArchivePath(dir, name string) (string, bool) {
+	if dir == "/" {
+		return strings.TrimPrefix(name, "/"), name != "/"
+	}
+	return strings.CutPrefix(name, dir+"/")
+}
@@ -0,0 +1,259 @@
+package wsl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeBrowseArchive writes a tar.gz archive laid out like wsl --export
+// output to dir and returns its path. Directory entries are deliberately
+// missing for /home/user so they have to be inferred.
+func writeBrowseArchive(t *testing.T, dir string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	headers := []struct {
+		hdr     tar.Header
+		content string
+	}{
+		{tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{tar.Header{Name: "./etc/", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{tar.Header{Name: "./etc/os-release", Typeflag: tar.TypeReg, Mode: 0644}, "NAME=\"Ubuntu\"\n"},
+		{tar.Header{Name: "./etc/hostname", Typeflag: tar.TypeReg, Mode: 0644}, "ubuntu\n"},
+		{tar.Header{Name: "./home/user/.bashrc", Typeflag: tar.TypeReg, Mode: 0644}, "alias ll='ls -l'\n"},
+		{tar.Header{Name: "./home/user/.config/app.conf", Typeflag: tar.TypeReg, Mode: 0600}, "key=value\n"},
+		{tar.Header{Name: "./home/user/hosts", Typeflag: tar.TypeLink, Linkname: "./etc/hostname"}, ""},
+		{tar.Header{Name: "./etc/localtime", Typeflag: tar.TypeSymlink, Linkname: "/usr/share/zoneinfo/UTC"}, ""},
+	}
+	for _, h := range headers {
+		h.hdr.Size = int64(len(h.content))
+		if err := tw.WriteHeader(&h.hdr); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		tw.Write([]byte(h.content))
+	}
+	tw.Close()
+	gz.Close()
+
+	return writeTestArchive(t, dir, "Ubuntu-20240301-143022.tar.gz", buf.Bytes())
+}
+
+// entryPaths returns the paths of entries
+func entryPaths(entries []BackupEntry) []string {
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.Path
+	}
+	return paths
+}
+
+func TestListBackupFiles(t *testing.T) {
+	archive := writeBrowseArchive(t, t.TempDir())
+
+	t.Run("lists the root directory", func(t *testing.T) {
+		entries, err := ListBackupFiles(archive, "/", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := entryPaths(entries)
+		if len(got) != 2 || got[0] != "/etc" || got[1] != "/home" {
+			t.Errorf("expected [/etc /home], got %v", got)
+		}
+		if entries[1].Type != "dir" {
+			t.Errorf("expected inferred /home to be a dir, got %q", entries[1].Type)
+		}
+	})
+
+	t.Run("lists a directory without an entry of its own", func(t *testing.T) {
+		entries, err := ListBackupFiles(archive, "home/user", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := entryPaths(entries)
+		if len(got) != 3 || got[0] != "/home/user/.bashrc" || got[1] != "/home/user/.config" || got[2] != "/home/user/hosts" {
+			t.Errorf("unexpected entries %v", got)
+		}
+		if entries[2].Type != "hardlink" || entries[2].LinkTarget != "/etc/hostname" {
+			t.Errorf("expected hard link to /etc/hostname, got %+v", entries[2])
+		}
+	})
+
+	t.Run("lists recursively", func(t *testing.T) {
+		entries, err := ListBackupFiles(archive, "/home", true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(entries) != 3 {
+			t.Errorf("expected 3 entries, got %v", entryPaths(entries))
+		}
+	})
+
+	t.Run("describes a single file", func(t *testing.T) {
+		entries, err := ListBackupFiles(archive, "/etc/hostname", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(entries) != 1 || entries[0].Type != "file" || entries[0].Size != int64(len("ubuntu\n")) {
+			t.Errorf("unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("returns ErrNotInBackup for missing paths", func(t *testing.T) {
+		if _, err := ListBackupFiles(archive, "/nope", false); !errors.Is(err, ErrNotInBackup) {
+			t.Errorf("expected ErrNotInBackup, got %v", err)
+		}
+	})
+}
+
+func TestOpenBackupFile(t *testing.T) {
+	archive := writeBrowseArchive(t, t.TempDir())
+
+	t.Run("streams file content", func(t *testing.T) {
+		r, entry, err := OpenBackupFile(archive, "/home/user/.bashrc")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(data) != "alias ll='ls -l'\n" || entry.Size != int64(len(data)) {
+			t.Errorf("unexpected content %q (entry %+v)", data, entry)
+		}
+	})
+
+	t.Run("refuses directories", func(t *testing.T) {
+		if _, _, err := OpenBackupFile(archive, "/etc"); err == nil {
+			t.Error("expected error for directory")
+		}
+	})
+
+	t.Run("returns ErrNotInBackup for missing files", func(t *testing.T) {
+		if _, _, err := OpenBackupFile(archive, "/etc/missing"); !errors.Is(err, ErrNotInBackup) {
+			t.Errorf("expected ErrNotInBackup, got %v", err)
+		}
+	})
+}
+
+func TestExtractBackupFiles(t *testing.T) {
+	archive := writeBrowseArchive(t, t.TempDir())
+
+	t.Run("extracts a file into an existing directory", func(t *testing.T) {
+		dest := t.TempDir()
+
+		result, err := ExtractBackupFiles(archive, "/home/user/.bashrc", dest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, ".bashrc"))
+		if err != nil || string(data) != "alias ll='ls -l'\n" {
+			t.Errorf("expected extracted .bashrc, got %q (%v)", data, err)
+		}
+		if result.Files != 1 {
+			t.Errorf("expected 1 file, got %d", result.Files)
+		}
+	})
+
+	t.Run("extracts a file to a new path", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "restored.conf")
+
+		if _, err := ExtractBackupFiles(archive, "/home/user/.config/app.conf", dest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if data, err := os.ReadFile(dest); err != nil || string(data) != "key=value\n" {
+			t.Errorf("expected extracted file, got %q (%v)", data, err)
+		}
+	})
+
+	t.Run("extracts a directory including hard links outside it", func(t *testing.T) {
+		dest := t.TempDir()
+
+		result, err := ExtractBackupFiles(archive, "/home/user", dest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for name, want := range map[string]string{
+			".bashrc":          "alias ll='ls -l'\n",
+			".config/app.conf": "key=value\n",
+			"hosts":            "ubuntu\n",
+		} {
+			data, err := os.ReadFile(filepath.Join(dest, "user", filepath.FromSlash(name)))
+			if err != nil || string(data) != want {
+				t.Errorf("%s: expected %q, got %q (%v)", name, want, data, err)
+			}
+		}
+		if result.Files != 3 {
+			t.Errorf("expected 3 files, got %d", result.Files)
+		}
+	})
+
+	t.Run("doesn't write through symlinks in the archive", func(t *testing.T) {
+		outside := t.TempDir()
+		victim := filepath.Join(outside, "victim")
+		if err := os.WriteFile(victim, []byte("safe\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		headers := []struct {
+			hdr     tar.Header
+			content string
+		}{
+			{tar.Header{Name: "./escape", Typeflag: tar.TypeSymlink, Linkname: outside}, ""},
+			{tar.Header{Name: "./escape/evil", Typeflag: tar.TypeReg, Mode: 0644}, "owned\n"},
+			{tar.Header{Name: "./escape/sub/evil", Typeflag: tar.TypeReg, Mode: 0644}, "owned\n"},
+			{tar.Header{Name: "./victim", Typeflag: tar.TypeSymlink, Linkname: victim}, ""},
+			{tar.Header{Name: "./victim", Typeflag: tar.TypeReg, Mode: 0644}, "owned\n"},
+		}
+		for _, h := range headers {
+			h.hdr.Size = int64(len(h.content))
+			tw.WriteHeader(&h.hdr)
+			tw.Write([]byte(h.content))
+		}
+		tw.Close()
+		gz.Close()
+		hostile := writeTestArchive(t, t.TempDir(), "Evil-20240301-143022.tar.gz", buf.Bytes())
+
+		result, err := ExtractBackupFiles(hostile, "/", t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, name := range []string{"evil", filepath.Join("sub", "evil")} {
+			if _, err := os.Stat(filepath.Join(outside, name)); !os.IsNotExist(err) {
+				t.Errorf("expected nothing written to %s outside dest, got %v", name, err)
+			}
+		}
+		if data, _ := os.ReadFile(victim); string(data) != "safe\n" {
+			t.Errorf("expected the file outside dest to be untouched, got %q", data)
+		}
+		if len(result.Skipped) < 2 {
+			t.Errorf("expected the entries below the symlink to be skipped, got %v", result.Skipped)
+		}
+	})
+
+	t.Run("returns ErrNotInBackup for missing paths", func(t *testing.T) {
+		if _, err := ExtractBackupFiles(archive, "/nope", t.TempDir()); !errors.Is(err, ErrNotInBackup) {
+			t.Errorf("expected ErrNotInBackup, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package wsl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"wslp/internal/version"
+)
+
+// backupTimestampFormat is the layout used in auto-generated backup
+// filenames, e.g. Ubuntu-20240301-143022.tar.gz
+const backupTimestampFormat = "20060102-150405"
+
+// backupNamePattern matches auto-generated backup filenames. Distro names
+// may themselves contain dashes (e.g. "Ubuntu-24.04"), so the timestamp is
+// anchored to the end of the name rather than split on the first dash.
+var backupNamePattern = regexp.MustCompile(`^(.+)-(\d{8}-\d{6})\.(tar\.gz|tar\.zst|tar\.xz|tar|vhdx)(\.age)?$`)
+
+// manifestSuffix is appended to an archive's filename to form the path of
+// its manifest sidecar, e.g. Ubuntu-20240301-143022.tar.gz.json
+const manifestSuffix = ".json"
+
+// BackupManifest describes what a backup archive contains. It is written
+// as a JSON sidecar next to each archive by BackupDistros.
+type BackupManifest struct {
+	Distro          string            `json:"distro"`
+	GUID            string            `json:"guid"`
+	WSLVersion      int               `json:"wslVersion"`
+	DefaultUID      uint32            `json:"defaultUid"`
+	Flavor          string            `json:"flavor"`
+	EnvironmentVars map[string]string `json:"environmentVars"`
+	Archive         string            `json:"archive"`
+	Format          BackupFormat      `json:"format"`
+	Size            int64             `json:"size"`
+	SHA256          string            `json:"sha256"`
+	// Encryption is the method an encrypted archive was encrypted with,
+	// either age-x25519 or age-scrypt. Empty for unencrypted archives.
+	Encryption string `json:"encryption,omitempty"`
+	// Recipients are the public keys an age-x25519 archive was encrypted
+	// to
+	Recipients  []string  `json:"recipients,omitempty"`
+	WslpVersion string    `json:"wslpVersion"`
+	Created     time.Time `json:"created"`
+}
+
+// BackupInfo describes a backup archive found in the backup directory
+type BackupInfo struct {
+	// ID identifies the backup within its directory. It is the archive's
+	// filename.
+	ID string `json:"id"`
+	// Distro is the name of the backed up distro, taken from the manifest
+	// or parsed from the filename. Empty for custom-named archives that
+	// have no manifest.
+	Distro    string    `json:"distro"`
+	Timestamp time.Time `json:"timestamp"`
+	FilePath  string    `json:"filePath"`
+	Size      int64     `json:"size"`
+	// Manifest is nil for archives without a readable manifest sidecar,
+	// e.g. those created before manifests were introduced.
+	Manifest *BackupManifest `json:"manifest,omitempty"`
+}
+
+// ManifestPath returns the path of the manifest sidecar for an archive
+func ManifestPath(archivePath string) string {
+	return archivePath + manifestSuffix
+}
+
+// ReadManifest reads the manifest sidecar of an archive
+func ReadManifest(archivePath string) (BackupManifest, error) {
+	var manifest BackupManifest
+
+	data, err := os.ReadFile(ManifestPath(archivePath))
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// WriteManifest builds the manifest for a freshly exported archive of distro
+// and writes it next to the archive. Distro details are best-effort: if
+// they cannot be read the manifest still records the archive's size and
+// checksum.
+func WriteManifest(ctx context.Context, b Backuper, distro, archivePath string) (BackupManifest, error) {
+	size, sum, err := checksumFile(archivePath)
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("failed to checksum archive: %w", err)
+	}
+
+	return writeManifest(ctx, b, distro, archivePath, BackupManifest{Size: size, SHA256: sum})
+}
+
+// writeManifest completes and writes a manifest whose size, checksum and
+// encryption details are already known
+func writeManifest(ctx context.Context, b Backuper, distro, archivePath string, manifest BackupManifest) (BackupManifest, error) {
+	manifest.Distro = distro
+	manifest.Archive = filepath.Base(archivePath)
+	manifest.WslpVersion = version.Version
+	manifest.Created = time.Now()
+	manifest.Format, _ = FormatFromPath(archivePath)
+
+	if info, err := b.Info(ctx, distro); err == nil {
+		manifest.GUID = info.GUID
+		manifest.WSLVersion = info.WSLVersion
+		manifest.DefaultUID = info.DefaultUID
+		manifest.Flavor = info.Flavor
+		manifest.EnvironmentVars = info.EnvironmentVars
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := os.WriteFile(ManifestPath(archivePath), data, 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// checksumFile returns the size and hex-encoded SHA-256 of a file
+func checksumFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ListBackups returns the backup archives in backupDir, newest first.
+// Distro and timestamp come from each archive's manifest sidecar when
+// present, or else are parsed from auto-generated filenames; custom-named
+// archives without a manifest are included with an empty Distro and their
+// modification time as Timestamp. A missing backup directory is treated as
+// containing no backups.
+func ListBackups(backupDir string) ([]BackupInfo, error) {
+	backups := []BackupInfo{}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backups, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupArchive(entry.Name()) {
+			continue
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		backup := BackupInfo{
+			ID:        entry.Name(),
+			FilePath:  filepath.Join(backupDir, entry.Name()),
+			Size:      fileInfo.Size(),
+			Timestamp: fileInfo.ModTime(),
+		}
+
+		distro, timestamp, parsed := parseBackupName(entry.Name())
+		if parsed {
+			backup.Distro = distro
+			backup.Timestamp = timestamp
+		}
+
+		if manifest, err := ReadManifest(backup.FilePath); err == nil {
+			backup.Manifest = &manifest
+			backup.Distro = manifest.Distro
+			// Keep the filename timestamp when there is one so backups
+			// can still be selected by it
+			if !parsed && !manifest.Created.IsZero() {
+				backup.Timestamp = manifest.Created.Local()
+			}
+		}
+
+		backups = append(backups, backup)
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+
+	return backups, nil
+}
+
+// FindBackup selects the backup of distro taken at timestamp (in the
+// YYYYMMDD-HHMMSS format used in backup filenames). An empty timestamp or
+// "latest" selects the most recent backup of distro.
+func FindBackup(backups []BackupInfo, distro, timestamp string) (BackupInfo, error) {
+	latest := timestamp == "" || strings.EqualFold(timestamp, "latest")
+
+	var found *BackupInfo
+	for i := range backups {
+		b := backups[i]
+		// NOTE: Case-insensitive comparison, matching how WSL itself
+		// treats distro names.
+		if !strings.EqualFold(b.Distro, distro) {
+			continue
+		}
+		if latest {
+			if found == nil || b.Timestamp.After(found.Timestamp) {
+				found = &backups[i]
+			}
+			continue
+		}
+		if b.Timestamp.Format(backupTimestampFormat) == timestamp {
+			found = &backups[i]
+			break
+		}
+	}
+
+	if found == nil {
+		if latest {
+			return BackupInfo{}, fmt.Errorf("no backups found for %s", distro)
+		}
+		return BackupInfo{}, fmt.Errorf("no backup of %s found with timestamp %s", distro, timestamp)
+	}
+
+	return *found, nil
+}
+
+// parseBackupName extracts the distro name and timestamp from an
+// auto-generated backup filename
+func parseBackupName(name string) (string, time.Time, bool) {
+	m := backupNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+
+	timestamp, err := time.ParseInLocation(backupTimestampFormat, m[2], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return m[1], timestamp, true
+}
+
+// isBackupArchive reports whether name has an extension produced by
+// BackupDistros
+func isBackupArchive(name string) bool {
+	_, ok := FormatFromPath(name)
+	return ok
+}
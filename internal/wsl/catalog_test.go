@@ -0,0 +1,163 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBackupFiles creates empty files with the given names in dir
+func writeBackupFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestListBackups(t *testing.T) {
+	t.Run("returns empty slice for missing directory", func(t *testing.T) {
+		backups, err := ListBackups(filepath.Join(t.TempDir(), "missing"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(backups) != 0 {
+			t.Errorf("expected 0 backups, got %d", len(backups))
+		}
+	})
+
+	t.Run("parses distro and timestamp from generated names", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "Ubuntu-24.04-20240301-143022.tar.gz")
+
+		backups, err := ListBackups(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(backups) != 1 {
+			t.Fatalf("expected 1 backup, got %d", len(backups))
+		}
+		if backups[0].Distro != "Ubuntu-24.04" {
+			t.Errorf("expected distro Ubuntu-24.04, got %q", backups[0].Distro)
+		}
+		want := time.Date(2024, 3, 1, 14, 30, 22, 0, time.Local)
+		if !backups[0].Timestamp.Equal(want) {
+			t.Errorf("expected timestamp %v, got %v", want, backups[0].Timestamp)
+		}
+		if backups[0].Size != int64(len("backup")) {
+			t.Errorf("expected size %d, got %d", len("backup"), backups[0].Size)
+		}
+	})
+
+	t.Run("includes custom-named archives and skips other files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "my-backup.tar", "notes.txt", "Debian-20240301-143022.tar.gz")
+
+		backups, err := ListBackups(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(backups) != 2 {
+			t.Fatalf("expected 2 backups, got %d", len(backups))
+		}
+		for _, b := range backups {
+			if filepath.Base(b.FilePath) == "my-backup.tar" && b.Distro != "" {
+				t.Errorf("expected empty distro for custom-named archive, got %q", b.Distro)
+			}
+		}
+	})
+
+	t.Run("reads distro from manifest of custom-named archives", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "before-upgrade.tar.gz")
+		manifest := `{"distro":"Ubuntu","created":"2024-03-01T14:30:22Z","flavor":"ubuntu"}`
+		if err := os.WriteFile(filepath.Join(dir, "before-upgrade.tar.gz.json"), []byte(manifest), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+
+		backups, err := ListBackups(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(backups) != 1 {
+			t.Fatalf("expected 1 backup, got %d", len(backups))
+		}
+		if backups[0].Distro != "Ubuntu" {
+			t.Errorf("expected distro from manifest, got %q", backups[0].Distro)
+		}
+		if backups[0].Manifest == nil || backups[0].Manifest.Flavor != "ubuntu" {
+			t.Errorf("expected manifest to be attached, got %+v", backups[0].Manifest)
+		}
+		if !backups[0].Timestamp.Equal(time.Date(2024, 3, 1, 14, 30, 22, 0, time.UTC)) {
+			t.Errorf("expected timestamp from manifest, got %v", backups[0].Timestamp)
+		}
+	})
+
+	t.Run("sorts newest first", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz", "Ubuntu-20240201-000000.tar.gz")
+
+		backups, err := ListBackups(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(backups) != 3 {
+			t.Fatalf("expected 3 backups, got %d", len(backups))
+		}
+		if filepath.Base(backups[0].FilePath) != "Ubuntu-20240301-000000.tar.gz" {
+			t.Errorf("expected newest backup first, got %s", backups[0].FilePath)
+		}
+	})
+}
+
+func TestFindBackup(t *testing.T) {
+	backups := []BackupInfo{
+		{Distro: "Ubuntu", Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), FilePath: "old"},
+		{Distro: "Ubuntu", Timestamp: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), FilePath: "new"},
+		{Distro: "Debian", Timestamp: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), FilePath: "debian"},
+	}
+
+	t.Run("selects latest backup by default", func(t *testing.T) {
+		b, err := FindBackup(backups, "Ubuntu", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.FilePath != "new" {
+			t.Errorf("expected latest backup, got %s", b.FilePath)
+		}
+	})
+
+	t.Run("accepts latest keyword case-insensitively", func(t *testing.T) {
+		b, err := FindBackup(backups, "ubuntu", "LATEST")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.FilePath != "new" {
+			t.Errorf("expected latest backup, got %s", b.FilePath)
+		}
+	})
+
+	t.Run("selects backup by timestamp", func(t *testing.T) {
+		b, err := FindBackup(backups, "Ubuntu", "20240101-000000")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.FilePath != "old" {
+			t.Errorf("expected old backup, got %s", b.FilePath)
+		}
+	})
+
+	t.Run("returns error for unknown timestamp", func(t *testing.T) {
+		if _, err := FindBackup(backups, "Ubuntu", "20990101-000000"); err == nil {
+			t.Error("expected error for unknown timestamp")
+		}
+	})
+
+	t.Run("returns error for distro without backups", func(t *testing.T) {
+		if _, err := FindBackup(backups, "Fedora", ""); err == nil {
+			t.Error("expected error for distro without backups")
+		}
+	})
+}
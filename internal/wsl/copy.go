@@ -3,11 +3,14 @@ package wsl
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
 	gowsl "github.com/ubuntu/gowsl"
+
+	"wslp/internal/audit"
 )
 
 // CopyResult contains the result of copying a distro
@@ -35,7 +38,7 @@ func (r RealCopier) IsRegistered(ctx context.Context, name string) (bool, error)
 
 // Export exports a distro to a tar.gz file
 func (r RealCopier) Export(ctx context.Context, distroName, outputPath string) error {
-	return RealBackuper{}.Export(ctx, distroName, outputPath)
+	return RealBackuper{}.Export(ctx, distroName, outputPath, FormatTarGz)
 }
 
 // Import imports a distro from a tar file using gowsl
@@ -59,6 +62,13 @@ func CopyDistro(ctx context.Context, c Copier, source, newName, installDir strin
 		return result
 	}
 
+	unlock, err := lockDistros(ctx, "copy", source, newName)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer unlock()
+
 	// Check source exists
 	registered, err := c.IsRegistered(ctx, source)
 	if err != nil {
@@ -81,6 +91,25 @@ func CopyDistro(ctx context.Context, c Copier, source, newName, installDir strin
 		return result
 	}
 
+	if IsDryRun(ctx) {
+		result.Success = true
+		result.Message = fmt.Sprintf("Would copy %s to %s (dry run)", source, newName)
+		return result
+	}
+
+	defer func() {
+		entry := audit.Entry{
+			Action:  audit.ActionCopy,
+			Distro:  source,
+			Details: map[string]string{"newName": newName, "installDir": installDir},
+			Success: result.Success,
+		}
+		if !result.Success {
+			entry.Error = result.Message
+		}
+		recordAudit(ctx, entry)
+	}()
+
 	// Resolve install dir
 	if installDir == "" {
 		home, err := os.UserHomeDir()
@@ -92,22 +121,38 @@ func CopyDistro(ctx context.Context, c Copier, source, newName, installDir strin
 	}
 
 	// Create install dir
+	_, statErr := os.Stat(installDir)
+	createdDir := os.IsNotExist(statErr)
 	if err := os.MkdirAll(installDir, 0755); err != nil {
 		result.Message = fmt.Sprintf("Failed to create install directory: %v", err)
 		return result
 	}
 
+	// If the copy fails or is cancelled, remove the install directory
+	// created for it along with any partial disk, unless the distro ended
+	// up registered anyway
+	defer func() {
+		if result.Success || !createdDir {
+			return
+		}
+		if registered, err := c.IsRegistered(context.WithoutCancel(ctx), newName); err == nil && !registered {
+			os.RemoveAll(installDir)
+		}
+	}()
+
 	// Export to a temp file
 	timestamp := time.Now().Format("20060102-150405")
 	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("wslp-copy-%s-%s.tar.gz", source, timestamp))
-	defer os.Remove(tmpFile)
+	defer removePartial(tmpFile)
 
+	slog.Debug("Exporting distro to copy", "distro", source, "path", tmpFile)
 	if err := c.Export(ctx, source, tmpFile); err != nil {
 		result.Message = fmt.Sprintf("Export failed: %v", err)
 		return result
 	}
 
 	// Import under the new name
+	slog.Debug("Importing copy", "distro", newName, "installDir", installDir)
 	if err := c.Import(ctx, newName, tmpFile, installDir); err != nil {
 		result.Message = fmt.Sprintf("Import failed: %v", err)
 		return result
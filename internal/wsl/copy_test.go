@@ -3,6 +3,8 @@ package wsl
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -180,6 +182,37 @@ func TestCopyDistro(t *testing.T) {
 		}
 	})
 
+	t.Run("removes install directory it created when Import fails", func(t *testing.T) {
+		mock := &mockCopier{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			importErrors:        map[string]error{"Ubuntu-Copy": context.Canceled},
+		}
+		installDir := filepath.Join(t.TempDir(), "Ubuntu-Copy")
+
+		result := CopyDistro(ctx, mock, "Ubuntu", "Ubuntu-Copy", installDir)
+
+		if result.Success {
+			t.Fatal("expected failure when Import errors")
+		}
+		if _, err := os.Stat(installDir); !os.IsNotExist(err) {
+			t.Error("expected install directory to be removed")
+		}
+	})
+
+	t.Run("keeps existing install directory when Import fails", func(t *testing.T) {
+		mock := &mockCopier{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			importErrors:        map[string]error{"Ubuntu-Copy": errors.New("import failed")},
+		}
+		installDir := t.TempDir()
+
+		CopyDistro(ctx, mock, "Ubuntu", "Ubuntu-Copy", installDir)
+
+		if _, err := os.Stat(installDir); err != nil {
+			t.Error("expected existing install directory to be kept")
+		}
+	})
+
 	t.Run("successfully copies a distro with custom install directory", func(t *testing.T) {
 		mock := &mockCopier{
 			isRegisteredResults: map[string]bool{
@@ -2,9 +2,16 @@ package wsl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 
 	gowsl "github.com/ubuntu/gowsl"
+
+	"wslp/internal/audit"
 )
 
 // DefaultGetter retrieves the name of the default WSL distribution
@@ -62,9 +69,59 @@ func SetDefaultDistro(ctx context.Context, name string, s DefaultSetter) error {
 		return fmt.Errorf("distro %s is not registered", name)
 	}
 
-	if err := s.SetAsDefault(ctx, name); err != nil {
+	err = s.SetAsDefault(ctx, name)
+	recordAudit(ctx, audit.Entry{Action: audit.ActionSetDefault, Distro: name, Success: err == nil, Error: errorMessage(err)})
+	if err != nil {
 		return fmt.Errorf("failed to set default distro: %w", err)
 	}
 
 	return nil
 }
+
+// previousDefaultFile is the file in the state directory remembering the
+// default distro before the last change
+const previousDefaultFile = "previous-default"
+
+// ChangeDefaultDistro sets a WSL distribution as the default, like
+// SetDefaultDistro, and remembers the default it replaces in stateDir so
+// PreviousDefaultDistro can return it. Nothing is remembered if stateDir
+// is empty or the current default can't be found.
+func ChangeDefaultDistro(ctx context.Context, name string, g DefaultGetter, s DefaultSetter, stateDir string) error {
+	current, err := g.GetDefault(ctx)
+	if err != nil {
+		// There is no default when no distro is registered
+		current = ""
+	}
+
+	if err := SetDefaultDistro(ctx, name, s); err != nil {
+		return err
+	}
+
+	if stateDir == "" || current == "" || strings.EqualFold(current, name) {
+		return nil
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err == nil {
+		err = os.WriteFile(filepath.Join(stateDir, previousDefaultFile), []byte(current+"\n"), 0o644)
+	}
+	if err != nil {
+		slog.Warn("Failed to remember the previous default distro", "distro", current, "error", err)
+	}
+	return nil
+}
+
+// PreviousDefaultDistro returns the default distro before the last change
+// ChangeDefaultDistro made, or "" if none is remembered in stateDir
+func PreviousDefaultDistro(stateDir string) (string, error) {
+	if stateDir == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, previousDefaultFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the previous default distro: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
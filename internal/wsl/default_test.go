@@ -105,3 +105,87 @@ func TestSetDefaultDistro(t *testing.T) {
 		}
 	})
 }
+
+func TestChangeDefaultDistro(t *testing.T) {
+	t.Run("remembers the default it replaces", func(t *testing.T) {
+		dir := t.TempDir()
+		setter := &mockDefaultSetter{registered: true}
+
+		err := ChangeDefaultDistro(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, setter, dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if setter.setName != "Debian" {
+			t.Errorf("expected Debian to be set as default, got %s", setter.setName)
+		}
+
+		previous, err := PreviousDefaultDistro(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if previous != "Ubuntu" {
+			t.Errorf("expected previous default Ubuntu, got %q", previous)
+		}
+	})
+
+	t.Run("keeps the previous default when nothing changes", func(t *testing.T) {
+		dir := t.TempDir()
+		setter := &mockDefaultSetter{registered: true}
+
+		if err := ChangeDefaultDistro(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, setter, dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ChangeDefaultDistro(context.Background(), "debian", &mockDefaultGetter{name: "Debian"}, setter, dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		previous, _ := PreviousDefaultDistro(dir)
+		if previous != "Ubuntu" {
+			t.Errorf("expected previous default Ubuntu, got %q", previous)
+		}
+	})
+
+	t.Run("remembers nothing if setting fails", func(t *testing.T) {
+		dir := t.TempDir()
+		setter := &mockDefaultSetter{registered: true, setFails: true}
+
+		err := ChangeDefaultDistro(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, setter, dir)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		previous, _ := PreviousDefaultDistro(dir)
+		if previous != "" {
+			t.Errorf("expected no previous default, got %q", previous)
+		}
+	})
+
+	t.Run("sets the default without a current one", func(t *testing.T) {
+		dir := t.TempDir()
+		setter := &mockDefaultSetter{registered: true}
+
+		err := ChangeDefaultDistro(context.Background(), "Debian", &mockDefaultGetter{shouldFail: true}, setter, dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		previous, _ := PreviousDefaultDistro(dir)
+		if previous != "" {
+			t.Errorf("expected no previous default, got %q", previous)
+		}
+	})
+
+	t.Run("no state dir", func(t *testing.T) {
+		setter := &mockDefaultSetter{registered: true}
+
+		err := ChangeDefaultDistro(context.Background(), "Debian", &mockDefaultGetter{name: "Ubuntu"}, setter, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		previous, err := PreviousDefaultDistro("")
+		if err != nil || previous != "" {
+			t.Errorf("expected no previous default, got %q, %v", previous, err)
+		}
+	})
+}
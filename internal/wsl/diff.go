@@ -0,0 +1,192 @@
+package wsl
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileChange describes a path that differs between two backups
+type FileChange struct {
+	Path string `json:"path"`
+	// Change is one of added, removed or modified
+	Change string `json:"change"`
+	// Reasons lists what differs for modified paths: type, size, mode,
+	// mtime, content or target
+	Reasons []string     `json:"reasons,omitempty"`
+	Old     *BackupEntry `json:"old,omitempty"`
+	New     *BackupEntry `json:"new,omitempty"`
+}
+
+// BackupDiff contains the differences between two backups
+type BackupDiff struct {
+	Old      string       `json:"old"`
+	New      string       `json:"new"`
+	Added    int          `json:"added"`
+	Removed  int          `json:"removed"`
+	Modified int          `json:"modified"`
+	Changes  []FileChange `json:"changes"`
+}
+
+// indexedEntry is an archive entry along with a hash of its content
+type indexedEntry struct {
+	entry  BackupEntry
+	sha256 string
+}
+
+// DiffBackups compares two backup archives and reports the paths added,
+// removed or modified in newArchive relative to oldArchive. Paths are
+// compared by type, size, mode, modification time, link target and, for
+// regular files, a SHA-256 of their content. Directory modification times
+// are ignored since they change whenever a file inside is added or removed.
+func DiffBackups(oldArchive, newArchive string) (BackupDiff, error) {
+	diff := BackupDiff{
+		Old:     oldArchive,
+		New:     newArchive,
+		Changes: []FileChange{},
+	}
+
+	oldIndex, err := indexArchive(oldArchive)
+	if err != nil {
+		return diff, fmt.Errorf("failed to read %s: %w", oldArchive, err)
+	}
+	newIndex, err := indexArchive(newArchive)
+	if err != nil {
+		return diff, fmt.Errorf("failed to read %s: %w", newArchive, err)
+	}
+
+	for path, n := range newIndex {
+		entry := n.entry
+		o, ok := oldIndex[path]
+		if !ok {
+			diff.Added++
+			diff.Changes = append(diff.Changes, FileChange{Path: path, Change: "added", New: &entry})
+			continue
+		}
+
+		if reasons := compareEntries(o, n); len(reasons) > 0 {
+			old := o.entry
+			diff.Modified++
+			diff.Changes = append(diff.Changes, FileChange{Path: path, Change: "modified", Reasons: reasons, Old: &old, New: &entry})
+		}
+	}
+
+	for path, o := range oldIndex {
+		if _, ok := newIndex[path]; !ok {
+			entry := o.entry
+			diff.Removed++
+			diff.Changes = append(diff.Changes, FileChange{Path: path, Change: "removed", Old: &entry})
+		}
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Path < diff.Changes[j].Path
+	})
+
+	return diff, nil
+}
+
+// DiffBackupWithDistro compares a backup archive with the current contents
+// of a registered distro, which is exported to a temporary uncompressed
+// archive for the comparison and removed afterwards. New in the result
+// names the distro rather than the temporary file.
+func DiffBackupWithDistro(ctx context.Context, b Backuper, oldArchive, distro string) (BackupDiff, error) {
+	unlock, err := lockDistros(ctx, "diff", distro)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	defer unlock()
+
+	registered, err := b.IsRegistered(ctx, distro)
+	if err != nil {
+		return BackupDiff{}, fmt.Errorf("failed to check registration: %w", err)
+	}
+	if !registered {
+		return BackupDiff{}, fmt.Errorf("distro %s is not registered", distro)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "wslp-diff-")
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	liveArchive := filepath.Join(tmpDir, distro+FormatTar.Extension())
+	if err := b.Export(ctx, distro, liveArchive, FormatTar); err != nil {
+		return BackupDiff{}, fmt.Errorf("failed to export %s: %w", distro, err)
+	}
+
+	diff, err := DiffBackups(oldArchive, liveArchive)
+	diff.New = distro + " (live)"
+	return diff, err
+}
+
+// indexArchive streams an archive and records every entry by path. File
+// contents are hashed as they are read rather than kept in memory.
+func indexArchive(archivePath string) (map[string]indexedEntry, error) {
+	ar, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	index := map[string]indexedEntry{}
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := cleanArchivePath(hdr.Name)
+		if name == "/" {
+			continue
+		}
+
+		indexed := indexedEntry{entry: newBackupEntry(name, hdr)}
+		if hdr.Typeflag == tar.TypeReg {
+			h := sha256.New()
+			if _, err := io.Copy(h, ar); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			indexed.sha256 = hex.EncodeToString(h.Sum(nil))
+		}
+		index[name] = indexed
+	}
+
+	return index, nil
+}
+
+// compareEntries lists how two versions of a path differ
+func compareEntries(o, n indexedEntry) []string {
+	var reasons []string
+
+	if o.entry.Type != n.entry.Type {
+		return []string{"type"}
+	}
+	if o.entry.Size != n.entry.Size {
+		reasons = append(reasons, "size")
+	}
+	if o.entry.Mode != n.entry.Mode {
+		reasons = append(reasons, "mode")
+	}
+	if n.entry.Type != "dir" && !o.entry.ModTime.Equal(n.entry.ModTime) {
+		reasons = append(reasons, "mtime")
+	}
+	if o.sha256 != n.sha256 {
+		reasons = append(reasons, "content")
+	}
+	if o.entry.LinkTarget != n.entry.LinkTarget {
+		reasons = append(reasons, "target")
+	}
+
+	return reasons
+}
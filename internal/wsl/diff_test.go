@@ -0,0 +1,157 @@
+package wsl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// diffFile is a regular file written by buildDiffArchive
+type diffFile struct {
+	content string
+	mode    int64
+	modTime time.Time
+}
+
+// buildDiffArchive returns a tar.gz archive containing files
+func buildDiffArchive(files map[string]diffFile) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, f := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: f.mode, Size: int64(len(f.content)), ModTime: f.modTime})
+		tw.Write([]byte(f.content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestDiffBackups(t *testing.T) {
+	friday := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	oldArchive := writeTestArchive(t, dir, "Ubuntu-20240301-120000.tar.gz", buildDiffArchive(map[string]diffFile{
+		"./etc/hostname":      {"ubuntu\n", 0644, friday},
+		"./etc/hosts":         {"127.0.0.1 localhost\n", 0644, friday},
+		"./usr/bin/tool":      {"#!/bin/sh\n", 0644, friday},
+		"./home/user/.bashrc": {"alias ll='ls -l'\n", 0644, friday},
+		"./tmp/scratch":       {"x", 0644, friday},
+	}))
+	newArchive := writeTestArchive(t, dir, "Ubuntu-20240304-120000.tar.gz", buildDiffArchive(map[string]diffFile{
+		"./etc/hostname":      {"ubuntu\n", 0644, friday},
+		"./etc/hosts":         {"127.0.0.1 devbox\n", 0644, monday},
+		"./usr/bin/tool":      {"#!/bin/sh\n", 0755, friday},
+		"./home/user/.bashrc": {"alias ll='ls -l'\n", 0644, monday},
+		"./home/user/notes":   {"todo\n", 0644, monday},
+	}))
+
+	diff, err := DiffBackups(oldArchive, newArchive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff.Added != 1 || diff.Removed != 1 || diff.Modified != 3 {
+		t.Errorf("expected 1 added, 1 removed, 3 modified, got %d, %d, %d", diff.Added, diff.Removed, diff.Modified)
+	}
+
+	want := map[string]struct {
+		change  string
+		reasons []string
+	}{
+		"/etc/hosts":         {"modified", []string{"size", "mtime", "content"}},
+		"/usr/bin/tool":      {"modified", []string{"mode"}},
+		"/home/user/.bashrc": {"modified", []string{"mtime"}},
+		"/home/user/notes":   {"added", nil},
+		"/tmp/scratch":       {"removed", nil},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), diff.Changes)
+	}
+	for i, c := range diff.Changes {
+		if i > 0 && diff.Changes[i-1].Path >= c.Path {
+			t.Errorf("expected changes sorted by path, got %s before %s", diff.Changes[i-1].Path, c.Path)
+		}
+		w, ok := want[c.Path]
+		if !ok {
+			t.Errorf("unexpected change %+v", c)
+			continue
+		}
+		if c.Change != w.change || len(c.Reasons) != len(w.reasons) {
+			t.Errorf("%s: expected %s %v, got %s %v", c.Path, w.change, w.reasons, c.Change, c.Reasons)
+			continue
+		}
+		for j := range w.reasons {
+			if c.Reasons[j] != w.reasons[j] {
+				t.Errorf("%s: expected reasons %v, got %v", c.Path, w.reasons, c.Reasons)
+				break
+			}
+		}
+	}
+}
+
+func TestDiffBackupsIdentical(t *testing.T) {
+	archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-120000.tar.gz", buildTestArchive(testRootFS))
+
+	diff, err := DiffBackups(archive, archive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(diff.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", diff.Changes)
+	}
+}
+
+func TestDiffBackupWithDistro(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{"./home/user/notes": "todo\n"}
+	for name, content := range testRootFS {
+		files[name] = content
+	}
+	archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-120000.tar.gz", buildTestArchive(files))
+
+	t.Run("compares a backup with a fresh export", func(t *testing.T) {
+		mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+		diff, err := DiffBackupWithDistro(ctx, mock, archive, "Ubuntu")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff.New != "Ubuntu (live)" {
+			t.Errorf("expected the distro as the new side, got %q", diff.New)
+		}
+		if diff.Removed != 1 || diff.Added != 0 || diff.Modified != 0 || diff.Changes[0].Path != "/home/user/notes" {
+			t.Errorf("expected /home/user/notes to be removed, got %+v", diff.Changes)
+		}
+		if mock.exportedFormat != FormatTar {
+			t.Errorf("expected an uncompressed export, got %q", mock.exportedFormat)
+		}
+	})
+
+	t.Run("returns error for unregistered distro", func(t *testing.T) {
+		mock := &mockBackuper{}
+
+		if _, err := DiffBackupWithDistro(ctx, mock, archive, "Ubuntu"); err == nil || !strings.Contains(err.Error(), "not registered") {
+			t.Fatalf("expected not registered error, got %v", err)
+		}
+	})
+
+	t.Run("returns error when the export fails", func(t *testing.T) {
+		mock := &mockBackuper{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			exportErrors:        map[string]error{"Ubuntu": errors.New("export failed")},
+		}
+
+		if _, err := DiffBackupWithDistro(ctx, mock, archive, "Ubuntu"); err == nil || !strings.Contains(err.Error(), "failed to export") {
+			t.Fatalf("expected export error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package wsl
+
+import "context"
+
+// dryRunKey marks contexts whose operations only check they could run
+type dryRunKey struct{}
+
+// WithDryRun returns a context whose operations, such as unregister,
+// rename or backup, check they could run, through the same interfaces
+// they use otherwise, but stop before changing anything. Their results
+// say what they would have done.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was made by WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,120 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"wslp/internal/audit"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := WithDryRun(context.Background())
+
+	t.Run("marks the context", func(t *testing.T) {
+		if IsDryRun(context.Background()) {
+			t.Error("expected a plain context not to be a dry run")
+		}
+		if !IsDryRun(ctx) {
+			t.Error("expected a dry run")
+		}
+	})
+
+	t.Run("terminate checks the distro without terminating it", func(t *testing.T) {
+		useAuditLog(t)
+		mock := &mockTerminator{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+		results := TerminateDistros(ctx, mock, []string{"Ubuntu", "Missing"})
+
+		if !results[0].Success || !strings.Contains(results[0].Message, "dry run") {
+			t.Errorf("unexpected result %+v", results[0])
+		}
+		if results[1].Success {
+			t.Error("expected an unregistered distro to fail")
+		}
+		if len(mock.terminatedDistros) != 0 {
+			t.Errorf("expected nothing terminated, got %v", mock.terminatedDistros)
+		}
+	})
+
+	t.Run("unregister checks the distro without unregistering it", func(t *testing.T) {
+		log := useAuditLog(t)
+		mock := &mockUnregisterer{registered: true}
+
+		results := UnregisterDistros(ctx, mock, []string{"Ubuntu"})
+		if !results[0].Success || !strings.Contains(results[0].Message, "Would unregister Ubuntu") {
+			t.Errorf("unexpected result %+v", results[0])
+		}
+		if err := UnregisterDistro(ctx, "Ubuntu", mock); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(mock.unregistered) != 0 {
+			t.Errorf("expected nothing unregistered, got %v", mock.unregistered)
+		}
+
+		if err := UnregisterDistro(ctx, "Ubuntu", &mockUnregisterer{}); err == nil {
+			t.Error("expected an unregistered distro to fail")
+		}
+
+		entries, _ := log.Read(audit.Filter{})
+		if len(entries) != 0 {
+			t.Errorf("expected nothing audited, got %+v", entries)
+		}
+	})
+
+	t.Run("rename checks both names without renaming", func(t *testing.T) {
+		useAuditLog(t)
+		mock := &mockRenamer{isRegisteredResults: map[string]bool{"Ubuntu": true, "Debian": true}}
+
+		result := RenameDistro(ctx, mock, "Ubuntu", "Ubuntu-old")
+		if !result.Success || result.Message != "Would rename Ubuntu to Ubuntu-old (dry run)" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if mock.renamedName != "" {
+			t.Errorf("expected no rename, got %q", mock.renamedName)
+		}
+
+		if result := RenameDistro(ctx, mock, "Ubuntu", "Debian"); result.Success {
+			t.Error("expected a taken name to fail")
+		}
+	})
+
+	t.Run("copy checks both names without exporting", func(t *testing.T) {
+		useAuditLog(t)
+		mock := &mockCopier{
+			isRegisteredResults: map[string]bool{"Ubuntu": true},
+			exportErrors:        map[string]error{"Ubuntu": errors.New("export called")},
+		}
+
+		result := CopyDistro(ctx, mock, "Ubuntu", "Ubuntu-copy", t.TempDir())
+		if !result.Success || !strings.Contains(result.Message, "Would copy Ubuntu to Ubuntu-copy") {
+			t.Errorf("unexpected result %+v", result)
+		}
+
+		if result := CopyDistro(ctx, mock, "Missing", "Copy", t.TempDir()); result.Success {
+			t.Error("expected an unregistered source to fail")
+		}
+	})
+
+	t.Run("backup names the archive without exporting", func(t *testing.T) {
+		dir := t.TempDir()
+		mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+		results := BackupDistros(ctx, mock, []string{"Ubuntu", "Missing"}, dir, BackupOptions{CustomName: "nightly"})
+
+		if !results[0].Success || !strings.HasSuffix(results[0].FilePath, "nightly.tar.gz") {
+			t.Errorf("unexpected result %+v", results[0])
+		}
+		if results[1].Success {
+			t.Error("expected an unregistered distro to fail")
+		}
+		if mock.exportedFormat != "" {
+			t.Error("expected nothing exported")
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("expected nothing written, got %d file(s)", len(entries))
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package wsl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+
+	"wslp/internal/config"
+)
+
+// encryptedSuffix is appended to the filename of encrypted archives, e.g.
+// Ubuntu-20240301-143022.tar.gz.age
+const encryptedSuffix = ".age"
+
+// Encryption methods recorded in backup manifests
+const (
+	// EncryptionX25519 is age encryption to one or more public keys
+	EncryptionX25519 = "age-x25519"
+	// EncryptionScrypt is age encryption with a passphrase
+	EncryptionScrypt = "age-scrypt"
+)
+
+// ErrNoDecryptionKey is returned when reading an encrypted backup without
+// any configured way to decrypt it
+var ErrNoDecryptionKey = errors.New("backup is encrypted but no decryption key is configured (set backup_encryption.identity_file or WSLP_BACKUP_PASSPHRASE)")
+
+// IsEncrypted reports whether an archive is encrypted, based on its name
+func IsEncrypted(archivePath string) bool {
+	return strings.HasSuffix(strings.ToLower(archivePath), encryptedSuffix)
+}
+
+// manifestEncryption is what a manifest records about how its archive was
+// encrypted. It never includes secrets.
+type manifestEncryption struct {
+	method     string
+	recipients []string
+}
+
+// backupEncryption returns the recipients new backups are encrypted to,
+// along with the details recorded in their manifests. Public-key
+// recipients take precedence over a passphrase, since age can't mix the
+// two.
+func backupEncryption() ([]age.Recipient, manifestEncryption, error) {
+	enc, err := config.GetBackupEncryption()
+	if err != nil {
+		return nil, manifestEncryption{}, err
+	}
+
+	if len(enc.Recipients) > 0 {
+		recipients := make([]age.Recipient, 0, len(enc.Recipients))
+		for _, key := range enc.Recipients {
+			r, err := age.ParseX25519Recipient(key)
+			if err != nil {
+				return nil, manifestEncryption{}, fmt.Errorf("invalid recipient %q: %w", key, err)
+			}
+			recipients = append(recipients, r)
+		}
+		return recipients, manifestEncryption{method: EncryptionX25519, recipients: enc.Recipients}, nil
+	}
+
+	if enc.Passphrase != "" {
+		r, err := age.NewScryptRecipient(enc.Passphrase)
+		if err != nil {
+			return nil, manifestEncryption{}, err
+		}
+		return []age.Recipient{r}, manifestEncryption{method: EncryptionScrypt}, nil
+	}
+
+	return nil, manifestEncryption{}, fmt.Errorf("encryption requested but no recipients or passphrase are configured (set backup_encryption.recipients or WSLP_BACKUP_PASSPHRASE)")
+}
+
+// decryptionIdentities returns every configured key that might decrypt a
+// backup: the identities in the identity file and the passphrase
+func decryptionIdentities() ([]age.Identity, error) {
+	enc, err := config.GetBackupEncryption()
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []age.Identity
+	if enc.IdentityFile != "" {
+		f, err := os.Open(enc.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open identity file: %w", err)
+		}
+		defer f.Close()
+
+		ids, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity file: %w", err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	if enc.Passphrase != "" {
+		id, err := age.NewScryptIdentity(enc.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, id)
+	}
+
+	if len(identities) == 0 {
+		return nil, ErrNoDecryptionKey
+	}
+
+	return identities, nil
+}
+
+// decrypt wraps the raw stream of an encrypted archive with a decrypting
+// reader
+func decrypt(raw io.Reader) (io.Reader, error) {
+	identities, err := decryptionIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(raw, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	return r, nil
+}
+
+// removeArchive removes a plaintext archive once it's encrypted. Tests
+// replace it to make the removal fail.
+var removeArchive = os.Remove
+
+// encryptArchive encrypts archivePath to a new file with the encrypted
+// suffix and removes the plaintext. It returns the path, size and checksum
+// of the encrypted archive.
+func encryptArchive(archivePath string, recipients []age.Recipient) (string, archiveStats, error) {
+	var stats archiveStats
+	encryptedPath := archivePath + encryptedSuffix
+
+	src, err := os.Open(archivePath)
+	if err != nil {
+		return "", stats, err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(encryptedPath)
+	if err != nil {
+		return "", stats, err
+	}
+
+	h := sha256.New()
+	counter := &countingWriter{}
+	w, err := age.Encrypt(io.MultiWriter(dst, h, counter), recipients...)
+	if err == nil {
+		_, err = io.Copy(w, src)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", stats, err
+	}
+
+	src.Close()
+	if err := removeArchive(archivePath); err != nil {
+		// Don't leave an encrypted copy without a manifest beside the
+		// plaintext; the caller reports the plaintext
+		os.Remove(encryptedPath)
+		return "", stats, fmt.Errorf("failed to remove unencrypted archive: %w", err)
+	}
+
+	stats.size = counter.n
+	stats.sha256 = hex.EncodeToString(h.Sum(nil))
+	return encryptedPath, stats, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,232 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/spf13/viper"
+)
+
+// useEncryptionKey configures a fresh X25519 key pair for encrypting and
+// decrypting backups, returning it
+func useEncryptionKey(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("failed to generate identity: %v", err)
+	}
+
+	identityFile := filepath.Join(t.TempDir(), "age-key.txt")
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write identity file: %v", err)
+	}
+
+	useNoEncryptionKey(t)
+	viper.Set("backup_encryption.recipients", []string{identity.Recipient().String()})
+	viper.Set("backup_encryption.identity_file", identityFile)
+
+	return identity
+}
+
+// useNoEncryptionKey clears any configured keys for the duration of a test
+func useNoEncryptionKey(t *testing.T) {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+}
+
+func TestBackupDistrosEncrypt(t *testing.T) {
+	ctx := context.Background()
+	identity := useEncryptionKey(t)
+	backupDir := t.TempDir()
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, backupDir, BackupOptions{Format: FormatTarZst, Encrypt: true})
+
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected successful backup, got %+v", results)
+	}
+	archivePath := resultPath(t, results[0])
+	if !strings.HasSuffix(archivePath, ".tar.zst.age") {
+		t.Errorf("expected encrypted archive, got %s", archivePath)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(archivePath, ".age")); !os.IsNotExist(err) {
+		t.Error("expected the unencrypted archive to be removed")
+	}
+
+	manifest, err := ReadManifest(archivePath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if manifest.Encryption != EncryptionX25519 {
+		t.Errorf("expected encryption %s, got %q", EncryptionX25519, manifest.Encryption)
+	}
+	if len(manifest.Recipients) != 1 || manifest.Recipients[0] != identity.Recipient().String() {
+		t.Errorf("expected recipient %s, got %v", identity.Recipient(), manifest.Recipients)
+	}
+	if manifest.Format != FormatTarZst {
+		t.Errorf("expected format %s, got %s", FormatTarZst, manifest.Format)
+	}
+	data, _ := os.ReadFile(ManifestPath(archivePath))
+	if strings.Contains(string(data), identity.String()) {
+		t.Error("manifest must not contain the secret key")
+	}
+
+	backups, err := ListBackups(backupDir)
+	if err != nil || len(backups) != 1 || backups[0].Distro != "Ubuntu" {
+		t.Fatalf("expected encrypted backup to be listed, got %+v (%v)", backups, err)
+	}
+
+	// Browsing and verifying decrypt transparently
+	entries, err := ListBackupFiles(archivePath, "/etc", false)
+	if err != nil {
+		t.Fatalf("failed to list encrypted backup: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Error("expected entries in /etc")
+	}
+
+	verified := VerifyBackup(archivePath)
+	if !verified.Success || !verified.Encrypted || !verified.ChecksumVerified || verified.Entries == 0 {
+		t.Errorf("expected encrypted backup to verify fully, got %+v", verified)
+	}
+}
+
+func TestBackupDistrosEncryptPassphrase(t *testing.T) {
+	ctx := context.Background()
+	useNoEncryptionKey(t)
+	t.Setenv("WSLP_BACKUP_PASSPHRASE", "correct horse battery staple")
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{Encrypt: true})
+
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected successful backup, got %+v", results)
+	}
+
+	manifest, err := ReadManifest(resultPath(t, results[0]))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if manifest.Encryption != EncryptionScrypt || len(manifest.Recipients) != 0 {
+		t.Errorf("expected scrypt encryption without recipients, got %q %v", manifest.Encryption, manifest.Recipients)
+	}
+
+	rc, _, err := OpenBackupFile(resultPath(t, results[0]), "/etc/os-release")
+	if err != nil {
+		t.Fatalf("failed to open file in encrypted backup: %v", err)
+	}
+	rc.Close()
+
+	t.Setenv("WSLP_BACKUP_PASSPHRASE", "wrong")
+	if _, err := ListBackupFiles(resultPath(t, results[0]), "/", false); err == nil {
+		t.Error("expected an error with the wrong passphrase")
+	}
+}
+
+func TestBackupDistrosEncryptWithoutKey(t *testing.T) {
+	ctx := context.Background()
+	useNoEncryptionKey(t)
+	backupDir := t.TempDir()
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, backupDir, BackupOptions{Encrypt: true})
+
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("expected backup to fail, got %+v", results)
+	}
+	if !strings.Contains(results[0].Message, "no recipients or passphrase") {
+		t.Errorf("expected missing key message, got %q", results[0].Message)
+	}
+	if mock.exportedFormat != "" {
+		t.Error("expected nothing to be exported")
+	}
+}
+
+func TestBackupDistrosEncryptRemoveFails(t *testing.T) {
+	ctx := context.Background()
+	useEncryptionKey(t)
+	backupDir := t.TempDir()
+
+	previous := removeArchive
+	removeArchive = func(string) error { return errors.New("file in use") }
+	t.Cleanup(func() { removeArchive = previous })
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, backupDir, BackupOptions{Encrypt: true})
+
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("expected backup to fail, got %+v", results)
+	}
+	if !strings.Contains(results[0].Message, "unencrypted archive is still at") {
+		t.Errorf("expected the plaintext to be reported, got %q", results[0].Message)
+	}
+	encrypted, _ := filepath.Glob(filepath.Join(backupDir, "*"+encryptedSuffix))
+	if len(encrypted) != 0 {
+		t.Errorf("expected the encrypted archive to be removed, got %v", encrypted)
+	}
+}
+
+func TestVerifyEncryptedBackupWithoutKey(t *testing.T) {
+	ctx := context.Background()
+	useEncryptionKey(t)
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{Encrypt: true})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected successful backup, got %+v", results)
+	}
+	archivePath := resultPath(t, results[0])
+
+	useNoEncryptionKey(t)
+
+	result := VerifyBackup(archivePath)
+	if !result.Success || !result.ChecksumVerified || result.Entries != 0 {
+		t.Errorf("expected checksum-only verification, got %+v", result)
+	}
+	if !strings.Contains(result.Message, "contents were not checked") {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+
+	if _, err := ListBackupFiles(archivePath, "/", false); !errors.Is(err, ErrNoDecryptionKey) {
+		t.Errorf("expected ErrNoDecryptionKey, got %v", err)
+	}
+
+	os.Remove(ManifestPath(archivePath))
+	if result := VerifyBackup(archivePath); result.Success {
+		t.Errorf("expected failure without key or manifest, got %+v", result)
+	}
+}
+
+func TestEncryptedVHDX(t *testing.T) {
+	ctx := context.Background()
+	useEncryptionKey(t)
+
+	mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	results := BackupDistros(ctx, mock, []string{"Ubuntu"}, t.TempDir(), BackupOptions{Format: FormatVHDX, Encrypt: true})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected successful backup, got %+v", results)
+	}
+	if !strings.HasSuffix(results[0].FilePath, ".vhdx.age") {
+		t.Errorf("expected encrypted VHDX, got %s", results[0].FilePath)
+	}
+
+	if result := VerifyBackup(resultPath(t, results[0])); !result.Success {
+		t.Errorf("expected encrypted VHDX to verify, got %+v", result)
+	}
+}
+
+func TestBackupFilenameEncryptedCustomName(t *testing.T) {
+	format, filename := backupFilename("Ubuntu", BackupOptions{CustomName: "snap.tar.xz.age"})
+	if format != FormatTarXz || filename != "snap.tar.xz" {
+		t.Errorf("expected snap.tar.xz, got %s (%s)", filename, format)
+	}
+}
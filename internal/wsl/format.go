@@ -0,0 +1,313 @@
+package wsl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	gowsl "github.com/ubuntu/gowsl"
+	"github.com/ulikunitz/xz"
+)
+
+// BackupFormat is the file format of a backup archive
+type BackupFormat string
+
+const (
+	// FormatTar is an uncompressed tar archive
+	FormatTar BackupFormat = "tar"
+	// FormatTarGz is a gzip-compressed tar archive, as written by
+	// wsl --export by default
+	FormatTarGz BackupFormat = "tar.gz"
+	// FormatTarZst is a zstd-compressed tar archive
+	FormatTarZst BackupFormat = "tar.zst"
+	// FormatTarXz is an xz-compressed tar archive
+	FormatTarXz BackupFormat = "tar.xz"
+	// FormatVHDX is a copy of a WSL2 distro's virtual disk
+	FormatVHDX BackupFormat = "vhdx"
+)
+
+// DefaultBackupFormat is used when no format is requested
+const DefaultBackupFormat = FormatTarGz
+
+// BackupFormats lists the supported formats, in the order they are
+// checked against filenames
+var BackupFormats = []BackupFormat{FormatTarGz, FormatTarZst, FormatTarXz, FormatTar, FormatVHDX}
+
+// ParseBackupFormat parses a format name. Besides the format names
+// themselves it accepts common aliases such as gzip, zstd, xz and vhd. An
+// empty name selects DefaultBackupFormat.
+func ParseBackupFormat(name string) (BackupFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(name), ".") {
+	case "":
+		return DefaultBackupFormat, nil
+	case "tar":
+		return FormatTar, nil
+	case "tar.gz", "tgz", "gz", "gzip":
+		return FormatTarGz, nil
+	case "tar.zst", "zst", "zstd":
+		return FormatTarZst, nil
+	case "tar.xz", "xz":
+		return FormatTarXz, nil
+	case "vhdx", "vhd":
+		return FormatVHDX, nil
+	}
+
+	return "", fmt.Errorf("unsupported backup format %q (use tar, tar.gz, zstd, xz or vhdx)", name)
+}
+
+// FormatFromPath returns the format of an archive based on its extension.
+// The encrypted suffix is ignored, so Ubuntu.tar.zst.age is a tar.zst.
+func FormatFromPath(path string) (BackupFormat, bool) {
+	lower := strings.TrimSuffix(strings.ToLower(path), encryptedSuffix)
+	for _, f := range BackupFormats {
+		if strings.HasSuffix(lower, f.Extension()) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// Extension returns the filename extension of the format, e.g. .tar.zst
+func (f BackupFormat) Extension() string {
+	return "." + string(f)
+}
+
+// exportArgs returns the wsl.exe arguments that export distroName to
+// outputPath in a format wsl.exe writes natively
+func exportArgs(distroName, outputPath string, format BackupFormat) ([]string, error) {
+	switch format {
+	case FormatTar:
+		return []string{"--export", distroName, outputPath, "--format", "tar"}, nil
+	case FormatTarGz:
+		return []string{"--export", distroName, outputPath, "--format", "tar.gz"}, nil
+	case FormatVHDX:
+		return []string{"--export", distroName, outputPath, "--vhd"}, nil
+	}
+
+	return nil, fmt.Errorf("wsl.exe cannot export %s archives", format)
+}
+
+// exportCompressed streams an uncompressed export of distroName from
+// wsl.exe through a compressor written in Go. wsl.exe only compresses
+// with gzip, and zstd in particular is much faster on large distros.
+func exportCompressed(ctx context.Context, distroName, outputPath string, format BackupFormat) (err error) {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			removePartial(outputPath)
+		}
+	}()
+
+	w, err := compress(format, f)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "wsl.exe", "--export", distroName, "-", "--format", "tar")
+	cmd.WaitDelay = exportWaitDelay
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("export failed: %v", err)
+	}
+
+	_, copyErr := io.Copy(w, stdout)
+	if copyErr != nil {
+		// Unblock wsl.exe so Wait doesn't hang on a full pipe
+		io.Copy(io.Discard, stdout)
+	}
+	closeErr := w.Close()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("export cancelled: %w", ctx.Err())
+		}
+		return fmt.Errorf("export failed: %v (output: %s)", err, stderr.String())
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to write %s archive: %w", format, copyErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write %s archive: %w", format, closeErr)
+	}
+
+	return nil
+}
+
+// compress wraps w with the compressor for format
+func compress(format BackupFormat, w io.Writer) (io.WriteCloser, error) {
+	switch format {
+	case FormatTarZst:
+		return zstd.NewWriter(w)
+	case FormatTarXz:
+		return xz.NewWriter(w)
+	}
+
+	return nil, fmt.Errorf("no compressor for %s archives", format)
+}
+
+// decompress wraps the raw stream of an archive with the decompressor its
+// extension calls for. Encrypted archives are decrypted first.
+func decompress(archivePath string, raw io.Reader) (io.ReadCloser, error) {
+	format, _ := FormatFromPath(archivePath)
+
+	if IsEncrypted(archivePath) && format != FormatVHDX {
+		var err error
+		if raw, err = decrypt(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case FormatTarGz:
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("not a gzip stream: %w", err)
+		}
+		return gz, nil
+
+	case FormatTarZst:
+		zr, err := zstd.NewReader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("not a zstd stream: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+
+	case FormatTarXz:
+		xr, err := xz.NewReader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("not an xz stream: %w", err)
+		}
+		return io.NopCloser(xr), nil
+
+	case FormatVHDX:
+		return nil, fmt.Errorf("VHDX backups are disk images, not archives, and cannot be read file by file")
+	}
+
+	return io.NopCloser(raw), nil
+}
+
+// importArchive registers newName from a backup archive of any supported
+// format, storing its virtual disk in installDir. wsl.exe imports tar,
+// tar.gz and VHDX files directly; zstd, xz and encrypted archives are
+// decompressed and decrypted in Go and streamed to it.
+func importArchive(ctx context.Context, newName, archivePath, installDir string) error {
+	format, _ := FormatFromPath(archivePath)
+
+	if IsEncrypted(archivePath) {
+		if format == FormatVHDX {
+			return importEncryptedVHDX(ctx, newName, archivePath, installDir)
+		}
+		return importCompressed(ctx, newName, archivePath, installDir)
+	}
+
+	switch format {
+	case FormatVHDX:
+		if err := os.MkdirAll(installDir, 0700); err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, "wsl.exe", "--import", newName, installDir, archivePath, "--vhd")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("import failed: %v (output: %s)", err, string(output))
+		}
+		return nil
+
+	case FormatTarZst, FormatTarXz:
+		return importCompressed(ctx, newName, archivePath, installDir)
+	}
+
+	_, err := gowsl.Import(ctx, newName, archivePath, installDir)
+	return err
+}
+
+// importCompressed decompresses an archive in Go and streams the tar to
+// wsl.exe --import
+func importCompressed(ctx context.Context, newName, archivePath, installDir string) error {
+	if err := os.MkdirAll(installDir, 0700); err != nil {
+		return err
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(archivePath, f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.CommandContext(ctx, "wsl.exe", "--import", newName, installDir, "-")
+	cmd.Stdin = r
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("import failed: %v (output: %s)", err, string(output))
+	}
+
+	return nil
+}
+
+// importEncryptedVHDX decrypts a VHDX backup into installDir and registers
+// the disk in place, so the plaintext image exists only as the distro's
+// own disk
+func importEncryptedVHDX(ctx context.Context, newName, archivePath, installDir string) (err error) {
+	if err := os.MkdirAll(installDir, 0700); err != nil {
+		return err
+	}
+
+	src, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r, err := decrypt(src)
+	if err != nil {
+		return err
+	}
+
+	diskPath := filepath.Join(installDir, "ext4.vhdx")
+	dst, err := os.OpenFile(diskPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(diskPath)
+		}
+	}()
+
+	_, err = io.Copy(dst, r)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decrypt disk image: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "wsl.exe", "--import-in-place", newName, diskPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("import failed: %v (output: %s)", err, string(output))
+	}
+
+	return nil
+}
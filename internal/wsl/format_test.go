@@ -0,0 +1,74 @@
+package wsl
+
+import (
+	"testing"
+)
+
+func TestParseBackupFormat(t *testing.T) {
+	tests := map[string]BackupFormat{
+		"":        DefaultBackupFormat,
+		"tar":     FormatTar,
+		"TAR.GZ":  FormatTarGz,
+		"gzip":    FormatTarGz,
+		"zstd":    FormatTarZst,
+		".tar.xz": FormatTarXz,
+		"xz":      FormatTarXz,
+		"vhd":     FormatVHDX,
+		"vhdx":    FormatVHDX,
+	}
+
+	for name, want := range tests {
+		got, err := ParseBackupFormat(name)
+		if err != nil {
+			t.Errorf("ParseBackupFormat(%q): unexpected error: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseBackupFormat(%q) = %s, want %s", name, got, want)
+		}
+	}
+
+	if _, err := ParseBackupFormat("rar"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]BackupFormat{
+		"Ubuntu-20240301-143022.tar.gz":  FormatTarGz,
+		"Ubuntu-20240301-143022.TAR.ZST": FormatTarZst,
+		"backup.tar.xz":                  FormatTarXz,
+		"backup.tar":                     FormatTar,
+		"backup.vhdx":                    FormatVHDX,
+	}
+
+	for path, want := range tests {
+		if got, ok := FormatFromPath(path); !ok || got != want {
+			t.Errorf("FormatFromPath(%q) = %s, %v, want %s", path, got, ok, want)
+		}
+	}
+
+	if _, ok := FormatFromPath("notes.txt"); ok {
+		t.Error("expected no format for notes.txt")
+	}
+}
+
+func TestBrowseCompressedFormats(t *testing.T) {
+	for _, format := range []BackupFormat{FormatTar, FormatTarZst, FormatTarXz} {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022"+format.Extension(), buildFormatArchive(format, testRootFS))
+
+		entries, err := ListBackupFiles(archive, "/etc", false)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", format, err)
+			continue
+		}
+		if len(entries) != 2 {
+			t.Errorf("%s: expected 2 entries, got %v", format, entryPaths(entries))
+		}
+	}
+
+	vhdx := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.vhdx", buildFormatArchive(FormatVHDX, nil))
+	if _, err := ListBackupFiles(vhdx, "/", false); err == nil {
+		t.Error("expected error browsing a VHDX backup")
+	}
+}
@@ -72,6 +72,13 @@ func PrintInstallResults(out io.Writer, results []InstallResult) {
 func installOne(ctx context.Context, distro string) InstallResult {
 	result := InstallResult{Distro: distro}
 
+	unlock, err := lockDistros(ctx, "install", distro)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer unlock()
+
 	if err := gowsl.Install(ctx, distro); err != nil {
 		result.Message = err.Error()
 		return result
@@ -12,6 +12,10 @@ type DistroInfo struct {
 	Name    string `json:"name"`
 	State   string `json:"state"`
 	Running bool   `json:"running"`
+	// Busy is set while an operation, such as a backup, holds the
+	// distro's lock. Operation names it.
+	Busy      bool   `json:"busy"`
+	Operation string `json:"operation,omitempty"`
 }
 
 // Lister retrieves the names of registered WSL distributions
@@ -55,10 +59,13 @@ func ListDistros(ctx context.Context, l Lister) ([]DistroInfo, error) {
 			running = (state == gowsl.Running)
 		}
 
+		operation, busy := Locks.Busy(name)
 		result[i] = DistroInfo{
-			Name:    name,
-			State:   stateStr,
-			Running: running,
+			Name:      name,
+			State:     stateStr,
+			Running:   running,
+			Busy:      busy,
+			Operation: operation,
 		}
 	}
 
@@ -0,0 +1,264 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Locks serializes the operations that change a distro, such as renames,
+// backups and copies, so two of them never run on the same distro at
+// once. Until SetDir is called it only covers this process.
+var Locks = NewLockManager("")
+
+// lockPollInterval is how often a waiting operation retries a busy lock
+var lockPollInterval = 500 * time.Millisecond
+
+// BusyError is returned when a distro is locked by another operation
+type BusyError struct {
+	Distro string
+	// Operation is what holds the lock, e.g. backup
+	Operation string
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s is busy: %s in progress", e.Distro, e.Operation)
+}
+
+// IsBusy reports whether err is, or wraps, a BusyError
+func IsBusy(err error) bool {
+	var busy *BusyError
+	return errors.As(err, &busy)
+}
+
+// LockManager locks distros by name for the duration of an operation.
+// Names are compared case-insensitively, as WSL does. With a directory
+// set, a lock file per distro also makes other processes, such as the CLI
+// and the API server, wait for each other.
+type LockManager struct {
+	mu   sync.Mutex
+	dir  string
+	held map[string]*distroLock
+}
+
+// distroLock is a lock held by this process
+type distroLock struct {
+	operation string
+	// file is the locked lock file, or nil without a lock directory
+	file *os.File
+}
+
+// NewLockManager creates a lock manager keeping its lock files in dir, or
+// locking within this process only if dir is empty
+func NewLockManager(dir string) *LockManager {
+	return &LockManager{dir: dir, held: make(map[string]*distroLock)}
+}
+
+// SetDir sets the directory of the lock files. Locks already held are
+// unaffected.
+func (m *LockManager) SetDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = dir
+}
+
+// TryLock locks distro for operation, or returns a *BusyError if another
+// operation holds it. The returned function releases the lock.
+func (m *LockManager) TryLock(distro, operation string) (unlock func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := lockKey(distro)
+	if held, ok := m.held[key]; ok {
+		return nil, &BusyError{Distro: distro, Operation: held.operation}
+	}
+
+	lock := &distroLock{operation: operation}
+	if m.dir != "" {
+		file, err := lockFile(m.lockPath(key), distro, operation)
+		if err != nil {
+			return nil, err
+		}
+		lock.file = file
+	}
+	m.held[key] = lock
+
+	var once sync.Once
+	return func() { once.Do(func() { m.release(key, lock) }) }, nil
+}
+
+// Lock locks distro for operation, waiting while another operation holds
+// it until ctx is done
+func (m *LockManager) Lock(ctx context.Context, distro, operation string) (unlock func(), err error) {
+	for waiting := false; ; waiting = true {
+		unlock, err := m.TryLock(distro, operation)
+		if !IsBusy(err) {
+			return unlock, err
+		}
+		if !waiting {
+			slog.Info("Waiting for busy distro", "distro", distro, "operation", operation, "busy", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (stopped waiting: %v)", err, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Busy returns the operation holding distro's lock, in this process or
+// another, and whether there is one
+func (m *LockManager) Busy(distro string) (operation string, busy bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := lockKey(distro)
+	if held, ok := m.held[key]; ok {
+		return held.operation, true
+	}
+	if m.dir == "" {
+		return "", false
+	}
+
+	file, err := os.OpenFile(m.lockPath(key), os.O_RDWR, 0)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	// Holders clear the file when they release it, so only a recorded
+	// operation needs checking. It may have been left by a process that
+	// exited without releasing it, in which case the lock is free.
+	operation = lockOperation(file)
+	if operation == "" {
+		return "", false
+	}
+	if err := tryLockFile(file); err != nil {
+		return operation, true
+	}
+	unlockFile(file)
+	return "", false
+}
+
+// release unlocks a lock taken by TryLock
+func (m *LockManager) release(key string, lock *distroLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.held[key] == lock {
+		delete(m.held, key)
+	}
+	if lock.file != nil {
+		// Clear the operation, so the file doesn't name a finished one
+		lock.file.Truncate(0)
+		unlockFile(lock.file)
+		lock.file.Close()
+	}
+}
+
+func (m *LockManager) lockPath(key string) string {
+	return filepath.Join(m.dir, url.PathEscape(key)+".lock")
+}
+
+// lockKey is the key distro is locked under
+func lockKey(distro string) string {
+	return strings.ToLower(distro)
+}
+
+// lockFile opens and locks the lock file at path, recording operation in
+// it. If another process holds it, it returns a *BusyError naming the
+// operation recorded there.
+func lockFile(path, distro, operation string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := tryLockFile(file); err != nil {
+		defer file.Close()
+		if errors.Is(err, errLocked) {
+			operation := lockOperation(file)
+			if operation == "" {
+				operation = "another operation"
+			}
+			return nil, &BusyError{Distro: distro, Operation: operation}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(operation), 0)
+	}
+	if err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return file, nil
+}
+
+// lockOperation reads the operation recorded in a lock file, or "" if
+// there is none
+func lockOperation(file *os.File) string {
+	data, _ := io.ReadAll(io.NewSectionReader(file, 0, 256))
+	return strings.TrimSpace(string(data))
+}
+
+// lockWaitKey marks contexts whose operations wait for busy distros
+type lockWaitKey struct{}
+
+// WithLockWait returns a context whose operations wait for busy distros to
+// become free, rather than failing with a *BusyError
+func WithLockWait(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockWaitKey{}, true)
+}
+
+// lockDistros locks distros for operation with Locks, waiting if ctx was
+// made by WithLockWait. They are locked in a fixed order, so operations
+// locking several distros can't deadlock.
+func lockDistros(ctx context.Context, operation string, distros ...string) (unlock func(), err error) {
+	sorted := make([]string, 0, len(distros))
+	seen := make(map[string]bool)
+	for _, distro := range distros {
+		if key := lockKey(distro); !seen[key] {
+			seen[key] = true
+			sorted = append(sorted, distro)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return lockKey(sorted[i]) < lockKey(sorted[j]) })
+
+	wait, _ := ctx.Value(lockWaitKey{}).(bool)
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, distro := range sorted {
+		var unlock func()
+		if wait {
+			unlock, err = Locks.Lock(ctx, distro, operation)
+		} else {
+			unlock, err = Locks.TryLock(distro, operation)
+		}
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return unlockAll, nil
+}
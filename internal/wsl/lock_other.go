@@ -0,0 +1,27 @@
+//go:build !windows
+
+package wsl
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// errLocked is returned by tryLockFile when another process holds the lock
+var errLocked = errors.New("file is locked")
+
+// tryLockFile takes an exclusive lock on file without waiting. The lock is
+// released if the process exits.
+func tryLockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN)
+}
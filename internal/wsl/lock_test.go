@@ -0,0 +1,186 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useLocks replaces Locks for the duration of a test
+func useLocks(t *testing.T, m *LockManager) {
+	t.Helper()
+
+	previous, interval := Locks, lockPollInterval
+	Locks, lockPollInterval = m, 10*time.Millisecond
+	t.Cleanup(func() { Locks, lockPollInterval = previous, interval })
+}
+
+func TestLockManager(t *testing.T) {
+	t.Run("reports the operation holding a distro", func(t *testing.T) {
+		m := NewLockManager("")
+		unlock, err := m.TryLock("Ubuntu", "backup")
+		if err != nil {
+			t.Fatalf("TryLock failed: %v", err)
+		}
+
+		_, err = m.TryLock("ubuntu", "rename")
+		var busy *BusyError
+		if !errors.As(err, &busy) || busy.Operation != "backup" {
+			t.Fatalf("expected a BusyError naming the backup, got %v", err)
+		}
+		if err.Error() != "ubuntu is busy: backup in progress" {
+			t.Errorf("unexpected message %q", err.Error())
+		}
+		if operation, ok := m.Busy("UBUNTU"); !ok || operation != "backup" {
+			t.Errorf("expected Ubuntu busy with backup, got %q, %v", operation, ok)
+		}
+
+		unlock()
+		unlock()
+		if _, ok := m.Busy("Ubuntu"); ok {
+			t.Error("expected Ubuntu to be free after unlocking")
+		}
+		if unlock, err := m.TryLock("Ubuntu", "rename"); err != nil {
+			t.Errorf("expected the lock to be free, got %v", err)
+		} else {
+			unlock()
+		}
+	})
+
+	t.Run("waits for a busy distro", func(t *testing.T) {
+		m := NewLockManager("")
+		useLocks(t, m)
+		unlock, _ := m.TryLock("Ubuntu", "backup")
+		time.AfterFunc(50*time.Millisecond, unlock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		unlockRename, err := m.Lock(ctx, "Ubuntu", "rename")
+		if err != nil {
+			t.Fatalf("expected to get the lock once released, got %v", err)
+		}
+		unlockRename()
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		m := NewLockManager("")
+		useLocks(t, m)
+		unlock, _ := m.TryLock("Ubuntu", "backup")
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := m.Lock(ctx, "Ubuntu", "rename"); !IsBusy(err) {
+			t.Errorf("expected a busy error, got %v", err)
+		}
+	})
+
+	t.Run("coordinates through lock files", func(t *testing.T) {
+		// Two managers sharing a directory stand in for the CLI and the
+		// server
+		dir := t.TempDir()
+		cli, server := NewLockManager(dir), NewLockManager(dir)
+
+		unlock, err := cli.TryLock("Ubuntu", "backup")
+		if err != nil {
+			t.Fatalf("TryLock failed: %v", err)
+		}
+		if _, err := server.TryLock("Ubuntu", "unregister"); err == nil || err.Error() != "Ubuntu is busy: backup in progress" {
+			t.Errorf("expected the other manager to see the backup, got %v", err)
+		}
+		if operation, ok := server.Busy("Ubuntu"); !ok || operation != "backup" {
+			t.Errorf("expected Ubuntu busy with backup, got %q, %v", operation, ok)
+		}
+
+		unlock()
+		if _, ok := server.Busy("Ubuntu"); ok {
+			t.Error("expected Ubuntu to be free after unlocking")
+		}
+		unlock, err = server.TryLock("Ubuntu", "unregister")
+		if err != nil {
+			t.Fatalf("expected the lock to be free, got %v", err)
+		}
+		unlock()
+	})
+
+	t.Run("ignores operations left by exited processes", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "ubuntu.lock"), []byte("backup"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		m := NewLockManager(dir)
+		if _, ok := m.Busy("Ubuntu"); ok {
+			t.Error("expected an unlocked lock file not to count")
+		}
+		unlock, err := m.TryLock("Ubuntu", "rename")
+		if err != nil {
+			t.Fatalf("TryLock failed: %v", err)
+		}
+		unlock()
+	})
+}
+
+func TestLockDistros(t *testing.T) {
+	t.Run("locks every distro or none", func(t *testing.T) {
+		m := NewLockManager("")
+		useLocks(t, m)
+		unlockDebian, _ := m.TryLock("Debian", "backup")
+		defer unlockDebian()
+
+		if _, err := lockDistros(context.Background(), "copy", "Ubuntu", "Debian"); !IsBusy(err) {
+			t.Fatalf("expected a busy error, got %v", err)
+		}
+		if _, ok := m.Busy("Ubuntu"); ok {
+			t.Error("expected Ubuntu to be released when Debian was busy")
+		}
+	})
+
+	t.Run("locks the same distro once", func(t *testing.T) {
+		useLocks(t, NewLockManager(""))
+
+		unlock, err := lockDistros(context.Background(), "copy", "Ubuntu", "ubuntu")
+		if err != nil {
+			t.Fatalf("lockDistros failed: %v", err)
+		}
+		unlock()
+	})
+
+	t.Run("waits when asked to", func(t *testing.T) {
+		m := NewLockManager("")
+		useLocks(t, m)
+		unlock, _ := m.TryLock("Ubuntu", "backup")
+		time.AfterFunc(50*time.Millisecond, unlock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		unlockAll, err := lockDistros(WithLockWait(ctx), "rename", "Ubuntu")
+		if err != nil {
+			t.Fatalf("expected to wait for the lock, got %v", err)
+		}
+		unlockAll()
+	})
+}
+
+func TestOperationsTakeLocks(t *testing.T) {
+	m := NewLockManager("")
+	useLocks(t, m)
+	unlock, _ := m.TryLock("Ubuntu", "backup")
+	defer unlock()
+
+	renamer := &mockRenamer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+	result := RenameDistro(context.Background(), renamer, "Ubuntu", "MyUbuntu")
+	if result.Success || !strings.Contains(result.Message, "busy: backup in progress") {
+		t.Errorf("expected the rename to be refused, got %+v", result)
+	}
+	if renamer.renamedName != "" {
+		t.Error("expected the registry not to be touched")
+	}
+	if _, ok := m.Busy("MyUbuntu"); ok {
+		t.Error("expected the new name's lock to be released")
+	}
+}
@@ -0,0 +1,31 @@
+package wsl
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// errLocked is returned by tryLockFile when another process holds the lock
+var errLocked = errors.New("file is locked")
+
+// lockOffset is where the locked byte lies, well past the recorded
+// operation, since Windows stops other processes reading a locked range
+const lockOffset = 0x7fffffff
+
+// tryLockFile takes an exclusive lock on file without waiting. Windows
+// releases it if the process exits.
+func tryLockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffset}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,199 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"wslp/internal/config"
+)
+
+// PruneResult contains the result of pruning a single backup
+type PruneResult struct {
+	Distro   string `json:"distro"`
+	FilePath string `json:"filePath"`
+	Size     int64  `json:"size"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+}
+
+// pruneCandidate is a backup selected for deletion and the rule that
+// selected it
+type pruneCandidate struct {
+	backup BackupInfo
+	reason string
+}
+
+// PruneBackups deletes the backups in backupDir that policy does not keep,
+// along with their manifests. With dryRun, nothing is deleted and the
+// results describe what would be. Custom-named backups whose distro is
+// unknown are never pruned.
+func PruneBackups(backupDir string, policy config.RetentionPolicy, dryRun bool) ([]PruneResult, error) {
+	results := []PruneResult{}
+
+	if !policy.Enabled() {
+		return results, fmt.Errorf("no retention policy configured")
+	}
+
+	backups, err := ListBackups(backupDir)
+	if err != nil {
+		return results, err
+	}
+
+	for _, c := range selectPrunable(backups, policy) {
+		result := PruneResult{
+			Distro:   c.backup.Distro,
+			FilePath: c.backup.FilePath,
+			Size:     c.backup.Size,
+			Success:  false,
+		}
+
+		if dryRun {
+			result.Success = true
+			result.Message = fmt.Sprintf("Would delete (%s)", c.reason)
+			results = append(results, result)
+			continue
+		}
+
+		if err := os.Remove(c.backup.FilePath); err != nil {
+			result.Message = fmt.Sprintf("Failed to delete: %v", err)
+			results = append(results, result)
+			continue
+		}
+		// The manifest is useless without its archive
+		if err := os.Remove(ManifestPath(c.backup.FilePath)); err != nil && !os.IsNotExist(err) {
+			result.Success = true
+			result.Message = fmt.Sprintf("Deleted (%s), but failed to delete manifest: %v", c.reason, err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Success = true
+		result.Message = fmt.Sprintf("Deleted (%s)", c.reason)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// AutoPrune applies the retention policy after a backup run. It does
+// nothing unless a policy is configured and at least one backup succeeded,
+// so a failing backup never causes older good backups to be deleted.
+func AutoPrune(results []BackupResult, backupDir string, policy config.RetentionPolicy) ([]PruneResult, error) {
+	if !policy.Enabled() {
+		return nil, nil
+	}
+
+	for _, r := range results {
+		if r.Success {
+			return PruneBackups(backupDir, policy, false)
+		}
+	}
+
+	return nil, nil
+}
+
+// selectPrunable returns the backups that policy does not keep, oldest
+// first. The keep rules are applied to each distro's backups separately;
+// the size limit then removes the oldest remaining backups across all
+// distros, but never the most recent backup of a distro.
+func selectPrunable(backups []BackupInfo, policy config.RetentionPolicy) []pruneCandidate {
+	byDistro := map[string][]BackupInfo{}
+	var total int64
+	for _, b := range backups {
+		total += b.Size
+		if b.Distro == "" {
+			continue
+		}
+		key := strings.ToLower(b.Distro)
+		byDistro[key] = append(byDistro[key], b)
+	}
+
+	hasKeepRules := policy.KeepLast > 0 || policy.KeepDaily > 0 || policy.KeepWeekly > 0 || policy.KeepMonthly > 0
+
+	candidates := []pruneCandidate{}
+	kept := []BackupInfo{}
+	newest := map[string]bool{}
+
+	for _, group := range byDistro {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Timestamp.After(group[j].Timestamp)
+		})
+		newest[group[0].FilePath] = true
+
+		keep := map[string]bool{}
+		if !hasKeepRules {
+			for _, b := range group {
+				keep[b.FilePath] = true
+			}
+		}
+		for i := 0; i < policy.KeepLast && i < len(group); i++ {
+			keep[group[i].FilePath] = true
+		}
+		keepPeriods(group, keep, policy.KeepDaily, func(t time.Time) string {
+			return t.Format("2006-01-02")
+		})
+		keepPeriods(group, keep, policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		})
+		keepPeriods(group, keep, policy.KeepMonthly, func(t time.Time) string {
+			return t.Format("2006-01")
+		})
+
+		for _, b := range group {
+			if keep[b.FilePath] {
+				kept = append(kept, b)
+				continue
+			}
+			candidates = append(candidates, pruneCandidate{backup: b, reason: "not kept by retention policy"})
+			total -= b.Size
+		}
+	}
+
+	if policy.MaxTotalBytes > 0 && total > policy.MaxTotalBytes {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return kept[i].Timestamp.Before(kept[j].Timestamp)
+		})
+		for _, b := range kept {
+			if total <= policy.MaxTotalBytes {
+				break
+			}
+			if newest[b.FilePath] {
+				continue
+			}
+			candidates = append(candidates, pruneCandidate{backup: b, reason: "over max total size"})
+			total -= b.Size
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].backup.Timestamp.Before(candidates[j].backup.Timestamp)
+	})
+
+	return candidates
+}
+
+// keepPeriods marks the most recent backup in each of the n most recent
+// periods (as identified by period) that have backups. group must be
+// sorted newest first.
+func keepPeriods(group []BackupInfo, keep map[string]bool, n int, period func(time.Time) string) {
+	if n <= 0 {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, b := range group {
+		p := period(b.Timestamp)
+		if seen[p] {
+			continue
+		}
+		if len(seen) == n {
+			return
+		}
+		seen[p] = true
+		keep[b.FilePath] = true
+	}
+}
@@ -0,0 +1,201 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wslp/internal/config"
+)
+
+// backupAt builds a BackupInfo for distro taken at the given date
+func backupAt(distro string, year int, month time.Month, day, hour int, size int64) BackupInfo {
+	ts := time.Date(year, month, day, hour, 0, 0, 0, time.Local)
+	return BackupInfo{
+		Distro:    distro,
+		Timestamp: ts,
+		FilePath:  distro + "-" + ts.Format(backupTimestampFormat) + ".tar.gz",
+		Size:      size,
+	}
+}
+
+// prunedPaths returns the file paths of the candidates
+func prunedPaths(candidates []pruneCandidate) []string {
+	paths := make([]string, len(candidates))
+	for i, c := range candidates {
+		paths[i] = c.backup.FilePath
+	}
+	return paths
+}
+
+func TestSelectPrunable(t *testing.T) {
+	t.Run("keeps last N per distro", func(t *testing.T) {
+		backups := []BackupInfo{
+			backupAt("Ubuntu", 2024, 3, 1, 0, 1),
+			backupAt("Ubuntu", 2024, 3, 2, 0, 1),
+			backupAt("Ubuntu", 2024, 3, 3, 0, 1),
+			backupAt("Debian", 2024, 3, 1, 0, 1),
+		}
+
+		got := prunedPaths(selectPrunable(backups, config.RetentionPolicy{KeepLast: 2}))
+
+		if len(got) != 1 || got[0] != backups[0].FilePath {
+			t.Errorf("expected only the oldest Ubuntu backup to be pruned, got %v", got)
+		}
+	})
+
+	t.Run("keeps newest backup of each day", func(t *testing.T) {
+		backups := []BackupInfo{
+			backupAt("Ubuntu", 2024, 3, 1, 9, 1),
+			backupAt("Ubuntu", 2024, 3, 1, 18, 1),
+			backupAt("Ubuntu", 2024, 3, 2, 9, 1),
+			backupAt("Ubuntu", 2024, 3, 3, 9, 1),
+		}
+
+		got := prunedPaths(selectPrunable(backups, config.RetentionPolicy{KeepDaily: 2}))
+
+		// Days 3 and 2 are kept; both backups from day 1 go
+		if len(got) != 2 || got[0] != backups[0].FilePath || got[1] != backups[1].FilePath {
+			t.Errorf("expected both day-1 backups to be pruned, got %v", got)
+		}
+	})
+
+	t.Run("combines rules", func(t *testing.T) {
+		backups := []BackupInfo{
+			backupAt("Ubuntu", 2024, 1, 15, 0, 1),
+			backupAt("Ubuntu", 2024, 2, 15, 0, 1),
+			backupAt("Ubuntu", 2024, 3, 1, 0, 1),
+			backupAt("Ubuntu", 2024, 3, 2, 0, 1),
+		}
+
+		got := prunedPaths(selectPrunable(backups, config.RetentionPolicy{KeepLast: 1, KeepMonthly: 2}))
+
+		// Monthly keeps Mar 2 and Feb 15, last keeps Mar 2
+		if len(got) != 2 || got[0] != backups[0].FilePath || got[1] != backups[2].FilePath {
+			t.Errorf("expected Jan 15 and Mar 1 to be pruned, got %v", got)
+		}
+	})
+
+	t.Run("enforces max total size but keeps newest per distro", func(t *testing.T) {
+		backups := []BackupInfo{
+			backupAt("Ubuntu", 2024, 3, 1, 0, 100),
+			backupAt("Ubuntu", 2024, 3, 2, 0, 100),
+			backupAt("Debian", 2024, 3, 1, 0, 100),
+		}
+
+		candidates := selectPrunable(backups, config.RetentionPolicy{MaxTotalBytes: 150})
+
+		got := prunedPaths(candidates)
+		if len(got) != 1 || got[0] != backups[0].FilePath {
+			t.Errorf("expected oldest Ubuntu backup to be pruned, got %v", got)
+		}
+		if len(candidates) == 1 && !strings.Contains(candidates[0].reason, "size") {
+			t.Errorf("expected size reason, got %q", candidates[0].reason)
+		}
+	})
+
+	t.Run("never prunes backups of unknown distros", func(t *testing.T) {
+		backups := []BackupInfo{
+			{FilePath: "custom.tar.gz", Timestamp: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local), Size: 1000},
+			backupAt("Ubuntu", 2024, 3, 1, 0, 1),
+		}
+
+		got := prunedPaths(selectPrunable(backups, config.RetentionPolicy{KeepLast: 1, MaxTotalBytes: 10}))
+
+		if len(got) != 0 {
+			t.Errorf("expected nothing to be pruned, got %v", got)
+		}
+	})
+}
+
+func TestPruneBackups(t *testing.T) {
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir,
+			"Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240101-000000.tar.gz.json",
+			"Ubuntu-20240201-000000.tar.gz",
+			"Ubuntu-20240301-000000.tar.gz",
+		)
+		return dir
+	}
+
+	t.Run("returns error when no policy is configured", func(t *testing.T) {
+		if _, err := PruneBackups(setup(t), config.RetentionPolicy{}, false); err == nil {
+			t.Error("expected error for empty policy")
+		}
+	})
+
+	t.Run("dry run deletes nothing", func(t *testing.T) {
+		dir := setup(t)
+
+		results, err := PruneBackups(dir, config.RetentionPolicy{KeepLast: 1}, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+		for _, r := range results {
+			if !strings.Contains(r.Message, "Would delete") {
+				t.Errorf("expected dry-run message, got %q", r.Message)
+			}
+			if _, err := os.Stat(r.FilePath); err != nil {
+				t.Errorf("expected %s to still exist", r.FilePath)
+			}
+		}
+	})
+
+	t.Run("deletes archives and manifests", func(t *testing.T) {
+		dir := setup(t)
+
+		results, err := PruneBackups(dir, config.RetentionPolicy{KeepLast: 1}, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+		for _, r := range results {
+			if !r.Success {
+				t.Errorf("expected success, got %q", r.Message)
+			}
+		}
+
+		remaining, _ := filepath.Glob(filepath.Join(dir, "*"))
+		if len(remaining) != 1 || filepath.Base(remaining[0]) != "Ubuntu-20240301-000000.tar.gz" {
+			t.Errorf("expected only the newest backup to remain, got %v", remaining)
+		}
+	})
+}
+
+func TestAutoPrune(t *testing.T) {
+	dir := t.TempDir()
+	writeBackupFiles(t, dir, "Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz")
+	policy := config.RetentionPolicy{KeepLast: 1}
+
+	t.Run("does nothing when all backups failed", func(t *testing.T) {
+		results, err := AutoPrune([]BackupResult{{Distro: "Ubuntu"}}, dir, policy)
+		if err != nil || len(results) != 0 {
+			t.Errorf("expected no pruning, got %v (err %v)", results, err)
+		}
+	})
+
+	t.Run("does nothing without a policy", func(t *testing.T) {
+		results, err := AutoPrune([]BackupResult{{Distro: "Ubuntu", Success: true}}, dir, config.RetentionPolicy{})
+		if err != nil || len(results) != 0 {
+			t.Errorf("expected no pruning, got %v (err %v)", results, err)
+		}
+	})
+
+	t.Run("prunes after a successful backup", func(t *testing.T) {
+		results, err := AutoPrune([]BackupResult{{Distro: "Ubuntu", Success: true}}, dir, policy)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || filepath.Base(results[0].FilePath) != "Ubuntu-20240101-000000.tar.gz" {
+			t.Errorf("expected oldest backup to be pruned, got %v", results)
+		}
+	})
+}
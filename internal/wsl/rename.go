@@ -7,6 +7,8 @@ import (
 
 	gowsl "github.com/ubuntu/gowsl"
 	"golang.org/x/sys/windows/registry"
+
+	"wslp/internal/audit"
 )
 
 // RenameResult contains the result of renaming a distro
@@ -95,6 +97,14 @@ func RenameDistro(ctx context.Context, r Renamer, oldName, newName string) Renam
 		return result
 	}
 
+	// Also lock the new name, so nothing else can take it meanwhile
+	unlock, err := lockDistros(ctx, "rename", oldName, newName)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer unlock()
+
 	// Check if old distro exists
 	registered, err := r.IsRegistered(ctx, oldName)
 	if err != nil {
@@ -124,8 +134,21 @@ func RenameDistro(ctx context.Context, r Renamer, oldName, newName string) Renam
 		return result
 	}
 
+	if IsDryRun(ctx) {
+		result.Success = true
+		result.Message = fmt.Sprintf("Would rename %s to %s (dry run)", oldName, newName)
+		return result
+	}
+
 	// Rename in registry
 	err = r.RenameInRegistry(guid, newName)
+	recordAudit(ctx, audit.Entry{
+		Action:  audit.ActionRename,
+		Distro:  oldName,
+		Details: map[string]string{"newName": newName, "guid": guid},
+		Success: err == nil,
+		Error:   errorMessage(err),
+	})
 	if err != nil {
 		result.Message = fmt.Sprintf("Failed to rename: %v", err)
 		return result
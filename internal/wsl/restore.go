@@ -0,0 +1,204 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"wslp/internal/audit"
+)
+
+// RestoreResult contains the result of restoring a distro from a backup
+type RestoreResult struct {
+	Distro   string `json:"distro"`
+	NewName  string `json:"newName"`
+	FilePath string `json:"filePath"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+}
+
+// RestoreOptions contains options for restore operations
+type RestoreOptions struct {
+	// Timestamp selects the backup to restore (YYYYMMDD-HHMMSS, as used in
+	// backup filenames). Empty or "latest" selects the most recent backup.
+	Timestamp string
+	// File restores a specific archive instead of looking one up by distro
+	// name and timestamp. Required for backups saved with a custom name.
+	File string
+	// NewName registers the restored distro under a different name.
+	// If empty, the original distro name is used.
+	NewName string
+	// InstallDir is where WSL stores the restored distro's virtual disk.
+	// If empty it defaults to %USERPROFILE%\WSLRestores\<name>.
+	InstallDir string
+	// Overwrite unregisters an existing distro with the target name before
+	// importing, once the backup has been verified. Without it, restoring
+	// onto a registered name is refused.
+	Overwrite bool
+}
+
+// Restorer interface for restoring distros from backup archives
+type Restorer interface {
+	IsRegistered(ctx context.Context, name string) (bool, error)
+	Unregister(ctx context.Context, name string) error
+	Import(ctx context.Context, newName, tarPath, installDir string) error
+}
+
+// RealRestorer implements Restorer using gowsl
+type RealRestorer struct{}
+
+// IsRegistered checks if a distro is registered
+func (r RealRestorer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return RealBackuper{}.IsRegistered(ctx, name)
+}
+
+// Unregister unregisters a distro that is about to be overwritten
+func (r RealRestorer) Unregister(ctx context.Context, name string) error {
+	return RealUnregisterer{}.Unregister(ctx, name)
+}
+
+// Import imports a distro from a backup archive of any supported format
+func (r RealRestorer) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	return importArchive(ctx, newName, tarPath, installDir)
+}
+
+// RestoreDistro re-imports a backup of distro found in backupDir.
+// The backup is selected by opts.File, or else by opts.Timestamp among the
+// backups of distro. The restored distro is registered as opts.NewName, or
+// as distro if no new name is given.
+func RestoreDistro(ctx context.Context, r Restorer, distro, backupDir string, opts RestoreOptions) RestoreResult {
+	result := RestoreResult{
+		Distro:  distro,
+		NewName: opts.NewName,
+		Success: false,
+	}
+
+	if distro == "" && opts.File == "" {
+		result.Message = "No distro or backup file specified"
+		return result
+	}
+
+	if result.NewName == "" {
+		result.NewName = distro
+	}
+	if result.NewName == "" {
+		result.Message = "A new name is required when restoring from a file"
+		return result
+	}
+
+	// Select the archive to restore
+	if opts.File != "" {
+		if _, err := os.Stat(opts.File); err != nil {
+			result.Message = fmt.Sprintf("Backup file not found: %v", err)
+			return result
+		}
+		result.FilePath = opts.File
+	} else {
+		backups, err := ListBackups(backupDir)
+		if err != nil {
+			result.Message = fmt.Sprintf("Error listing backups: %v", err)
+			return result
+		}
+		backup, err := FindBackup(backups, distro, opts.Timestamp)
+		if err != nil {
+			result.Message = fmt.Sprintf("Backup not found: %v", err)
+			return result
+		}
+		result.FilePath = backup.FilePath
+	}
+
+	unlock, err := lockDistros(ctx, "restore", result.NewName)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer unlock()
+
+	// Refuse to clobber a registered distro unless asked to
+	exists, err := r.IsRegistered(ctx, result.NewName)
+	if err != nil {
+		result.Message = fmt.Sprintf("Error checking registration: %v", err)
+		return result
+	}
+	if exists && !opts.Overwrite {
+		result.Message = fmt.Sprintf("Distro %s already exists (use overwrite to replace it)", result.NewName)
+		return result
+	}
+
+	// Only replace a distro with a backup known to be intact and
+	// decryptable, or a bad backup would lose both
+	if exists {
+		if err := checkRestorable(result.FilePath); err != nil {
+			result.Message = fmt.Sprintf("Not replacing %s: %v", result.NewName, err)
+			return result
+		}
+	}
+
+	if IsDryRun(ctx) {
+		result.Success = true
+		result.Message = fmt.Sprintf("Would restore %s from %s (dry run)", result.NewName, filepath.Base(result.FilePath))
+		return result
+	}
+
+	// Resolve install dir
+	installDir := opts.InstallDir
+	if installDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			result.Message = fmt.Sprintf("Failed to resolve home directory: %v", err)
+			return result
+		}
+		installDir = filepath.Join(home, "WSLRestores", result.NewName)
+	}
+
+	if err := os.MkdirAll(installDir, 0755); err != nil {
+		result.Message = fmt.Sprintf("Failed to create install directory: %v", err)
+		return result
+	}
+
+	if exists {
+		err := r.Unregister(ctx, result.NewName)
+		recordAudit(ctx, audit.Entry{
+			Action:  audit.ActionUnregister,
+			Distro:  result.NewName,
+			Success: err == nil,
+			Error:   errorMessage(err),
+			Details: map[string]string{"replacedBy": "restore", "backup": result.FilePath},
+		})
+		if err != nil {
+			result.Message = fmt.Sprintf("Failed to unregister existing distro: %v", err)
+			return result
+		}
+
+		// The old distro is gone, so don't let a cancelled request or a
+		// deadline stop the import and leave neither
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	if err := r.Import(ctx, result.NewName, result.FilePath, installDir); err != nil {
+		result.Message = fmt.Sprintf("Import failed: %v", err)
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Successfully restored %s from %s", result.NewName, filepath.Base(result.FilePath))
+	return result
+}
+
+// checkRestorable verifies a backup before it replaces a registered distro:
+// that it is intact and, if encrypted, that a key to decrypt it is
+// configured
+func checkRestorable(archivePath string) error {
+	verify := VerifyBackup(archivePath)
+	if !verify.Success {
+		return errors.New(verify.Message)
+	}
+	if verify.Encrypted {
+		if _, err := decryptionIdentities(); err != nil {
+			return fmt.Errorf("backup can't be decrypted: %w", err)
+		}
+	}
+	return nil
+}
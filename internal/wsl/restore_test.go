@@ -0,0 +1,303 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wslp/internal/audit"
+)
+
+type mockRestorer struct {
+	isRegisteredResults map[string]bool
+	isRegisteredErrors  map[string]error
+	unregisterErr       error
+	importErr           error
+	unregistered        []string
+	importedPath        string
+	// onUnregister runs after a successful Unregister
+	onUnregister func()
+}
+
+func (m *mockRestorer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	if err, ok := m.isRegisteredErrors[name]; ok {
+		return false, err
+	}
+	return m.isRegisteredResults[name], nil
+}
+
+func (m *mockRestorer) Unregister(ctx context.Context, name string) error {
+	if m.unregisterErr != nil {
+		return m.unregisterErr
+	}
+	m.unregistered = append(m.unregistered, name)
+	if m.onUnregister != nil {
+		m.onUnregister()
+	}
+	return nil
+}
+
+func (m *mockRestorer) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	if m.importErr != nil {
+		return m.importErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.importedPath = tarPath
+	return nil
+}
+
+func TestRestoreDistro(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz")
+		return dir
+	}
+
+	t.Run("restores latest backup under original name", func(t *testing.T) {
+		dir := setup(t)
+		mock := &mockRestorer{}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{InstallDir: t.TempDir()})
+
+		if !result.Success {
+			t.Fatalf("expected success, got message: %s", result.Message)
+		}
+		if result.NewName != "Ubuntu" {
+			t.Errorf("expected new name Ubuntu, got %s", result.NewName)
+		}
+		if filepath.Base(mock.importedPath) != "Ubuntu-20240301-000000.tar.gz" {
+			t.Errorf("expected latest backup to be imported, got %s", mock.importedPath)
+		}
+	})
+
+	t.Run("restores backup by timestamp under new name", func(t *testing.T) {
+		dir := setup(t)
+		mock := &mockRestorer{}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{
+			Timestamp:  "20240101-000000",
+			NewName:    "Ubuntu-Old",
+			InstallDir: t.TempDir(),
+		})
+
+		if !result.Success {
+			t.Fatalf("expected success, got message: %s", result.Message)
+		}
+		if result.NewName != "Ubuntu-Old" {
+			t.Errorf("expected new name Ubuntu-Old, got %s", result.NewName)
+		}
+		if filepath.Base(mock.importedPath) != "Ubuntu-20240101-000000.tar.gz" {
+			t.Errorf("expected selected backup to be imported, got %s", mock.importedPath)
+		}
+	})
+
+	t.Run("restores a specific file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "custom.tar")
+		mock := &mockRestorer{}
+
+		result := RestoreDistro(ctx, mock, "", dir, RestoreOptions{
+			File:       filepath.Join(dir, "custom.tar"),
+			NewName:    "Custom",
+			InstallDir: t.TempDir(),
+		})
+
+		if !result.Success {
+			t.Fatalf("expected success, got message: %s", result.Message)
+		}
+	})
+
+	t.Run("requires a new name when restoring a file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBackupFiles(t, dir, "custom.tar")
+
+		result := RestoreDistro(ctx, &mockRestorer{}, "", dir, RestoreOptions{File: filepath.Join(dir, "custom.tar")})
+
+		if result.Success {
+			t.Error("expected failure without a new name")
+		}
+	})
+
+	t.Run("fails when no backup exists", func(t *testing.T) {
+		result := RestoreDistro(ctx, &mockRestorer{}, "Debian", setup(t), RestoreOptions{})
+
+		if result.Success {
+			t.Error("expected failure for distro without backups")
+		}
+		if !strings.Contains(result.Message, "Backup not found") {
+			t.Errorf("expected 'Backup not found' message, got: %s", result.Message)
+		}
+	})
+
+	t.Run("refuses to overwrite a registered distro", func(t *testing.T) {
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", setup(t), RestoreOptions{InstallDir: t.TempDir()})
+
+		if result.Success {
+			t.Error("expected failure when target is registered")
+		}
+		if !strings.Contains(result.Message, "already exists") {
+			t.Errorf("expected 'already exists' message, got: %s", result.Message)
+		}
+		if mock.importedPath != "" {
+			t.Error("expected no import to happen")
+		}
+	})
+
+	t.Run("overwrites a registered distro when asked", func(t *testing.T) {
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		dir := t.TempDir()
+		writeTestArchive(t, dir, "Ubuntu-20240301-000000.tar.gz", buildTestArchive(testRootFS))
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+
+		if !result.Success {
+			t.Fatalf("expected success, got message: %s", result.Message)
+		}
+		if len(mock.unregistered) != 1 || mock.unregistered[0] != "Ubuntu" {
+			t.Errorf("expected Ubuntu to be unregistered first, got %v", mock.unregistered)
+		}
+	})
+
+	t.Run("imports after replacing a distro even if cancelled", func(t *testing.T) {
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}, onUnregister: cancel}
+		dir := t.TempDir()
+		archive := writeTestArchive(t, dir, "Ubuntu-20240301-000000.tar.gz", buildTestArchive(testRootFS))
+
+		result := RestoreDistro(cancelCtx, mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+
+		if !result.Success {
+			t.Fatalf("expected the import to finish, got message: %s", result.Message)
+		}
+		if mock.importedPath != archive {
+			t.Errorf("expected %s to be imported, got %q", archive, mock.importedPath)
+		}
+	})
+
+	t.Run("records replacing a distro in the audit log", func(t *testing.T) {
+		log := useAuditLog(t)
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		dir := t.TempDir()
+		archive := writeTestArchive(t, dir, "Ubuntu-20240301-000000.tar.gz", buildTestArchive(testRootFS))
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+		if !result.Success {
+			t.Fatalf("expected success, got message: %s", result.Message)
+		}
+
+		entries, err := log.Read(audit.Filter{Action: audit.ActionUnregister})
+		if err != nil {
+			t.Fatalf("failed to read the audit log: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Distro != "Ubuntu" || !entries[0].Success {
+			t.Fatalf("expected the unregister to be recorded, got %+v", entries)
+		}
+		if entries[0].Details["replacedBy"] != "restore" || entries[0].Details["backup"] != archive {
+			t.Errorf("expected restore details, got %v", entries[0].Details)
+		}
+	})
+
+	t.Run("keeps a registered distro if the backup is corrupt", func(t *testing.T) {
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		dir := t.TempDir()
+		valid := buildTestArchive(testRootFS)
+		writeTestArchive(t, dir, "Ubuntu-20240301-000000.tar.gz", valid[:len(valid)/2])
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+
+		if result.Success {
+			t.Fatal("expected failure for a truncated backup")
+		}
+		if !strings.Contains(result.Message, "Not replacing Ubuntu") {
+			t.Errorf("expected the distro to be kept, got: %s", result.Message)
+		}
+		if len(mock.unregistered) != 0 || mock.importedPath != "" {
+			t.Errorf("expected no unregister or import, got %v and %q", mock.unregistered, mock.importedPath)
+		}
+	})
+
+	t.Run("keeps a registered distro if the backup can't be decrypted", func(t *testing.T) {
+		useEncryptionKey(t)
+		dir := t.TempDir()
+		backuper := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		if results := BackupDistros(ctx, backuper, []string{"Ubuntu"}, dir, BackupOptions{Encrypt: true}); !results[0].Success {
+			t.Fatalf("expected successful backup, got %+v", results)
+		}
+		useNoEncryptionKey(t)
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+
+		if result.Success || len(mock.unregistered) != 0 {
+			t.Errorf("expected the distro to be kept, got %+v (unregistered %v)", result, mock.unregistered)
+		}
+	})
+
+	t.Run("dry run doesn't unregister or import", func(t *testing.T) {
+		mock := &mockRestorer{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		dir := t.TempDir()
+		writeTestArchive(t, dir, "Ubuntu-20240301-000000.tar.gz", buildTestArchive(testRootFS))
+
+		result := RestoreDistro(WithDryRun(ctx), mock, "Ubuntu", dir, RestoreOptions{
+			InstallDir: t.TempDir(),
+			Overwrite:  true,
+		})
+
+		if !result.Success || !strings.Contains(result.Message, "dry run") {
+			t.Errorf("expected a dry run success, got %+v", result)
+		}
+		if len(mock.unregistered) != 0 || mock.importedPath != "" {
+			t.Errorf("expected no unregister or import, got %v and %q", mock.unregistered, mock.importedPath)
+		}
+	})
+
+	t.Run("handles IsRegistered error", func(t *testing.T) {
+		mock := &mockRestorer{isRegisteredErrors: map[string]error{"Ubuntu": errors.New("check failed")}}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", setup(t), RestoreOptions{InstallDir: t.TempDir()})
+
+		if result.Success {
+			t.Error("expected failure when IsRegistered errors")
+		}
+		if !strings.Contains(result.Message, "Error checking registration") {
+			t.Errorf("expected registration error message, got: %s", result.Message)
+		}
+	})
+
+	t.Run("handles Import error", func(t *testing.T) {
+		mock := &mockRestorer{importErr: errors.New("import failed")}
+
+		result := RestoreDistro(ctx, mock, "Ubuntu", setup(t), RestoreOptions{InstallDir: t.TempDir()})
+
+		if result.Success {
+			t.Error("expected failure when Import errors")
+		}
+		if !strings.Contains(result.Message, "Import failed") {
+			t.Errorf("expected 'Import failed' message, got: %s", result.Message)
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"wslp/internal/config"
+)
+
+// SelectionError is returned when a selector is invalid or picks no
+// distros
+type SelectionError struct {
+	Reason string
+}
+
+func (e *SelectionError) Error() string {
+	return e.Reason
+}
+
+// IsSelectionError reports whether err is, or wraps, a SelectionError
+func IsSelectionError(err error) bool {
+	var sel *SelectionError
+	return errors.As(err, &sel)
+}
+
+// selectionErrorf formats a SelectionError
+func selectionErrorf(format string, args ...interface{}) error {
+	return &SelectionError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Selector picks the distros a bulk operation acts on, by name, glob
+// pattern, tag or state. The selector flags of the bulk commands and the
+// request bodies of the server's bulk endpoints both use it.
+type Selector struct {
+	// Distros are names or glob patterns such as Ubuntu-*. Names are used
+	// as given, even if they aren't registered, so the operation reports
+	// them; patterns must match at least one distro.
+	Distros []string `json:"distros,omitempty"`
+	// All selects every registered distro
+	All bool `json:"all,omitempty"`
+	// Running and Stopped select the distros in that state, or only keep
+	// those in that state among the distros selected otherwise
+	Running bool `json:"running,omitempty"`
+	Stopped bool `json:"stopped,omitempty"`
+	// Tags selects the distros given any of these tags under distro_tags
+	// in the config
+	Tags []string `json:"tags,omitempty"`
+	// Exclude drops the distros matching any of these names or patterns
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Empty reports whether the selector selects nothing at all
+func (s Selector) Empty() bool {
+	return len(s.Distros) == 0 && !s.All && !s.Running && !s.Stopped && len(s.Tags) == 0
+}
+
+// Explicit reports whether the selector only names distros, so resolving
+// it needs no list of the registered distros
+func (s Selector) Explicit() bool {
+	if s.All || s.Running || s.Stopped || len(s.Tags) > 0 {
+		return false
+	}
+	for _, name := range s.Distros {
+		if isPattern(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks the selector selects something and its patterns are
+// well formed
+func (s Selector) Validate() error {
+	if s.Empty() {
+		return selectionErrorf("no distros specified")
+	}
+	if s.Running && s.Stopped {
+		return selectionErrorf("running and stopped can't be selected together")
+	}
+	for _, pattern := range append(append([]string{}, s.Distros...), s.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return selectionErrorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the names of the distros the selector picks from
+// distros, in the order they were selected. tags maps distro names, in
+// lower case, to their tags.
+func (s Selector) Resolve(distros []DistroInfo, tags map[string][]string) ([]string, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+
+	// Running or stopped alone select from every distro
+	if s.All || (len(s.Distros) == 0 && len(s.Tags) == 0) {
+		for _, d := range distros {
+			add(d.Name)
+		}
+	}
+	for _, name := range s.Distros {
+		if !isPattern(name) {
+			add(name)
+			continue
+		}
+		matched := false
+		for _, d := range distros {
+			if matchName(name, d.Name) {
+				add(d.Name)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, selectionErrorf("no distro matches %q", name)
+		}
+	}
+	for _, tag := range s.Tags {
+		matched := false
+		for _, d := range distros {
+			if hasTag(tags[strings.ToLower(d.Name)], tag) {
+				add(d.Name)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, selectionErrorf("no registered distro is tagged %q", tag)
+		}
+	}
+
+	selected := names[:0]
+	for _, name := range names {
+		if s.Running || s.Stopped {
+			d, ok := findDistro(distros, name)
+			if !ok || d.Running != s.Running {
+				continue
+			}
+		}
+		if s.excludes(name) {
+			continue
+		}
+		selected = append(selected, name)
+	}
+
+	if len(selected) == 0 {
+		return nil, selectionErrorf("no distros match the selection")
+	}
+	return selected, nil
+}
+
+// excludes reports whether name matches one of the selector's exclusions
+func (s Selector) excludes(name string) bool {
+	for _, pattern := range s.Exclude {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectDistros returns the registered distros sel picks. Explicit
+// selectors are resolved without listing the distros.
+func SelectDistros(ctx context.Context, l Lister, sel Selector) ([]string, error) {
+	if sel.Explicit() {
+		return sel.Resolve(nil, nil)
+	}
+
+	distros, err := ListDistros(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Resolve(distros, config.GetDistroTags())
+}
+
+// SelectAvailableDistros returns the distros to install that sel picks
+// from the ones available online. Only names and patterns can select
+// them, as the other selectors apply to registered distros.
+func SelectAvailableDistros(ctx context.Context, sel Selector) ([]string, error) {
+	if sel.All || sel.Running || sel.Stopped || len(sel.Tags) > 0 {
+		return nil, selectionErrorf("only names and patterns can select distros to install")
+	}
+	if sel.Explicit() {
+		return sel.Resolve(nil, nil)
+	}
+
+	available, err := GetAvailableDistros(ctx)
+	if err != nil {
+		return nil, err
+	}
+	distros := make([]DistroInfo, len(available))
+	for i, a := range available {
+		distros[i] = DistroInfo{Name: a.Name}
+	}
+	return sel.Resolve(distros, nil)
+}
+
+// isPattern reports whether name is a glob pattern rather than a name
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchName reports whether name matches pattern, ignoring case as
+// Windows does for distro names
+func matchName(pattern, name string) bool {
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return matched
+}
+
+// hasTag reports whether tags contains tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// findDistro returns the distro in distros named name
+func findDistro(distros []DistroInfo, name string) (DistroInfo, bool) {
+	for _, d := range distros {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return DistroInfo{}, false
+}
@@ -0,0 +1,102 @@
+package wsl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelectorResolve(t *testing.T) {
+	distros := []DistroInfo{
+		{Name: "Ubuntu-22.04", Running: true},
+		{Name: "Ubuntu-24.04"},
+		{Name: "Debian", Running: true},
+		{Name: "docker-desktop", Running: true},
+		{Name: "docker-desktop-data"},
+	}
+	tags := map[string][]string{
+		"ubuntu-24.04": {"dev"},
+		"debian":       {"Dev", "work"},
+	}
+
+	tests := []struct {
+		name string
+		sel  Selector
+		want string
+	}{
+		{"names as given", Selector{Distros: []string{"Missing", "debian"}}, "Missing, debian"},
+		{"glob patterns", Selector{Distros: []string{"ubuntu-*"}}, "Ubuntu-22.04, Ubuntu-24.04"},
+		{"all", Selector{All: true}, "Ubuntu-22.04, Ubuntu-24.04, Debian, docker-desktop, docker-desktop-data"},
+		{"all but excluded", Selector{All: true, Exclude: []string{"docker-desktop*", "Debian"}}, "Ubuntu-22.04, Ubuntu-24.04"},
+		{"running", Selector{Running: true}, "Ubuntu-22.04, Debian, docker-desktop"},
+		{"stopped", Selector{Stopped: true}, "Ubuntu-24.04, docker-desktop-data"},
+		{"running among patterns", Selector{Distros: []string{"Ubuntu-*"}, Running: true}, "Ubuntu-22.04"},
+		{"tags", Selector{Tags: []string{"dev"}}, "Ubuntu-24.04, Debian"},
+		{"without duplicates", Selector{Distros: []string{"Debian", "D*"}, Tags: []string{"work"}}, "Debian, docker-desktop, docker-desktop-data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sel.Resolve(distros, tags)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if strings.Join(got, ", ") != tt.want {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	failures := []struct {
+		name string
+		sel  Selector
+		want string
+	}{
+		{"nothing selected", Selector{}, "no distros specified"},
+		{"pattern without matches", Selector{Distros: []string{"arch*"}}, `"arch*"`},
+		{"unknown tag", Selector{Tags: []string{"prod"}}, `"prod"`},
+		{"running and stopped", Selector{Running: true, Stopped: true}, "together"},
+		{"bad pattern", Selector{Distros: []string{"Ubuntu["}}, "invalid pattern"},
+		{"everything excluded", Selector{Distros: []string{"Debian"}, Exclude: []string{"*"}}, "no distros match"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.sel.Resolve(distros, tags)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !IsSelectionError(err) {
+				t.Errorf("expected a selection error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSelectDistros(t *testing.T) {
+	t.Run("doesn't list distros for explicit names", func(t *testing.T) {
+		names, err := SelectDistros(context.Background(), failingLister{}, Selector{Distros: []string{"Ubuntu", "Debian"}, Exclude: []string{"debian"}})
+		if err != nil || strings.Join(names, ",") != "Ubuntu" {
+			t.Errorf("got %v, %v", names, err)
+		}
+	})
+
+	t.Run("reports listing errors", func(t *testing.T) {
+		if _, err := SelectDistros(context.Background(), failingLister{}, Selector{All: true}); err == nil || IsSelectionError(err) {
+			t.Errorf("expected the listing error, got %v", err)
+		}
+	})
+
+	t.Run("only names and patterns select distros to install", func(t *testing.T) {
+		if _, err := SelectAvailableDistros(context.Background(), Selector{Running: true}); err == nil {
+			t.Error("expected an error")
+		}
+		names, err := SelectAvailableDistros(context.Background(), Selector{Distros: []string{"Ubuntu"}})
+		if err != nil || len(names) != 1 {
+			t.Errorf("got %v, %v", names, err)
+		}
+	})
+}
+
+// failingLister fails to list distros
+type failingLister struct{}
+
+func (failingLister) List(ctx context.Context) ([]string, error) {
+	return nil, errors.New("wsl.exe not found")
+}
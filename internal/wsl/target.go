@@ -0,0 +1,369 @@
+package wsl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"wslp/internal/config"
+)
+
+// minPartSize is the smallest part S3 accepts in a multipart upload, other
+// than the last
+const minPartSize = 5 << 20
+
+// maxParts is the most parts S3 accepts in a multipart upload
+const maxParts = 10000
+
+// BackupTarget is where finished backups are stored. Backups are always
+// exported, verified and encrypted in a local directory first; the target
+// then takes the archive and its manifest.
+type BackupTarget interface {
+	// Store moves the file at localPath to the target under its base name
+	Store(ctx context.Context, localPath string) error
+	// URI identifies the file called name on the target
+	URI(name string) string
+}
+
+// NewBackupTarget creates the target described by cfg
+func NewBackupTarget(cfg config.BackupTarget) (BackupTarget, error) {
+	switch cfg.Type {
+	case config.TargetLocal:
+		return LocalTarget{Dir: cfg.Path}, nil
+	case config.TargetSMB:
+		return SMBTarget{Path: cfg.Path}, nil
+	case config.TargetS3:
+		return NewS3Target(cfg)
+	}
+
+	return nil, fmt.Errorf("unsupported backup target type %q", cfg.Type)
+}
+
+// ResolveBackupDestination resolves where a backup goes, for the CLI, the
+// server and the TUI alike. It returns the directory the backup is
+// written to and, unless that is where it stays, the target it is then
+// stored in. targetName is a name from backup_targets or a target URI,
+// and defaults to backup_target. An explicit dir saves there unless a
+// target is also given; backups to other than a local directory are
+// staged in dir or the configured backup directory.
+func ResolveBackupDestination(targetName, dir string) (string, BackupTarget, error) {
+	explicitDir := dir != ""
+	if !explicitDir {
+		dir = config.GetBackupDir()
+	}
+	if targetName == "" && explicitDir {
+		return dir, nil, nil
+	}
+
+	cfg, err := config.GetBackupTarget(targetName)
+	if err != nil {
+		return "", nil, err
+	}
+	target, err := NewBackupTarget(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	if local, ok := target.(LocalTarget); ok {
+		return local.Dir, nil, nil
+	}
+
+	return dir, target, nil
+}
+
+// LocalTarget stores backups in a directory on the local machine
+type LocalTarget struct {
+	Dir string
+}
+
+// Store moves localPath into the directory. Backups staged in the
+// directory itself are left where they are.
+func (t LocalTarget) Store(ctx context.Context, localPath string) error {
+	return moveToDir(ctx, localPath, t.Dir)
+}
+
+// URI returns a file:// URI for name
+func (t LocalTarget) URI(name string) string {
+	abs, err := filepath.Abs(filepath.Join(t.Dir, name))
+	if err != nil {
+		abs = filepath.Join(t.Dir, name)
+	}
+
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		// C:/path becomes file:///C:/path
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// LocalPath returns the path of a file:// URI, as returned by LocalTarget
+func LocalPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	p := u.Path
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), true
+}
+
+// SMBTarget stores backups on a Windows network share, given as a UNC path
+// such as \\nas\backups\wsl. Windows opens UNC paths like local ones, so
+// no SMB client is needed.
+type SMBTarget struct {
+	Path string
+}
+
+// Store copies localPath to the share and removes the local copy
+func (t SMBTarget) Store(ctx context.Context, localPath string) error {
+	return moveToDir(ctx, localPath, t.Path)
+}
+
+// URI returns an smb:// URI for name
+func (t SMBTarget) URI(name string) string {
+	unc := strings.Trim(strings.ReplaceAll(t.Path, `\`, "/"), "/")
+	server, share, _ := strings.Cut(unc, "/")
+	return (&url.URL{Scheme: "smb", Host: server, Path: path.Join("/", share, name)}).String()
+}
+
+// moveToDir moves a file into dir, copying it when a rename isn't possible,
+// e.g. across drives or onto a network share. The copy is written under a
+// temporary name and renamed once complete, so an interrupted transfer
+// never looks like a finished backup.
+func moveToDir(ctx context.Context, localPath, dir string) error {
+	dest := filepath.Join(dir, filepath.Base(localPath))
+	if same, _ := sameFile(localPath, dest); same {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(localPath, dest); err == nil {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	partial := dest + ".partial"
+	dst, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partial, dest)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to copy %s to %s: %w", filepath.Base(localPath), dir, err)
+	}
+
+	src.Close()
+	return os.Remove(localPath)
+}
+
+// sameFile reports whether two paths refer to the same existing file
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+// objectStore is the part of the S3 API used to upload backups. It is
+// implemented with minio-go for real services and faked in tests.
+type objectStore interface {
+	CreateMultipartUpload(ctx context.Context, bucket, key string) (uploadID string, err error)
+	UploadPart(ctx context.Context, bucket, key, uploadID string, part int, data io.Reader, size int64) (etag string, err error)
+	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, etags []string) error
+	AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error
+}
+
+// S3Target stores backups in a bucket of Amazon S3 or any S3-compatible
+// service such as MinIO
+type S3Target struct {
+	Bucket string
+	// Prefix is prepended to object keys, e.g. wsl/Ubuntu-20240301-143022.tar.gz
+	Prefix string
+	// PartSize is the size of each part of a multipart upload, and the
+	// most memory an upload uses. Files too large to upload in maxParts
+	// parts of this size use larger parts.
+	PartSize int64
+	store    objectStore
+}
+
+// NewS3Target connects to the service described by cfg
+func NewS3Target(cfg config.BackupTarget) (*S3Target, error) {
+	core, err := minio.NewCore(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", cfg.Endpoint, err)
+	}
+
+	return newS3Target(cfg, minioStore{core}), nil
+}
+
+// newS3Target creates an S3 target that uploads through store
+func newS3Target(cfg config.BackupTarget, store objectStore) *S3Target {
+	partSize := cfg.PartSize
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+
+	return &S3Target{
+		Bucket:   cfg.Bucket,
+		Prefix:   strings.Trim(cfg.Prefix, "/"),
+		PartSize: partSize,
+		store:    store,
+	}
+}
+
+// Store uploads localPath and removes the local copy. The file is streamed
+// in parts of PartSize bytes, or larger ones for files that would need more
+// than maxParts, so uploads use bounded memory. A failed upload is aborted
+// so the service discards its parts.
+func (t *S3Target) Store(ctx context.Context, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := t.upload(ctx, t.key(filepath.Base(localPath)), f, t.partSize(info.Size())); err != nil {
+		return fmt.Errorf("failed to upload %s to s3://%s: %w", filepath.Base(localPath), t.Bucket, err)
+	}
+
+	f.Close()
+	return os.Remove(localPath)
+}
+
+// partSize returns the part size to upload size bytes in at most maxParts
+// parts: PartSize, or else the smallest whole number of MiB that fits
+func (t *S3Target) partSize(size int64) int64 {
+	if size <= t.PartSize*maxParts {
+		return t.PartSize
+	}
+	const mib = 1 << 20
+	perPart := (size + maxParts - 1) / maxParts
+	return (perPart + mib - 1) / mib * mib
+}
+
+// upload streams r to key as a multipart upload in parts of partSize bytes
+func (t *S3Target) upload(ctx context.Context, key string, r io.Reader, partSize int64) error {
+	uploadID, err := t.store.CreateMultipartUpload(ctx, t.Bucket, key)
+	if err != nil {
+		return err
+	}
+
+	var etags []string
+	buf := make([]byte, partSize)
+	for part := 1; ; part++ {
+		n, readErr := io.ReadFull(r, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			t.store.AbortMultipartUpload(context.Background(), t.Bucket, key, uploadID)
+			return readErr
+		}
+
+		// An empty file still needs one (empty) part
+		if n > 0 || part == 1 {
+			etag, err := t.store.UploadPart(ctx, t.Bucket, key, uploadID, part, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				t.store.AbortMultipartUpload(context.Background(), t.Bucket, key, uploadID)
+				return fmt.Errorf("part %d: %w", part, err)
+			}
+			etags = append(etags, etag)
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := t.store.CompleteMultipartUpload(ctx, t.Bucket, key, uploadID, etags); err != nil {
+		t.store.AbortMultipartUpload(context.Background(), t.Bucket, key, uploadID)
+		return err
+	}
+
+	return nil
+}
+
+// URI returns an s3:// URI for name
+func (t *S3Target) URI(name string) string {
+	return "s3://" + t.Bucket + "/" + t.key(name)
+}
+
+// key returns the object key for name
+func (t *S3Target) key(name string) string {
+	if t.Prefix == "" {
+		return name
+	}
+	return t.Prefix + "/" + name
+}
+
+// minioStore implements objectStore with minio-go's low-level client
+type minioStore struct {
+	core *minio.Core
+}
+
+func (m minioStore) CreateMultipartUpload(ctx context.Context, bucket, key string) (string, error) {
+	return m.core.NewMultipartUpload(ctx, bucket, key, minio.PutObjectOptions{})
+}
+
+func (m minioStore) UploadPart(ctx context.Context, bucket, key, uploadID string, part int, data io.Reader, size int64) (string, error) {
+	p, err := m.core.PutObjectPart(ctx, bucket, key, uploadID, part, data, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return p.ETag, nil
+}
+
+func (m minioStore) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, etags []string) error {
+	parts := make([]minio.CompletePart, len(etags))
+	for i, etag := range etags {
+		parts[i] = minio.CompletePart{PartNumber: i + 1, ETag: etag}
+	}
+	_, err := m.core.CompleteMultipartUpload(ctx, bucket, key, uploadID, parts, minio.PutObjectOptions{})
+	return err
+}
+
+func (m minioStore) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	return m.core.AbortMultipartUpload(ctx, bucket, key, uploadID)
+}
@@ -0,0 +1,349 @@
+package wsl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"wslp/internal/config"
+)
+
+// resultPath returns the local path of a backup stored in a LocalTarget
+func resultPath(t *testing.T, r BackupResult) string {
+	t.Helper()
+
+	p, ok := LocalPath(r.FilePath)
+	if !ok {
+		t.Fatalf("expected a file:// URI, got %s", r.FilePath)
+	}
+	return p
+}
+
+// fakeObjectStore is an in-memory stand-in for an S3-compatible service
+type fakeObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	// partSizes records the size of every part uploaded, in order
+	partSizes []int64
+	aborted   int
+	// failPart makes uploading this part number fail
+	failPart int
+}
+
+func newFakeObjectStore() *fakeObjectStore {
+	return &fakeObjectStore{objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+}
+
+func (f *fakeObjectStore) CreateMultipartUpload(ctx context.Context, bucket, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+	f.uploads[id] = map[int][]byte{}
+	return id, nil
+}
+
+func (f *fakeObjectStore) UploadPart(ctx context.Context, bucket, key, uploadID string, part int, data io.Reader, size int64) (string, error) {
+	if part == f.failPart {
+		return "", errors.New("connection reset")
+	}
+
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(b)) != size {
+		return "", fmt.Errorf("part %d: got %d bytes, declared %d", part, len(b), size)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploads[uploadID][part] = b
+	f.partSizes = append(f.partSizes, size)
+	return fmt.Sprintf("etag-%d", part), nil
+}
+
+func (f *fakeObjectStore) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, etags []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := f.uploads[uploadID]
+	if len(parts) != len(etags) {
+		return fmt.Errorf("completing %d parts, uploaded %d", len(etags), len(parts))
+	}
+
+	var obj []byte
+	for i, etag := range etags {
+		if etag != fmt.Sprintf("etag-%d", i+1) {
+			return fmt.Errorf("unexpected etag %s for part %d", etag, i+1)
+		}
+		obj = append(obj, parts[i+1]...)
+	}
+	f.objects[bucket+"/"+key] = obj
+	delete(f.uploads, uploadID)
+	return nil
+}
+
+func (f *fakeObjectStore) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.uploads, uploadID)
+	f.aborted++
+	return nil
+}
+
+func TestNewBackupTarget(t *testing.T) {
+	tests := map[string]struct {
+		cfg  config.BackupTarget
+		want string
+	}{
+		"local": {config.BackupTarget{Type: config.TargetLocal, Path: "/backups"}, "file:///backups/a.tar.gz"},
+		"smb":   {config.BackupTarget{Type: config.TargetSMB, Path: `\\nas\backups\wsl`}, "smb://nas/backups/wsl/a.tar.gz"},
+		"s3":    {config.BackupTarget{Type: config.TargetS3, Endpoint: "localhost:9000", Bucket: "bucket", Prefix: "/wsl/"}, "s3://bucket/wsl/a.tar.gz"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := NewBackupTarget(tc.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := target.URI("a.tar.gz"); got != tc.want {
+				t.Errorf("URI() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := NewBackupTarget(config.BackupTarget{Type: "ftp"}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestResolveBackupDestination(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	backupDir := t.TempDir()
+	viper.Set("backup_dir", backupDir)
+	local := t.TempDir()
+
+	tests := map[string]struct {
+		backupTarget string
+		target       string
+		dir          string
+		wantDir      string
+		wantTarget   bool
+	}{
+		"defaults to the backup directory":        {wantDir: backupDir},
+		"stages remote targets in the backup dir": {backupTarget: "smb://nas/backups", wantDir: backupDir, wantTarget: true},
+		"saves to an explicit dir":                {backupTarget: "smb://nas/backups", dir: "/staging", wantDir: "/staging"},
+		"stages a given target in the dir":        {target: "smb://nas/backups", dir: "/staging", wantDir: "/staging", wantTarget: true},
+		"writes to a local target directly":       {target: local, dir: "/staging", wantDir: local},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			viper.Set("backup_target", tc.backupTarget)
+
+			dir, target, err := ResolveBackupDestination(tc.target, tc.dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dir != tc.wantDir {
+				t.Errorf("expected dir %s, got %s", tc.wantDir, dir)
+			}
+			if (target != nil) != tc.wantTarget {
+				t.Errorf("expected a target: %v, got %v", tc.wantTarget, target)
+			}
+		})
+	}
+
+	if _, _, err := ResolveBackupDestination("nowhere", ""); err == nil {
+		t.Error("expected error for an unknown target")
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	uri := LocalTarget{Dir: dir}.URI("my backup.tar.gz")
+
+	got, ok := LocalPath(uri)
+	if !ok || got != filepath.Join(dir, "my backup.tar.gz") {
+		t.Errorf("LocalPath(%s) = %s, %v", uri, got, ok)
+	}
+
+	if _, ok := LocalPath("s3://bucket/a.tar.gz"); ok {
+		t.Error("expected s3 URI to have no local path")
+	}
+}
+
+func TestMoveToDir(t *testing.T) {
+	ctx := context.Background()
+	src := writeTestArchive(t, t.TempDir(), "a.tar.gz", []byte("backup"))
+	share := filepath.Join(t.TempDir(), "share")
+
+	if err := (SMBTarget{Path: share}).Store(ctx, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(share, "a.tar.gz"))
+	if err != nil || string(data) != "backup" {
+		t.Errorf("expected file on the share, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("expected the staged file to be removed")
+	}
+
+	// Storing a file already in the target directory leaves it alone
+	dest := filepath.Join(share, "a.tar.gz")
+	if err := (LocalTarget{Dir: share}).Store(ctx, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Error("expected file to be left in place")
+	}
+}
+
+func TestS3TargetStore(t *testing.T) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 25)
+
+	t.Run("uploads in parts", func(t *testing.T) {
+		store := newFakeObjectStore()
+		target := &S3Target{Bucket: "bucket", Prefix: "wsl", PartSize: 100, store: store}
+		src := writeTestArchive(t, t.TempDir(), "a.tar.gz", content)
+
+		if err := target.Store(ctx, src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := store.objects["bucket/wsl/a.tar.gz"]; !bytes.Equal(got, content) {
+			t.Errorf("uploaded object differs: got %d bytes", len(got))
+		}
+		if want := []int64{100, 100, 50}; fmt.Sprint(store.partSizes) != fmt.Sprint(want) {
+			t.Errorf("expected parts %v, got %v", want, store.partSizes)
+		}
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Error("expected the staged file to be removed")
+		}
+	})
+
+	t.Run("uploads empty files as one part", func(t *testing.T) {
+		store := newFakeObjectStore()
+		target := &S3Target{Bucket: "bucket", PartSize: 100, store: store}
+		src := writeTestArchive(t, t.TempDir(), "empty.json", nil)
+
+		if err := target.Store(ctx, src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := store.objects["bucket/empty.json"]; !ok || len(store.partSizes) != 1 {
+			t.Errorf("expected one empty part, got %v", store.partSizes)
+		}
+	})
+
+	t.Run("aborts failed uploads and keeps the file", func(t *testing.T) {
+		store := newFakeObjectStore()
+		store.failPart = 2
+		target := &S3Target{Bucket: "bucket", PartSize: 100, store: store}
+		src := writeTestArchive(t, t.TempDir(), "a.tar.gz", content)
+
+		err := target.Store(ctx, src)
+		if err == nil || !strings.Contains(err.Error(), "part 2") {
+			t.Fatalf("expected part 2 to fail, got %v", err)
+		}
+		if store.aborted != 1 || len(store.objects) != 0 {
+			t.Errorf("expected the upload to be aborted, got %d aborts and %d objects", store.aborted, len(store.objects))
+		}
+		if _, err := os.Stat(src); err != nil {
+			t.Error("expected the staged file to be kept")
+		}
+	})
+
+	t.Run("grows parts to stay within the part limit", func(t *testing.T) {
+		target := &S3Target{PartSize: 16 << 20}
+		tests := map[int64]int64{
+			0:                   16 << 20,
+			16 << 20 * maxParts: 16 << 20,
+			16<<20*maxParts + 1: 17 << 20,
+			1 << 40:             105 << 20,
+		}
+		for size, want := range tests {
+			got := target.partSize(size)
+			if got != want {
+				t.Errorf("partSize(%d) = %d, want %d", size, got, want)
+			}
+			if parts := (size + got - 1) / got; parts > maxParts {
+				t.Errorf("partSize(%d) needs %d parts", size, parts)
+			}
+		}
+	})
+
+	t.Run("enforces minimum part size", func(t *testing.T) {
+		target := newS3Target(config.BackupTarget{Bucket: "bucket", PartSize: 1024}, newFakeObjectStore())
+		if target.PartSize != minPartSize {
+			t.Errorf("expected part size %d, got %d", minPartSize, target.PartSize)
+		}
+	})
+}
+
+func TestBackupDistrosTarget(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uploads archive and manifest", func(t *testing.T) {
+		stagingDir := t.TempDir()
+		store := newFakeObjectStore()
+		target := &S3Target{Bucket: "bucket", Prefix: "wsl", PartSize: 64, store: store}
+
+		mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, stagingDir, BackupOptions{CustomName: "snap", Target: target})
+
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("expected successful backup, got %+v", results)
+		}
+		if results[0].FilePath != "s3://bucket/wsl/snap.tar.gz" {
+			t.Errorf("expected s3 URI, got %s", results[0].FilePath)
+		}
+		if results[0].ManifestPath != "s3://bucket/wsl/snap.tar.gz.json" {
+			t.Errorf("expected manifest s3 URI, got %s", results[0].ManifestPath)
+		}
+		if _, ok := store.objects["bucket/wsl/snap.tar.gz"]; !ok {
+			t.Error("expected archive to be uploaded")
+		}
+		if _, ok := store.objects["bucket/wsl/snap.tar.gz.json"]; !ok {
+			t.Error("expected manifest to be uploaded")
+		}
+		if entries, _ := os.ReadDir(stagingDir); len(entries) != 0 {
+			t.Errorf("expected staging directory to be empty, got %d files", len(entries))
+		}
+	})
+
+	t.Run("keeps staged backup when the upload fails", func(t *testing.T) {
+		stagingDir := t.TempDir()
+		store := newFakeObjectStore()
+		store.failPart = 1
+		target := &S3Target{Bucket: "bucket", PartSize: 64, store: store}
+
+		mock := &mockBackuper{isRegisteredResults: map[string]bool{"Ubuntu": true}}
+		results := BackupDistros(ctx, mock, []string{"Ubuntu"}, stagingDir, BackupOptions{CustomName: "snap", Target: target})
+
+		if len(results) != 1 || results[0].Success {
+			t.Fatalf("expected failed backup, got %+v", results)
+		}
+		if !strings.Contains(results[0].Message, "kept at") {
+			t.Errorf("expected message to say where the backup was kept, got %q", results[0].Message)
+		}
+		if _, err := os.Stat(filepath.Join(stagingDir, "snap.tar.gz")); err != nil {
+			t.Error("expected staged archive to be kept")
+		}
+	})
+}
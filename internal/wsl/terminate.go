@@ -73,8 +73,22 @@ func TerminateDistros(ctx context.Context, t Terminator, distros []string) []Ter
 			continue
 		}
 
-		// Terminate the distro
+		// Terminate the distro, unless an operation needs it running
+		unlock, err := lockDistros(ctx, "terminate", distroName)
+		if err != nil {
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if IsDryRun(ctx) {
+			unlock()
+			result.Success = true
+			result.Message = "Would terminate (dry run)"
+			results = append(results, result)
+			continue
+		}
 		err = t.Terminate(ctx, distroName)
+		unlock()
 		if err != nil {
 			result.Message = fmt.Sprintf("Failed to terminate: %v", err)
 			results = append(results, result)
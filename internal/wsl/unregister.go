@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	gowsl "github.com/ubuntu/gowsl"
+
+	"wslp/internal/audit"
 )
 
 // Unregisterer checks registration status and unregisters a WSL distribution
@@ -47,6 +49,12 @@ type UnregisterResult struct {
 
 // UnregisterDistro unregisters a WSL distribution
 func UnregisterDistro(ctx context.Context, name string, u Unregisterer) error {
+	unlock, err := lockDistros(ctx, "unregister", name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	registered, err := u.IsRegistered(ctx, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if distro is registered: %w", err)
@@ -56,7 +64,13 @@ func UnregisterDistro(ctx context.Context, name string, u Unregisterer) error {
 		return fmt.Errorf("distro %s is not registered", name)
 	}
 
-	if err := u.Unregister(ctx, name); err != nil {
+	if IsDryRun(ctx) {
+		return nil
+	}
+
+	err = u.Unregister(ctx, name)
+	recordAudit(ctx, audit.Entry{Action: audit.ActionUnregister, Distro: name, Success: err == nil, Error: errorMessage(err)})
+	if err != nil {
 		return fmt.Errorf("failed to unregister distro: %w", err)
 	}
 
@@ -73,20 +87,40 @@ func UnregisterDistros(ctx context.Context, u Unregisterer, distros []string) []
 			Success: false,
 		}
 
+		// Lock before checking, so the distro can't change in between
+		unlock, err := lockDistros(ctx, "unregister", distroName)
+		if err != nil {
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+
 		registered, err := u.IsRegistered(ctx, distroName)
 		if err != nil {
+			unlock()
 			result.Message = fmt.Sprintf("Error checking registration: %v", err)
 			results = append(results, result)
 			continue
 		}
 
 		if !registered {
+			unlock()
 			result.Message = fmt.Sprintf("Distro %s is not registered", distroName)
 			results = append(results, result)
 			continue
 		}
 
-		if err := u.Unregister(ctx, distroName); err != nil {
+		if IsDryRun(ctx) {
+			unlock()
+			result.Success = true
+			result.Message = fmt.Sprintf("Would unregister %s (dry run)", distroName)
+			results = append(results, result)
+			continue
+		}
+		err = u.Unregister(ctx, distroName)
+		unlock()
+		recordAudit(ctx, audit.Entry{Action: audit.ActionUnregister, Distro: distroName, Success: err == nil, Error: errorMessage(err)})
+		if err != nil {
 			result.Message = fmt.Sprintf("Failed to unregister: %v", err)
 			results = append(results, result)
 			continue
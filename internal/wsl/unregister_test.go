@@ -3,6 +3,7 @@ package wsl
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -11,9 +12,12 @@ type mockUnregisterer struct {
 	checkFails   bool
 	unregFails   bool
 	unregistered []string
+	// checks counts the IsRegistered calls
+	checks int
 }
 
 func (m *mockUnregisterer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	m.checks++
 	if m.checkFails {
 		return false, errors.New("mock check error")
 	}
@@ -69,3 +73,47 @@ func TestUnregisterDistro(t *testing.T) {
 		}
 	})
 }
+
+func TestUnregisterDistros(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unregisters registered distros", func(t *testing.T) {
+		mock := &mockUnregisterer{registered: true}
+
+		results := UnregisterDistros(ctx, mock, []string{"Ubuntu", "Debian"})
+
+		if len(results) != 2 || !results[0].Success || !results[1].Success {
+			t.Fatalf("expected both to succeed, got %+v", results)
+		}
+		if len(mock.unregistered) != 2 {
+			t.Errorf("expected 2 distros unregistered, got %v", mock.unregistered)
+		}
+	})
+
+	t.Run("reports unregistered distros", func(t *testing.T) {
+		mock := &mockUnregisterer{registered: false}
+
+		results := UnregisterDistros(ctx, mock, []string{"Ubuntu"})
+
+		if results[0].Success || !strings.Contains(results[0].Message, "not registered") {
+			t.Errorf("expected not registered, got %+v", results[0])
+		}
+	})
+
+	t.Run("checks registration only once the distro is locked", func(t *testing.T) {
+		m := NewLockManager("")
+		useLocks(t, m)
+		unlock, _ := m.TryLock("Ubuntu", "backup")
+		defer unlock()
+		mock := &mockUnregisterer{registered: true}
+
+		results := UnregisterDistros(ctx, mock, []string{"Ubuntu"})
+
+		if results[0].Success || !strings.Contains(results[0].Message, "backup") {
+			t.Errorf("expected the distro to be busy, got %+v", results[0])
+		}
+		if mock.checks != 0 {
+			t.Errorf("expected no registration check while locked, got %d", mock.checks)
+		}
+	})
+}
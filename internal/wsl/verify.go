@@ -0,0 +1,222 @@
+package wsl
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyResult contains the result of verifying a backup archive
+type VerifyResult struct {
+	Distro   string `json:"distro"`
+	FilePath string `json:"filePath"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	// Entries is the number of files, directories and links in the archive
+	Entries int `json:"entries"`
+	// ChecksumVerified is true when the archive matched the checksum
+	// recorded in its manifest. Archives without a manifest are verified
+	// structurally only.
+	ChecksumVerified bool `json:"checksumVerified"`
+	// Encrypted is true for age-encrypted archives
+	Encrypted bool `json:"encrypted"`
+}
+
+// archiveStats describes a backup archive that passed verifyArchive
+type archiveStats struct {
+	size    int64
+	sha256  string
+	entries int
+	// locked is set when the archive is encrypted and no key is
+	// configured, so only its checksum could be computed
+	locked bool
+}
+
+// VerifyBackup checks that a backup archive is intact: that it matches the
+// checksum in its manifest (if it has one), that the whole compressed
+// stream decompresses with valid checksums, that every tar header and entry
+// can be read, and that it contains /etc/os-release. VHDX disk images are
+// only checked for their signature and checksum. Encrypted archives are
+// decrypted on the fly; without a key only their checksum is checked.
+func VerifyBackup(archivePath string) VerifyResult {
+	result := VerifyResult{
+		FilePath:  archivePath,
+		Success:   false,
+		Encrypted: IsEncrypted(archivePath),
+	}
+
+	if distro, _, ok := parseBackupName(filepath.Base(archivePath)); ok {
+		result.Distro = distro
+	}
+
+	manifest, manifestErr := ReadManifest(archivePath)
+	if manifestErr == nil {
+		result.Distro = manifest.Distro
+	}
+
+	stats, err := verifyArchive(archivePath)
+	result.Size = stats.size
+	result.SHA256 = stats.sha256
+	result.Entries = stats.entries
+	if err != nil {
+		result.Message = fmt.Sprintf("Archive is corrupt: %v", err)
+		return result
+	}
+
+	if manifestErr == nil && manifest.SHA256 != "" {
+		if !strings.EqualFold(manifest.SHA256, stats.sha256) {
+			result.Message = fmt.Sprintf("Checksum mismatch: manifest records %s, archive is %s", manifest.SHA256, stats.sha256)
+			return result
+		}
+		result.ChecksumVerified = true
+	}
+
+	if stats.locked {
+		if !result.ChecksumVerified {
+			result.Message = "Archive is encrypted and has no manifest checksum; configure a decryption key to verify its contents"
+			return result
+		}
+		result.Success = true
+		result.Message = "Archive is encrypted; checksum matches manifest but contents were not checked (no decryption key configured)"
+		return result
+	}
+
+	result.Success = true
+	if result.ChecksumVerified {
+		result.Message = fmt.Sprintf("Archive is intact (%d entries, checksum matches manifest)", stats.entries)
+	} else {
+		result.Message = fmt.Sprintf("Archive is intact (%d entries, no manifest checksum to compare)", stats.entries)
+	}
+
+	return result
+}
+
+// verifyArchive streams an archive end to end, returning its size and
+// checksum. An error means the archive is truncated, corrupt
+// or does not look like a distro's root filesystem.
+func verifyArchive(archivePath string) (archiveStats, error) {
+	var stats archiveStats
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return stats, err
+	}
+	stats.size = fileInfo.Size()
+
+	h := sha256.New()
+	raw := io.TeeReader(f, h)
+
+	if IsEncrypted(archivePath) {
+		if _, err := decryptionIdentities(); errors.Is(err, ErrNoDecryptionKey) {
+			if _, err := io.Copy(io.Discard, raw); err != nil {
+				return stats, err
+			}
+			stats.sha256 = hex.EncodeToString(h.Sum(nil))
+			stats.locked = true
+			return stats, nil
+		}
+	}
+
+	if format, _ := FormatFromPath(archivePath); format == FormatVHDX {
+		return verifyVHDX(archivePath, raw, h, stats)
+	}
+
+	r, err := decompress(archivePath, raw)
+	if err != nil {
+		return stats, err
+	}
+	defer r.Close()
+
+	foundOSRelease := false
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("invalid tar header after %d entries: %w", stats.entries, err)
+		}
+		stats.entries++
+
+		if isOSRelease(hdr.Name) {
+			foundOSRelease = true
+		}
+
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return stats, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+	}
+
+	// Read to the end of the stream so the compression checksums are checked
+	// even when the tar end-of-archive marker came first
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return stats, fmt.Errorf("corrupt compressed stream: %w", err)
+	}
+	// Hash any trailing bytes the decompressor didn't consume
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return stats, err
+	}
+	stats.sha256 = hex.EncodeToString(h.Sum(nil))
+
+	if stats.entries == 0 {
+		return stats, fmt.Errorf("archive is empty")
+	}
+	if !foundOSRelease {
+		return stats, fmt.Errorf("archive does not contain /etc/os-release")
+	}
+
+	return stats, nil
+}
+
+// vhdxSignature starts every VHDX file
+const vhdxSignature = "vhdxfile"
+
+// verifyVHDX checks the signature of a VHDX disk image and hashes the rest
+// of it. The filesystem inside the image can't be checked without mounting
+// it, so entries is always zero.
+func verifyVHDX(archivePath string, raw io.Reader, h hash.Hash, stats archiveStats) (archiveStats, error) {
+	disk := raw
+	if IsEncrypted(archivePath) {
+		var err error
+		if disk, err = decrypt(raw); err != nil {
+			return stats, err
+		}
+	}
+
+	signature := make([]byte, len(vhdxSignature))
+	if _, err := io.ReadFull(disk, signature); err != nil || string(signature) != vhdxSignature {
+		return stats, fmt.Errorf("not a VHDX disk image")
+	}
+
+	if _, err := io.Copy(io.Discard, disk); err != nil {
+		return stats, err
+	}
+	// Hash any trailing bytes the decrypter didn't consume
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return stats, err
+	}
+	stats.sha256 = hex.EncodeToString(h.Sum(nil))
+
+	return stats, nil
+}
+
+// isOSRelease reports whether a tar entry name refers to /etc/os-release
+func isOSRelease(name string) bool {
+	return cleanArchivePath(name) == "/etc/os-release"
+}
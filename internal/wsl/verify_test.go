@@ -0,0 +1,176 @@
+package wsl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRootFS is the content of the archives produced by buildTestArchive
+// unless a test supplies its own files
+var testRootFS = map[string]string{
+	"./etc/os-release": "NAME=\"Ubuntu\"\n",
+	"./etc/hostname":   "ubuntu\n",
+}
+
+// buildTestArchive returns a tar.gz archive containing files
+func buildTestArchive(files map[string]string) []byte {
+	return buildFormatArchive(FormatTarGz, files)
+}
+
+// buildFormatArchive returns an archive in format containing files. VHDX
+// images are faked with just their signature.
+func buildFormatArchive(format BackupFormat, files map[string]string) []byte {
+	var buf bytes.Buffer
+	if format == FormatVHDX {
+		buf.WriteString(vhdxSignature + "disk image")
+		return buf.Bytes()
+	}
+
+	var w io.WriteCloser
+	switch format {
+	case FormatTar:
+		w = nopWriteCloser{&buf}
+	case FormatTarGz:
+		w = gzip.NewWriter(&buf)
+	default:
+		w, _ = compress(format, &buf)
+	}
+
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	w.Close()
+	return buf.Bytes()
+}
+
+// nopWriteCloser adds a no-op Close to an io.Writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// writeTestArchive writes an archive with the given content to dir/name
+// and returns its path
+func writeTestArchive(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+	archive := filepath.Join(dir, name)
+	if err := os.WriteFile(archive, content, 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return archive
+}
+
+func TestVerifyBackup(t *testing.T) {
+	valid := buildTestArchive(testRootFS)
+
+	t.Run("accepts an intact archive", func(t *testing.T) {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", valid)
+
+		result := VerifyBackup(archive)
+
+		if !result.Success {
+			t.Fatalf("expected success, got %q", result.Message)
+		}
+		if result.Distro != "Ubuntu" {
+			t.Errorf("expected distro Ubuntu, got %q", result.Distro)
+		}
+		if result.Entries != 2 {
+			t.Errorf("expected 2 entries, got %d", result.Entries)
+		}
+		if result.ChecksumVerified {
+			t.Error("expected checksum not to be verified without a manifest")
+		}
+	})
+
+	t.Run("checks the manifest checksum", func(t *testing.T) {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", valid)
+		if _, err := WriteManifest(context.Background(), &mockBackuper{}, "Ubuntu", archive); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+
+		result := VerifyBackup(archive)
+
+		if !result.Success || !result.ChecksumVerified {
+			t.Errorf("expected verified checksum, got %+v", result)
+		}
+	})
+
+	t.Run("rejects a checksum mismatch", func(t *testing.T) {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", valid)
+		manifest := `{"distro":"Ubuntu","sha256":"0000"}`
+		if err := os.WriteFile(ManifestPath(archive), []byte(manifest), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+
+		result := VerifyBackup(archive)
+
+		if result.Success || !strings.Contains(result.Message, "Checksum mismatch") {
+			t.Errorf("expected checksum mismatch, got %+v", result)
+		}
+	})
+
+	t.Run("rejects a truncated archive", func(t *testing.T) {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", valid[:len(valid)/2])
+
+		if result := VerifyBackup(archive); result.Success {
+			t.Error("expected truncated archive to fail verification")
+		}
+	})
+
+	t.Run("rejects a bad gzip checksum", func(t *testing.T) {
+		corrupt := bytes.Clone(valid)
+		// The last 8 bytes hold the CRC-32 and size of the data
+		corrupt[len(corrupt)-8] ^= 0xff
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", corrupt)
+
+		if result := VerifyBackup(archive); result.Success {
+			t.Error("expected bad CRC to fail verification")
+		}
+	})
+
+	t.Run("rejects an archive without os-release", func(t *testing.T) {
+		content := buildTestArchive(map[string]string{"./etc/hostname": "ubuntu\n"})
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.tar.gz", content)
+
+		result := VerifyBackup(archive)
+
+		if result.Success || !strings.Contains(result.Message, "os-release") {
+			t.Errorf("expected missing os-release error, got %+v", result)
+		}
+	})
+
+	t.Run("accepts every backup format", func(t *testing.T) {
+		for _, format := range BackupFormats {
+			archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022"+format.Extension(), buildFormatArchive(format, testRootFS))
+
+			if result := VerifyBackup(archive); !result.Success {
+				t.Errorf("%s: expected success, got %q", format, result.Message)
+			}
+		}
+	})
+
+	t.Run("rejects a file that isn't a VHDX image", func(t *testing.T) {
+		archive := writeTestArchive(t, t.TempDir(), "Ubuntu-20240301-143022.vhdx", valid)
+
+		if result := VerifyBackup(archive); result.Success {
+			t.Error("expected tar.gz content in a .vhdx file to fail verification")
+		}
+	})
+
+	t.Run("reports a missing file", func(t *testing.T) {
+		if result := VerifyBackup(filepath.Join(t.TempDir(), "missing.tar.gz")); result.Success {
+			t.Error("expected missing archive to fail verification")
+		}
+	})
+}
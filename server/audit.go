@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wslp/internal/audit"
+)
+
+// defaultAuditLimit is how many audit entries /api/audit returns unless
+// asked for more
+const defaultAuditLimit = 100
+
+// auditMiddleware records the client making each request as the actor of
+// the changes it makes, so the audit log tells API clients apart from the
+// CLI
+func auditMiddleware(next http.Handler) http.Handler {
+	process := audit.ProcessActor()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := audit.Actor{
+			Tool:      audit.ToolAPI,
+			User:      process.User,
+			PID:       process.PID,
+			Request:   r.Method + " " + r.URL.Path,
+			Remote:    r.RemoteAddr,
+			UserAgent: r.UserAgent(),
+		}
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), actor)))
+	})
+}
+
+// recordAudit records an action made by the request in ctx. A log that
+// can't be written is reported, but doesn't fail the request.
+func recordAudit(ctx context.Context, entry audit.Entry) {
+	if err := audit.Default.Record(ctx, entry); err != nil {
+		slog.Warn("Failed to record in the audit log", "action", entry.Action, "error", err)
+	}
+}
+
+// recordTelemetryChange records turning Ubuntu telemetry on or off
+func recordTelemetryChange(ctx context.Context, enabled bool, err error) {
+	entry := audit.Entry{
+		Action:  audit.ActionTelemetry,
+		Details: map[string]string{"enabled": strconv.FormatBool(enabled)},
+		Success: err == nil,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	recordAudit(ctx, entry)
+}
+
+// handleAudit lists entries from the audit log, oldest first. ?distro=,
+// ?action= and ?since= (e.g. 24h, 7d or 2024-03-01) select entries and
+// ?limit= caps how many of the most recent are returned.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	since, err := audit.ParseSince(query.Get("since"), time.Now())
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultAuditLimit
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			writeError(w, r, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := audit.Default.Read(audit.Filter{
+		Distro: query.Get("distro"),
+		Action: query.Get("action"),
+		Since:  since,
+		Limit:  limit,
+	})
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"entries": entries,
+	})
+}
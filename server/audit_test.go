@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wslp/client"
+	"wslp/internal/audit"
+)
+
+// useAuditLog records to a fresh audit log for the duration of a test
+func useAuditLog(t *testing.T) *audit.Log {
+	t.Helper()
+
+	previous := audit.Default
+	audit.Default = audit.New(filepath.Join(t.TempDir(), "audit.log"))
+	t.Cleanup(func() { audit.Default = previous })
+	return audit.Default
+}
+
+func TestAudit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("records API changes with the client making them", func(t *testing.T) {
+		useAuditLog(t)
+		c := newTestClient(t, &Server{
+			unregisterer:  &mockUnregisterer{registered: true},
+			defaultGetter: &mockDefaultGetter{},
+			defaultSetter: &mockDefaultSetter{registered: true},
+		})
+		c.HTTPClient = &http.Client{Transport: userAgentTransport("wslp-gui/1.0")}
+
+		if _, err := c.Unregister(ctx, "Ubuntu"); err != nil {
+			t.Fatalf("Unregister failed: %v", err)
+		}
+		if err := c.SetDefault(ctx, "Debian"); err != nil {
+			t.Fatalf("SetDefault failed: %v", err)
+		}
+
+		entries, err := c.Audit(ctx, client.AuditOptions{})
+		if err != nil {
+			t.Fatalf("Audit failed: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %+v", entries)
+		}
+		e := entries[0]
+		if e.Action != audit.ActionUnregister || e.Distro != "Ubuntu" || !e.Success {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if e.Actor.Tool != audit.ToolAPI || e.Actor.UserAgent != "wslp-gui/1.0" || e.Actor.Request != "DELETE /api/v1/distros/Ubuntu" || e.Actor.Remote == "" {
+			t.Errorf("unexpected actor %+v", e.Actor)
+		}
+
+		entries, err = c.Audit(ctx, client.AuditOptions{Action: audit.ActionSetDefault, Since: "1h", Limit: 5})
+		if err != nil || len(entries) != 1 || entries[0].Distro != "Debian" {
+			t.Errorf("expected the set-default, got %+v, %v", entries, err)
+		}
+	})
+
+	t.Run("rejects bad queries", func(t *testing.T) {
+		useAuditLog(t)
+		srv := &Server{token: "test-token"}
+
+		for _, query := range []string{"since=last+week", "limit=0", "limit=many"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/audit?"+query, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+			srv.handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, w.Code)
+			}
+		}
+	})
+
+	t.Run("records shutdowns", func(t *testing.T) {
+		log := useAuditLog(t)
+		srv := &Server{}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/shutdown", nil)
+		w := httptest.NewRecorder()
+		srv.handleShutdown(w, req)
+
+		entries, _ := log.Read(audit.Filter{Action: audit.ActionShutdown})
+		if len(entries) != 1 || !entries[0].Success {
+			t.Errorf("expected a shutdown to be recorded, got %+v", entries)
+		}
+	})
+
+	t.Run("records telemetry changes", func(t *testing.T) {
+		log := useAuditLog(t)
+
+		recordTelemetryChange(context.Background(), false, errors.New("access denied"))
+
+		entries, _ := log.Read(audit.Filter{})
+		if len(entries) != 1 || entries[0].Details["enabled"] != "false" || entries[0].Error != "access denied" {
+			t.Errorf("unexpected entries %+v", entries)
+		}
+	})
+}
+
+// userAgentTransport sets the User-Agent of every request
+type userAgentTransport string
+
+func (u userAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("User-Agent", string(u))
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newToken returns a random token for authenticating API clients. A new
+// one is made each time the server starts.
+func newToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// TokenFile returns the file the token of the server on port is written
+// to while it runs, so the GUI and scripts run by the same user can read
+// it
+func TokenFile(stateDir, port string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("server-%s.token", port))
+}
+
+// writeTokenFile writes token to path, readable only by the current user
+func writeTokenFile(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
+
+// publicPaths can be read without the token: they describe the API and
+// reveal nothing about the machine
+var publicPaths = map[string]bool{
+	"/api/v1/openapi.json": true,
+}
+
+// authMiddleware rejects requests unless they carry the server's token in
+// an Authorization: Bearer header. Reads need it too, as backups, the audit
+// log and events reveal what is on the machine. Only CORS preflights, which
+// browsers send without credentials, and publicPaths are let through.
+func authMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || (r.Method == http.MethodGet && publicPaths[r.URL.Path]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !validToken(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wslp"`)
+			writeError(w, r, "Missing or invalid API token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validToken reports whether r carries token as a bearer token
+func validToken(r *http.Request, token string) bool {
+	scheme, given, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(given)), []byte(token)) == 1
+}
+
+// originAllowed reports whether a browser page from origin may call the
+// server. With no allowed origins configured, only pages served from this
+// machine are allowed.
+func originAllowed(origin string, allowed []string) bool {
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether bind only accepts connections from this
+// machine
+func isLoopback(bind string) bool {
+	if bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
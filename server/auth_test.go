@@ -0,0 +1,187 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	handler := authMiddleware("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]struct {
+		method        string
+		authorization string
+		want          int
+	}{
+		"rejects GET without a token":     {"GET", "", http.StatusUnauthorized},
+		"rejects HEAD without a token":    {"HEAD", "", http.StatusUnauthorized},
+		"allows GET with the token":       {"GET", "Bearer secret", http.StatusOK},
+		"allows OPTIONS without a token":  {"OPTIONS", "", http.StatusOK},
+		"rejects POST without a token":    {"POST", "", http.StatusUnauthorized},
+		"rejects DELETE without a token":  {"DELETE", "", http.StatusUnauthorized},
+		"rejects a wrong token":           {"POST", "Bearer wrong", http.StatusUnauthorized},
+		"rejects other schemes":           {"POST", "Basic secret", http.StatusUnauthorized},
+		"allows POST with the token":      {"POST", "Bearer secret", http.StatusOK},
+		"accepts any case for the scheme": {"DELETE", "bearer secret", http.StatusOK},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/unregister", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, rec.Code)
+			}
+			if tc.want == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Error("expected a WWW-Authenticate header")
+			}
+		})
+	}
+
+	t.Run("serves the OpenAPI document without a token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("guards reads that leak data", func(t *testing.T) {
+		for _, path := range []string{"/api/backups/x.tar.gz/files?download=true", "/api/audit", "/api/events", "/metrics"} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401 for %s, got %d", path, rec.Code)
+			}
+		}
+	})
+
+	t.Run("rejects everything without a server token", func(t *testing.T) {
+		handler := authMiddleware("", http.NotFoundHandler())
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/shutdown", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rec.Code)
+		}
+	})
+}
+
+func TestWriteTokenFile(t *testing.T) {
+	path := TokenFile(filepath.Join(t.TempDir(), "state"), "8080")
+	if filepath.Base(path) != "server-8080.token" {
+		t.Errorf("unexpected token file %s", path)
+	}
+
+	if err := writeTokenFile(path, "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != "secret" {
+		t.Errorf("expected token in file, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1": true,
+		"::1":       true,
+		"localhost": true,
+		"0.0.0.0":   false,
+		"":          false,
+		"10.0.0.5":  false,
+	}
+
+	for bind, want := range tests {
+		if got := isLoopback(bind); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", bind, got, want)
+		}
+	}
+}
+
+func TestStartRequiresToken(t *testing.T) {
+	stateDir := useScheduleConfig(t)
+	viper.Set("state_dir", stateDir)
+
+	// Find a free port for the server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	srv := NewServer("127.0.0.1", port)
+	srv.lister = &mockLister{}
+	srv.defaultGetter = &mockDefaultGetter{}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+
+	tokenFile := TokenFile(stateDir, port)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(tokenFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the token file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, _ := os.ReadFile(tokenFile)
+	token := strings.TrimSpace(string(data))
+
+	shutdown := func(token string) int {
+		req, _ := http.NewRequest("POST", "http://127.0.0.1:"+port+"/api/shutdown", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := shutdown(""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the token, got %d", code)
+	}
+	if code := shutdown(token); code != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", code)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server to stop")
+	}
+	if _, err := os.Stat(tokenFile); !os.IsNotExist(err) {
+		t.Error("expected the token file to be removed on shutdown")
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"wslp/client"
+)
+
+// newTestClient serves srv's routes for the duration of a test, returning
+// a client for them
+func newTestClient(t *testing.T, srv *Server) *client.Client {
+	t.Helper()
+
+	srv.token = "test-token"
+	if srv.jobs == nil {
+		srv.jobs = newTestJobManager(t)
+	}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return client.New(ts.URL, srv.token)
+}
+
+// TestClient calls the server through the client package, so a change to
+// either that breaks the other fails here
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("lists distros", func(t *testing.T) {
+		c := newTestClient(t, &Server{lister: &mockLister{distros: []string{"Ubuntu", "Debian"}}})
+
+		distros, err := c.ListDistros(ctx)
+		if err != nil {
+			t.Fatalf("ListDistros failed: %v", err)
+		}
+		if len(distros) != 2 || distros[0].Name != "Ubuntu" || distros[1].Name != "Debian" {
+			t.Errorf("unexpected distros %+v", distros)
+		}
+	})
+
+	t.Run("gets and sets the default", func(t *testing.T) {
+		c := newTestClient(t, &Server{
+			defaultGetter: &mockDefaultGetter{defaultDistro: "Ubuntu"},
+			defaultSetter: &mockDefaultSetter{registered: true},
+		})
+
+		name, err := c.GetDefault(ctx)
+		if err != nil || name != "Ubuntu" {
+			t.Errorf("expected Ubuntu, got %q, %v", name, err)
+		}
+		if err := c.SetDefault(ctx, "Debian"); err != nil {
+			t.Errorf("SetDefault failed: %v", err)
+		}
+	})
+
+	t.Run("returns result types", func(t *testing.T) {
+		unregisterer := &mockUnregisterer{registered: true}
+		c := newTestClient(t, &Server{
+			unregisterer: unregisterer,
+			terminator:   &mockTerminator{registered: true},
+		})
+
+		unregistered, err := c.Unregister(ctx, "Old Distro")
+		if err != nil || !unregistered.Success || unregistered.Distro != "Old Distro" {
+			t.Errorf("unexpected unregister result %+v, %v", unregistered, err)
+		}
+		if len(unregisterer.unregistered) != 1 || unregisterer.unregistered[0] != "Old Distro" {
+			t.Errorf("expected the escaped name to reach the server, got %v", unregisterer.unregistered)
+		}
+
+		terminated, err := c.Terminate(ctx, "Ubuntu")
+		if err != nil || !terminated.Success {
+			t.Errorf("unexpected terminate result %+v, %v", terminated, err)
+		}
+	})
+
+	t.Run("runs jobs", func(t *testing.T) {
+		c := newTestClient(t, &Server{copier: &namedCopier{registered: map[string]bool{"Ubuntu": true}}})
+
+		job, err := c.Copy(ctx, "Ubuntu", "Ubuntu-copy", "")
+		if err != nil {
+			t.Fatalf("Copy failed: %v", err)
+		}
+		if job.Kind != "copy" || job.ID == "" {
+			t.Errorf("unexpected job %+v", job)
+		}
+
+		job, err = c.WaitForJob(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("WaitForJob failed: %v", err)
+		}
+		var result client.CopyResult
+		if err := job.DecodeResult(&result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		if job.State != client.JobSucceeded || !result.Success || result.NewName != "Ubuntu-copy" {
+			t.Errorf("unexpected job %+v with result %+v", job, result)
+		}
+
+		jobs, err := c.Jobs(ctx)
+		if err != nil || len(jobs) != 1 || jobs[0].ID != job.ID {
+			t.Errorf("expected the copy job, got %+v, %v", jobs, err)
+		}
+		if _, err := c.CancelJob(ctx, job.ID); client.ErrorCode(err) != CodeJobFinished {
+			t.Errorf("expected %s, got %v", CodeJobFinished, err)
+		}
+	})
+
+	t.Run("returns server errors", func(t *testing.T) {
+		c := newTestClient(t, &Server{})
+
+		_, err := c.GetJob(ctx, "missing")
+		apiErr, ok := err.(*client.Error)
+		if !ok {
+			t.Fatalf("expected *client.Error, got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != CodeJobNotFound {
+			t.Errorf("unexpected error %+v", apiErr)
+		}
+	})
+
+	t.Run("requires the token", func(t *testing.T) {
+		c := newTestClient(t, &Server{terminator: &mockTerminator{registered: true}})
+		c.Token = ""
+
+		if _, err := c.Terminate(ctx, "Ubuntu"); client.ErrorCode(err) != CodeUnauthorized {
+			t.Errorf("expected %s, got %v", CodeUnauthorized, err)
+		}
+	})
+
+	t.Run("serves the OpenAPI document", func(t *testing.T) {
+		c := newTestClient(t, &Server{})
+
+		spec, err := c.OpenAPI(ctx)
+		if err != nil || string(spec) != string(openAPISpec) {
+			t.Errorf("expected the embedded spec, got %d bytes, %v", len(spec), err)
+		}
+	})
+}
+
+func TestStartOnSocket(t *testing.T) {
+	stateDir := useScheduleConfig(t)
+	viper.Set("state_dir", stateDir)
+
+	path := filepath.Join(t.TempDir(), "wslp.sock")
+	srv, err := NewSocketServer("unix://" + path)
+	if err != nil {
+		t.Fatalf("NewSocketServer failed: %v", err)
+	}
+	srv.lister = &mockLister{distros: []string{"Ubuntu"}}
+	srv.terminator = &mockTerminator{registered: true}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := client.New("unix://"+path, "")
+	for {
+		if _, err := c.ListDistros(ctx); err == nil {
+			break
+		} else if ctx.Err() != nil {
+			t.Fatalf("timed out waiting for the server: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if result, err := c.Terminate(ctx, "Ubuntu"); err != nil || !result.Success {
+		t.Errorf("expected a change without the token to succeed, got %+v, %v", result, err)
+	}
+	if entries, _ := os.ReadDir(stateDir); len(entries) != 0 {
+		t.Errorf("expected no token file, found %v", entries)
+	}
+
+	if err := c.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the server to stop")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the socket to be removed on shutdown")
+	}
+}
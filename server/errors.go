@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// v1Prefix is the path every /api/v1 route starts with
+const v1Prefix = "/api/v1/"
+
+// Error codes sent in /api/v1 error responses. Clients should match on
+// these rather than on messages, which are meant for people.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+	CodeTimeout          = "timeout"
+	// CodeOperationFailed is sent when WSL reports an operation on a
+	// distro failed
+	CodeOperationFailed  = "operation_failed"
+	CodeJobNotFound      = "job_not_found"
+	CodeJobFinished      = "job_finished"
+	CodeBackupNotFound   = "backup_not_found"
+	CodeScheduleNotFound = "schedule_not_found"
+	CodeScheduleRunning  = "schedule_running"
+	// CodeDistroBusy is sent when another operation, perhaps run from the
+	// CLI, holds the distro
+	CodeDistroBusy = "distro_busy"
+)
+
+// ErrorBody describes what went wrong with an /api/v1 request
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of every /api/v1 error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// writeError replies to r with an error. It takes the same arguments as
+// http.Error, which it falls back to for the unversioned routes; /api/v1
+// routes get an ErrorResponse with a code matching the status.
+func writeError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	writeErrorCode(w, r, statusCode(status), message, status)
+}
+
+// writeErrorCode is writeError with a more specific code than the status
+// gives, e.g. CodeJobNotFound
+func writeErrorCode(w http.ResponseWriter, r *http.Request, code, message string, status int) {
+	if !strings.HasPrefix(r.URL.Path, v1Prefix) {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// statusCode returns the error code for an HTTP status
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
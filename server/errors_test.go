@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	t.Run("writes plain text for unversioned routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		writeError(rec, httptest.NewRequest("GET", "/api/distros", nil), "boom", http.StatusInternalServerError)
+
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != "boom" {
+			t.Errorf("expected plain text error, got %d: %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	tests := map[int]string{
+		http.StatusBadRequest:          CodeInvalidRequest,
+		http.StatusUnauthorized:        CodeUnauthorized,
+		http.StatusNotFound:            CodeNotFound,
+		http.StatusConflict:            CodeConflict,
+		http.StatusGatewayTimeout:      CodeTimeout,
+		http.StatusInternalServerError: CodeInternal,
+	}
+
+	for status, code := range tests {
+		t.Run("writes "+code+" for /api/v1", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest("GET", "/api/v1/distros", nil), "boom", status)
+
+			var response ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+			if rec.Code != status || response.Error.Code != code || response.Error.Message != "boom" {
+				t.Errorf("expected %d %s, got %d %+v", status, code, rec.Code, response.Error)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("unexpected content type %q", ct)
+			}
+		})
+	}
+}
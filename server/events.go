@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventBuffer is how many events a subscriber can fall behind by
+	// before it is dropped. Dropped clients reconnect with Last-Event-ID
+	// and catch up from the recent events.
+	eventBuffer = 64
+	// recentEvents is how many past events are kept for clients
+	// reconnecting with Last-Event-ID
+	recentEvents = 256
+	// keepaliveInterval is how often an idle event stream is sent a
+	// comment, so proxies and clients don't time it out
+	keepaliveInterval = 15 * time.Second
+)
+
+// Event is a server-sent event
+type Event struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// OperationEvent is the data of events about one distro within a job:
+// operation.started, operation.finished, operation.progress and
+// operation.phase
+type OperationEvent struct {
+	JobID string `json:"jobId"`
+	// Kind is the job's operation, e.g. install, backup or copy
+	Kind string `json:"kind"`
+	JobItem
+	// BytesWritten is how much of a backup has been exported so far
+	BytesWritten int64 `json:"bytesWritten,omitempty"`
+	// Phase is the step a copy is at: export or import
+	Phase string `json:"phase,omitempty"`
+}
+
+// DistroEvent is the data of distro.added, distro.removed and
+// distro.state events
+type DistroEvent struct {
+	Name     string `json:"name"`
+	State    string `json:"state,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// DefaultEvent is the data of default.changed events
+type DefaultEvent struct {
+	Default  string `json:"default"`
+	Previous string `json:"previous"`
+}
+
+// EventBroker fans events out to the clients of /api/events. A nil broker
+// discards events.
+type EventBroker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[chan Event]struct{}
+	recent      []Event
+	closed      bool
+}
+
+// NewEventBroker creates an event broker with no subscribers
+func NewEventBroker() *EventBroker {
+	return &EventBroker{subscribers: map[chan Event]struct{}{}}
+}
+
+// Publish sends an event to every subscriber. Subscribers too far behind
+// to take it are dropped rather than holding up the publisher.
+func (b *EventBroker) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now(), Data: data}
+	b.recent = append(b.recent, event)
+	if len(b.recent) > recentEvents {
+		b.recent = b.recent[len(b.recent)-recentEvents:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now
+// on, along with the recent events after lastID that the caller missed.
+// The channel is closed if the subscriber falls behind or the broker is
+// closed; call unsubscribe once done with it.
+func (b *EventBroker) Subscribe(lastID uint64) (events <-chan Event, missed []Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, eventBuffer)
+	if b.closed {
+		close(ch)
+		return ch, nil, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	if lastID > 0 {
+		for _, event := range b.recent {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	return ch, missed, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers
+func (b *EventBroker) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// Close ends every subscription, so open event streams finish and don't
+// hold up the server shutting down
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// handleEvents streams events to the client as server-sent events until it
+// disconnects. ?types= takes a comma-separated list of event types or
+// prefixes (e.g. distro.,default.changed) to limit the stream to. Clients
+// reconnecting with a Last-Event-ID header are sent the recent events they
+// missed first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var types []string
+	if t := r.URL.Query().Get("types"); t != "" {
+		types = strings.Split(t, ",")
+	}
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	events, missed, unsubscribe := s.events.Subscribe(lastID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		writeEvent(w, event, types)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event, types)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes an event in server-sent event format, unless types is
+// non-empty and the event matches none of them
+func writeEvent(w http.ResponseWriter, event Event, types []string) {
+	if len(types) > 0 {
+		wanted := false
+		for _, t := range types {
+			if strings.HasPrefix(event.Type, strings.TrimSpace(t)) {
+				wanted = true
+				break
+			}
+		}
+		if !wanted {
+			return
+		}
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForSubscribers waits until the broker has n subscribers
+func waitForSubscribers(t *testing.T, b *EventBroker, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d subscriber(s), have %d", n, b.Subscribers())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readEvent reads the next event from a server-sent event stream,
+// returning its lines
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// drainEvents returns the events received so far
+func drainEvents(events <-chan Event) []Event {
+	var received []Event
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestEventBroker(t *testing.T) {
+	t.Run("delivers events to subscribers", func(t *testing.T) {
+		b := NewEventBroker()
+		events, missed, unsubscribe := b.Subscribe(0)
+		defer unsubscribe()
+
+		b.Publish("distro.added", DistroEvent{Name: "Ubuntu"})
+
+		event := <-events
+		if event.ID != 1 || event.Type != "distro.added" || event.Data.(DistroEvent).Name != "Ubuntu" {
+			t.Errorf("unexpected event %+v", event)
+		}
+		if len(missed) != 0 {
+			t.Errorf("expected nothing missed, got %+v", missed)
+		}
+	})
+
+	t.Run("replays events after last ID", func(t *testing.T) {
+		b := NewEventBroker()
+		for range 3 {
+			b.Publish("job.queued", nil)
+		}
+
+		_, missed, unsubscribe := b.Subscribe(1)
+		defer unsubscribe()
+
+		if len(missed) != 2 || missed[0].ID != 2 || missed[1].ID != 3 {
+			t.Errorf("expected events 2 and 3, got %+v", missed)
+		}
+	})
+
+	t.Run("drops subscribers that fall behind", func(t *testing.T) {
+		b := NewEventBroker()
+		events, _, unsubscribe := b.Subscribe(0)
+		defer unsubscribe()
+
+		for range eventBuffer + 1 {
+			b.Publish("operation.progress", nil)
+		}
+
+		if b.Subscribers() != 0 {
+			t.Error("expected subscriber to be dropped")
+		}
+		received := 0
+		for range events {
+			received++
+		}
+		if received != eventBuffer {
+			t.Errorf("expected %d buffered events before the channel closed, got %d", eventBuffer, received)
+		}
+	})
+
+	t.Run("close ends subscriptions", func(t *testing.T) {
+		b := NewEventBroker()
+		events, _, unsubscribe := b.Subscribe(0)
+		defer unsubscribe()
+
+		b.Close()
+
+		if _, ok := <-events; ok {
+			t.Error("expected channel to be closed")
+		}
+		late, _, _ := b.Subscribe(0)
+		if _, ok := <-late; ok {
+			t.Error("expected subscribing after close to return a closed channel")
+		}
+	})
+
+	t.Run("nil broker discards events", func(t *testing.T) {
+		var b *EventBroker
+		b.Publish("job.queued", nil)
+		if b.Subscribers() != 0 {
+			t.Error("expected no subscribers")
+		}
+	})
+}
+
+// namedCopier is a mockCopier where only the listed distros are
+// registered
+type namedCopier struct {
+	mockCopier
+	registered map[string]bool
+}
+
+func (c *namedCopier) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return c.registered[name], nil
+}
+
+func TestJobEvents(t *testing.T) {
+	b := NewEventBroker()
+	events, _, unsubscribe := b.Subscribe(0)
+	defer unsubscribe()
+
+	m := NewJobManager(b)
+	defer m.Shutdown()
+	srv := &Server{copier: &namedCopier{registered: map[string]bool{"Ubuntu": true}}, jobs: m, events: b}
+
+	body := fmt.Sprintf(`{"source":"Ubuntu","newName":"Ubuntu-copy","installDir":%q}`, t.TempDir())
+	rec := httptest.NewRecorder()
+	srv.handleCopy(rec, testRequest("POST", "/api/copy", []byte(body)))
+	if job := waitForAcceptedJob(t, srv, rec); job.State != JobSucceeded {
+		t.Fatalf("expected copy to succeed, got %+v", job)
+	}
+
+	var types, phases []string
+	for _, event := range drainEvents(events) {
+		types = append(types, event.Type)
+		if event.Type == "operation.phase" {
+			phases = append(phases, event.Data.(OperationEvent).Phase)
+		}
+	}
+
+	want := "job.queued,job.started,operation.started,operation.phase,operation.phase,operation.finished,job.finished"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("expected events %s, got %s", want, got)
+	}
+	if strings.Join(phases, ",") != "export,import" {
+		t.Errorf("expected export then import phases, got %v", phases)
+	}
+}
+
+func TestHandleEvents(t *testing.T) {
+	t.Run("returns 405 for non-GET", func(t *testing.T) {
+		srv := &Server{events: NewEventBroker()}
+		rec := httptest.NewRecorder()
+		srv.handleEvents(rec, httptest.NewRequest("POST", "/api/events", nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("streams events", func(t *testing.T) {
+		b := NewEventBroker()
+		srv := &Server{events: b}
+		ts := httptest.NewServer(http.HandlerFunc(srv.handleEvents))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"?types=distro.,default.changed", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		waitForSubscribers(t, b, 1)
+
+		b.Publish("job.queued", nil)
+		b.Publish("distro.state", DistroEvent{Name: "Ubuntu", State: "Running", Previous: "Stopped"})
+
+		r := bufio.NewReader(resp.Body)
+		lines := readEvent(t, r)
+		want := []string{"id: 2", "event: distro.state", `data: {"name":"Ubuntu","state":"Running","previous":"Stopped"}`}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("expected filtered event %q, got %q", want, lines)
+		}
+
+		b.Close()
+		if _, err := r.ReadString('\n'); err == nil {
+			t.Error("expected stream to end when the broker closes")
+		}
+	})
+
+	t.Run("replays missed events", func(t *testing.T) {
+		b := NewEventBroker()
+		b.Publish("distro.added", DistroEvent{Name: "Ubuntu"})
+		b.Publish("distro.added", DistroEvent{Name: "Debian"})
+		srv := &Server{events: b}
+		ts := httptest.NewServer(http.HandlerFunc(srv.handleEvents))
+		defer ts.Close()
+		defer b.Close()
+
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		lines := readEvent(t, bufio.NewReader(resp.Body))
+		if len(lines) != 3 || lines[0] != "id: 2" || !strings.Contains(lines[2], "Debian") {
+			t.Errorf("expected event 2 to be replayed, got %q", lines)
+		}
+	})
+}
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned for an unknown job ID
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobFinished is returned when cancelling a job that has already finished
+var ErrJobFinished = errors.New("job has already finished")
+
+// maxFinishedJobs is how many finished jobs are remembered. Older ones are
+// forgotten as new jobs finish.
+const maxFinishedJobs = 100
+
+// JobState is the state of a job, or of one distro within it
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// Finished reports whether the state is final
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// JobItem tracks one distro within a job
+type JobItem struct {
+	Distro     string     `json:"distro"`
+	State      JobState   `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Job is a long-running operation run in the background, such as an
+// install or backup
+type Job struct {
+	ID string `json:"id"`
+	// Kind is the operation, e.g. install, backup or copy
+	Kind  string   `json:"kind"`
+	State JobState `json:"state"`
+	// Error says why the job failed or was cancelled
+	Error string    `json:"error,omitempty"`
+	Items []JobItem `json:"items"`
+	// Result is the operation's response, in the same shape the endpoint
+	// returned before it ran as a job. Set once the job finishes.
+	Result     interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// JobFunc runs a job, reporting the progress of each distro through p. It
+// should stop early once ctx is cancelled.
+type JobFunc func(ctx context.Context, p *JobProgress) (interface{}, error)
+
+// JobProgress records the progress of the distros within a running job
+type JobProgress struct {
+	m  *JobManager
+	mj *managedJob
+}
+
+// Start marks the i-th distro of the job as running
+func (p *JobProgress) Start(i int) {
+	p.m.mu.Lock()
+	defer p.m.mu.Unlock()
+
+	now := p.m.now()
+	item := &p.mj.job.Items[i]
+	item.State = JobRunning
+	item.StartedAt = &now
+	p.publish("operation.started", i, nil)
+}
+
+// Finish marks the i-th distro of the job as done
+func (p *JobProgress) Finish(i int, success bool, message string) {
+	p.m.mu.Lock()
+	defer p.m.mu.Unlock()
+
+	now := p.m.now()
+	item := &p.mj.job.Items[i]
+	item.State = JobSucceeded
+	if !success {
+		item.State = JobFailed
+	}
+	item.Message = message
+	item.FinishedAt = &now
+	p.publish("operation.finished", i, nil)
+	p.m.metrics.observeOperation(p.mj.job.Kind, success)
+}
+
+// Progress reports how many bytes the i-th distro's backup has written
+func (p *JobProgress) Progress(i int, bytesWritten int64) {
+	p.m.mu.Lock()
+	defer p.m.mu.Unlock()
+
+	p.publish("operation.progress", i, func(e *OperationEvent) { e.BytesWritten = bytesWritten })
+}
+
+// Phase reports the step the i-th distro is at, such as export or import
+func (p *JobProgress) Phase(i int, phase string) {
+	p.m.mu.Lock()
+	defer p.m.mu.Unlock()
+
+	p.publish("operation.phase", i, func(e *OperationEvent) { e.Phase = phase })
+}
+
+// publish publishes an event about the i-th distro, letting set fill in
+// any extra fields. The caller must hold p.m.mu.
+func (p *JobProgress) publish(eventType string, i int, set func(*OperationEvent)) {
+	event := OperationEvent{JobID: p.mj.job.ID, Kind: p.mj.job.Kind, JobItem: p.mj.job.Items[i]}
+	if set != nil {
+		set(&event)
+	}
+	p.m.events.Publish(eventType, event)
+}
+
+// managedJob is a job along with what's needed to run and cancel it
+type managedJob struct {
+	job Job
+	fn  JobFunc
+	// timeout limits how long the job runs for, once started. Zero means
+	// no limit.
+	timeout time.Duration
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// JobManager runs jobs in the background, one at a time in the order they
+// were submitted, so long-running WSL operations don't compete with each
+// other for the disk
+type JobManager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// events receives job.* and operation.* events. May be nil.
+	events *EventBroker
+	// metrics records the outcome of each distro within a job. May be nil.
+	metrics *Metrics
+	// wake signals the worker that a job was queued
+	wake chan struct{}
+	// now returns the current time. Tests replace it to control the clock.
+	now func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*managedJob
+	// order holds job IDs, oldest first
+	order []string
+}
+
+// NewJobManager creates a job manager and starts its worker, which runs
+// until Shutdown is called. Job progress is published to events.
+func NewJobManager(events *EventBroker) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &JobManager{
+		ctx:    ctx,
+		cancel: cancel,
+		events: events,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+		jobs:   map[string]*managedJob{},
+	}
+	go m.work()
+	return m
+}
+
+// Submit queues a job working on distros, returning it straight away. The
+// job is cancelled if it runs for longer than timeout, unless timeout is
+// zero.
+func (m *JobManager) Submit(kind string, distros []string, timeout time.Duration, fn JobFunc) Job {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	items := make([]JobItem, len(distros))
+	for i, distro := range distros {
+		items[i] = JobItem{Distro: distro, State: JobQueued}
+	}
+
+	mj := &managedJob{
+		job: Job{
+			ID:        newJobID(),
+			Kind:      kind,
+			State:     JobQueued,
+			Items:     items,
+			CreatedAt: m.now(),
+		},
+		fn:      fn,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.jobs[mj.job.ID] = mj
+	m.order = append(m.order, mj.job.ID)
+	job := mj.snapshot()
+	m.events.Publish("job.queued", job)
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+
+	return job
+}
+
+// Get returns the job with the given ID
+func (m *JobManager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mj, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return mj.snapshot(), nil
+}
+
+// List returns every job, newest first
+func (m *JobManager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, m.jobs[m.order[i]].snapshot())
+	}
+	return jobs
+}
+
+// Cancel cancels a job. A queued job is cancelled straight away; a running
+// job has its context cancelled and is marked cancelled once it stops.
+func (m *JobManager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mj, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if mj.job.State.Finished() {
+		return mj.snapshot(), ErrJobFinished
+	}
+
+	mj.cancel()
+	if mj.job.State == JobQueued {
+		m.finish(mj, nil, context.Canceled)
+	}
+	return mj.snapshot(), nil
+}
+
+// Wait blocks until the job with the given ID finishes or ctx is done,
+// returning the job
+func (m *JobManager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	mj, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	select {
+	case <-mj.done:
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+	return m.Get(id)
+}
+
+// Shutdown cancels every queued and running job and stops the worker
+func (m *JobManager) Shutdown() {
+	m.cancel()
+}
+
+// work runs queued jobs in order until the manager is shut down
+func (m *JobManager) work() {
+	for {
+		mj := m.next()
+		if mj == nil {
+			select {
+			case <-m.wake:
+				continue
+			case <-m.ctx.Done():
+				m.cancelQueued()
+				return
+			}
+		}
+		m.run(mj)
+	}
+}
+
+// next returns the oldest queued job, or nil if there is none
+func (m *JobManager) next() *managedJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range m.order {
+		if mj := m.jobs[id]; mj.job.State == JobQueued {
+			return mj
+		}
+	}
+	return nil
+}
+
+// run runs a job and records its outcome
+func (m *JobManager) run(mj *managedJob) {
+	m.mu.Lock()
+	// The job may have been cancelled since it was picked
+	if mj.job.State != JobQueued {
+		m.mu.Unlock()
+		return
+	}
+	now := m.now()
+	mj.job.State = JobRunning
+	mj.job.StartedAt = &now
+	m.events.Publish("job.started", mj.snapshot())
+	m.mu.Unlock()
+	slog.Info("Job started", "job", mj.job.ID, "kind", mj.job.Kind)
+
+	ctx := mj.ctx
+	if mj.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mj.timeout)
+		defer cancel()
+	}
+
+	result, err := mj.fn(ctx, &JobProgress{m: m, mj: mj})
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	m.mu.Lock()
+	m.finish(mj, result, err)
+	m.mu.Unlock()
+}
+
+// cancelQueued cancels the jobs still waiting when the manager shuts down
+func (m *JobManager) cancelQueued() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mj := range m.jobs {
+		if mj.job.State == JobQueued {
+			m.finish(mj, nil, context.Canceled)
+		}
+	}
+}
+
+// finish records the outcome of a job and forgets the oldest finished jobs
+// beyond maxFinishedJobs. The caller must hold m.mu.
+func (m *JobManager) finish(mj *managedJob, result interface{}, err error) {
+	now := m.now()
+	mj.job.Result = result
+	mj.job.FinishedAt = &now
+	mj.cancel()
+
+	failed := 0
+	for i := range mj.job.Items {
+		item := &mj.job.Items[i]
+		switch {
+		case item.State == JobFailed:
+			failed++
+		case !item.State.Finished() && errors.Is(err, context.Canceled):
+			item.State = JobCancelled
+		case !item.State.Finished():
+			item.State = JobFailed
+			failed++
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		mj.job.State = JobCancelled
+		mj.job.Error = "Job was cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		mj.job.State = JobFailed
+		mj.job.Error = fmt.Sprintf("Job timed out after %s", mj.timeout)
+	case err != nil:
+		mj.job.State = JobFailed
+		mj.job.Error = err.Error()
+	case failed > 0:
+		mj.job.State = JobFailed
+		mj.job.Error = fmt.Sprintf("%d of %d distribution(s) failed", failed, len(mj.job.Items))
+	default:
+		mj.job.State = JobSucceeded
+	}
+	close(mj.done)
+	m.events.Publish("job.finished", mj.snapshot())
+
+	level := slog.LevelInfo
+	if mj.job.State == JobFailed {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "Job finished", "job", mj.job.ID, "kind", mj.job.Kind, "state", mj.job.State, "error", mj.job.Error)
+
+	m.forgetOldJobs()
+}
+
+// forgetOldJobs drops the oldest finished jobs beyond maxFinishedJobs. The
+// caller must hold m.mu.
+func (m *JobManager) forgetOldJobs() {
+	finished := 0
+	for _, id := range m.order {
+		if m.jobs[id].job.State.Finished() {
+			finished++
+		}
+	}
+
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > maxFinishedJobs && m.jobs[id].job.State.Finished() {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+// snapshot returns a copy of the job that is safe to use without holding
+// the manager's lock. The caller must hold m.mu.
+func (mj *managedJob) snapshot() Job {
+	job := mj.job
+	job.Items = append([]JobItem(nil), mj.job.Items...)
+	return job
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
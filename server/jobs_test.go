@@ -0,0 +1,298 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestJobManager creates a job manager that is shut down when the test
+// ends
+func newTestJobManager(t *testing.T) *JobManager {
+	t.Helper()
+
+	m := NewJobManager(nil)
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+// waitForJob waits a few seconds for a job to finish
+func waitForJob(t *testing.T, m *JobManager, id string) Job {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("failed waiting for job %s: %v", id, err)
+	}
+	return job
+}
+
+// waitForAcceptedJob checks a handler started a job and waits for it to
+// finish
+func waitForAcceptedJob(t *testing.T, srv *Server, rec *httptest.ResponseRecorder) Job {
+	t.Helper()
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var job Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/jobs/"+job.ID {
+		t.Errorf("unexpected Location %q", loc)
+	}
+	return waitForJob(t, srv.jobs, job.ID)
+}
+
+// blockingJob returns a job that marks its first distro running, signals
+// started, then waits until released or cancelled
+func blockingJob(started chan<- struct{}, release <-chan struct{}) JobFunc {
+	return func(ctx context.Context, p *JobProgress) (interface{}, error) {
+		p.Start(0)
+		close(started)
+		select {
+		case <-release:
+			p.Finish(0, true, "done")
+			return "ok", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestJobManager(t *testing.T) {
+	t.Run("runs jobs and records results", func(t *testing.T) {
+		m := newTestJobManager(t)
+
+		job := m.Submit("backup", []string{"Ubuntu", "Debian"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			for i := range 2 {
+				p.Start(i)
+				p.Finish(i, i == 0, fmt.Sprintf("item %d", i))
+			}
+			return "result", nil
+		})
+		if job.State != JobQueued || job.ID == "" || len(job.Items) != 2 {
+			t.Fatalf("expected a queued job, got %+v", job)
+		}
+
+		job = waitForJob(t, m, job.ID)
+		if job.State != JobFailed || job.Error != "1 of 2 distribution(s) failed" {
+			t.Errorf("expected job to fail with one failed distro, got %s (%s)", job.State, job.Error)
+		}
+		if job.Result != "result" || job.StartedAt == nil || job.FinishedAt == nil {
+			t.Errorf("expected result and timestamps, got %+v", job)
+		}
+		if job.Items[0].State != JobSucceeded || job.Items[1].State != JobFailed || job.Items[1].Message != "item 1" {
+			t.Errorf("unexpected items %+v", job.Items)
+		}
+	})
+
+	t.Run("marks job failed on error", func(t *testing.T) {
+		m := newTestJobManager(t)
+
+		job := m.Submit("copy", []string{"Ubuntu"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			return nil, errors.New("boom")
+		})
+
+		job = waitForJob(t, m, job.ID)
+		if job.State != JobFailed || job.Error != "boom" || job.Items[0].State != JobFailed {
+			t.Errorf("expected failed job, got %+v", job)
+		}
+	})
+
+	t.Run("runs jobs one at a time in order", func(t *testing.T) {
+		m := newTestJobManager(t)
+		started, release := make(chan struct{}), make(chan struct{})
+
+		first := m.Submit("backup", []string{"Ubuntu"}, 0, blockingJob(started, release))
+		second := m.Submit("backup", []string{"Debian"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			p.Start(0)
+			p.Finish(0, true, "done")
+			return nil, nil
+		})
+		<-started
+
+		if job, _ := m.Get(second.ID); job.State != JobQueued {
+			t.Errorf("expected second job to wait, got %s", job.State)
+		}
+		if jobs := m.List(); len(jobs) != 2 || jobs[0].ID != second.ID {
+			t.Errorf("expected newest job first, got %+v", jobs)
+		}
+
+		close(release)
+		if job := waitForJob(t, m, second.ID); job.State != JobSucceeded {
+			t.Errorf("expected second job to succeed, got %s", job.State)
+		}
+		if job, _ := m.Get(first.ID); job.State != JobSucceeded {
+			t.Errorf("expected first job to succeed, got %s", job.State)
+		}
+	})
+
+	t.Run("cancels running and queued jobs", func(t *testing.T) {
+		m := newTestJobManager(t)
+		started := make(chan struct{})
+
+		running := m.Submit("backup", []string{"Ubuntu", "Debian"}, 0, blockingJob(started, nil))
+		queued := m.Submit("install", []string{"Debian"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			t.Error("expected cancelled job not to run")
+			return nil, nil
+		})
+		<-started
+
+		job, err := m.Cancel(queued.ID)
+		if err != nil || job.State != JobCancelled || job.Items[0].State != JobCancelled {
+			t.Errorf("expected queued job to be cancelled straight away, got %+v (%v)", job, err)
+		}
+
+		if _, err := m.Cancel(running.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		job = waitForJob(t, m, running.ID)
+		if job.State != JobCancelled || job.Items[0].State != JobCancelled || job.Items[1].State != JobCancelled {
+			t.Errorf("expected running job to be cancelled, got %+v", job)
+		}
+
+		if _, err := m.Cancel(running.ID); !errors.Is(err, ErrJobFinished) {
+			t.Errorf("expected ErrJobFinished, got %v", err)
+		}
+		if _, err := m.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("expected ErrJobNotFound, got %v", err)
+		}
+	})
+
+	t.Run("fails jobs that time out", func(t *testing.T) {
+		m := newTestJobManager(t)
+		started := make(chan struct{})
+
+		job := m.Submit("backup", []string{"Ubuntu"}, 10*time.Millisecond, blockingJob(started, nil))
+
+		job = waitForJob(t, m, job.ID)
+		if job.State != JobFailed || job.Error != "Job timed out after 10ms" || job.Items[0].State != JobFailed {
+			t.Errorf("expected job to time out, got %+v", job)
+		}
+	})
+
+	t.Run("forgets old finished jobs", func(t *testing.T) {
+		m := newTestJobManager(t)
+
+		var last Job
+		for range maxFinishedJobs + 5 {
+			last = m.Submit("copy", nil, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+				return nil, nil
+			})
+		}
+		waitForJob(t, m, last.ID)
+
+		if jobs := m.List(); len(jobs) != maxFinishedJobs {
+			t.Errorf("expected %d jobs, got %d", maxFinishedJobs, len(jobs))
+		}
+	})
+}
+
+func TestHandleJobs(t *testing.T) {
+	m := newTestJobManager(t)
+	srv := &Server{jobs: m}
+	started := make(chan struct{})
+	running := m.Submit("backup", []string{"Ubuntu"}, 0, blockingJob(started, nil))
+	<-started
+
+	t.Run("returns 405 for non-GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.handleJobs(rec, httptest.NewRequest("POST", "/api/jobs", nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("lists jobs", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.handleJobs(rec, httptest.NewRequest("GET", "/api/jobs", nil))
+
+		var response struct {
+			Jobs []Job `json:"jobs"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(response.Jobs) != 1 || response.Jobs[0].ID != running.ID || response.Jobs[0].State != JobRunning {
+			t.Errorf("expected the running job, got %+v", response.Jobs)
+		}
+	})
+
+	// The cases run in order, so the job is only cancelled after it has
+	// been fetched
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		want   int
+	}{
+		{"returns 405 for POST", "POST", running.ID, http.StatusMethodNotAllowed},
+		{"returns 404 for unknown job", "GET", "missing", http.StatusNotFound},
+		{"returns job", "GET", running.ID, http.StatusOK},
+		{"cancels job", "DELETE", running.ID, http.StatusOK},
+		{"returns 404 cancelling unknown job", "DELETE", "missing", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/jobs/"+tc.id, nil)
+			req.SetPathValue("id", tc.id)
+			srv.handleJob(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("expected %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("returns 409 cancelling finished job", func(t *testing.T) {
+		waitForJob(t, m, running.ID)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/api/jobs/"+running.ID, nil)
+		req.SetPathValue("id", running.ID)
+		srv.handleJob(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleCopy(t *testing.T) {
+	t.Run("returns 400 for missing newName", func(t *testing.T) {
+		srv := &Server{jobs: newTestJobManager(t)}
+		rec := httptest.NewRecorder()
+		body := `{"source":"Ubuntu"}`
+		srv.handleCopy(rec, testRequest("POST", "/api/copy", []byte(body)))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("copies in a job", func(t *testing.T) {
+		srv := &Server{copier: &mockCopier{registered: true}, jobs: newTestJobManager(t)}
+		rec := httptest.NewRecorder()
+		body := `{"source":"Ubuntu","newName":"Ubuntu-copy"}`
+		srv.handleCopy(rec, testRequest("POST", "/api/copy", []byte(body)))
+
+		job := waitForAcceptedJob(t, srv, rec)
+		if job.Kind != "copy" || len(job.Items) != 1 || job.Items[0].Distro != "Ubuntu" {
+			t.Errorf("unexpected job %+v", job)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wslp/internal/wsl"
+)
+
+// waitForLocks reports whether a request asked, with ?wait=true, to wait
+// for busy distros rather than fail
+func waitForLocks(r *http.Request) bool {
+	wait, _ := strconv.ParseBool(r.URL.Query().Get("wait"))
+	return wait
+}
+
+// lockContext returns ctx, made to wait for busy distros if wait is set
+func lockContext(ctx context.Context, wait bool) context.Context {
+	if wait {
+		return wsl.WithLockWait(ctx)
+	}
+	return ctx
+}
+
+// checkNotBusy replies 409 Conflict if another operation holds one of
+// distros, returning false, unless the request waits for them. The
+// operation itself still takes the locks, so this only spares clients a
+// job that would fail straight away.
+func checkNotBusy(w http.ResponseWriter, r *http.Request, distros ...string) bool {
+	if waitForLocks(r) {
+		return true
+	}
+	for _, distro := range distros {
+		if operation, busy := wsl.Locks.Busy(distro); busy {
+			err := &wsl.BusyError{Distro: distro, Operation: operation}
+			writeErrorCode(w, r, CodeDistroBusy, err.Error(), http.StatusConflict)
+			return false
+		}
+	}
+	return true
+}
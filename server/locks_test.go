@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wslp/internal/wsl"
+)
+
+// holdLock locks distro for operation in a fresh wsl.Locks for the
+// duration of a test, returning the function releasing it
+func holdLock(t *testing.T, distro, operation string) func() {
+	t.Helper()
+
+	previous := wsl.Locks
+	wsl.Locks = wsl.NewLockManager("")
+	t.Cleanup(func() { wsl.Locks = previous })
+
+	unlock, err := wsl.Locks.TryLock(distro, operation)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	t.Cleanup(unlock)
+	return unlock
+}
+
+func TestBusyDistros(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	t.Run("refuses operations on a busy distro", func(t *testing.T) {
+		holdLock(t, "Ubuntu", "backup")
+		handler := newV1Handler(&Server{terminator: &mockTerminator{registered: true}})
+
+		req := httptest.NewRequest("POST", "/api/v1/distros/Ubuntu/terminate", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid error response: %v", err)
+		}
+		if resp.Error.Code != CodeDistroBusy || resp.Error.Message != "Ubuntu is busy: backup in progress" {
+			t.Errorf("unexpected error %+v", resp.Error)
+		}
+		doc.checkSchema(t, doc.responseSchema(t, "/distros/{name}/terminate", "post", http.StatusConflict), w.Body.Bytes())
+	})
+
+	t.Run("waits for a busy distro when asked to", func(t *testing.T) {
+		unlock := holdLock(t, "Ubuntu", "backup")
+		handler := newV1Handler(&Server{terminator: &mockTerminator{registered: true}})
+		time.AfterFunc(50*time.Millisecond, unlock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req := httptest.NewRequest("POST", "/api/v1/distros/Ubuntu/terminate?wait=true", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200 once the backup finished, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("refuses jobs on a busy distro", func(t *testing.T) {
+		holdLock(t, "Ubuntu-copy", "restore")
+		jobs := newTestJobManager(t)
+		handler := newV1Handler(&Server{copier: &mockCopier{registered: true}, jobs: jobs})
+
+		req := httptest.NewRequest("POST", "/api/v1/distros/Ubuntu/copy", strings.NewReader(`{"newName":"Ubuntu-copy"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		if len(jobs.List()) != 0 {
+			t.Error("expected no job to be started")
+		}
+	})
+
+	t.Run("lists busy distros", func(t *testing.T) {
+		holdLock(t, "Ubuntu", "backup")
+		handler := newV1Handler(&Server{lister: &mockLister{distros: []string{"Ubuntu", "Debian"}}})
+
+		req := httptest.NewRequest("GET", "/api/v1/distros", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var resp struct {
+			Distros []wsl.DistroInfo `json:"distros"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		distros := resp.Distros
+		if len(distros) != 2 {
+			t.Fatalf("expected 2 distros, got %+v", distros)
+		}
+		if !distros[0].Busy || distros[0].Operation != "backup" {
+			t.Errorf("expected Ubuntu busy with backup, got %+v", distros[0])
+		}
+		if distros[1].Busy {
+			t.Errorf("expected Debian not to be busy, got %+v", distros[1])
+		}
+	})
+}
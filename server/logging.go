@@ -0,0 +1,33 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// logRequests logs each request once it has been handled. Changes are
+// logged at info level and reads, which the GUI makes every few seconds,
+// at debug level. Server errors are logged as warnings.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= http.StatusInternalServerError:
+			level = slog.LevelWarn
+		case r.Method == http.MethodGet || r.Method == http.MethodOptions:
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "Handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(started),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"wslp/internal/wsl"
+)
+
+// metricsNamespace prefixes every metric the server exports
+const metricsNamespace = "wslp"
+
+// jobKinds are the kinds of job the server runs, reported even while none
+// are in flight so their series don't come and go
+var jobKinds = []string{"install", "backup", "copy"}
+
+// Metrics records what the server has done since it started, served in the
+// Prometheus format by /metrics. A nil *Metrics records nothing, so tests
+// can leave it out.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	operations      *prometheus.CounterVec
+	backupSize      *prometheus.GaugeVec
+	backupDuration  *prometheus.HistogramVec
+	lastBackup      *prometheus.GaugeVec
+}
+
+// NewMetrics creates the server's metrics, along with the Go runtime and
+// process metrics
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by route pattern and method. Event streams are not included.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "operations_total",
+			Help:      "Operations run on distros, by operation (install, backup, copy or terminate) and outcome (success or failure).",
+		}, []string{"operation", "outcome"}),
+		backupSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "backup_size_bytes",
+			Help:      "Size of the last successful backup of each distro.",
+		}, []string{"distro"}),
+		// Exports of large distros take minutes, so the buckets run from
+		// a few seconds to a couple of hours
+		backupDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "backup_duration_seconds",
+			Help:      "Time taken by successful backups, from the start of the export until the archive was stored.",
+			Buckets:   prometheus.ExponentialBuckets(5, 2, 11),
+		}, []string{"distro"}),
+		lastBackup: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "backup_last_success_timestamp_seconds",
+			Help:      "When the last successful backup of each distro finished, as a Unix timestamp.",
+		}, []string{"distro"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.operations,
+		m.backupSize,
+		m.backupDuration,
+		m.lastBackup,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// observeOperation records the outcome of an operation on a distro, e.g.
+// a terminate
+func (m *Metrics) observeOperation(operation string, success bool) {
+	if m == nil {
+		return
+	}
+	outcome := "success"
+	if !success {
+		outcome = "failure"
+	}
+	m.operations.WithLabelValues(operation, outcome).Inc()
+}
+
+// observeBackup records the size and duration of a successful backup.
+// Its outcome is recorded separately, with observeOperation.
+func (m *Metrics) observeBackup(result wsl.BackupResult) {
+	if m == nil || !result.Success {
+		return
+	}
+	m.backupSize.WithLabelValues(result.Distro).Set(float64(result.Size))
+	m.backupDuration.WithLabelValues(result.Distro).Observe(result.Duration)
+	m.lastBackup.WithLabelValues(result.Distro).SetToCurrentTime()
+}
+
+// instrument counts and times the requests handled by mux. It must wrap
+// the mux directly, as the route is the pattern the mux matched, which
+// keeps the number of series bounded whatever paths clients request.
+// Requests turned away by the middleware around it are not counted.
+func (m *Metrics) instrument(mux *http.ServeMux) http.Handler {
+	if m == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		// An event stream lasts as long as the client stays connected,
+		// which says nothing about how fast the server is
+		if rec.Header().Get("Content-Type") != "text/event-stream" {
+			m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(started).Seconds())
+		}
+	})
+}
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush lets event streams flush through the recorder
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// handleMetrics serves the metrics in the Prometheus text format. The
+// number of distros and jobs is read at each scrape, so those are always
+// current.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := prometheus.NewRegistry()
+	state.MustRegister(&stateCollector{ctx: r.Context(), s: s})
+	gatherers := prometheus.Gatherers{state}
+	if s.metrics != nil {
+		gatherers = append(gatherers, s.metrics.registry)
+	}
+
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(w, r)
+}
+
+var (
+	distrosDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "distros"),
+		"Registered distros, by state (registered, running or busy). Registered counts every distro.",
+		[]string{"state"}, nil,
+	)
+	wslUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "wsl_up"),
+		"Whether the registered distros could be listed at the last scrape.",
+		nil, nil,
+	)
+	jobsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "jobs_in_flight"),
+		"Jobs queued or running, by kind and state.",
+		[]string{"kind", "state"}, nil,
+	)
+)
+
+// stateCollector reports the distros and jobs as they are when scraped
+type stateCollector struct {
+	ctx context.Context
+	s   *Server
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- distrosDesc
+	ch <- wslUpDesc
+	ch <- jobsDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.s.lister != nil {
+		c.collectDistros(ch)
+	}
+	if c.s.jobs != nil {
+		c.collectJobs(ch)
+	}
+}
+
+func (c *stateCollector) collectDistros(ch chan<- prometheus.Metric) {
+	distros, err := wsl.ListDistros(c.ctx, c.s.lister)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(wslUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(wslUpDesc, prometheus.GaugeValue, 1)
+
+	running, busy := 0, 0
+	for _, distro := range distros {
+		if distro.Running {
+			running++
+		}
+		if distro.Busy {
+			busy++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(distrosDesc, prometheus.GaugeValue, float64(len(distros)), "registered")
+	ch <- prometheus.MustNewConstMetric(distrosDesc, prometheus.GaugeValue, float64(running), "running")
+	ch <- prometheus.MustNewConstMetric(distrosDesc, prometheus.GaugeValue, float64(busy), "busy")
+}
+
+func (c *stateCollector) collectJobs(ch chan<- prometheus.Metric) {
+	counts := map[string]map[JobState]int{}
+	for _, kind := range jobKinds {
+		counts[kind] = map[JobState]int{}
+	}
+	for _, job := range c.s.jobs.List() {
+		if job.State.Finished() {
+			continue
+		}
+		if counts[job.Kind] == nil {
+			counts[job.Kind] = map[JobState]int{}
+		}
+		counts[job.Kind][job.State]++
+	}
+
+	for kind, states := range counts {
+		for _, state := range []JobState{JobQueued, JobRunning} {
+			ch <- prometheus.MustNewConstMetric(jobsDesc, prometheus.GaugeValue, float64(states[state]), kind, string(state))
+		}
+	}
+}
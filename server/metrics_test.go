@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wslp/internal/wsl"
+)
+
+// scrape fetches /metrics from srv, failing the test on any error
+func scrape(t *testing.T, srv *Server) string {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+srv.token)
+	w := httptest.NewRecorder()
+	srv.handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+// checkMetrics fails the test unless every line in want is in the scraped
+// metrics
+func checkMetrics(t *testing.T, metrics string, want ...string) {
+	t.Helper()
+
+	for _, line := range want {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("expected %q in metrics:\n%s", line, metrics)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("counts requests and operations", func(t *testing.T) {
+		srv := &Server{
+			lister:     &mockLister{distros: []string{"Ubuntu", "Debian"}},
+			terminator: &mockTerminator{registered: true},
+			jobs:       newTestJobManager(t),
+			metrics:    NewMetrics(),
+			token:      "test-token",
+		}
+		handler := srv.handler()
+
+		for _, path := range []string{"/api/v1/distros/Ubuntu/terminate", "/api/v1/distros/Debian/terminate"} {
+			req := httptest.NewRequest("POST", path, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+		}
+		req := httptest.NewRequest("GET", "/nowhere", nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		checkMetrics(t, scrape(t, srv),
+			`wslp_http_requests_total{code="200",method="POST",route="/api/v1/distros/{name}/terminate"} 2`,
+			`wslp_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+			`wslp_http_request_duration_seconds_count{method="POST",route="/api/v1/distros/{name}/terminate"} 2`,
+			`wslp_operations_total{operation="terminate",outcome="success"} 2`,
+			`wslp_distros{state="registered"} 2`,
+			`wslp_distros{state="running"} 0`,
+			`wslp_wsl_up 1`,
+			`wslp_jobs_in_flight{kind="backup",state="running"} 0`,
+		)
+	})
+
+	t.Run("counts job outcomes", func(t *testing.T) {
+		metrics := NewMetrics()
+		jobs := newTestJobManager(t)
+		jobs.metrics = metrics
+		srv := &Server{jobs: jobs, metrics: metrics, token: "test-token"}
+
+		job := jobs.Submit("copy", []string{"Ubuntu"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			p.Start(0)
+			p.Finish(0, false, "copy failed")
+			return nil, nil
+		})
+		waitForJob(t, jobs, job.ID)
+
+		checkMetrics(t, scrape(t, srv), `wslp_operations_total{operation="copy",outcome="failure"} 1`)
+	})
+
+	t.Run("reports jobs in flight", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		srv := &Server{jobs: newTestJobManager(t), token: "test-token"}
+
+		job := srv.jobs.Submit("backup", []string{"Ubuntu"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			if got, _ := srv.jobs.Get(job.ID); got.State == JobRunning {
+				break
+			} else if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for the job to start")
+			}
+		}
+		srv.jobs.Submit("install", []string{"Debian"}, 0, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+			return nil, nil
+		})
+
+		checkMetrics(t, scrape(t, srv),
+			`wslp_jobs_in_flight{kind="backup",state="running"} 1`,
+			`wslp_jobs_in_flight{kind="install",state="queued"} 1`,
+		)
+	})
+
+	t.Run("records backups", func(t *testing.T) {
+		srv := &Server{metrics: NewMetrics(), token: "test-token"}
+		srv.metrics.observeBackup(wsl.BackupResult{Distro: "Ubuntu", Success: true, Size: 2048, Duration: 12})
+		srv.metrics.observeBackup(wsl.BackupResult{Distro: "Debian", Success: false})
+
+		metrics := scrape(t, srv)
+		checkMetrics(t, metrics,
+			`wslp_backup_size_bytes{distro="Ubuntu"} 2048`,
+			`wslp_backup_duration_seconds_bucket{distro="Ubuntu",le="20"} 1`,
+			`wslp_backup_duration_seconds_count{distro="Ubuntu"} 1`,
+		)
+		if strings.Contains(metrics, `distro="Debian"`) {
+			t.Errorf("expected failed backups not to be recorded:\n%s", metrics)
+		}
+	})
+
+	t.Run("reports when WSL can't be listed", func(t *testing.T) {
+		srv := &Server{lister: &mockLister{err: errors.New("wsl unavailable")}, token: "test-token"}
+
+		metrics := scrape(t, srv)
+		checkMetrics(t, metrics, `wslp_wsl_up 0`)
+		if strings.Contains(metrics, "wslp_distros{") {
+			t.Errorf("expected no distro counts:\n%s", metrics)
+		}
+	})
+
+	t.Run("streams events through the instrumented handler", func(t *testing.T) {
+		srv := &Server{events: NewEventBroker(), metrics: NewMetrics(), token: "test-token"}
+		ts := httptest.NewServer(srv.handler())
+		t.Cleanup(ts.Close)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/api/v1/events", nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		srv.events.Publish("distro.added", map[string]string{"name": "Ubuntu"})
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read the stream: %v", err)
+		}
+		if !strings.HasPrefix(line, "id: ") {
+			t.Errorf("expected the event to be flushed, got %q", line)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"wslp/internal/config"
+	"wslp/internal/wsl"
+)
+
+// ErrScheduleNotFound is returned for an unknown schedule ID
+var ErrScheduleNotFound = errors.New("backup schedule not found")
+
+// ErrScheduleRunning is returned when a schedule is run while a previous
+// run is still in progress
+var ErrScheduleRunning = errors.New("backup schedule is already running")
+
+// scheduleStateFile holds the status of every schedule in the state
+// directory, so last runs survive restarts and missed runs can be detected
+const scheduleStateFile = "schedules.json"
+
+// ScheduleStatus is the state of a backup schedule
+type ScheduleStatus struct {
+	config.BackupSchedule
+	// NextRun is when the schedule runs next
+	NextRun time.Time `json:"nextRun"`
+	// Running is true while a run is in progress
+	Running bool `json:"running"`
+	// LastRun is when the last run started. Nil if it never ran.
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	// LastDuration is how long the last run took, in seconds
+	LastDuration float64 `json:"lastDuration,omitempty"`
+	// LastStatus is success, partial or failed
+	LastStatus  string `json:"lastStatus,omitempty"`
+	LastMessage string `json:"lastMessage,omitempty"`
+	// LastTrigger is schedule, catch-up or manual
+	LastTrigger string             `json:"lastTrigger,omitempty"`
+	LastResults []wsl.BackupResult `json:"lastResults,omitempty"`
+	LastPruned  []wsl.PruneResult  `json:"lastPruned,omitempty"`
+}
+
+// scheduledBackup is a schedule along with its parsed cron expression
+type scheduledBackup struct {
+	cron   cron.Schedule
+	status ScheduleStatus
+}
+
+// Scheduler runs backups on the schedules in the backup_schedules config,
+// while the server is running
+type Scheduler struct {
+	backuper  wsl.Backuper
+	statePath string
+	// metrics records the outcome of each backup. May be nil.
+	metrics *Metrics
+	// now returns the current time. Tests replace it to control the clock.
+	now func() time.Time
+
+	mu        sync.Mutex
+	schedules []*scheduledBackup
+}
+
+// cronParser accepts standard five-field expressions and descriptors such
+// as @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// NewScheduler creates a scheduler for schedules, keeping its state in
+// stateDir. Status saved by a previous server is loaded so last runs are
+// remembered.
+func NewScheduler(b wsl.Backuper, schedules []config.BackupSchedule, stateDir string) (*Scheduler, error) {
+	s := &Scheduler{
+		backuper:  b,
+		statePath: filepath.Join(stateDir, scheduleStateFile),
+		now:       time.Now,
+	}
+
+	saved := s.loadState()
+	for _, cfg := range schedules {
+		sched, err := cronParser.Parse(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("backup schedule %q: invalid cron expression %q: %w", cfg.ID, cfg.Cron, err)
+		}
+
+		status := saved[cfg.ID]
+		status.BackupSchedule = cfg
+		status.Running = false
+		s.schedules = append(s.schedules, &scheduledBackup{cron: sched, status: status})
+	}
+
+	return s, nil
+}
+
+// Run runs the schedules until ctx is cancelled. Schedules with catch-up
+// enabled whose next run passed while the server was down run once
+// straight away.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sb := range s.schedules {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, sb)
+		}()
+	}
+	wg.Wait()
+}
+
+// loop runs a single schedule until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, sb *scheduledBackup) {
+	s.mu.Lock()
+	missed := sb.status.CatchUp && !sb.status.NextRun.IsZero() && sb.status.NextRun.Before(s.now())
+	s.mu.Unlock()
+
+	if missed {
+		s.RunNow(ctx, sb.status.ID, "catch-up")
+	}
+
+	for {
+		s.mu.Lock()
+		next := sb.cron.Next(s.now())
+		sb.status.NextRun = next
+		s.mu.Unlock()
+		s.saveState()
+
+		// Wake up at least every minute rather than sleeping until the next
+		// run, so a clock change or the machine sleeping doesn't delay it
+		for {
+			wait := next.Sub(s.now())
+			if wait <= 0 {
+				break
+			}
+			if wait > time.Minute {
+				wait = time.Minute
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		s.RunNow(ctx, sb.status.ID, "schedule")
+	}
+}
+
+// Schedules returns the status of every schedule
+func (s *Scheduler) Schedules() []ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ScheduleStatus, len(s.schedules))
+	for i, sb := range s.schedules {
+		statuses[i] = sb.status
+	}
+	return statuses
+}
+
+// RunNow runs the schedule with the given ID immediately and waits for it
+// to finish. trigger records why it ran.
+func (s *Scheduler) RunNow(ctx context.Context, id, trigger string) (ScheduleStatus, error) {
+	s.mu.Lock()
+	var sb *scheduledBackup
+	for _, candidate := range s.schedules {
+		if candidate.status.ID == id {
+			sb = candidate
+		}
+	}
+	if sb == nil {
+		s.mu.Unlock()
+		return ScheduleStatus{}, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	if sb.status.Running {
+		status := sb.status
+		s.mu.Unlock()
+		return status, ErrScheduleRunning
+	}
+	sb.status.Running = true
+	cfg := sb.status.BackupSchedule
+	s.mu.Unlock()
+
+	started := s.now()
+	slog.Info("Running backup schedule", "schedule", id, "trigger", trigger, "distros", cfg.Distros)
+	results, pruned, err := s.backup(ctx, cfg)
+
+	s.mu.Lock()
+	sb.status.Running = false
+	sb.status.LastRun = &started
+	sb.status.LastDuration = s.now().Sub(started).Seconds()
+	sb.status.LastTrigger = trigger
+	sb.status.LastResults = results
+	sb.status.LastPruned = pruned
+	sb.status.LastStatus, sb.status.LastMessage = summarizeRun(results, err)
+	status := sb.status
+	s.mu.Unlock()
+
+	level := slog.LevelInfo
+	if status.LastStatus != "success" {
+		level = slog.LevelWarn
+	}
+	slog.Log(ctx, level, "Backup schedule finished", "schedule", id, "status", status.LastStatus, "message", status.LastMessage, "duration", status.LastDuration)
+
+	s.saveState()
+	return status, nil
+}
+
+// backup runs the backups of a schedule and applies its retention policy
+func (s *Scheduler) backup(ctx context.Context, cfg config.BackupSchedule) ([]wsl.BackupResult, []wsl.PruneResult, error) {
+	format, err := wsl.ParseBackupFormat(cfg.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryption, err := config.GetBackupEncryption()
+	if err != nil {
+		return nil, nil, err
+	}
+	encrypt := encryption.Enabled
+	if cfg.Encrypt != nil {
+		encrypt = *cfg.Encrypt
+	}
+
+	backupDir, target, err := wsl.ResolveBackupDestination(cfg.Target, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	opts := wsl.BackupOptions{Format: format, Encrypt: encrypt, Target: target}
+	// A schedule waits for other operations on its distros, rather than
+	// skipping them until the next run
+	results := wsl.BackupDistros(wsl.WithLockWait(ctx), s.backuper, cfg.Distros, backupDir, opts)
+	for _, result := range results {
+		s.metrics.observeOperation("backup", result.Success)
+		s.metrics.observeBackup(result)
+	}
+
+	// Only local backups can be listed, so remote targets are not pruned
+	if target != nil {
+		return results, nil, nil
+	}
+	policy := cfg.Retention
+	if !policy.Enabled() {
+		policy = config.GetRetentionPolicy()
+	}
+	pruned, err := wsl.AutoPrune(results, backupDir, policy)
+	if err != nil {
+		return results, pruned, fmt.Errorf("backups completed but pruning failed: %w", err)
+	}
+
+	return results, pruned, nil
+}
+
+// summarizeRun describes the outcome of a scheduled run
+func summarizeRun(results []wsl.BackupResult, err error) (string, string) {
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	switch {
+	case len(results) == 0 && err != nil:
+		return "failed", err.Error()
+	case err != nil:
+		return "partial", fmt.Sprintf("Backed up %d/%d distribution(s); %v", succeeded, len(results), err)
+	case succeeded == len(results):
+		return "success", fmt.Sprintf("Backed up %d/%d distribution(s)", succeeded, len(results))
+	case succeeded == 0:
+		return "failed", fmt.Sprintf("Backed up %d/%d distribution(s)", succeeded, len(results))
+	}
+	return "partial", fmt.Sprintf("Backed up %d/%d distribution(s)", succeeded, len(results))
+}
+
+// loadState reads the saved status of every schedule, by ID. A missing or
+// unreadable state file just means nothing is known yet.
+func (s *Scheduler) loadState() map[string]ScheduleStatus {
+	state := map[string]ScheduleStatus{}
+
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		slog.Warn("Ignoring invalid schedule state", "path", s.statePath, "error", err)
+	}
+	return state
+}
+
+// saveState writes the status of every schedule to the state file
+func (s *Scheduler) saveState() {
+	s.mu.Lock()
+	state := make(map[string]ScheduleStatus, len(s.schedules))
+	for _, sb := range s.schedules {
+		state[sb.status.ID] = sb.status
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0755); err == nil {
+		err = os.WriteFile(s.statePath, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("Failed to save schedule state", "path", s.statePath, "error", err)
+	}
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"wslp/internal/config"
+)
+
+// useScheduleConfig points backups at a temporary directory for the
+// duration of a test, returning it
+func useScheduleConfig(t *testing.T) string {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+
+	backupDir := t.TempDir()
+	viper.Set("backup_dir", backupDir)
+	return backupDir
+}
+
+// waitForSchedule polls the scheduler until cond holds for the schedule
+// with the given ID, failing the test after a few seconds
+func waitForSchedule(t *testing.T, s *Scheduler, id string, cond func(ScheduleStatus) bool) ScheduleStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, status := range s.Schedules() {
+			if status.ID == id && cond(status) {
+				return status
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for schedule %s", id)
+	return ScheduleStatus{}
+}
+
+func TestNewScheduler(t *testing.T) {
+	t.Run("rejects invalid cron expression", func(t *testing.T) {
+		schedules := []config.BackupSchedule{{ID: "nightly", Cron: "not a cron", Distros: []string{"Ubuntu"}}}
+		if _, err := NewScheduler(&mockBackuper{}, schedules, t.TempDir()); err == nil {
+			t.Error("expected error for invalid cron expression")
+		}
+	})
+
+	t.Run("accepts descriptors", func(t *testing.T) {
+		schedules := []config.BackupSchedule{{ID: "nightly", Cron: "@daily", Distros: []string{"Ubuntu"}}}
+		s, err := NewScheduler(&mockBackuper{}, schedules, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := s.Schedules(); len(got) != 1 || got[0].ID != "nightly" || got[0].LastRun != nil {
+			t.Errorf("unexpected schedules %+v", got)
+		}
+	})
+}
+
+func TestSchedulerRunNow(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("backs up and remembers the run", func(t *testing.T) {
+		backupDir := useScheduleConfig(t)
+		stateDir := t.TempDir()
+		schedules := []config.BackupSchedule{{ID: "nightly", Cron: "0 2 * * *", Distros: []string{"Ubuntu"}}}
+		mock := &mockBackuper{registered: true, writeArchive: true}
+
+		s, err := NewScheduler(mock, schedules, stateDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		status, err := s.RunNow(ctx, "nightly", "manual")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.LastStatus != "success" || status.LastTrigger != "manual" || status.LastRun == nil {
+			t.Errorf("expected successful manual run, got %+v", status)
+		}
+		if len(status.LastResults) != 1 || !status.LastResults[0].Success {
+			t.Errorf("expected one successful backup, got %+v", status.LastResults)
+		}
+		if entries, _ := os.ReadDir(backupDir); len(entries) != 2 {
+			t.Errorf("expected archive and manifest in backup dir, got %d files", len(entries))
+		}
+
+		// A new scheduler picks up the saved status
+		reloaded, err := NewScheduler(mock, schedules, stateDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := reloaded.Schedules()
+		if len(got) != 1 || got[0].LastRun == nil || got[0].LastStatus != "success" {
+			t.Errorf("expected saved status to be loaded, got %+v", got)
+		}
+	})
+
+	t.Run("reports failed backups", func(t *testing.T) {
+		useScheduleConfig(t)
+		schedules := []config.BackupSchedule{{ID: "nightly", Cron: "@daily", Distros: []string{"Ubuntu"}}}
+
+		s, err := NewScheduler(&mockBackuper{registered: true, exportErr: errors.New("export failed")}, schedules, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		status, err := s.RunNow(ctx, "nightly", "manual")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.LastStatus != "failed" {
+			t.Errorf("expected failed run, got %q (%s)", status.LastStatus, status.LastMessage)
+		}
+	})
+
+	t.Run("returns error for unknown schedule", func(t *testing.T) {
+		s, err := NewScheduler(&mockBackuper{}, nil, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.RunNow(ctx, "missing", "manual"); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	tests := map[string]struct {
+		catchUp bool
+	}{
+		"runs missed backup":  {catchUp: true},
+		"skips missed backup": {catchUp: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			useScheduleConfig(t)
+			stateDir := t.TempDir()
+
+			// The server was down when the schedule was last due
+			missed := time.Now().Add(-time.Hour)
+			state := map[string]ScheduleStatus{"nightly": {NextRun: missed}}
+			data, _ := json.Marshal(state)
+			if err := os.WriteFile(filepath.Join(stateDir, scheduleStateFile), data, 0644); err != nil {
+				t.Fatalf("failed to write state: %v", err)
+			}
+
+			schedules := []config.BackupSchedule{{ID: "nightly", Cron: "@yearly", Distros: []string{"Ubuntu"}, CatchUp: tc.catchUp}}
+			s, err := NewScheduler(&mockBackuper{registered: true, writeArchive: true}, schedules, stateDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				s.Run(ctx)
+				close(done)
+			}()
+
+			status := waitForSchedule(t, s, "nightly", func(status ScheduleStatus) bool {
+				return status.NextRun.After(missed)
+			})
+			cancel()
+			<-done
+
+			if tc.catchUp && (status.LastTrigger != "catch-up" || status.LastStatus != "success") {
+				t.Errorf("expected a successful catch-up run, got %+v", status)
+			}
+			if !tc.catchUp && status.LastRun != nil {
+				t.Errorf("expected no run, got %+v", status)
+			}
+		})
+	}
+}
+
+func TestHandleSchedules(t *testing.T) {
+	t.Run("returns 405 for non-GET", func(t *testing.T) {
+		srv := &Server{}
+		req := httptest.NewRequest(http.MethodPost, "/api/schedules", nil)
+		w := httptest.NewRecorder()
+		srv.handleSchedules(w, req)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns empty list without scheduler", func(t *testing.T) {
+		srv := &Server{}
+		req := httptest.NewRequest(http.MethodGet, "/api/schedules", nil)
+		w := httptest.NewRecorder()
+		srv.handleSchedules(w, req)
+
+		var resp struct {
+			Schedules []ScheduleStatus `json:"schedules"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Schedules == nil || len(resp.Schedules) != 0 {
+			t.Errorf("expected empty list, got %+v", resp.Schedules)
+		}
+	})
+
+	t.Run("returns schedules", func(t *testing.T) {
+		schedules := []config.BackupSchedule{{ID: "nightly", Cron: "@daily", Distros: []string{"Ubuntu"}}}
+		scheduler, err := NewScheduler(&mockBackuper{}, schedules, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		srv := &Server{scheduler: scheduler}
+		req := httptest.NewRequest(http.MethodGet, "/api/schedules", nil)
+		w := httptest.NewRecorder()
+		srv.handleSchedules(w, req)
+
+		var resp struct {
+			Schedules []ScheduleStatus `json:"schedules"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Schedules) != 1 || resp.Schedules[0].ID != "nightly" {
+			t.Errorf("unexpected schedules %+v", resp.Schedules)
+		}
+	})
+}
+
+func TestHandleRunSchedule(t *testing.T) {
+	useScheduleConfig(t)
+	schedules := []config.BackupSchedule{{ID: "nightly", Cron: "@daily", Distros: []string{"Ubuntu"}}}
+	scheduler, err := NewScheduler(&mockBackuper{registered: true, writeArchive: true}, schedules, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		srv    *Server
+		method string
+		id     string
+		want   int
+	}{
+		"returns 405 for non-POST":      {&Server{scheduler: scheduler}, http.MethodGet, "nightly", http.StatusMethodNotAllowed},
+		"returns 404 without scheduler": {&Server{}, http.MethodPost, "nightly", http.StatusNotFound},
+		"returns 404 for unknown id":    {&Server{scheduler: scheduler}, http.MethodPost, "weekly", http.StatusNotFound},
+		"runs schedule":                 {&Server{scheduler: scheduler}, http.MethodPost, "nightly", http.StatusOK},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/schedules/"+tc.id+"/run", nil)
+			req.SetPathValue("id", tc.id)
+			w := httptest.NewRecorder()
+			tc.srv.handleRunSchedule(w, req)
+
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				return
+			}
+
+			var status ScheduleStatus
+			if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if status.LastTrigger != "manual" || status.LastStatus != "success" {
+				t.Errorf("expected successful manual run, got %+v", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"wslp/internal/wsl"
+)
+
+// selectDistros resolves the registered distros a bulk request selected by
+// name, pattern, tag or state. If it selects none, or they can't be
+// listed, it replies with an error and returns false.
+func (s *Server) selectDistros(w http.ResponseWriter, r *http.Request, sel wsl.Selector) ([]string, bool) {
+	distros, err := wsl.SelectDistros(r.Context(), s.lister, sel)
+	return checkSelection(w, r, distros, err)
+}
+
+// selectAvailableDistros resolves the distros an install request selected
+// by name or pattern from those available online
+func (s *Server) selectAvailableDistros(w http.ResponseWriter, r *http.Request, sel wsl.Selector) ([]string, bool) {
+	distros, err := wsl.SelectAvailableDistros(r.Context(), sel)
+	return checkSelection(w, r, distros, err)
+}
+
+// checkSelection replies with err, if any, as a bad request when the
+// selector was at fault
+func checkSelection(w http.ResponseWriter, r *http.Request, distros []string, err error) ([]string, bool) {
+	switch {
+	case wsl.IsSelectionError(err):
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	case err != nil:
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return distros, true
+}
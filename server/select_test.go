@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBulkSelectors(t *testing.T) {
+	lister := &mockLister{distros: []string{"Ubuntu-22.04", "Ubuntu-24.04", "docker-desktop"}}
+
+	post := func(srv *Server, path, body string) *httptest.ResponseRecorder {
+		srv.token = "test-token"
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		srv.handler().ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("unregisters the distros selected", func(t *testing.T) {
+		unregisterer := &mockUnregisterer{registered: true}
+		srv := &Server{lister: lister, unregisterer: unregisterer}
+
+		w := post(srv, "/api/unregister", `{"all": true, "exclude": ["docker-*"]}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if strings.Join(unregisterer.unregistered, ",") != "Ubuntu-22.04,Ubuntu-24.04" {
+			t.Errorf("unexpected distros unregistered %v", unregisterer.unregistered)
+		}
+	})
+
+	t.Run("terminates the distros matching a pattern", func(t *testing.T) {
+		srv := &Server{lister: lister, terminator: &mockTerminator{registered: true}}
+
+		w := post(srv, "/api/terminate", `{"distros": ["ubuntu-2?.04"]}`)
+		var response struct {
+			Results []struct {
+				Distro string `json:"distro"`
+			} `json:"results"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if w.Code != http.StatusOK || len(response.Results) != 2 || response.Results[1].Distro != "Ubuntu-24.04" {
+			t.Errorf("unexpected response %d %+v", w.Code, response)
+		}
+	})
+
+	t.Run("rejects selections that match nothing", func(t *testing.T) {
+		srv := &Server{lister: lister, terminator: &mockTerminator{registered: true}}
+
+		w := post(srv, "/api/v1/distros", `{"distros": ["Ubuntu"], "running": true}`)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_request") {
+			t.Errorf("expected invalid_request for installs selected by state, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w = post(srv, "/api/terminate", `{"distros": ["arch*"]}`)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "arch*") {
+			t.Errorf("expected status 400 naming the pattern, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("reports distros that can't be listed", func(t *testing.T) {
+		srv := &Server{lister: &mockLister{err: errors.New("wsl.exe not found")}, jobs: newTestJobManager(t)}
+
+		w := post(srv, "/api/backup", `{"all": true}`)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
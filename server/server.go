@@ -5,15 +5,37 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"wslp/internal/audit"
 	"wslp/internal/config"
+	"wslp/internal/socket"
 	"wslp/internal/wsl"
 )
 
 type Server struct {
+	// bind is the address to listen on, e.g. 127.0.0.1
+	bind string
 	port string
+	// network and socket are the Unix domain socket or named pipe to
+	// listen on instead of bind and port, when socket is set
+	network string
+	socket  string
+	// token must be sent as a bearer token by clients calling anything
+	// but the OpenAPI document. It is random for each server.
+	token string
+	// tokenFile is where Start wrote the token, removed again on Shutdown
+	tokenFile string
+	// allowedOrigins are the browser origins allowed by CORS. Empty allows
+	// origins on this machine only.
+	allowedOrigins []string
 	// HTTP server instance (nil until Start() is called)
 	httpServer *http.Server
 
@@ -28,13 +50,36 @@ type Server struct {
 	terminator         wsl.Terminator
 	renamer            wsl.Renamer
 	copier             wsl.Copier
+	restorer           wsl.Restorer
 	workshopRunner     wsl.WorkshopRunner
 	workshopController wsl.WorkshopController
+
+	// scheduler runs scheduled backups. Created by Start from the config
+	// unless already set.
+	scheduler     *Scheduler
+	stopScheduler context.CancelFunc
+
+	// jobs runs installs, backups and copies in the background
+	jobs *JobManager
+
+	// events is streamed to clients by /api/events
+	events      *EventBroker
+	stopWatcher context.CancelFunc
+
+	// metrics is served by /metrics. May be nil.
+	metrics *Metrics
 }
 
-func NewServer(port string) *Server {
+func NewServer(bind, port string) *Server {
+	events := NewEventBroker()
+	metrics := NewMetrics()
+	jobs := NewJobManager(events)
+	jobs.metrics = metrics
 	return &Server{
-		port: port,
+		bind:           bind,
+		port:           port,
+		token:          newToken(),
+		allowedOrigins: config.GetServerAllowedOrigins(),
 		// DI defaults - same Real* implementations used today
 		lister:             wsl.RealLister{},
 		defaultGetter:      wsl.RealDefaultGetter{},
@@ -44,21 +89,39 @@ func NewServer(port string) *Server {
 		terminator:         wsl.RealTerminator{},
 		renamer:            wsl.RealRenamer{},
 		copier:             wsl.RealCopier{},
+		restorer:           wsl.RealRestorer{},
 		workshopRunner:     wsl.RealWorkshopRunner{},
 		workshopController: wsl.RealWorkshopController{},
+		jobs:               jobs,
+		events:             events,
+		metrics:            metrics,
 	}
 }
 
-// Start runs the HTTP server, blocking until it is shut down via Shutdown
-// (triggered by an OS signal / Ctrl+C in cmd/serve.go, or via the
-// /api/shutdown endpoint used by the GUI when its window closes so the
-// server it depends on doesn't linger). It returns nil on a graceful
-// shutdown, or an error if the server failed to start/run for any other
-// reason.
-func (s *Server) Start() error {
+// NewSocketServer creates a server listening on a Unix domain socket or a
+// Windows named pipe, e.g. unix:///run/wslp.sock or npipe://wslp, rather
+// than a TCP port. Only the current user can connect to it, so requests
+// don't need the API token.
+func NewSocketServer(address string) (*Server, error) {
+	network, path, err := socket.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewServer("", "")
+	s.network = network
+	s.socket = path
+	s.token = ""
+	return s, nil
+}
+
+// handler returns the server's routes, wrapped in its middleware
+func (s *Server) handler() http.Handler {
 	mux := http.NewServeMux()
+	s.registerV1Routes(mux)
 
-	// Register routes
+	// The unversioned routes are kept as aliases of the /api/v1 routes
+	// while clients move over
 	mux.HandleFunc("/api/distros", s.handleListDistros)
 	mux.HandleFunc("/api/default", s.handleGetDefault)
 	mux.HandleFunc("/api/available", s.handleListAvailable)
@@ -66,10 +129,19 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/api/unregister", s.handleUnregister)
 	mux.HandleFunc("/api/set-default", s.handleSetDefault)
 	mux.HandleFunc("/api/backup", s.handleBackup)
+	mux.HandleFunc("/api/backups", s.handleListBackups)
+	mux.HandleFunc("/api/backups/prune", s.handlePruneBackups)
+	mux.HandleFunc("/api/backups/{id}/files", s.handleBackupFiles)
+	mux.HandleFunc("/api/schedules", s.handleSchedules)
+	mux.HandleFunc("/api/schedules/{id}/run", s.handleRunSchedule)
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/jobs/{id}", s.handleJob)
+	mux.HandleFunc("/api/events", s.handleEvents)
 	mux.HandleFunc("/api/terminate", s.handleTerminate)
 	mux.HandleFunc("/api/launch", s.handleLaunch)
 	mux.HandleFunc("/api/rename", s.handleRename)
 	mux.HandleFunc("/api/copy", s.handleCopy)
+	mux.HandleFunc("/api/restore", s.handleRestore)
 	mux.HandleFunc("/api/ubuntu-telemetry", s.handleUbuntuTelemetry)
 	mux.HandleFunc("/api/wsl-info", s.handleWSLInfo)
 	mux.HandleFunc("/api/distro-info", s.handleDistroInfo)
@@ -77,34 +149,129 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/api/workshop-action", s.handleWorkshopAction)
 	mux.HandleFunc("/api/workshop-shell", s.handleWorkshopShell)
 	mux.HandleFunc("/api/shutdown", s.handleShutdown)
+	mux.HandleFunc("/api/audit", s.handleAudit)
+	mux.HandleFunc("/metrics", s.handleMetrics)
 
+	handler := auditMiddleware(timeoutMiddleware(s.metrics.instrument(mux)))
+	// Connecting to a socket already requires being the user running the
+	// server, so only TCP clients need the token
+	if s.socket == "" {
+		handler = authMiddleware(s.token, handler)
+	}
 	// Add CORS middleware for Flutter
-	handler := corsMiddleware(mux)
+	return logRequests(corsMiddleware(s.allowedOrigins, handler))
+}
 
-	addr := fmt.Sprintf(":%s", s.port)
-	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: handler,
+// Start runs the HTTP server, blocking until it is shut down via Shutdown
+// (triggered by an OS signal / Ctrl+C in cmd/serve.go, or via the
+// /api/shutdown endpoint used by the GUI when its window closes so the
+// server it depends on doesn't linger). It returns nil on a graceful
+// shutdown, or an error if the server failed to start/run for any other
+// reason.
+func (s *Server) Start() error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
 	}
+	s.httpServer = &http.Server{Handler: s.handler()}
+
+	s.startScheduler()
+
+	watchCtx, stopWatcher := context.WithCancel(context.Background())
+	s.stopWatcher = stopWatcher
+	go s.watchDistros(watchCtx, watchInterval)
 
-	fmt.Printf("Starting server on http://localhost%s\n", addr)
-	err := s.httpServer.ListenAndServe()
+	if s.socket != "" {
+		slog.Info("Starting server", "address", s.network+"://"+s.socket)
+	} else {
+		slog.Info("Starting server", "address", "http://"+listener.Addr().String())
+	}
+	err = s.httpServer.Serve(listener)
 	if err != nil && errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
 	return err
 }
 
+// listen opens the server's socket, or its TCP port and then writes the
+// API token clients of the port need
+func (s *Server) listen() (net.Listener, error) {
+	if s.socket != "" {
+		return socket.Listen(s.network, s.socket)
+	}
+
+	// Listen before writing the token, so a second server started on the
+	// same port fails without replacing the running server's token
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.bind, s.port))
+	if err != nil {
+		return nil, err
+	}
+	tokenFile := TokenFile(config.GetStateDir(), s.port)
+	if err := writeTokenFile(tokenFile, s.token); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	s.tokenFile = tokenFile
+	slog.Info("API token written", "path", tokenFile)
+	if !isLoopback(s.bind) {
+		slog.Warn("Listening on a non-loopback address, so other machines can reach the server", "bind", s.bind)
+	}
+	return listener, nil
+}
+
 // Shutdown gracefully stops the running server, letting in-flight requests
 // finish (bounded by ctx). Safe to call even if the server hasn't finished
 // starting yet.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopScheduler != nil {
+		s.stopScheduler()
+	}
+	if s.jobs != nil {
+		s.jobs.Shutdown()
+	}
+	if s.stopWatcher != nil {
+		s.stopWatcher()
+	}
+	// Event streams never finish on their own, so end them or the HTTP
+	// server would wait for them until ctx expires
+	if s.events != nil {
+		s.events.Close()
+	}
+	if s.tokenFile != "" {
+		os.Remove(s.tokenFile)
+	}
 	if s.httpServer == nil {
 		return nil
 	}
 	return s.httpServer.Shutdown(ctx)
 }
 
+// startScheduler starts running the backup schedules from the config in
+// the background. A broken schedule config is reported but doesn't stop
+// the server from serving everything else.
+func (s *Server) startScheduler() {
+	if s.scheduler == nil {
+		schedules, err := config.GetBackupSchedules()
+		if err == nil {
+			s.scheduler, err = NewScheduler(s.backuper, schedules, config.GetStateDir())
+		}
+		if err == nil {
+			s.scheduler.metrics = s.metrics
+		}
+		if err != nil {
+			slog.Warn("Scheduled backups disabled", "error", err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopScheduler = cancel
+	if n := len(s.scheduler.Schedules()); n > 0 {
+		slog.Info("Running backup schedules", "count", n)
+	}
+	go s.scheduler.Run(ctx)
+}
+
 // handleShutdown gracefully stops the server. Intended to be called
 // automatically by the GUI when its window closes (not exposed as a
 // user-facing button — the GUI has no manual "stop server" control), so
@@ -113,10 +280,13 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // caller reliably sees success.
 func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	recordAudit(r.Context(), audit.Entry{Action: audit.ActionShutdown, Success: true})
+	slog.Info("Shutting down at the request of a client", "remote", r.RemoteAddr)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -135,13 +305,13 @@ func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleListDistros(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	distros, err := wsl.ListDistros(context.Background(), s.lister)
+	distros, err := wsl.ListDistros(r.Context(), s.lister)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -154,13 +324,13 @@ func (s *Server) handleListDistros(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetDefault(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	defaultDistro, err := wsl.GetDefaultDistro(context.Background(), s.defaultGetter)
+	defaultDistro, err := wsl.GetDefaultDistro(r.Context(), s.defaultGetter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -172,13 +342,13 @@ func (s *Server) handleGetDefault(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleListAvailable(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	distros, err := wsl.GetAvailableDistros(context.Background())
+	distros, err := wsl.GetAvailableDistros(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -191,53 +361,85 @@ func (s *Server) handleListAvailable(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request struct {
-		Distros []string `json:"distros"`
+	var request wsl.Selector
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if request.Empty() {
+		writeError(w, r, "No distros specified", http.StatusBadRequest)
 		return
 	}
 
-	if len(request.Distros) == 0 {
-		http.Error(w, "No distros specified", http.StatusBadRequest)
+	distros, ok := s.selectAvailableDistros(w, r, request)
+	if !ok {
 		return
 	}
 
-	results := wsl.InstallDistros(context.Background(), request.Distros, false)
+	s.startInstall(w, r, distros)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"results": results,
+// startInstall starts a job installing distros and replies with it
+func (s *Server) startInstall(w http.ResponseWriter, r *http.Request, distros []string) {
+	if !checkNotBusy(w, r, distros...) {
+		return
+	}
+	timeout, err := config.GetServerTimeout("install")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	wait := waitForLocks(r)
+	job := s.jobs.Submit("install", distros, timeout, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+		ctx = lockContext(ctx, wait)
+		results := make([]wsl.InstallResult, 0, len(distros))
+		for i, distro := range distros {
+			if ctx.Err() != nil {
+				break
+			}
+			p.Start(i)
+			result := wsl.InstallDistros(ctx, []string{distro}, false)[0]
+			p.Finish(i, result.Success, result.Message)
+			results = append(results, result)
+		}
+
+		return map[string]interface{}{
+			"results": results,
+		}, nil
 	})
+
+	writeJobAccepted(w, r, job)
 }
 
 func (s *Server) handleUnregister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request struct {
-		Distros []string `json:"distros"`
+	var request wsl.Selector
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if request.Empty() {
+		writeError(w, r, "No distros specified", http.StatusBadRequest)
 		return
 	}
 
-	if len(request.Distros) == 0 {
-		http.Error(w, "No distros specified", http.StatusBadRequest)
+	distros, ok := s.selectDistros(w, r, request)
+	if !ok {
 		return
 	}
 
-	results := wsl.UnregisterDistros(context.Background(), s.unregisterer, request.Distros)
+	results := wsl.UnregisterDistros(lockContext(r.Context(), waitForLocks(r)), s.unregisterer, distros)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -247,7 +449,7 @@ func (s *Server) handleUnregister(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleSetDefault(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -256,17 +458,17 @@ func (s *Server) handleSetDefault(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.Name == "" {
-		http.Error(w, "No distro name specified", http.StatusBadRequest)
+		writeError(w, r, "No distro name specified", http.StatusBadRequest)
 		return
 	}
 
-	if err := wsl.SetDefaultDistro(context.Background(), request.Name, s.defaultSetter); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := wsl.ChangeDefaultDistro(r.Context(), request.Name, s.defaultGetter, s.defaultSetter, config.GetStateDir()); err != nil {
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -279,77 +481,167 @@ func (s *Server) handleSetDefault(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request struct {
-		Distros    []string `json:"distros"`
-		CustomName string   `json:"customName,omitempty"`
-		BackupDir  string   `json:"backupDir,omitempty"`
-	}
-
+	var request backupRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if len(request.Distros) == 0 {
-		http.Error(w, "No distros specified", http.StatusBadRequest)
+	s.startBackup(w, r, request)
+}
+
+// backupRequest is the body of a request to back up distros
+type backupRequest struct {
+	// Selector picks the distros to back up, by name, pattern, tag or
+	// state
+	wsl.Selector
+	CustomName string `json:"customName,omitempty"`
+	BackupDir  string `json:"backupDir,omitempty"`
+	Format     string `json:"format,omitempty"`
+	// Encrypt defaults to backup_encryption.enabled
+	Encrypt *bool `json:"encrypt,omitempty"`
+	// Target is a name from backup_targets or a target URI. It
+	// defaults to backup_target, unless backupDir is given.
+	Target string `json:"target,omitempty"`
+}
+
+// startBackup starts a job backing up the distros in request and replies
+// with it
+func (s *Server) startBackup(w http.ResponseWriter, r *http.Request, request backupRequest) {
+	if request.Empty() {
+		writeError(w, r, "No distros specified", http.StatusBadRequest)
+		return
+	}
+	distros, ok := s.selectDistros(w, r, request.Selector)
+	if !ok {
+		return
+	}
+	request.Distros = distros
+	if !checkNotBusy(w, r, request.Distros...) {
 		return
 	}
 
 	// Validate custom name usage
 	if request.CustomName != "" && len(request.Distros) > 1 {
-		http.Error(w, "Custom name can only be used when backing up a single distribution", http.StatusBadRequest)
+		writeError(w, r, "Custom name can only be used when backing up a single distribution", http.StatusBadRequest)
 		return
 	}
 
-	// Determine backup directory
-	backupDir := request.BackupDir
-	if backupDir == "" {
-		backupDir = config.GetBackupDir()
+	// An empty format is inferred from the custom name
+	var format wsl.BackupFormat
+	if request.Format != "" {
+		var err error
+		if format, err = wsl.ParseBackupFormat(request.Format); err != nil {
+			writeError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	encryption, err := config.GetBackupEncryption()
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	encrypt := encryption.Enabled
+	if request.Encrypt != nil {
+		encrypt = *request.Encrypt
+	}
+
+	backupDir, target, err := wsl.ResolveBackupDestination(request.Target, request.BackupDir)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// Ensure backup directory exists
-	if err := config.EnsureBackupDir(); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create backup directory: %v", err), http.StatusInternalServerError)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		writeError(w, r, fmt.Sprintf("Failed to create backup directory: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	opts := wsl.BackupOptions{
 		CustomName: request.CustomName,
+		Format:     format,
+		Encrypt:    encrypt,
+		Target:     target,
 	}
 
-	results := wsl.BackupDistros(context.Background(), s.backuper, request.Distros, backupDir, opts)
+	timeout, err := config.GetServerTimeout("backup")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"results": results,
+	wait := waitForLocks(r)
+	job := s.jobs.Submit("backup", request.Distros, timeout, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+		ctx = lockContext(ctx, wait)
+		results := make([]wsl.BackupResult, 0, len(request.Distros))
+		for i, distro := range request.Distros {
+			if ctx.Err() != nil {
+				break
+			}
+			p.Start(i)
+			distroOpts := opts
+			distroOpts.Progress = func(_ string, bytesWritten int64) {
+				p.Progress(i, bytesWritten)
+			}
+			result := wsl.BackupDistros(ctx, s.backuper, []string{distro}, backupDir, distroOpts)[0]
+			p.Finish(i, result.Success, result.Message)
+			s.metrics.observeBackup(result)
+			results = append(results, result)
+		}
+
+		response := map[string]interface{}{
+			"results": results,
+		}
+
+		// Apply the retention policy, if one is configured. Only local
+		// backups can be listed, so remote targets are not pruned.
+		if target == nil {
+			pruned, err := wsl.AutoPrune(results, backupDir, config.GetRetentionPolicy())
+			if err != nil {
+				response["pruneError"] = err.Error()
+			} else if len(pruned) > 0 {
+				response["pruned"] = pruned
+			}
+		}
+
+		return response, nil
 	})
+
+	writeJobAccepted(w, r, job)
 }
 
 func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request struct {
-		Distros []string `json:"distros"`
+	var request wsl.Selector
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if request.Empty() {
+		writeError(w, r, "No distros specified", http.StatusBadRequest)
 		return
 	}
 
-	if len(request.Distros) == 0 {
-		http.Error(w, "No distros specified", http.StatusBadRequest)
+	distros, ok := s.selectDistros(w, r, request)
+	if !ok {
 		return
 	}
 
-	results := wsl.TerminateDistros(context.Background(), s.terminator, request.Distros)
+	results := wsl.TerminateDistros(lockContext(r.Context(), waitForLocks(r)), s.terminator, distros)
+	for _, result := range results {
+		s.metrics.observeOperation("terminate", result.Success)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -359,7 +651,7 @@ func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleLaunch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -368,18 +660,18 @@ func (s *Server) handleLaunch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.Name == "" {
-		http.Error(w, "No distro name specified", http.StatusBadRequest)
+		writeError(w, r, "No distro name specified", http.StatusBadRequest)
 		return
 	}
 
 	// Launch in terminal (non-blocking)
-	if err := wsl.LaunchInTerminal(context.Background(), request.Name); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := wsl.LaunchInTerminal(r.Context(), request.Name); err != nil {
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -392,7 +684,7 @@ func (s *Server) handleLaunch(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -402,16 +694,16 @@ func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.OldName == "" || request.NewName == "" {
-		http.Error(w, "Both old and new names are required", http.StatusBadRequest)
+		writeError(w, r, "Both old and new names are required", http.StatusBadRequest)
 		return
 	}
 
-	result := wsl.RenameDistro(context.Background(), s.renamer, request.OldName, request.NewName)
+	result := wsl.RenameDistro(lockContext(r.Context(), waitForLocks(r)), s.renamer, request.OldName, request.NewName)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -419,13 +711,13 @@ func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleWSLInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	info, err := wsl.GetWSLSystemInfo(context.Background())
+	info, err := wsl.GetWSLSystemInfo(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -435,19 +727,19 @@ func (s *Server) handleWSLInfo(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleDistroInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	name := r.URL.Query().Get("name")
 	if name == "" {
-		http.Error(w, "No distro name specified", http.StatusBadRequest)
+		writeError(w, r, "No distro name specified", http.StatusBadRequest)
 		return
 	}
 
-	info, err := wsl.GetDistroDetailInfo(context.Background(), name)
+	info, err := wsl.GetDistroDetailInfo(r.Context(), name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -461,17 +753,17 @@ func (s *Server) handleDistroInfo(w http.ResponseWriter, r *http.Request) {
 // it just reports zero workshops.
 func (s *Server) handleWorkshops(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	name := r.URL.Query().Get("distro")
 	if name == "" {
-		http.Error(w, "No distro name specified", http.StatusBadRequest)
+		writeError(w, r, "No distro name specified", http.StatusBadRequest)
 		return
 	}
 
-	workshops := wsl.GetWorkshops(context.Background(), name, s.workshopRunner)
+	workshops := wsl.GetWorkshops(r.Context(), name, s.workshopRunner)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -484,7 +776,7 @@ func (s *Server) handleWorkshops(w http.ResponseWriter, r *http.Request) {
 // seconds).
 func (s *Server) handleWorkshopAction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -496,23 +788,23 @@ func (s *Server) handleWorkshopAction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.Distro == "" || request.Project == "" || request.Name == "" {
-		http.Error(w, "distro, project and name are required", http.StatusBadRequest)
+		writeError(w, r, "distro, project and name are required", http.StatusBadRequest)
 		return
 	}
 
 	var err error
 	switch request.Action {
 	case "start":
-		err = wsl.StartWorkshop(context.Background(), request.Distro, request.Project, request.Name, s.workshopController)
+		err = wsl.StartWorkshop(r.Context(), request.Distro, request.Project, request.Name, s.workshopController)
 	case "stop":
-		err = wsl.StopWorkshop(context.Background(), request.Distro, request.Project, request.Name, s.workshopController)
+		err = wsl.StopWorkshop(r.Context(), request.Distro, request.Project, request.Name, s.workshopController)
 	default:
-		http.Error(w, "action must be 'start' or 'stop'", http.StatusBadRequest)
+		writeError(w, r, "action must be 'start' or 'stop'", http.StatusBadRequest)
 		return
 	}
 
@@ -539,7 +831,7 @@ func (s *Server) handleWorkshopAction(w http.ResponseWriter, r *http.Request) {
 // workshop in a new terminal window (non-blocking), mirroring /api/launch.
 func (s *Server) handleWorkshopShell(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -550,17 +842,17 @@ func (s *Server) handleWorkshopShell(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.Distro == "" || request.Project == "" || request.Name == "" {
-		http.Error(w, "distro, project and name are required", http.StatusBadRequest)
+		writeError(w, r, "distro, project and name are required", http.StatusBadRequest)
 		return
 	}
 
-	if err := wsl.LaunchWorkshopShell(context.Background(), request.Distro, request.Project, request.Name); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := wsl.LaunchWorkshopShell(r.Context(), request.Distro, request.Project, request.Name); err != nil {
+		writeError(w, r, err.Error(), failureStatus(r))
 		return
 	}
 
@@ -573,7 +865,7 @@ func (s *Server) handleWorkshopShell(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -584,19 +876,297 @@ func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if request.Source == "" || request.NewName == "" {
-		http.Error(w, "Both source and newName are required", http.StatusBadRequest)
+		writeError(w, r, "Both source and newName are required", http.StatusBadRequest)
+		return
+	}
+
+	s.startCopy(w, r, request.Source, request.NewName, request.InstallDir)
+}
+
+// startCopy starts a job copying source to newName and replies with it
+func (s *Server) startCopy(w http.ResponseWriter, r *http.Request, source, newName, installDir string) {
+	if !checkNotBusy(w, r, source, newName) {
+		return
+	}
+	timeout, err := config.GetServerTimeout("copy")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	result := wsl.CopyDistro(context.Background(), s.copier, request.Source, request.NewName, request.InstallDir)
+	wait := waitForLocks(r)
+	// The job outlives the request, so carry over who made it
+	actor := audit.ActorFrom(r.Context())
+	job := s.jobs.Submit("copy", []string{source}, timeout, func(ctx context.Context, p *JobProgress) (interface{}, error) {
+		ctx = audit.WithActor(lockContext(ctx, wait), actor)
+		p.Start(0)
+		copier := phaseCopier{Copier: s.copier, phase: func(phase string) { p.Phase(0, phase) }}
+		result := wsl.CopyDistro(ctx, copier, source, newName, installDir)
+		p.Finish(0, result.Success, result.Message)
+		return result, nil
+	})
+
+	writeJobAccepted(w, r, job)
+}
+
+// phaseCopier reports each step of a copy as it starts
+type phaseCopier struct {
+	wsl.Copier
+	phase func(string)
+}
+
+func (c phaseCopier) Export(ctx context.Context, distroName, outputPath string) error {
+	c.phase("export")
+	return c.Copier.Export(ctx, distroName, outputPath)
+}
+
+func (c phaseCopier) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	c.phase("import")
+	return c.Copier.Import(ctx, newName, tarPath, installDir)
+}
+
+// handleListBackups reads the backup catalog, optionally filtered by
+// ?distro=. Each entry includes the archive's manifest when it has one.
+func (s *Server) handleListBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only the configured backup directory is listed: a directory given by
+	// the caller could point the server anywhere on disk
+	backups, err := wsl.ListBackups(config.GetBackupDir())
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if distro := r.URL.Query().Get("distro"); distro != "" {
+		filtered := []wsl.BackupInfo{}
+		for _, b := range backups {
+			if strings.EqualFold(b.Distro, distro) {
+				filtered = append(filtered, b)
+			}
+		}
+		backups = filtered
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backups": backups,
+		"count":   len(backups),
+	})
+}
+
+// handlePruneBackups deletes backups in the configured backup directory not
+// kept by the retention policy. The configured policy is used unless the
+// request supplies one.
+func (s *Server) handlePruneBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		DryRun    bool                    `json:"dryRun,omitempty"`
+		BackupDir string                  `json:"backupDir,omitempty"`
+		Policy    *config.RetentionPolicy `json:"policy,omitempty"`
+	}
+
+	// An empty body prunes with the configured policy
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	policy := config.GetRetentionPolicy()
+	if request.Policy != nil {
+		policy = *request.Policy
+	}
+
+	if !policy.Enabled() {
+		writeError(w, r, "No retention policy configured", http.StatusBadRequest)
+		return
+	}
+
+	// Only the configured directory is pruned, so a client can't have
+	// files deleted anywhere else
+	backupDir := config.GetBackupDir()
+	if request.BackupDir != "" && !sameDir(request.BackupDir, backupDir) {
+		writeError(w, r, "backupDir must be the configured backup directory", http.StatusBadRequest)
+		return
+	}
+
+	results, err := wsl.PruneBackups(backupDir, policy, request.DryRun)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var freed int64
+	for _, result := range results {
+		if result.Success {
+			freed += result.Size
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":    results,
+		"freedBytes": freed,
+		"dryRun":     request.DryRun,
+	})
+}
+
+// sameDir reports whether a and b are the same existing directory
+func sameDir(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// handleBackupFiles lists the contents of a directory inside a backup
+// (?path=, default /). The backup is identified by the id returned by
+// /api/backups. With ?download=true and a path naming a regular file, the
+// file's content is streamed instead.
+func (s *Server) handleBackupFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	archive, ok := findBackupArchive(w, r, id, "")
+	if !ok {
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		path = "/"
+	}
+
+	if r.URL.Query().Get("download") == "true" {
+		content, entry, err := wsl.OpenBackupFile(archive, path)
+		if err != nil {
+			if errors.Is(err, wsl.ErrNotInBackup) {
+				writeError(w, r, err.Error(), http.StatusNotFound)
+			} else {
+				writeError(w, r, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(entry.Path)))
+		w.Header().Set("Content-Length", fmt.Sprint(entry.Size))
+		io.Copy(w, content)
+		return
+	}
+
+	entries, err := wsl.ListBackupFiles(archive, path, r.URL.Query().Get("recursive") == "true")
+	if err != nil {
+		if errors.Is(err, wsl.ErrNotInBackup) {
+			writeError(w, r, err.Error(), http.StatusNotFound)
+		} else {
+			writeError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backup":  id,
+		"path":    path,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// findBackupArchive returns the path of the backup with the given id in
+// backupDir (the configured backup directory if empty). If there is no
+// such backup it replies with an error and returns false.
+func findBackupArchive(w http.ResponseWriter, r *http.Request, id, backupDir string) (string, bool) {
+	// IDs are archive filenames; anything with a path in it could escape
+	// the backup directory
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		writeError(w, r, "Invalid backup ID", http.StatusBadRequest)
+		return "", false
+	}
+
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+
+	archive := filepath.Join(backupDir, id)
+	if _, err := os.Stat(archive); err != nil {
+		writeErrorCode(w, r, CodeBackupNotFound, "Backup not found", http.StatusNotFound)
+		return "", false
+	}
+	return archive, true
+}
+
+// handleRestore lists the backups available for restore (GET, same as
+// /api/backups) or re-imports one of them (POST).
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleListBackups(w, r)
+
+	case http.MethodPost:
+		var request struct {
+			Distro     string `json:"distro"`
+			Timestamp  string `json:"timestamp,omitempty"`
+			File       string `json:"file,omitempty"`
+			NewName    string `json:"newName,omitempty"`
+			InstallDir string `json:"installDir,omitempty"`
+			Overwrite  bool   `json:"overwrite,omitempty"`
+			BackupDir  string `json:"backupDir,omitempty"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if request.Distro == "" && request.File == "" {
+			writeError(w, r, "Either distro or file is required", http.StatusBadRequest)
+			return
+		}
+
+		backupDir := request.BackupDir
+		if backupDir == "" {
+			backupDir = config.GetBackupDir()
+		}
+
+		opts := wsl.RestoreOptions{
+			Timestamp:  request.Timestamp,
+			File:       request.File,
+			NewName:    request.NewName,
+			InstallDir: request.InstallDir,
+			Overwrite:  request.Overwrite,
+		}
+
+		result := wsl.RestoreDistro(lockContext(r.Context(), waitForLocks(r)), s.restorer, request.Distro, backupDir, opts)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+
+	default:
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
 func (s *Server) handleUbuntuTelemetry(w http.ResponseWriter, r *http.Request) {
@@ -614,11 +1184,13 @@ func (s *Server) handleUbuntuTelemetry(w http.ResponseWriter, r *http.Request) {
 			Enabled bool `json:"enabled"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			writeError(w, r, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		if err := wsl.SetUbuntuTelemetryStatus(request.Enabled); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		err := wsl.SetUbuntuTelemetryStatus(request.Enabled)
+		recordTelemetryChange(r.Context(), request.Enabled, err)
+		if err != nil {
+			writeError(w, r, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -626,15 +1198,83 @@ func (s *Server) handleUbuntuTelemetry(w http.ResponseWriter, r *http.Request) {
 		})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
+// timeoutMiddleware cancels the context of each request once it has run
+// longer than the timeout configured for its endpoint, so the WSL
+// operation it started is stopped too. Streams only time out if their own
+// endpoint has a timeout, not after the default.
+func timeoutMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		endpoint := endpointName(r.URL.Path)
+		timeout, err := config.GetServerTimeout(endpoint)
+		if err != nil {
+			writeError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if streaming(r) && !config.HasServerTimeout(endpoint) {
+			timeout = 0
+		}
+
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// endpointName returns the name an endpoint's timeout is configured
+// under: the first segment of its path after /api/ or /api/v1/, e.g.
+// backup for /api/backup and schedules for /api/v1/schedules/nightly/run.
+// Restoring a backup through /api/v1/backups/{id}/restore is restore, as
+// it is for /api/restore.
+func endpointName(path string) string {
+	if strings.HasPrefix(path, v1Prefix) {
+		if strings.HasSuffix(path, "/restore") {
+			return "restore"
+		}
+		path = "/api/" + strings.TrimPrefix(path, v1Prefix)
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+	return name
+}
+
+// streaming reports whether r is answered with a stream that lasts as long
+// as the client wants: the event stream, or a file downloaded from a backup
+func streaming(r *http.Request) bool {
+	return endpointName(r.URL.Path) == "events" || r.URL.Query().Get("download") == "true"
+}
+
+// failureStatus returns the status for a WSL operation that failed while
+// handling r: 504 if it was stopped because the endpoint timed out,
+// otherwise 500
+func failureStatus(r *http.Request) int {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// corsMiddleware lets browser pages from the allowed origins (see
+// originAllowed) call the server and turns away pages from anywhere else.
+// Requests without an Origin header don't come from a browser page and are
+// passed through.
+func corsMiddleware(allowed []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !originAllowed(origin, allowed) {
+				writeError(w, r, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -644,3 +1284,105 @@ func corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// handleSchedules lists the backup schedules with their last run and next
+// run
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	schedules := []ScheduleStatus{}
+	if s.scheduler != nil {
+		schedules = s.scheduler.Schedules()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedules": schedules,
+	})
+}
+
+// handleRunSchedule runs a backup schedule immediately, returning its
+// status once the run finishes
+func (s *Server) handleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.scheduler == nil {
+		writeError(w, r, "No backup schedules configured", http.StatusNotFound)
+		return
+	}
+
+	status, err := s.scheduler.RunNow(r.Context(), r.PathValue("id"), "manual")
+	switch {
+	case errors.Is(err, ErrScheduleNotFound):
+		writeErrorCode(w, r, CodeScheduleNotFound, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrScheduleRunning):
+		writeErrorCode(w, r, CodeScheduleRunning, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// writeJobAccepted responds to a request that started a job with 202
+// Accepted and the job, which can then be followed at /api/jobs/{id}, or
+// /api/v1/jobs/{id} for requests to /api/v1
+func writeJobAccepted(w http.ResponseWriter, r *http.Request, job Job) {
+	location := "/api/jobs/" + job.ID
+	if strings.HasPrefix(r.URL.Path, v1Prefix) {
+		location = v1Prefix + "jobs/" + job.ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleJobs lists the running jobs and recently finished ones, newest
+// first
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs": s.jobs.List(),
+	})
+}
+
+// handleJob returns a job (GET) or cancels it (DELETE)
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	var job Job
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		job, err = s.jobs.Get(r.PathValue("id"))
+	case http.MethodDelete:
+		job, err = s.jobs.Cancel(r.PathValue("id"))
+	default:
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		writeErrorCode(w, r, CodeJobNotFound, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrJobFinished):
+		writeErrorCode(w, r, CodeJobFinished, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -1,14 +1,24 @@
 package server
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/spf13/viper"
+
+	"wslp/internal/wsl"
 )
 
 // Mock implementations for testing
@@ -67,16 +77,25 @@ func (m *mockUnregisterer) Unregister(ctx context.Context, name string) error {
 type mockBackuper struct {
 	registered bool
 	exportErr  error
+	// writeArchive makes Export write a valid tar.gz archive
+	writeArchive bool
 }
 
 func (m *mockBackuper) IsRegistered(ctx context.Context, name string) (bool, error) {
 	return m.registered, nil
 }
 
-func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string) error {
+func (m *mockBackuper) Export(ctx context.Context, distroName, outputPath string, format wsl.BackupFormat) error {
+	if m.exportErr == nil && m.writeArchive {
+		return os.WriteFile(outputPath, testArchive(), 0644)
+	}
 	return m.exportErr
 }
 
+func (m *mockBackuper) Info(ctx context.Context, name string) (wsl.DistroDetailInfo, error) {
+	return wsl.DistroDetailInfo{Name: name}, nil
+}
+
 type mockTerminator struct {
 	registered bool
 	err        error
@@ -126,6 +145,23 @@ func (m *mockCopier) Import(ctx context.Context, newName, tarPath, installDir st
 	return m.importErr
 }
 
+type mockRestorer struct {
+	registered bool
+	importErr  error
+}
+
+func (m *mockRestorer) IsRegistered(ctx context.Context, name string) (bool, error) {
+	return m.registered, nil
+}
+
+func (m *mockRestorer) Unregister(ctx context.Context, name string) error {
+	return nil
+}
+
+func (m *mockRestorer) Import(ctx context.Context, newName, tarPath, installDir string) error {
+	return m.importErr
+}
+
 type mockWorkshopRunner struct {
 	output []byte
 	err    error
@@ -160,30 +196,31 @@ func parseJSONResponse(t *testing.T, body []byte, v interface{}) {
 // CORS middleware tests
 
 func TestCORSMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
 	t.Run("adds CORS headers to responses", func(t *testing.T) {
-		handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-		}))
+		handler := corsMiddleware(nil, ok)
 
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", "http://localhost:5000")
 		handler.ServeHTTP(rec, req)
 
-		if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		if rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:5000" {
 			t.Error("missing Access-Control-Allow-Origin header")
 		}
-		if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, DELETE, OPTIONS" {
+		if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
 			t.Error("missing or incorrect Access-Control-Allow-Methods header")
 		}
-		if rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type" {
+		if rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" {
 			t.Error("missing Access-Control-Allow-Headers header")
 		}
 	})
 
 	t.Run("handles OPTIONS requests", func(t *testing.T) {
-		handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-		}))
+		handler := corsMiddleware(nil, ok)
 
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest("OPTIONS", "/", nil)
@@ -193,6 +230,137 @@ func TestCORSMiddleware(t *testing.T) {
 			t.Errorf("expected 200, got %d", rec.Code)
 		}
 	})
+
+	tests := map[string]struct {
+		allowed []string
+		method  string
+		origin  string
+		want    int
+	}{
+		"allows requests without an origin":    {nil, "POST", "", http.StatusOK},
+		"allows loopback origins by default":   {nil, "POST", "http://127.0.0.1:8000", http.StatusOK},
+		"rejects other origins by default":     {nil, "POST", "https://evil.example", http.StatusForbidden},
+		"allows configured origins":            {[]string{"https://wslp.example"}, "POST", "https://wslp.example", http.StatusOK},
+		"rejects origins not configured":       {[]string{"https://wslp.example"}, "POST", "http://localhost:5000", http.StatusForbidden},
+		"allows any origin with a wildcard":    {[]string{"*"}, "POST", "https://evil.example", http.StatusOK},
+		"rejects preflight from other origins": {nil, "OPTIONS", "https://evil.example", http.StatusForbidden},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/unregister", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			corsMiddleware(tc.allowed, ok).ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, rec.Code)
+			}
+			if tc.want == http.StatusForbidden && rec.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Error("expected no Access-Control-Allow-Origin header for a rejected origin")
+			}
+		})
+	}
+}
+
+// Timeout middleware tests
+
+// blockingLister blocks until its context is done
+type blockingLister struct{}
+
+func (blockingLister) List(ctx context.Context) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Run("returns 504 when the endpoint times out", func(t *testing.T) {
+		viper.Set("server_timeouts.distros", "10ms")
+		srv := &Server{lister: blockingLister{}}
+		handler := timeoutMiddleware(http.HandlerFunc(srv.handleListDistros))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/distros", nil))
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+	})
+
+	t.Run("leaves endpoints without a timeout alone", func(t *testing.T) {
+		viper.Set("server_timeouts.events", "0")
+		handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				t.Error("expected no deadline")
+			}
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/events", nil))
+	})
+
+	t.Run("lets streams outlive the default timeout", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("server_timeouts.default", "10ms")
+		handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(30 * time.Millisecond)
+			if err := r.Context().Err(); err != nil {
+				t.Errorf("%s: expected the stream to outlive the default timeout, got %v", r.URL, err)
+			}
+		}))
+
+		for _, path := range []string{"/api/v1/events", "/api/events", "/api/v1/backups/a.tar.gz/files?path=/etc/hosts&download=true"} {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}
+	})
+
+	t.Run("times out streams with a timeout of their own", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("server_timeouts.events", "10ms")
+		handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); !ok {
+				t.Error("expected a deadline")
+			}
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/events", nil))
+	})
+
+	t.Run("returns 500 for an invalid timeout", func(t *testing.T) {
+		viper.Set("server_timeouts.default", "soon")
+		handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("expected handler not to run")
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/wsl-info", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d", rec.Code)
+		}
+	})
+}
+
+func TestEndpointName(t *testing.T) {
+	tests := map[string]string{
+		"/api/backup":                "backup",
+		"/api/schedules/nightly/run": "schedules",
+		"/api/set-default":           "set-default",
+		"/api/v1/distros/Ubuntu":     "distros",
+		"/api/v1/events":             "events",
+		"/api/v1/backups/a/restore":  "restore",
+		"/":                          "",
+	}
+
+	for path, want := range tests {
+		if got := endpointName(path); got != want {
+			t.Errorf("endpointName(%q) = %q, want %q", path, got, want)
+		}
+	}
 }
 
 // handleListDistros tests
@@ -294,7 +462,7 @@ func TestHandleGetDefault(t *testing.T) {
 
 func TestHandleSetDefault(t *testing.T) {
 	t.Run("returns 405 for non-POST methods", func(t *testing.T) {
-		srv := &Server{defaultSetter: &mockDefaultSetter{}}
+		srv := &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{}}
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest("GET", "/api/set-default", nil)
 
@@ -306,7 +474,7 @@ func TestHandleSetDefault(t *testing.T) {
 	})
 
 	t.Run("returns 400 for invalid JSON", func(t *testing.T) {
-		srv := &Server{defaultSetter: &mockDefaultSetter{}}
+		srv := &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{}}
 		rec := httptest.NewRecorder()
 		req := testRequest("POST", "/api/set-default", []byte("{invalid"))
 
@@ -318,7 +486,7 @@ func TestHandleSetDefault(t *testing.T) {
 	})
 
 	t.Run("returns 400 for empty name", func(t *testing.T) {
-		srv := &Server{defaultSetter: &mockDefaultSetter{}}
+		srv := &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{}}
 		rec := httptest.NewRecorder()
 		body, _ := json.Marshal(map[string]string{"name": ""})
 		req := testRequest("POST", "/api/set-default", body)
@@ -331,7 +499,7 @@ func TestHandleSetDefault(t *testing.T) {
 	})
 
 	t.Run("returns 500 on setter error", func(t *testing.T) {
-		srv := &Server{defaultSetter: &mockDefaultSetter{setErr: errors.New("mock error")}}
+		srv := &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{setErr: errors.New("mock error")}}
 		rec := httptest.NewRecorder()
 		body, _ := json.Marshal(map[string]string{"name": "Ubuntu"})
 		req := testRequest("POST", "/api/set-default", body)
@@ -344,7 +512,7 @@ func TestHandleSetDefault(t *testing.T) {
 	})
 
 	t.Run("returns success on valid request", func(t *testing.T) {
-		srv := &Server{defaultSetter: &mockDefaultSetter{registered: true}}
+		srv := &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{registered: true}}
 		rec := httptest.NewRecorder()
 		body, _ := json.Marshal(map[string]string{"name": "Ubuntu"})
 		req := testRequest("POST", "/api/set-default", body)
@@ -362,6 +530,29 @@ func TestHandleSetDefault(t *testing.T) {
 			t.Errorf("expected success true, got %v", response["success"])
 		}
 	})
+
+	t.Run("remembers the previous default", func(t *testing.T) {
+		stateDir := t.TempDir()
+		viper.Set("state_dir", stateDir)
+		defer viper.Set("state_dir", "")
+
+		srv := &Server{
+			defaultGetter: &mockDefaultGetter{defaultDistro: "Debian"},
+			defaultSetter: &mockDefaultSetter{registered: true},
+		}
+		rec := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]string{"name": "Ubuntu"})
+		req := testRequest("POST", "/api/set-default", body)
+
+		srv.handleSetDefault(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if previous, _ := wsl.PreviousDefaultDistro(stateDir); previous != "Debian" {
+			t.Errorf("expected previous default Debian, got %q", previous)
+		}
+	})
 }
 
 // handleUnregister tests
@@ -540,6 +731,492 @@ func TestHandleRename(t *testing.T) {
 	})
 }
 
+// handleListBackups tests
+
+func TestHandleBackup(t *testing.T) {
+	t.Run("returns 405 for non-POST methods", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/backup", nil)
+
+		srv.handleBackup(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns 400 for unsupported format", func(t *testing.T) {
+		srv := &Server{backuper: &mockBackuper{registered: true}}
+		rec := httptest.NewRecorder()
+		body := `{"distros":["Ubuntu"],"format":"rar"}`
+		req := httptest.NewRequest("POST", "/api/backup", strings.NewReader(body))
+
+		srv.handleBackup(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns 400 for unknown target", func(t *testing.T) {
+		srv := &Server{backuper: &mockBackuper{registered: true}}
+		rec := httptest.NewRecorder()
+		body := `{"distros":["Ubuntu"],"target":"nowhere"}`
+		req := httptest.NewRequest("POST", "/api/backup", strings.NewReader(body))
+
+		srv.handleBackup(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("fails encrypted backup without keys", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		viper.Set("backup_dir", t.TempDir())
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+
+		srv := &Server{backuper: &mockBackuper{registered: true}, jobs: newTestJobManager(t)}
+		rec := httptest.NewRecorder()
+		body := `{"distros":["Ubuntu"],"encrypt":true}`
+		req := httptest.NewRequest("POST", "/api/backup", strings.NewReader(body))
+
+		srv.handleBackup(rec, req)
+
+		job := waitForAcceptedJob(t, srv, rec)
+		if job.State != JobFailed || job.Items[0].State != JobFailed {
+			t.Errorf("expected a failed job, got %+v", job)
+		}
+		results := job.Result.(map[string]interface{})["results"].([]wsl.BackupResult)
+		if len(results) != 1 || results[0].Success {
+			t.Errorf("expected a failed result, got %+v", results)
+		}
+	})
+
+	t.Run("backs up in a job", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		viper.Set("backup_dir", t.TempDir())
+		t.Setenv("WSLP_BACKUP_PASSPHRASE", "")
+
+		srv := &Server{backuper: &mockBackuper{registered: true, writeArchive: true}, jobs: newTestJobManager(t)}
+		rec := httptest.NewRecorder()
+		body := `{"distros":["Ubuntu","Debian"]}`
+		req := httptest.NewRequest("POST", "/api/backup", strings.NewReader(body))
+
+		srv.handleBackup(rec, req)
+
+		job := waitForAcceptedJob(t, srv, rec)
+		if job.Kind != "backup" || job.State != JobSucceeded || job.StartedAt == nil || job.FinishedAt == nil {
+			t.Errorf("expected a finished backup job, got %+v", job)
+		}
+		if len(job.Items) != 2 || job.Items[0].Distro != "Ubuntu" || job.Items[1].State != JobSucceeded {
+			t.Errorf("expected a succeeded item per distro, got %+v", job.Items)
+		}
+	})
+}
+
+// useBackupDir makes dir the configured backup directory for the duration
+// of a test
+func useBackupDir(t *testing.T, dir string) {
+	t.Helper()
+
+	viper.Set("backup_dir", dir)
+	t.Cleanup(viper.Reset)
+}
+
+func TestHandleListBackups(t *testing.T) {
+	t.Run("returns 405 for non-GET methods", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/backups", nil)
+
+		srv.handleListBackups(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns backups with manifests", func(t *testing.T) {
+		dir := t.TempDir()
+		archive := filepath.Join(dir, "Ubuntu-20240301-143022.tar.gz")
+		if err := os.WriteFile(archive, []byte("backup"), 0644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		if err := os.WriteFile(archive+".json", []byte(`{"distro":"Ubuntu","flavor":"ubuntu"}`), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+
+		useBackupDir(t, dir)
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/backups", nil)
+
+		srv.handleListBackups(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+
+		var response struct {
+			Backups []wsl.BackupInfo `json:"backups"`
+			Count   int              `json:"count"`
+		}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if response.Count != 1 {
+			t.Fatalf("expected count 1, got %d", response.Count)
+		}
+		if response.Backups[0].Manifest == nil || response.Backups[0].Manifest.Flavor != "ubuntu" {
+			t.Errorf("expected manifest in response, got %+v", response.Backups[0])
+		}
+	})
+}
+
+func TestHandlePruneBackups(t *testing.T) {
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, name := range []string{"Ubuntu-20240101-000000.tar.gz", "Ubuntu-20240301-000000.tar.gz"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		useBackupDir(t, dir)
+		return dir
+	}
+
+	t.Run("returns 405 for non-POST methods", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/backups/prune", nil)
+
+		srv.handlePruneBackups(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns 400 without a policy", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		setup(t)
+		body := `{}`
+		req := httptest.NewRequest("POST", "/api/backups/prune", strings.NewReader(body))
+
+		srv.handlePruneBackups(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("dry run reports without deleting", func(t *testing.T) {
+		setup(t)
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		body := `{"dryRun":true,"policy":{"keepLast":1}}`
+		req := httptest.NewRequest("POST", "/api/backups/prune", strings.NewReader(body))
+
+		srv.handlePruneBackups(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response struct {
+			Results    []wsl.PruneResult `json:"results"`
+			FreedBytes int64             `json:"freedBytes"`
+			DryRun     bool              `json:"dryRun"`
+		}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if len(response.Results) != 1 || !response.DryRun {
+			t.Fatalf("expected 1 dry-run result, got %+v", response)
+		}
+		if response.FreedBytes != int64(len("backup")) {
+			t.Errorf("expected freedBytes %d, got %d", len("backup"), response.FreedBytes)
+		}
+		if _, err := os.Stat(response.Results[0].FilePath); err != nil {
+			t.Error("expected backup to still exist after dry run")
+		}
+	})
+
+	t.Run("deletes old backups", func(t *testing.T) {
+		dir := setup(t)
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"backupDir":%q,"policy":{"keepLast":1}}`, dir+string(filepath.Separator))
+		req := httptest.NewRequest("POST", "/api/backups/prune", strings.NewReader(body))
+
+		srv.handlePruneBackups(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, err := os.Stat(filepath.Join(dir, "Ubuntu-20240101-000000.tar.gz")); !os.IsNotExist(err) {
+			t.Error("expected old backup to be deleted")
+		}
+	})
+
+	t.Run("refuses to prune another directory", func(t *testing.T) {
+		setup(t)
+		other := t.TempDir()
+		old := filepath.Join(other, "Ubuntu-20240101-000000.tar.gz")
+		for _, name := range []string{old, filepath.Join(other, "Ubuntu-20240301-000000.tar.gz")} {
+			if err := os.WriteFile(name, []byte("backup"), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"backupDir":%q,"policy":{"keepLast":1}}`, other)
+		req := httptest.NewRequest("POST", "/api/backups/prune", strings.NewReader(body))
+
+		srv.handlePruneBackups(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, err := os.Stat(old); err != nil {
+			t.Error("expected backups outside the backup directory to be kept")
+		}
+	})
+}
+
+// writeTestBackup writes a minimal tar.gz backup archive to dir and
+// returns its filename
+func writeTestBackup(t *testing.T, dir string) string {
+	t.Helper()
+
+	name := "Ubuntu-20240301-143022.tar.gz"
+	if err := os.WriteFile(filepath.Join(dir, name), testArchive(), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return name
+}
+
+// testArchive returns a minimal tar.gz backup of a distro
+func testArchive() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := "NAME=\"Ubuntu\"\n"
+	tw.WriteHeader(&tar.Header{Name: "./etc/os-release", Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestHandleBackupFiles(t *testing.T) {
+	newRequest := func(id, query string) *http.Request {
+		req := httptest.NewRequest("GET", "/api/backups/"+id+"/files?"+query, nil)
+		req.SetPathValue("id", id)
+		return req
+	}
+
+	t.Run("returns 405 for non-GET methods", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/backups/x/files", nil)
+		req.SetPathValue("id", "x")
+
+		srv.handleBackupFiles(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("lists files in a backup", func(t *testing.T) {
+		dir := t.TempDir()
+		id := writeTestBackup(t, dir)
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+
+		useBackupDir(t, dir)
+		srv.handleBackupFiles(rec, newRequest(id, "path=/etc"))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response struct {
+			Entries []wsl.BackupEntry `json:"entries"`
+			Count   int               `json:"count"`
+		}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if response.Count != 1 || response.Entries[0].Path != "/etc/os-release" {
+			t.Errorf("expected /etc/os-release, got %+v", response.Entries)
+		}
+	})
+
+	t.Run("downloads a file", func(t *testing.T) {
+		dir := t.TempDir()
+		id := writeTestBackup(t, dir)
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+
+		useBackupDir(t, dir)
+		srv.handleBackupFiles(rec, newRequest(id, "path=/etc/os-release&download=true"))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Body.String() != "NAME=\"Ubuntu\"\n" {
+			t.Errorf("unexpected content %q", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 404 for missing paths and backups", func(t *testing.T) {
+		dir := t.TempDir()
+		id := writeTestBackup(t, dir)
+		useBackupDir(t, dir)
+		srv := &Server{}
+
+		for _, req := range []*http.Request{
+			newRequest(id, "path=/nope"),
+			newRequest("missing.tar.gz", ""),
+		} {
+			rec := httptest.NewRecorder()
+			srv.handleBackupFiles(rec, req)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s: expected 404, got %d", req.URL, rec.Code)
+			}
+		}
+	})
+
+	t.Run("rejects IDs with path separators", func(t *testing.T) {
+		srv := &Server{}
+		rec := httptest.NewRecorder()
+
+		srv.handleBackupFiles(rec, newRequest("..", ""))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+}
+
+// handleRestore tests
+
+func TestHandleRestore(t *testing.T) {
+	newBackupDir := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, name := range []string{"Ubuntu-20240301-143022.tar.gz", "Debian-20240301-143022.tar.gz"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		return dir
+	}
+
+	t.Run("returns 405 for unsupported methods", func(t *testing.T) {
+		srv := &Server{restorer: &mockRestorer{}}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/api/restore", nil)
+
+		srv.handleRestore(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+	})
+
+	t.Run("lists backups filtered by distro", func(t *testing.T) {
+		useBackupDir(t, newBackupDir(t))
+		srv := &Server{restorer: &mockRestorer{}}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/restore?distro=Ubuntu", nil)
+
+		srv.handleRestore(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+
+		var response map[string]interface{}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if response["count"].(float64) != 1 {
+			t.Errorf("expected count 1, got %v", response["count"])
+		}
+	})
+
+	t.Run("returns 400 for invalid JSON", func(t *testing.T) {
+		srv := &Server{restorer: &mockRestorer{}}
+		rec := httptest.NewRecorder()
+		req := testRequest("POST", "/api/restore", []byte("{invalid"))
+
+		srv.handleRestore(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns 400 without distro or file", func(t *testing.T) {
+		srv := &Server{restorer: &mockRestorer{}}
+		rec := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]string{"newName": "Ubuntu2"})
+		req := testRequest("POST", "/api/restore", body)
+
+		srv.handleRestore(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns restore result on success", func(t *testing.T) {
+		srv := &Server{restorer: &mockRestorer{}}
+		rec := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]string{
+			"distro":     "Ubuntu",
+			"backupDir":  newBackupDir(t),
+			"installDir": t.TempDir(),
+		})
+		req := testRequest("POST", "/api/restore", body)
+
+		srv.handleRestore(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+
+		var response map[string]interface{}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if response["success"] != true {
+			t.Errorf("expected success true, got %v (%v)", response["success"], response["message"])
+		}
+	})
+
+	t.Run("refuses to overwrite registered distro", func(t *testing.T) {
+		srv := &Server{restorer: &mockRestorer{registered: true}}
+		rec := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]string{
+			"distro":    "Ubuntu",
+			"backupDir": newBackupDir(t),
+		})
+		req := testRequest("POST", "/api/restore", body)
+
+		srv.handleRestore(rec, req)
+
+		var response map[string]interface{}
+		parseJSONResponse(t, rec.Body.Bytes(), &response)
+
+		if response["success"] != false {
+			t.Errorf("expected success false, got %v", response["success"])
+		}
+	})
+}
+
 // handleWorkshops tests
 
 func TestHandleWorkshops(t *testing.T) {
@@ -726,15 +1403,26 @@ func TestShutdown(t *testing.T) {
 
 func TestNewServer(t *testing.T) {
 	t.Run("creates server with port", func(t *testing.T) {
-		srv := NewServer("8080")
+		srv := NewServer("127.0.0.1", "8080")
 
 		if srv.port != "8080" {
 			t.Errorf("expected port 8080, got %s", srv.port)
 		}
+		if srv.bind != "127.0.0.1" {
+			t.Errorf("expected bind 127.0.0.1, got %s", srv.bind)
+		}
+	})
+
+	t.Run("creates a different token for each server", func(t *testing.T) {
+		first, second := NewServer("127.0.0.1", "8080"), NewServer("127.0.0.1", "8080")
+
+		if len(first.token) != 64 || first.token == second.token {
+			t.Errorf("expected distinct random tokens, got %q and %q", first.token, second.token)
+		}
 	})
 
 	t.Run("initializes with default implementations", func(t *testing.T) {
-		srv := NewServer("8080")
+		srv := NewServer("127.0.0.1", "8080")
 
 		if srv.lister == nil {
 			t.Error("lister should not be nil")
@@ -760,6 +1448,9 @@ func TestNewServer(t *testing.T) {
 		if srv.copier == nil {
 			t.Error("copier should not be nil")
 		}
+		if srv.restorer == nil {
+			t.Error("restorer should not be nil")
+		}
 		if srv.workshopRunner == nil {
 			t.Error("workshopRunner should not be nil")
 		}
@@ -770,7 +1461,7 @@ func TestNewServer(t *testing.T) {
 
 	t.Run("allows DI field injection for testing", func(t *testing.T) {
 		mock := &mockLister{distros: []string{"Test"}}
-		srv := NewServer("8080")
+		srv := NewServer("127.0.0.1", "8080")
 		srv.lister = mock
 
 		if srv.lister != mock {
@@ -778,3 +1469,17 @@ func TestNewServer(t *testing.T) {
 		}
 	})
 }
+
+func TestNewSocketServer(t *testing.T) {
+	srv, err := NewSocketServer("npipe://wslp-dev")
+	if err != nil {
+		t.Fatalf("NewSocketServer failed: %v", err)
+	}
+	if srv.network != "npipe" || srv.socket != `\\.\pipe\wslp-dev` || srv.token != "" || srv.lister == nil {
+		t.Errorf("unexpected server %+v", srv)
+	}
+
+	if _, err := NewSocketServer("tcp://127.0.0.1:8080"); err == nil {
+		t.Error("expected an error for an unsupported address")
+	}
+}
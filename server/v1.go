@@ -0,0 +1,445 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"wslp/internal/config"
+	"wslp/internal/wsl"
+)
+
+// openAPISpec describes the /api/v1 routes. TestOpenAPISpec checks it
+// against v1Routes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// v1Route is an /api/v1 route, with a handler for each method it allows
+type v1Route struct {
+	pattern  string
+	handlers map[string]http.HandlerFunc
+}
+
+// v1Routes returns the /api/v1 routes. Many reuse the handlers of the
+// unversioned routes, which reply with ErrorResponse bodies when called
+// under /api/v1.
+func (s *Server) v1Routes() []v1Route {
+	return []v1Route{
+		{"/api/v1/distros", map[string]http.HandlerFunc{
+			http.MethodGet:  s.handleListDistros,
+			http.MethodPost: s.handleInstall,
+		}},
+		{"/api/v1/distros/{name}", map[string]http.HandlerFunc{
+			http.MethodGet:    s.handleV1GetDistro,
+			http.MethodPatch:  s.handleV1RenameDistro,
+			http.MethodDelete: s.handleV1UnregisterDistro,
+		}},
+		{"/api/v1/distros/{name}/terminate", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleV1TerminateDistro,
+		}},
+		{"/api/v1/distros/{name}/launch", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleV1LaunchDistro,
+		}},
+		{"/api/v1/distros/{name}/copy", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleV1CopyDistro,
+		}},
+		{"/api/v1/distros/{name}/backups", map[string]http.HandlerFunc{
+			http.MethodGet:  s.handleV1ListDistroBackups,
+			http.MethodPost: s.handleV1BackupDistro,
+		}},
+		{"/api/v1/distros/{name}/workshops", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleV1ListWorkshops,
+		}},
+		{"/api/v1/distros/{name}/workshops/{project}/{workshop}/{action}", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleV1WorkshopAction,
+		}},
+		{"/api/v1/default", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleGetDefault,
+			http.MethodPut: s.handleV1SetDefault,
+		}},
+		{"/api/v1/available", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleListAvailable,
+		}},
+		{"/api/v1/backups", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleListBackups,
+		}},
+		{"/api/v1/backups/prune", map[string]http.HandlerFunc{
+			http.MethodPost: s.handlePruneBackups,
+		}},
+		{"/api/v1/backups/{id}/files", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleBackupFiles,
+		}},
+		{"/api/v1/backups/{id}/restore", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleV1RestoreBackup,
+		}},
+		{"/api/v1/schedules", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleSchedules,
+		}},
+		{"/api/v1/schedules/{id}/run", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleRunSchedule,
+		}},
+		{"/api/v1/jobs", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleJobs,
+		}},
+		{"/api/v1/jobs/{id}", map[string]http.HandlerFunc{
+			http.MethodGet:    s.handleJob,
+			http.MethodDelete: s.handleJob,
+		}},
+		{"/api/v1/events", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleEvents,
+		}},
+		{"/api/v1/wsl", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleWSLInfo,
+		}},
+		{"/api/v1/settings/ubuntu-telemetry", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleUbuntuTelemetry,
+			http.MethodPut: s.handleV1SetUbuntuTelemetry,
+		}},
+		{"/api/v1/audit", map[string]http.HandlerFunc{
+			http.MethodGet: s.handleAudit,
+		}},
+		{"/api/v1/shutdown", map[string]http.HandlerFunc{
+			http.MethodPost: s.handleShutdown,
+		}},
+		{"/api/v1/openapi.json", map[string]http.HandlerFunc{
+			http.MethodGet: handleOpenAPI,
+		}},
+	}
+}
+
+// registerV1Routes adds the /api/v1 routes to mux. Anything else under
+// /api/v1 is not found.
+func (s *Server) registerV1Routes(mux *http.ServeMux) {
+	for _, route := range s.v1Routes() {
+		mux.Handle(route.pattern, route)
+	}
+	mux.HandleFunc(v1Prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, "Not found", http.StatusNotFound)
+	})
+}
+
+// ServeHTTP calls the route's handler for the request method
+func (route v1Route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := route.handlers[r.Method]
+	if !ok {
+		w.Header().Set("Allow", strings.Join(route.methods(), ", "))
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	handler(w, r)
+}
+
+// methods returns the methods the route allows, sorted
+func (route v1Route) methods() []string {
+	methods := make([]string, 0, len(route.handlers))
+	for method := range route.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// handleOpenAPI serves the OpenAPI document describing /api/v1
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// writeJSON replies with v encoded as JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult replies with the result of an operation on a distro, or
+// with an operation_failed error if WSL reported it failed
+func writeResult(w http.ResponseWriter, r *http.Request, result interface{}, success bool, message string) {
+	if !success {
+		writeErrorCode(w, r, CodeOperationFailed, message, failureStatus(r))
+		return
+	}
+	writeJSON(w, result)
+}
+
+// decodeBody decodes the JSON request body into v. An empty body leaves v
+// unchanged. If the body is invalid it replies with an error and returns
+// false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// handleV1GetDistro returns the details of a distro, or not_found if no
+// distro has that name
+func (s *Server) handleV1GetDistro(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	registered, err := s.registered(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err.Error(), failureStatus(r))
+		return
+	}
+	if !registered {
+		writeError(w, r, fmt.Sprintf("Distro %s is not registered", name), http.StatusNotFound)
+		return
+	}
+
+	info, err := wsl.GetDistroDetailInfo(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err.Error(), failureStatus(r))
+		return
+	}
+	writeJSON(w, info)
+}
+
+// registered reports whether a distro called name is registered. WSL
+// distro names are case-insensitive.
+func (s *Server) registered(ctx context.Context, name string) (bool, error) {
+	distros, err := s.lister.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, distro := range distros {
+		if strings.EqualFold(distro, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// handleV1RenameDistro renames a distro to the name in the body
+func (s *Server) handleV1RenameDistro(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.Name == "" {
+		writeError(w, r, "No new name specified", http.StatusBadRequest)
+		return
+	}
+
+	if !checkNotBusy(w, r, r.PathValue("name"), request.Name) {
+		return
+	}
+
+	result := wsl.RenameDistro(lockContext(r.Context(), waitForLocks(r)), s.renamer, r.PathValue("name"), request.Name)
+	writeResult(w, r, result, result.Success, result.Message)
+}
+
+// handleV1UnregisterDistro unregisters a distro, deleting its data
+func (s *Server) handleV1UnregisterDistro(w http.ResponseWriter, r *http.Request) {
+	if !checkNotBusy(w, r, r.PathValue("name")) {
+		return
+	}
+	result := wsl.UnregisterDistros(lockContext(r.Context(), waitForLocks(r)), s.unregisterer, []string{r.PathValue("name")})[0]
+	writeResult(w, r, result, result.Success, result.Message)
+}
+
+// handleV1TerminateDistro stops a running distro
+func (s *Server) handleV1TerminateDistro(w http.ResponseWriter, r *http.Request) {
+	if !checkNotBusy(w, r, r.PathValue("name")) {
+		return
+	}
+	result := wsl.TerminateDistros(lockContext(r.Context(), waitForLocks(r)), s.terminator, []string{r.PathValue("name")})[0]
+	s.metrics.observeOperation("terminate", result.Success)
+	writeResult(w, r, result, result.Success, result.Message)
+}
+
+// handleV1LaunchDistro opens a distro in a new terminal window
+func (s *Server) handleV1LaunchDistro(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := wsl.LaunchInTerminal(r.Context(), name); err != nil {
+		writeErrorCode(w, r, CodeOperationFailed, err.Error(), failureStatus(r))
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"distro":  name,
+		"success": true,
+		"message": fmt.Sprintf("Launched %s in new terminal", name),
+	})
+}
+
+// handleV1CopyDistro starts a job copying a distro
+func (s *Server) handleV1CopyDistro(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		NewName    string `json:"newName"`
+		InstallDir string `json:"installDir,omitempty"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.NewName == "" {
+		writeError(w, r, "No new name specified", http.StatusBadRequest)
+		return
+	}
+
+	s.startCopy(w, r, r.PathValue("name"), request.NewName, request.InstallDir)
+}
+
+// handleV1ListDistroBackups lists the backups of a distro
+func (s *Server) handleV1ListDistroBackups(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	query.Set("distro", r.PathValue("name"))
+	r.URL.RawQuery = query.Encode()
+	s.handleListBackups(w, r)
+}
+
+// handleV1BackupDistro starts a job backing up a distro
+func (s *Server) handleV1BackupDistro(w http.ResponseWriter, r *http.Request) {
+	var request backupRequest
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	request.Selector = wsl.Selector{Distros: []string{r.PathValue("name")}}
+
+	s.startBackup(w, r, request)
+}
+
+// handleV1ListWorkshops lists the Workshop environments in a distro
+func (s *Server) handleV1ListWorkshops(w http.ResponseWriter, r *http.Request) {
+	workshops := wsl.GetWorkshops(r.Context(), r.PathValue("name"), s.workshopRunner)
+	writeJSON(w, map[string]interface{}{
+		"workshops": workshops,
+	})
+}
+
+// handleV1WorkshopAction starts or stops a Workshop environment, or opens
+// a shell in it
+func (s *Server) handleV1WorkshopAction(w http.ResponseWriter, r *http.Request) {
+	distro, project, name := r.PathValue("name"), r.PathValue("project"), r.PathValue("workshop")
+
+	var err error
+	var message string
+	switch r.PathValue("action") {
+	case "start":
+		err = wsl.StartWorkshop(r.Context(), distro, project, name, s.workshopController)
+		message = fmt.Sprintf("Workshop %s started", name)
+	case "stop":
+		err = wsl.StopWorkshop(r.Context(), distro, project, name, s.workshopController)
+		message = fmt.Sprintf("Workshop %s stopped", name)
+	case "shell":
+		err = wsl.LaunchWorkshopShell(r.Context(), distro, project, name)
+		message = fmt.Sprintf("Launched shell for workshop %s", name)
+	default:
+		writeError(w, r, "Action must be start, stop or shell", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		writeErrorCode(w, r, CodeOperationFailed, err.Error(), failureStatus(r))
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
+
+// handleV1SetDefault sets the default distro to the name in the body
+func (s *Server) handleV1SetDefault(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.Name == "" {
+		writeError(w, r, "No distro name specified", http.StatusBadRequest)
+		return
+	}
+
+	if err := wsl.ChangeDefaultDistro(r.Context(), request.Name, s.defaultGetter, s.defaultSetter, config.GetStateDir()); err != nil {
+		writeErrorCode(w, r, CodeOperationFailed, err.Error(), failureStatus(r))
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"default": request.Name,
+	})
+}
+
+// handleV1RestoreBackup re-imports a backup
+func (s *Server) handleV1RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		NewName    string `json:"newName,omitempty"`
+		InstallDir string `json:"installDir,omitempty"`
+		Overwrite  bool   `json:"overwrite,omitempty"`
+		BackupDir  string `json:"backupDir,omitempty"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+
+	backupDir := request.BackupDir
+	if backupDir == "" {
+		backupDir = config.GetBackupDir()
+	}
+	id := r.PathValue("id")
+	archive, ok := findBackupArchive(w, r, id, backupDir)
+	if !ok {
+		return
+	}
+
+	// Restore under the backed up distro's name unless given a new one
+	distro := ""
+	if backups, err := wsl.ListBackups(backupDir); err == nil {
+		for _, b := range backups {
+			if b.ID == id {
+				distro = b.Distro
+			}
+		}
+	}
+	if distro == "" && request.NewName == "" {
+		writeError(w, r, "A new name is required to restore a backup of an unknown distro", http.StatusBadRequest)
+		return
+	}
+
+	opts := wsl.RestoreOptions{
+		File:       archive,
+		NewName:    request.NewName,
+		InstallDir: request.InstallDir,
+		Overwrite:  request.Overwrite,
+	}
+	newName := request.NewName
+	if newName == "" {
+		newName = distro
+	}
+	if !checkNotBusy(w, r, newName) {
+		return
+	}
+	result := wsl.RestoreDistro(lockContext(r.Context(), waitForLocks(r)), s.restorer, distro, backupDir, opts)
+	writeResult(w, r, result, result.Success, result.Message)
+}
+
+// handleV1SetUbuntuTelemetry turns Ubuntu telemetry on or off
+func (s *Server) handleV1SetUbuntuTelemetry(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.Enabled == nil {
+		writeError(w, r, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	err := wsl.SetUbuntuTelemetryStatus(*request.Enabled)
+	recordTelemetryChange(r.Context(), *request.Enabled, err)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"enabled": *request.Enabled,
+	})
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// openAPIDoc is the part of the OpenAPI document the tests check
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters []openAPIParameter          `json:"parameters"`
+	Responses  map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref  string `json:"$ref"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+type openAPIResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *openAPISchema `json:"schema"`
+	} `json:"content"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Required   []string                  `json:"required"`
+}
+
+// loadOpenAPI parses the embedded OpenAPI document
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+// operation returns the operation for method on path, failing the test if
+// the document has none
+func (doc openAPIDoc) operation(t *testing.T, path, method string) openAPIOperation {
+	t.Helper()
+
+	raw, ok := doc.Paths[path][strings.ToLower(method)]
+	if !ok {
+		t.Fatalf("OpenAPI document has no %s %s", method, path)
+	}
+	var op openAPIOperation
+	if err := json.Unmarshal(raw, &op); err != nil {
+		t.Fatalf("invalid operation %s %s: %v", method, path, err)
+	}
+	return op
+}
+
+// resolve follows a schema's $ref
+func (doc openAPIDoc) resolve(t *testing.T, schema *openAPISchema) *openAPISchema {
+	t.Helper()
+
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+	resolved, ok := doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	if !ok {
+		t.Fatalf("unresolved schema %s", schema.Ref)
+	}
+	return resolved
+}
+
+// responseSchema returns the JSON schema documented for a response with
+// status, falling back to the default (error) response
+func (doc openAPIDoc) responseSchema(t *testing.T, path, method string, status int) *openAPISchema {
+	t.Helper()
+
+	responses := doc.operation(t, path, method).Responses
+	response, ok := responses[strconv.Itoa(status)]
+	if !ok {
+		if status < 400 {
+			t.Fatalf("OpenAPI document has no %d response for %s %s", status, method, path)
+		}
+		response = responses["default"]
+	}
+
+	switch response.Ref {
+	case "#/components/responses/Error":
+		return doc.Components.Schemas["Error"]
+	case "#/components/responses/JobAccepted":
+		return doc.Components.Schemas["Job"]
+	}
+	return doc.resolve(t, response.Content["application/json"].Schema)
+}
+
+// checkSchema checks a JSON object has the schema's required properties
+// and no undocumented ones
+func (doc openAPIDoc) checkSchema(t *testing.T, schema *openAPISchema, body []byte) {
+	t.Helper()
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("expected a JSON object, got %s", body)
+	}
+	for _, name := range schema.Required {
+		if _, ok := got[name]; !ok {
+			t.Errorf("response is missing required property %q: %s", name, body)
+		}
+	}
+	for name := range got {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("response has undocumented property %q: %s", name, body)
+		}
+	}
+}
+
+// newV1Handler returns a handler serving srv's /api/v1 routes
+func newV1Handler(srv *Server) http.Handler {
+	mux := http.NewServeMux()
+	srv.registerV1Routes(mux)
+	return mux
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	doc := loadOpenAPI(t)
+	srv := &Server{}
+	pathParam := regexp.MustCompile(`\{(\w+)\}`)
+
+	routes := map[string]bool{}
+	for _, route := range srv.v1Routes() {
+		path := strings.TrimPrefix(route.pattern, "/api/v1")
+		routes[path] = true
+
+		var documented []string
+		for key := range doc.Paths[path] {
+			if key != "parameters" {
+				documented = append(documented, strings.ToUpper(key))
+			}
+		}
+		sort.Strings(documented)
+		if strings.Join(documented, ",") != strings.Join(route.methods(), ",") {
+			t.Errorf("%s: handled methods %v, documented %v", path, route.methods(), documented)
+		}
+
+		// Every path parameter must be documented on the path or each
+		// operation
+		var shared []openAPIParameter
+		json.Unmarshal(doc.Paths[path]["parameters"], &shared)
+		for _, method := range route.methods() {
+			params := append(shared, doc.operation(t, path, method).Parameters...)
+			for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+				found := false
+				for _, p := range params {
+					if (p.In == "path" && p.Name == match[1]) ||
+						(match[1] == "name" && p.Ref == "#/components/parameters/DistroName") ||
+						(match[1] == "id" && p.Ref == "#/components/parameters/BackupID") {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("%s %s: path parameter %s is not documented", method, path, match[1])
+				}
+			}
+		}
+	}
+
+	for path := range doc.Paths {
+		if !routes[path] {
+			t.Errorf("%s is documented but not handled", path)
+		}
+	}
+
+	// Every reference must resolve
+	refs := regexp.MustCompile(`"\$ref":\s*"#/components/(\w+)/(\w+)"`)
+	var raw struct {
+		Components map[string]map[string]json.RawMessage `json:"components"`
+	}
+	json.Unmarshal(openAPISpec, &raw)
+	for _, match := range refs.FindAllStringSubmatch(string(openAPISpec), -1) {
+		if _, ok := raw.Components[match[1]][match[2]]; !ok {
+			t.Errorf("unresolved reference #/components/%s/%s", match[1], match[2])
+		}
+	}
+}
+
+func TestV1Routes(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	tests := []struct {
+		name   string
+		srv    *Server
+		method string
+		path   string
+		body   string
+		want   int
+		// spec is the documented path, if it differs from path
+		spec string
+		code string
+	}{
+		{
+			name:   "lists distros",
+			srv:    &Server{lister: &mockLister{distros: []string{"Ubuntu"}}},
+			method: "GET", path: "/distros", want: http.StatusOK,
+		},
+		{
+			name:   "reports list failures",
+			srv:    &Server{lister: &mockLister{err: errors.New("wsl unavailable")}},
+			method: "GET", path: "/distros", want: http.StatusInternalServerError, code: CodeInternal,
+		},
+		{
+			name:   "rejects install without distros",
+			srv:    &Server{jobs: newTestJobManager(t)},
+			method: "POST", path: "/distros", body: `{"distros":[]}`, want: http.StatusBadRequest, code: CodeInvalidRequest,
+		},
+		{
+			name:   "unregisters a distro",
+			srv:    &Server{unregisterer: &mockUnregisterer{registered: true}},
+			method: "DELETE", path: "/distros/Ubuntu", spec: "/distros/{name}", want: http.StatusOK,
+		},
+		{
+			name:   "reports failed unregister",
+			srv:    &Server{unregisterer: &mockUnregisterer{registered: false}},
+			method: "DELETE", path: "/distros/Ubuntu", spec: "/distros/{name}", want: http.StatusInternalServerError, code: CodeOperationFailed,
+		},
+		{
+			name:   "terminates a distro",
+			srv:    &Server{terminator: &mockTerminator{registered: true}},
+			method: "POST", path: "/distros/Ubuntu/terminate", spec: "/distros/{name}/terminate", want: http.StatusOK,
+		},
+		{
+			name:   "reports unknown distros",
+			srv:    &Server{lister: &mockLister{distros: []string{"Debian"}}},
+			method: "GET", path: "/distros/Ubuntu", spec: "/distros/{name}", want: http.StatusNotFound, code: CodeNotFound,
+		},
+		{
+			name:   "reports failure to list distros",
+			srv:    &Server{lister: &mockLister{err: errors.New("wsl not available")}},
+			method: "GET", path: "/distros/Ubuntu", spec: "/distros/{name}", want: http.StatusInternalServerError, code: CodeInternal,
+		},
+		{
+			name:   "rejects rename without a name",
+			srv:    &Server{renamer: &mockRenamer{registered: true}},
+			method: "PATCH", path: "/distros/Ubuntu", spec: "/distros/{name}", body: `{}`, want: http.StatusBadRequest, code: CodeInvalidRequest,
+		},
+		{
+			name:   "copies a distro in a job",
+			srv:    &Server{copier: &mockCopier{registered: true}, jobs: newTestJobManager(t)},
+			method: "POST", path: "/distros/Ubuntu/copy", spec: "/distros/{name}/copy", body: `{"newName":"Ubuntu-copy"}`, want: http.StatusAccepted,
+		},
+		{
+			name:   "gets the default distro",
+			srv:    &Server{defaultGetter: &mockDefaultGetter{defaultDistro: "Ubuntu"}},
+			method: "GET", path: "/default", want: http.StatusOK,
+		},
+		{
+			name:   "sets the default distro",
+			srv:    &Server{defaultGetter: &mockDefaultGetter{}, defaultSetter: &mockDefaultSetter{registered: true}},
+			method: "PUT", path: "/default", body: `{"name":"Ubuntu"}`, want: http.StatusOK,
+		},
+		{
+			name:   "rejects an unknown workshop action",
+			srv:    &Server{},
+			method: "POST", path: "/distros/Ubuntu/workshops/p/w/restart", spec: "/distros/{name}/workshops/{project}/{workshop}/{action}", want: http.StatusNotFound, code: CodeNotFound,
+		},
+		{
+			name:   "lists jobs",
+			srv:    &Server{jobs: newTestJobManager(t)},
+			method: "GET", path: "/jobs", want: http.StatusOK,
+		},
+		{
+			name:   "reports unknown jobs",
+			srv:    &Server{jobs: newTestJobManager(t)},
+			method: "GET", path: "/jobs/missing", spec: "/jobs/{id}", want: http.StatusNotFound, code: CodeJobNotFound,
+		},
+		{
+			name:   "rejects invalid backup IDs",
+			srv:    &Server{},
+			method: "POST", path: "/backups/..%2Fsecret/restore", spec: "/backups/{id}/restore", want: http.StatusBadRequest, code: CodeInvalidRequest,
+		},
+		{
+			name:   "rejects methods a route doesn't allow",
+			srv:    &Server{},
+			method: "DELETE", path: "/default", want: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newV1Handler(tc.srv).ServeHTTP(rec, testRequest(tc.method, "/api/v1"+tc.path, []byte(tc.body)))
+
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+
+			spec := tc.spec
+			if spec == "" {
+				spec = tc.path
+			}
+			if tc.code != "" {
+				var response ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Error.Code != tc.code {
+					t.Errorf("expected error code %s, got %s", tc.code, rec.Body.String())
+				}
+				doc.checkSchema(t, doc.Components.Schemas["Error"], rec.Body.Bytes())
+				return
+			}
+			doc.checkSchema(t, doc.responseSchema(t, spec, tc.method, rec.Code), rec.Body.Bytes())
+			if rec.Code == http.StatusAccepted && !strings.HasPrefix(rec.Header().Get("Location"), "/api/v1/jobs/") {
+				t.Errorf("expected the job's /api/v1 location, got %q", rec.Header().Get("Location"))
+			}
+		})
+	}
+
+	t.Run("returns not found for unknown routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newV1Handler(&Server{}).ServeHTTP(rec, testRequest("GET", "/api/v1/nothing", nil))
+
+		var response ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		if rec.Code != http.StatusNotFound || response.Error.Code != CodeNotFound {
+			t.Errorf("expected not_found, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("lists allowed methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newV1Handler(&Server{}).ServeHTTP(rec, testRequest("POST", "/api/v1/jobs/abc", nil))
+
+		if allow := rec.Header().Get("Allow"); allow != "DELETE, GET" {
+			t.Errorf("expected Allow: DELETE, GET, got %q", allow)
+		}
+	})
+
+	t.Run("serves the OpenAPI document", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newV1Handler(&Server{}).ServeHTTP(rec, testRequest("GET", "/api/v1/openapi.json", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != string(openAPISpec) {
+			t.Errorf("expected the OpenAPI document, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"wslp/internal/wsl"
+)
+
+// watchInterval is how often distro state is polled while anyone is
+// listening for events
+const watchInterval = 2 * time.Second
+
+// distroSnapshot is the state of the distros at one point in time
+type distroSnapshot struct {
+	// names holds the distro names in the order WSL lists them
+	names         []string
+	states        map[string]string
+	defaultDistro string
+}
+
+// watchDistros polls the distros until ctx is cancelled, publishing
+// distro.* and default.changed events when they change. Nothing is polled
+// while there are no subscribers, so an idle server doesn't keep calling
+// into WSL.
+func (s *Server) watchDistros(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *distroSnapshot
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if s.events.Subscribers() == 0 {
+			// Start afresh once someone subscribes, rather than reporting
+			// changes made while nobody was listening
+			last = nil
+			continue
+		}
+		last = s.pollDistros(ctx, last)
+	}
+}
+
+// pollDistros takes a snapshot of the distros and publishes how it differs
+// from last, returning the new snapshot. If the distros can't be listed,
+// last is kept so the changes are picked up by the next poll.
+func (s *Server) pollDistros(ctx context.Context, last *distroSnapshot) *distroSnapshot {
+	distros, err := wsl.ListDistros(ctx, s.lister)
+	if err != nil {
+		return last
+	}
+
+	snap := &distroSnapshot{states: make(map[string]string, len(distros))}
+	for _, d := range distros {
+		snap.names = append(snap.names, d.Name)
+		snap.states[d.Name] = d.State
+	}
+
+	// A failure to get the default keeps the previous one, rather than
+	// reporting it as changed
+	snap.defaultDistro, err = wsl.GetDefaultDistro(ctx, s.defaultGetter)
+	if err != nil && last != nil {
+		snap.defaultDistro = last.defaultDistro
+	}
+
+	if last != nil {
+		for _, event := range diffDistros(*last, *snap) {
+			s.events.Publish(event.Type, event.Data)
+		}
+	}
+	return snap
+}
+
+// diffDistros returns the events describing the changes from prev to cur
+func diffDistros(prev, cur distroSnapshot) []Event {
+	var events []Event
+
+	for _, name := range cur.names {
+		state := cur.states[name]
+		previous, existed := prev.states[name]
+		switch {
+		case !existed:
+			events = append(events, Event{Type: "distro.added", Data: DistroEvent{Name: name, State: state}})
+		case previous != state:
+			events = append(events, Event{Type: "distro.state", Data: DistroEvent{Name: name, State: state, Previous: previous}})
+		}
+	}
+
+	for _, name := range prev.names {
+		if _, exists := cur.states[name]; !exists {
+			events = append(events, Event{Type: "distro.removed", Data: DistroEvent{Name: name, Previous: prev.states[name]}})
+		}
+	}
+
+	if prev.defaultDistro != cur.defaultDistro {
+		events = append(events, Event{Type: "default.changed", Data: DefaultEvent{Default: cur.defaultDistro, Previous: prev.defaultDistro}})
+	}
+
+	return events
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDiffDistros(t *testing.T) {
+	prev := distroSnapshot{
+		names:         []string{"Ubuntu", "Debian"},
+		states:        map[string]string{"Ubuntu": "Stopped", "Debian": "Stopped"},
+		defaultDistro: "Ubuntu",
+	}
+
+	tests := map[string]struct {
+		cur  distroSnapshot
+		want []Event
+	}{
+		"no changes": {prev, nil},
+		"state change": {
+			distroSnapshot{
+				names:         []string{"Ubuntu", "Debian"},
+				states:        map[string]string{"Ubuntu": "Running", "Debian": "Stopped"},
+				defaultDistro: "Ubuntu",
+			},
+			[]Event{{Type: "distro.state", Data: DistroEvent{Name: "Ubuntu", State: "Running", Previous: "Stopped"}}},
+		},
+		"added, removed and new default": {
+			distroSnapshot{
+				names:         []string{"Ubuntu", "Alpine"},
+				states:        map[string]string{"Ubuntu": "Stopped", "Alpine": "Installing"},
+				defaultDistro: "Alpine",
+			},
+			[]Event{
+				{Type: "distro.added", Data: DistroEvent{Name: "Alpine", State: "Installing"}},
+				{Type: "distro.removed", Data: DistroEvent{Name: "Debian", Previous: "Stopped"}},
+				{Type: "default.changed", Data: DefaultEvent{Default: "Alpine", Previous: "Ubuntu"}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := diffDistros(prev, tc.cur)
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPollDistros(t *testing.T) {
+	ctx := context.Background()
+	b := NewEventBroker()
+	events, _, unsubscribe := b.Subscribe(0)
+	defer unsubscribe()
+
+	lister := &mockLister{distros: []string{"Ubuntu"}}
+	getter := &mockDefaultGetter{defaultDistro: "Ubuntu"}
+	srv := &Server{lister: lister, defaultGetter: getter, events: b}
+
+	// The first poll only records the starting point
+	snap := srv.pollDistros(ctx, nil)
+	if received := drainEvents(events); len(received) != 0 {
+		t.Errorf("expected no events from the first poll, got %+v", received)
+	}
+
+	lister.distros = []string{"Ubuntu", "Debian"}
+	getter.defaultDistro = "Debian"
+	snap = srv.pollDistros(ctx, snap)
+
+	var types []string
+	for _, event := range drainEvents(events) {
+		types = append(types, event.Type)
+	}
+	if fmt.Sprint(types) != "[distro.added default.changed]" {
+		t.Errorf("expected distro.added and default.changed, got %v", types)
+	}
+
+	// Errors keep the last snapshot rather than reporting everything as
+	// removed
+	lister.err = errors.New("wsl unavailable")
+	if got := srv.pollDistros(ctx, snap); got != snap {
+		t.Error("expected the last snapshot to be kept")
+	}
+	if received := drainEvents(events); len(received) != 0 {
+		t.Errorf("expected no events on error, got %+v", received)
+	}
+}